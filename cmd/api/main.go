@@ -1,15 +1,19 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	deviceModel "Backend_Lili/internal/device/model"
+	deviceService "Backend_Lili/internal/device/service"
 	priceModel "Backend_Lili/internal/price/model"
+	reminderModel "Backend_Lili/internal/reminder/model"
 	"Backend_Lili/internal/router"
 	"Backend_Lili/internal/user/model"
 
 	"github.com/beego/beego/v2/client/orm"
 	beego "github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/task"
 	_ "github.com/go-sql-driver/mysql"
 )
 
@@ -27,11 +31,17 @@ func init() {
 		log.Fatalf("数据库初始化失败: %v", err)
 	}
 
-	// 3. 注册路由
+	// 3. 改写旧语法的模板验证规则
+	migrateTemplateRules()
+
+	// 4. 注册路由
 	if err := registerRoutes(); err != nil {
 		log.Fatalf("路由注册失败: %v", err)
 	}
 
+	// 5. 注册定时任务
+	registerTasks()
+
 	log.Println("=== 初始化完成 ===")
 }
 
@@ -88,10 +98,31 @@ func initDatabase() error {
 	priceModel.Init()
 	log.Println("价格模块数据模型初始化完成")
 
+	// 初始化提醒模块数据模型
+	reminderModel.Init()
+	log.Println("提醒模块数据模型初始化完成")
+
 	log.Println("数据库初始化成功")
 	return nil
 }
 
+// migrateTemplateRules 把旧语法保存的模板验证规则改写为当前语法，失败时只记录日志，下次启动重试
+func migrateTemplateRules() {
+	report, err := deviceService.NewTemplateService().MigrateLegacyRules()
+	if err != nil {
+		log.Printf("改写模板验证规则失败: %v", err)
+	}
+	if report == nil {
+		return
+	}
+	if report.Templates > 0 || report.Versions > 0 {
+		log.Printf("已把 %d 个模板、%d 个模板字段版本的验证规则改写为当前语法", report.Templates, report.Versions)
+	}
+	for _, problem := range report.Problems {
+		log.Printf("模板验证规则需要人工检查: %s", problem)
+	}
+}
+
 // registerRoutes 注册路由
 func registerRoutes() error {
 	log.Println("正在注册路由...")
@@ -103,6 +134,25 @@ func registerRoutes() error {
 	return nil
 }
 
+// registerTasks 注册定时任务
+func registerTasks() {
+	log.Println("正在注册定时任务...")
+
+	// 每天凌晨3点彻底删除回收站中超过保留天数的设备
+	purgeRecycleBin := task.NewTask("purge_recycle_bin", "0 0 3 * * *", func(ctx context.Context) error {
+		purged, err := deviceService.NewDeviceService().PurgeExpiredDevices()
+		if err != nil {
+			log.Printf("清理回收站失败: %v", err)
+			return err
+		}
+		log.Printf("清理回收站完成，彻底删除设备 %d 台", purged)
+		return nil
+	})
+	task.AddTask("purge_recycle_bin", purgeRecycleBin)
+
+	log.Println("定时任务注册成功")
+}
+
 // main 主函数
 func main() {
 	log.Println("=== 启动Web服务器 ===")
@@ -132,5 +182,8 @@ func main() {
 		log.Printf("生产模式：服务器将监听所有网络接口 (0.0.0.0:%s)", port)
 	}
 
+	task.StartTask()
+	defer task.StopTask()
+
 	beego.Run()
 }
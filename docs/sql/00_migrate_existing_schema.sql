@@ -56,3 +56,212 @@ PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
 SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'price_histories' AND COLUMN_NAME = 'platform');
 SET @sql := IF(@c = 0, 'ALTER TABLE price_histories ADD COLUMN platform VARCHAR(50) NULL', 'SELECT 1');
 PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+-- ========== DEVICE_IMAGES 表补齐字段 ==========
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_images' AND COLUMN_NAME = 'storage_key');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_images ADD COLUMN storage_key VARCHAR(500) NULL', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_images' AND COLUMN_NAME = 'content_hash');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_images ADD COLUMN content_hash VARCHAR(64) NULL', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_images' AND COLUMN_NAME = 'content_type');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_images ADD COLUMN content_type VARCHAR(50) NULL', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_images' AND COLUMN_NAME = 'width');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_images ADD COLUMN width INT NOT NULL DEFAULT 0', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_images' AND COLUMN_NAME = 'height');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_images ADD COLUMN height INT NOT NULL DEFAULT 0', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_images' AND COLUMN_NAME = 'byte_size');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_images ADD COLUMN byte_size BIGINT NOT NULL DEFAULT 0', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_images' AND COLUMN_NAME = 'variants');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_images ADD COLUMN variants JSON NULL', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_images' AND COLUMN_NAME = 'caption');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_images ADD COLUMN caption VARCHAR(500) NULL', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+-- ========== DEVICE_ATTACHMENTS 表补齐字段 ==========
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_attachments' AND COLUMN_NAME = 'service_record_id');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_attachments ADD COLUMN service_record_id INT NULL', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+-- ========== DEVICES 表补齐字段 ==========
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'devices' AND COLUMN_NAME = 'parent_id');
+SET @sql := IF(@c = 0, 'ALTER TABLE devices ADD COLUMN parent_id INT NULL AFTER category_id', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+-- ========== 乐观锁版本号 ==========
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'devices' AND COLUMN_NAME = 'version');
+SET @sql := IF(@c = 0, 'ALTER TABLE devices ADD COLUMN version INT NOT NULL DEFAULT 1', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'price_alerts' AND COLUMN_NAME = 'version');
+SET @sql := IF(@c = 0, 'ALTER TABLE price_alerts ADD COLUMN version INT NOT NULL DEFAULT 1', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'categories' AND COLUMN_NAME = 'version');
+SET @sql := IF(@c = 0, 'ALTER TABLE categories ADD COLUMN version INT NOT NULL DEFAULT 1', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_templates' AND COLUMN_NAME = 'version');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_templates ADD COLUMN version INT NOT NULL DEFAULT 1', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+-- ========== 模板字段版本 ==========
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_templates' AND COLUMN_NAME = 'fields_version');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_templates ADD COLUMN fields_version INT NOT NULL DEFAULT 1', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'devices' AND COLUMN_NAME = 'template_version');
+SET @sql := IF(@c = 0, 'ALTER TABLE devices ADD COLUMN template_version INT NOT NULL DEFAULT 0', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+-- 已有模板的当前字段定义记为版本1，已有设备视为基于该版本创建（可重复执行）
+INSERT INTO device_template_versions (template_id, version, fields, created_at)
+SELECT t.id, t.fields_version, t.fields, NOW() FROM device_templates t
+WHERE NOT EXISTS (SELECT 1 FROM device_template_versions v WHERE v.template_id = t.id);
+
+UPDATE devices d JOIN device_templates t ON t.id = d.template_id
+SET d.template_version = t.fields_version
+WHERE d.template_version = 0;
+
+-- ========== 验证规则语法版本 ==========
+-- 已有模板与字段版本的验证规则按旧语法保存（rule_syntax = 1，min/max 表示长度），
+-- 服务启动时改写为当前语法并置为 2；之后新增的记录默认为当前语法
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_templates' AND COLUMN_NAME = 'rule_syntax');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_templates ADD COLUMN rule_syntax INT NOT NULL DEFAULT 1', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+ALTER TABLE device_templates ALTER COLUMN rule_syntax SET DEFAULT 2;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_template_versions' AND COLUMN_NAME = 'rule_syntax');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_template_versions ADD COLUMN rule_syntax INT NOT NULL DEFAULT 1', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+ALTER TABLE device_template_versions ALTER COLUMN rule_syntax SET DEFAULT 2;
+
+-- ========== 模板共享与审核 ==========
+-- 已有模板均为系统模板（owner_id 为空），默认公开且已审核
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_templates' AND COLUMN_NAME = 'owner_id');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_templates ADD COLUMN owner_id INT NULL', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_templates' AND COLUMN_NAME = 'visibility');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_templates ADD COLUMN visibility VARCHAR(20) NOT NULL DEFAULT ''public''', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_templates' AND COLUMN_NAME = 'review_status');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_templates ADD COLUMN review_status VARCHAR(20) NOT NULL DEFAULT ''approved''', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_templates' AND COLUMN_NAME = 'review_note');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_templates ADD COLUMN review_note VARCHAR(500) NULL', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_templates' AND COLUMN_NAME = 'reviewed_at');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_templates ADD COLUMN reviewed_at DATETIME NULL', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_templates' AND COLUMN_NAME = 'forked_from_id');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_templates ADD COLUMN forked_from_id INT NULL', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_templates' AND COLUMN_NAME = 'fork_count');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_templates ADD COLUMN fork_count INT NOT NULL DEFAULT 0', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_templates' AND COLUMN_NAME = 'rating_count');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_templates ADD COLUMN rating_count INT NOT NULL DEFAULT 0', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'device_templates' AND COLUMN_NAME = 'rating_avg');
+SET @sql := IF(@c = 0, 'ALTER TABLE device_templates ADD COLUMN rating_avg DECIMAL(3,2) NOT NULL DEFAULT 0', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+CREATE TABLE IF NOT EXISTS template_ratings (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  template_id INT NOT NULL,
+  user_id INT NOT NULL,
+  score TINYINT NOT NULL,
+  comment VARCHAR(500) NULL,
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL,
+  UNIQUE KEY uk_template_rating_user (template_id, user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- ========== 设备规格参数索引 ==========
+-- 建表后调用 POST /api/v1/devices/specs/reindex 为已有设备建立索引
+-- 设备规格参数索引（由规格参数写入时重建）
+CREATE TABLE IF NOT EXISTS device_spec_values (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  device_id INT NOT NULL,
+  user_id INT NOT NULL,
+  template_id INT NULL,
+  field_name VARCHAR(100) NOT NULL,
+  field_type VARCHAR(20) NOT NULL,
+  value_text VARCHAR(255) NOT NULL DEFAULT '',
+  value_num DOUBLE NULL,
+  dimension VARCHAR(20) NOT NULL DEFAULT '',
+  unit_factor DOUBLE NOT NULL DEFAULT 1,
+  value_date DATE NULL,
+  CONSTRAINT fk_device_spec_values_device FOREIGN KEY (device_id) REFERENCES devices(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- ========== 系统分类的个人设置 ==========
+-- 用户对系统分类的个人设置（隐藏、排序）
+CREATE TABLE IF NOT EXISTS user_category_prefs (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  user_id INT NOT NULL,
+  category_id INT NOT NULL,
+  hidden TINYINT(1) NOT NULL DEFAULT 0,
+  sort_order INT NULL,
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL,
+  UNIQUE KEY uk_user_category_pref (user_id, category_id),
+  CONSTRAINT fk_user_category_prefs_category FOREIGN KEY (category_id) REFERENCES categories(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- ========== 自动分类规则 ==========
+-- 自动分类规则，user_id 为空的是系统规则
+CREATE TABLE IF NOT EXISTS category_rules (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  user_id INT NULL,
+  name VARCHAR(100) NOT NULL,
+  brands VARCHAR(500) NULL,
+  model_keywords VARCHAR(500) NULL,
+  name_keywords VARCHAR(500) NULL,
+  pattern VARCHAR(255) NULL,
+  category_id INT NULL,
+  template_id INT NULL,
+  priority INT NOT NULL DEFAULT 0,
+  is_active TINYINT(1) NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- ========== REMINDERS 表补齐字段 ==========
+-- 按月/年重复的日；已有提醒为0，按 due_date 的日计算
+SET @t := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'reminders');
+SET @c := (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'reminders' AND COLUMN_NAME = 'anchor_day');
+SET @sql := IF(@t = 1 AND @c = 0, 'ALTER TABLE reminders ADD COLUMN anchor_day INT NOT NULL DEFAULT 0', 'SELECT 1');
+PREPARE s FROM @sql; EXECUTE s; DEALLOCATE PREPARE s;
+
+-- ========== 设备二维码 ==========
+-- 设备二维码（扫码查找设备的 token）
+CREATE TABLE IF NOT EXISTS device_qr_codes (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  device_id INT NOT NULL,
+  user_id INT NOT NULL,
+  token VARCHAR(64) NOT NULL,
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL,
+  CONSTRAINT fk_device_qr_codes_device FOREIGN KEY (device_id) REFERENCES devices(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -70,11 +70,42 @@ CREATE TABLE IF NOT EXISTS categories (
   type VARCHAR(20) NOT NULL DEFAULT 'system',
   user_id INT NULL,
   is_active TINYINT(1) NOT NULL DEFAULT 1,
+  version INT NOT NULL DEFAULT 1,
   created_at DATETIME NOT NULL,
   updated_at DATETIME NOT NULL,
   deleted_at DATETIME NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+-- 用户对系统分类的个人设置（隐藏、排序）
+CREATE TABLE IF NOT EXISTS user_category_prefs (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  user_id INT NOT NULL,
+  category_id INT NOT NULL,
+  hidden TINYINT(1) NOT NULL DEFAULT 0,
+  sort_order INT NULL,
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL,
+  UNIQUE KEY uk_user_category_pref (user_id, category_id),
+  CONSTRAINT fk_user_category_prefs_category FOREIGN KEY (category_id) REFERENCES categories(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 自动分类规则，user_id 为空的是系统规则
+CREATE TABLE IF NOT EXISTS category_rules (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  user_id INT NULL,
+  name VARCHAR(100) NOT NULL,
+  brands VARCHAR(500) NULL,
+  model_keywords VARCHAR(500) NULL,
+  name_keywords VARCHAR(500) NULL,
+  pattern VARCHAR(255) NULL,
+  category_id INT NULL,
+  template_id INT NULL,
+  priority INT NOT NULL DEFAULT 0,
+  is_active TINYINT(1) NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 CREATE TABLE IF NOT EXISTS device_templates (
   id INT PRIMARY KEY AUTO_INCREMENT,
   name VARCHAR(100) NOT NULL,
@@ -84,16 +115,53 @@ CREATE TABLE IF NOT EXISTS device_templates (
   fields JSON NOT NULL,
   is_active TINYINT(1) NOT NULL DEFAULT 1,
   use_count INT NOT NULL DEFAULT 0,
+  owner_id INT NULL,
+  visibility VARCHAR(20) NOT NULL DEFAULT 'public',
+  review_status VARCHAR(20) NOT NULL DEFAULT 'approved',
+  review_note VARCHAR(500) NULL,
+  reviewed_at DATETIME NULL,
+  forked_from_id INT NULL,
+  fork_count INT NOT NULL DEFAULT 0,
+  rating_count INT NOT NULL DEFAULT 0,
+  rating_avg DECIMAL(3,2) NOT NULL DEFAULT 0,
+  fields_version INT NOT NULL DEFAULT 1,
+  version INT NOT NULL DEFAULT 1,
+  rule_syntax INT NOT NULL DEFAULT 2,
   created_at DATETIME NOT NULL,
   updated_at DATETIME NOT NULL,
   deleted_at DATETIME NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+-- 模板字段定义历史版本（只增不改，仅旧语法的验证规则在启动时改写一次）
+CREATE TABLE IF NOT EXISTS device_template_versions (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  template_id INT NOT NULL,
+  version INT NOT NULL,
+  fields JSON NOT NULL,
+  rule_syntax INT NOT NULL DEFAULT 2,
+  created_by INT NULL,
+  created_at DATETIME NOT NULL,
+  UNIQUE KEY uk_template_version (template_id, version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 模板评分，每个用户对每个模板一条
+CREATE TABLE IF NOT EXISTS template_ratings (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  template_id INT NOT NULL,
+  user_id INT NOT NULL,
+  score TINYINT NOT NULL,
+  comment VARCHAR(500) NULL,
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL,
+  UNIQUE KEY uk_template_rating_user (template_id, user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 CREATE TABLE IF NOT EXISTS devices (
   id INT PRIMARY KEY AUTO_INCREMENT,
   user_id INT NULL,
   template_id INT NULL,
   category_id INT NULL,
+  parent_id INT NULL,
   name VARCHAR(200) NOT NULL,
   brand VARCHAR(100) NOT NULL,
   model VARCHAR(100) NOT NULL,
@@ -113,6 +181,8 @@ CREATE TABLE IF NOT EXISTS devices (
   sale_date DATE NULL,
   notes TEXT NULL,
   specifications JSON NULL,
+  template_version INT NOT NULL DEFAULT 0,
+  version INT NOT NULL DEFAULT 1,
   created_at DATETIME NOT NULL,
   updated_at DATETIME NOT NULL,
   deleted_at DATETIME NULL
@@ -122,12 +192,158 @@ CREATE TABLE IF NOT EXISTS device_images (
   id INT PRIMARY KEY AUTO_INCREMENT,
   device_id INT NOT NULL,
   image_url VARCHAR(500) NOT NULL,
+  storage_key VARCHAR(500) NULL,
+  content_hash VARCHAR(64) NULL,
+  content_type VARCHAR(50) NULL,
+  width INT NOT NULL DEFAULT 0,
+  height INT NOT NULL DEFAULT 0,
+  byte_size BIGINT NOT NULL DEFAULT 0,
+  variants JSON NULL,
+  caption VARCHAR(500) NULL,
   image_type VARCHAR(20) NOT NULL DEFAULT 'normal',
   sort_order INT NOT NULL DEFAULT 0,
   created_at DATETIME NOT NULL,
   CONSTRAINT fk_device_images_device FOREIGN KEY (device_id) REFERENCES devices(id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+-- 设备附件（小票、发票、保修卡、说明书）
+CREATE TABLE IF NOT EXISTS device_attachments (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  device_id INT NOT NULL,
+  user_id INT NOT NULL,
+  attachment_type VARCHAR(20) NOT NULL,
+  title VARCHAR(200) NOT NULL,
+  file_name VARCHAR(255) NOT NULL,
+  mime_type VARCHAR(100) NOT NULL,
+  byte_size BIGINT NOT NULL DEFAULT 0,
+  storage_key VARCHAR(500) NOT NULL,
+  invoice_number VARCHAR(50) NULL,
+  invoice_amount DECIMAL(10,2) NULL,
+  invoice_date DATE NULL,
+  service_record_id INT NULL,
+  created_at DATETIME NOT NULL,
+  CONSTRAINT fk_device_attachments_device FOREIGN KEY (device_id) REFERENCES devices(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 设备维修保养记录
+CREATE TABLE IF NOT EXISTS device_service_records (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  device_id INT NOT NULL,
+  user_id INT NOT NULL,
+  service_date DATE NOT NULL,
+  service_type VARCHAR(20) NOT NULL,
+  vendor VARCHAR(200) NULL,
+  cost DECIMAL(10,2) NOT NULL DEFAULT 0,
+  warranty_covered TINYINT(1) NOT NULL DEFAULT 0,
+  description TEXT NULL,
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL,
+  CONSTRAINT fk_device_service_records_device FOREIGN KEY (device_id) REFERENCES devices(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 设备其他支出（配件、订阅服务、平台费用）
+CREATE TABLE IF NOT EXISTS device_costs (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  device_id INT NOT NULL,
+  user_id INT NOT NULL,
+  cost_type VARCHAR(20) NOT NULL,
+  title VARCHAR(200) NOT NULL,
+  amount DECIMAL(10,2) NOT NULL,
+  cost_date DATE NOT NULL,
+  billing_cycle VARCHAR(20) NULL,
+  end_date DATE NULL,
+  notes TEXT NULL,
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL,
+  CONSTRAINT fk_device_costs_device FOREIGN KEY (device_id) REFERENCES devices(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 设备使用记录
+CREATE TABLE IF NOT EXISTS device_usage_logs (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  device_id INT NOT NULL,
+  user_id INT NOT NULL,
+  used_on DATE NOT NULL,
+  use_count INT NOT NULL DEFAULT 1,
+  note VARCHAR(200) NULL,
+  created_at DATETIME NOT NULL,
+  CONSTRAINT fk_device_usage_logs_device FOREIGN KEY (device_id) REFERENCES devices(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 设备变更事件（只追加，不更新）
+CREATE TABLE IF NOT EXISTS device_events (
+  id BIGINT PRIMARY KEY AUTO_INCREMENT,
+  device_id INT NOT NULL,
+  user_id INT NOT NULL,
+  actor_id INT NOT NULL DEFAULT 0,
+  event_type VARCHAR(30) NOT NULL,
+  changes JSON NULL,
+  metadata JSON NULL,
+  created_at DATETIME NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 电商订单导入的设备草稿
+CREATE TABLE IF NOT EXISTS device_drafts (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  user_id INT NOT NULL,
+  source VARCHAR(20) NOT NULL,
+  order_no VARCHAR(64) NULL,
+  order_date DATE NULL,
+  shop VARCHAR(200) NULL,
+  title VARCHAR(500) NOT NULL,
+  quantity INT NOT NULL DEFAULT 1,
+  price_paid DECIMAL(10,2) NOT NULL DEFAULT 0,
+  name VARCHAR(200) NOT NULL,
+  brand VARCHAR(100) NULL,
+  model VARCHAR(100) NULL,
+  category_id INT NULL,
+  template_id INT NULL,
+  purchase_price DECIMAL(10,2) NOT NULL DEFAULT 0,
+  serial_number VARCHAR(200) NULL,
+  notes TEXT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  device_id INT NULL,
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 设备标签
+CREATE TABLE IF NOT EXISTS device_tags (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  device_id INT NOT NULL,
+  tag_id INT NOT NULL,
+  created_at DATETIME NOT NULL,
+  CONSTRAINT fk_device_tags_device FOREIGN KEY (device_id) REFERENCES devices(id),
+  CONSTRAINT fk_device_tags_tag FOREIGN KEY (tag_id) REFERENCES tags(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 设备规格参数索引（由规格参数写入时重建）
+CREATE TABLE IF NOT EXISTS device_spec_values (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  device_id INT NOT NULL,
+  user_id INT NOT NULL,
+  template_id INT NULL,
+  field_name VARCHAR(100) NOT NULL,
+  field_type VARCHAR(20) NOT NULL,
+  value_text VARCHAR(255) NOT NULL DEFAULT '',
+  value_num DOUBLE NULL,
+  dimension VARCHAR(20) NOT NULL DEFAULT '',
+  unit_factor DOUBLE NOT NULL DEFAULT 1,
+  value_date DATE NULL,
+  CONSTRAINT fk_device_spec_values_device FOREIGN KEY (device_id) REFERENCES devices(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 设备二维码（扫码查找设备的 token）
+CREATE TABLE IF NOT EXISTS device_qr_codes (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  device_id INT NOT NULL,
+  user_id INT NOT NULL,
+  token VARCHAR(64) NOT NULL,
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL,
+  CONSTRAINT fk_device_qr_codes_device FOREIGN KEY (device_id) REFERENCES devices(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 -- 价格
 CREATE TABLE IF NOT EXISTS prices (
   id INT PRIMARY KEY AUTO_INCREMENT,
@@ -173,6 +389,7 @@ CREATE TABLE IF NOT EXISTS price_alerts (
   last_triggered_at DATETIME NULL,
   trigger_count INT NOT NULL DEFAULT 0,
   status VARCHAR(20) NOT NULL DEFAULT 'active',
+  version INT NOT NULL DEFAULT 1,
   created_at DATETIME NOT NULL,
   updated_at DATETIME NOT NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -207,6 +424,26 @@ CREATE TABLE IF NOT EXISTS price_predictions (
   created_at DATETIME NOT NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+-- 提醒（保修到期、电池保养、滤芯更换、自定义）
+CREATE TABLE IF NOT EXISTS reminders (
+  id INT PRIMARY KEY AUTO_INCREMENT,
+  user_id INT NOT NULL,
+  device_id INT NULL,
+  reminder_type VARCHAR(20) NOT NULL,
+  title VARCHAR(200) NOT NULL,
+  description VARCHAR(500) NULL,
+  due_date DATE NOT NULL,
+  recurrence_rule VARCHAR(100) NULL,
+  anchor_day INT NOT NULL DEFAULT 0,
+  lead_days INT NOT NULL DEFAULT 7,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  source VARCHAR(20) NOT NULL DEFAULT 'manual',
+  last_completed_at DATETIME NULL,
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL,
+  CONSTRAINT fk_reminders_device FOREIGN KEY (device_id) REFERENCES devices(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 -- 认证（黑名单与会话）
 CREATE TABLE IF NOT EXISTS token_blacklist (
   id INT PRIMARY KEY AUTO_INCREMENT,
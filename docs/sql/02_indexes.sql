@@ -19,8 +19,13 @@ ALTER TABLE user_tags ADD INDEX idx_user_tags_user_tag (user_id, tag_id);
 ALTER TABLE categories ADD INDEX idx_categories_type (type);
 ALTER TABLE categories ADD INDEX idx_categories_parent (parent_id);
 
+-- category_rules
+ALTER TABLE category_rules ADD INDEX idx_category_rules_user (user_id, is_active);
+
 -- device_templates
 ALTER TABLE device_templates ADD INDEX idx_device_templates_category (category_id);
+ALTER TABLE device_templates ADD INDEX idx_device_templates_owner (owner_id);
+ALTER TABLE device_templates ADD INDEX idx_device_templates_library (visibility, review_status);
 
 -- devices
 ALTER TABLE devices ADD INDEX idx_devices_user (user_id);
@@ -28,9 +33,52 @@ ALTER TABLE devices ADD INDEX idx_devices_category (category_id);
 ALTER TABLE devices ADD INDEX idx_devices_brand (brand);
 ALTER TABLE devices ADD INDEX idx_devices_status (status);
 ALTER TABLE devices ADD INDEX idx_devices_purchase_date (purchase_date);
+ALTER TABLE devices ADD INDEX idx_devices_parent (parent_id);
+ALTER TABLE devices ADD INDEX idx_devices_template_version (template_id, template_version);
+ALTER TABLE devices ADD INDEX idx_devices_user_serial (user_id, serial_number);
 
 -- device_images
 ALTER TABLE device_images ADD INDEX idx_device_images_device (device_id);
+ALTER TABLE device_images ADD INDEX idx_device_images_content_hash (content_hash);
+
+-- device_attachments
+ALTER TABLE device_attachments ADD INDEX idx_device_attachments_device_type (device_id, attachment_type);
+ALTER TABLE device_attachments ADD INDEX idx_device_attachments_user (user_id);
+ALTER TABLE device_attachments ADD INDEX idx_device_attachments_service_record (service_record_id);
+
+-- device_service_records
+ALTER TABLE device_service_records ADD INDEX idx_device_service_records_device_date (device_id, service_date);
+ALTER TABLE device_service_records ADD INDEX idx_device_service_records_user_date (user_id, service_date);
+
+-- device_costs
+ALTER TABLE device_costs ADD INDEX idx_device_costs_device_type (device_id, cost_type);
+ALTER TABLE device_costs ADD INDEX idx_device_costs_user (user_id);
+
+-- device_usage_logs
+ALTER TABLE device_usage_logs ADD INDEX idx_device_usage_logs_device_date (device_id, used_on);
+ALTER TABLE device_usage_logs ADD INDEX idx_device_usage_logs_user (user_id);
+
+-- device_events
+ALTER TABLE device_events ADD INDEX idx_device_events_device_time (device_id, created_at);
+ALTER TABLE device_events ADD INDEX idx_device_events_user_type (user_id, event_type);
+
+-- device_drafts
+ALTER TABLE device_drafts ADD INDEX idx_device_drafts_user_status (user_id, status);
+ALTER TABLE device_drafts ADD INDEX idx_device_drafts_order (user_id, source, order_no);
+
+-- device_tags
+ALTER TABLE device_tags ADD UNIQUE INDEX uk_device_tags_device_tag (device_id, tag_id);
+ALTER TABLE device_tags ADD INDEX idx_device_tags_tag (tag_id);
+
+-- device_spec_values
+ALTER TABLE device_spec_values ADD INDEX idx_device_spec_values_device (device_id);
+ALTER TABLE device_spec_values ADD INDEX idx_device_spec_values_num (user_id, field_name, value_num);
+ALTER TABLE device_spec_values ADD INDEX idx_device_spec_values_text (user_id, field_name, value_text);
+ALTER TABLE device_spec_values ADD INDEX idx_device_spec_values_date (user_id, field_name, value_date);
+
+-- device_qr_codes
+ALTER TABLE device_qr_codes ADD UNIQUE INDEX uk_device_qr_codes_device (device_id);
+ALTER TABLE device_qr_codes ADD UNIQUE INDEX uk_device_qr_codes_token (token);
 
 -- prices
 ALTER TABLE prices ADD INDEX idx_prices_user_device (user_id, device_id);
@@ -49,6 +97,10 @@ ALTER TABLE price_sources ADD INDEX idx_price_sources_status (status);
 -- price_predictions
 ALTER TABLE price_predictions ADD INDEX idx_price_predictions_user_device (user_id, device_id);
 
+-- reminders
+ALTER TABLE reminders ADD INDEX idx_reminders_user_status_due (user_id, status, due_date);
+ALTER TABLE reminders ADD INDEX idx_reminders_device_type (device_id, reminder_type);
+
 -- token_blacklist
 ALTER TABLE token_blacklist ADD INDEX idx_token_blacklist_token (token);
 
@@ -15,3 +15,22 @@ VALUES
  ('相机', '相机类设备', 0, 'system', 1, NOW(), NOW()),
  ('电脑', '电脑类设备', 0, 'system', 1, NOW(), NOW())
 ON DUPLICATE KEY UPDATE updated_at = VALUES(updated_at);
+
+-- 预置分类规则（示例），按名称关联上面的系统分类
+INSERT INTO category_rules (name, brands, model_keywords, name_keywords, category_id, priority, is_active, created_at, updated_at)
+SELECT 'iPhone', 'Apple,苹果', '', 'iPhone', c.id, 10, 1, NOW(), NOW() FROM categories c
+WHERE c.name = '手机' AND c.type = 'system'
+  AND NOT EXISTS (SELECT 1 FROM category_rules r WHERE r.user_id IS NULL AND r.name = 'iPhone')
+LIMIT 1;
+
+INSERT INTO category_rules (name, brands, model_keywords, name_keywords, category_id, priority, is_active, created_at, updated_at)
+SELECT 'MacBook', 'Apple,苹果', '', 'MacBook', c.id, 10, 1, NOW(), NOW() FROM categories c
+WHERE c.name = '电脑' AND c.type = 'system'
+  AND NOT EXISTS (SELECT 1 FROM category_rules r WHERE r.user_id IS NULL AND r.name = 'MacBook')
+LIMIT 1;
+
+INSERT INTO category_rules (name, brands, model_keywords, name_keywords, category_id, priority, is_active, created_at, updated_at)
+SELECT '相机', 'Canon,Nikon,Fujifilm,佳能,尼康,富士', '', '相机,微单,单反', c.id, 0, 1, NOW(), NOW() FROM categories c
+WHERE c.name = '相机' AND c.type = 'system'
+  AND NOT EXISTS (SELECT 1 FROM category_rules r WHERE r.user_id IS NULL AND r.name = '相机')
+LIMIT 1;
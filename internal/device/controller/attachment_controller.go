@@ -0,0 +1,227 @@
+package controller
+
+import (
+	base "Backend_Lili/internal/auth/controller"
+	"Backend_Lili/internal/device/service"
+	"Backend_Lili/pkg/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+type AttachmentController struct {
+	base.BaseController
+	attachmentService *service.AttachmentService
+}
+
+func NewAttachmentController() *AttachmentController {
+	return &AttachmentController{}
+}
+
+func (c *AttachmentController) Prepare() {
+	// 调用父类的Prepare方法
+	c.BaseController.Prepare()
+
+	// 初始化附件服务
+	c.attachmentService = service.NewAttachmentService()
+}
+
+// GetDeviceAttachments 获取设备附件列表
+// @router /devices/:deviceId/attachments [get]
+func (c *AttachmentController) GetDeviceAttachments() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	attachments, err := c.attachmentService.GetDeviceAttachments(deviceID, userID, c.GetString("type"))
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, attachments)
+}
+
+// UploadAttachment 上传设备附件
+// @router /devices/:deviceId/attachments [post]
+func (c *AttachmentController) UploadAttachment() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取上传的文件
+	file, header, err := c.GetFile("file")
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "获取上传文件失败")
+		return
+	}
+	defer file.Close()
+
+	// 解析其他参数
+	req := &service.UploadAttachmentRequest{
+		AttachmentType: c.GetString("attachment_type"),
+		Title:          c.GetString("title"),
+		InvoiceNumber:  c.GetString("invoice_number"),
+		InvoiceDate:    c.GetString("invoice_date"),
+	}
+	if amountStr := strings.TrimSpace(c.GetString("invoice_amount")); amountStr != "" {
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(amountStr, ",", ""), 64)
+		if err != nil {
+			utils.WriteError(c.Ctx, utils.ERROR_PARAM, "金额格式错误")
+			return
+		}
+		req.InvoiceAmount = &amount
+	}
+	if recordIDStr := strings.TrimSpace(c.GetString("service_record_id")); recordIDStr != "" {
+		recordID, err := strconv.Atoi(recordIDStr)
+		if err != nil {
+			utils.WriteError(c.Ctx, utils.ERROR_PARAM, "维修保养记录ID格式错误")
+			return
+		}
+		req.ServiceRecordID = &recordID
+	}
+
+	// 调用服务层
+	attachment, err := c.attachmentService.UploadAttachment(deviceID, userID, file, header, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, attachment)
+}
+
+// DownloadAttachment 下载设备附件
+// @router /devices/:deviceId/attachments/:attachmentId/file [get]
+func (c *AttachmentController) DownloadAttachment() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取附件ID
+	attachmentIDStr := c.Ctx.Input.Param(":attachmentId")
+	attachmentID, err := strconv.Atoi(attachmentIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "附件ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	attachment, reader, info, err := c.attachmentService.OpenAttachment(attachmentID, deviceID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+	defer reader.Close()
+
+	// PDF与图片默认在浏览器内预览，?download=1 时强制下载
+	disposition := "inline"
+	if download, _ := c.GetBool("download", false); download {
+		disposition = "attachment"
+	}
+
+	header := c.Ctx.ResponseWriter.Header()
+	header.Set("Content-Type", attachment.MimeType)
+	header.Set("Content-Disposition", disposition+"; filename*=UTF-8''"+url.PathEscape(attachment.FileName))
+	header.Set("Cache-Control", "private, max-age=86400")
+	if info.Size >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	if !info.ModTime.IsZero() {
+		header.Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	c.Ctx.ResponseWriter.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(c.Ctx.ResponseWriter, reader); err != nil {
+		logs.Warn("输出设备附件失败: attachment=%d, err=%v", attachmentID, err)
+	}
+}
+
+// DeleteAttachment 删除设备附件
+// @router /devices/:deviceId/attachments/:attachmentId [delete]
+func (c *AttachmentController) DeleteAttachment() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取附件ID
+	attachmentIDStr := c.Ctx.Input.Param(":attachmentId")
+	attachmentID, err := strconv.Atoi(attachmentIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "附件ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	err = c.attachmentService.DeleteAttachment(attachmentID, deviceID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, nil)
+}
@@ -85,6 +85,7 @@ func (c *CategoryController) GetCategoryDetail() {
 		return
 	}
 
+	utils.SetETag(c.Ctx, category.Version)
 	utils.WriteSuccess(c.Ctx, category)
 }
 
@@ -144,17 +145,26 @@ func (c *CategoryController) UpdateCustomCategory() {
 		return
 	}
 
+	// 读取客户端持有的版本号
+	version, fromHeader, err := utils.ResolveVersion(c.Ctx, req.Version)
+	if err != nil {
+		utils.HandleBusinessError(c.Ctx, err)
+		return
+	}
+	req.Version = version
+
 	// 调用服务层
 	category, err := c.categoryService.UpdateCustomCategory(userID, categoryID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
-			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+			utils.WriteVersionedError(c.Ctx, businessErr, fromHeader)
 		} else {
 			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
 		}
 		return
 	}
 
+	utils.SetETag(c.Ctx, category.Version)
 	utils.WriteSuccess(c.Ctx, category)
 }
 
@@ -176,8 +186,103 @@ func (c *CategoryController) DeleteCustomCategory() {
 		return
 	}
 
+	// 解析查询参数：子分类与设备的去向
+	req := &service.DeleteCategoryRequest{}
+	if err := c.ParseForm(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	transfer, err := c.categoryService.DeleteCustomCategory(userID, categoryID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, map[string]interface{}{
+		"message":  "分类删除成功",
+		"transfer": transfer,
+	})
+}
+
+// MoveCategory 移动自定义分类（连同子分类）到新的父分类下
+// @router /categories/:category_id/move [post]
+func (c *CategoryController) MoveCategory() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	categoryID, err := strconv.Atoi(c.Ctx.Input.Param(":category_id"))
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "分类ID格式错误")
+		return
+	}
+
+	// 解析请求体
+	req := &service.MoveCategoryRequest{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "请求体解析失败")
+		return
+	}
+
+	// 读取客户端持有的版本号
+	version, fromHeader, err := utils.ResolveVersion(c.Ctx, req.Version)
+	if err != nil {
+		utils.HandleBusinessError(c.Ctx, err)
+		return
+	}
+	req.Version = version
+
+	// 调用服务层
+	category, err := c.categoryService.MoveCategory(userID, categoryID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteVersionedError(c.Ctx, businessErr, fromHeader)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.SetETag(c.Ctx, category.Version)
+	utils.WriteSuccess(c.Ctx, category)
+}
+
+// MergeCategory 把自定义分类合并到目标分类
+// @router /categories/:category_id/merge [post]
+func (c *CategoryController) MergeCategory() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	categoryID, err := strconv.Atoi(c.Ctx.Input.Param(":category_id"))
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "分类ID格式错误")
+		return
+	}
+
+	// 解析请求体
+	req := &service.MergeCategoryRequest{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "请求体解析失败")
+		return
+	}
+
 	// 调用服务层
-	err = c.categoryService.DeleteCustomCategory(userID, categoryID)
+	transfer, err := c.categoryService.MergeCategory(userID, categoryID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
 			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
@@ -187,16 +292,92 @@ func (c *CategoryController) DeleteCustomCategory() {
 		return
 	}
 
+	utils.WriteSuccess(c.Ctx, transfer)
+}
+
+// SetCategoryHidden 隐藏或重新显示系统分类，只对当前用户生效
+// @router /categories/:category_id/hidden [put]
+func (c *CategoryController) SetCategoryHidden() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	categoryID, err := strconv.Atoi(c.Ctx.Input.Param(":category_id"))
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "分类ID格式错误")
+		return
+	}
+
+	// 解析请求体
+	req := &service.SetCategoryHiddenRequest{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "请求体解析失败")
+		return
+	}
+
+	// 调用服务层
+	if err := c.categoryService.SetCategoryHidden(userID, categoryID, req); err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, map[string]interface{}{
+		"category_id": categoryID,
+		"hidden":      req.Hidden,
+	})
+}
+
+// ResetCategoryPreferences 清除当前用户对系统分类的隐藏与排序设置
+// @router /categories/preferences [delete]
+func (c *CategoryController) ResetCategoryPreferences() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 调用服务层
+	if err := c.categoryService.ResetCategoryPreferences(userID); err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
 	utils.WriteSuccess(c.Ctx, map[string]interface{}{
-		"message": "分类删除成功",
+		"message": "分类设置已重置",
 	})
 }
 
 // GetSystemCategories 获取系统默认分类
 // @router /categories/system [get]
 func (c *CategoryController) GetSystemCategories() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	includeHidden, err := c.GetBool("include_hidden", false)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "include_hidden 参数格式错误")
+		return
+	}
+
 	// 调用服务层
-	categories, err := c.categoryService.GetSystemCategories()
+	categories, err := c.categoryService.GetSystemCategories(userID, includeHidden)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
 			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
@@ -333,3 +514,135 @@ func (c *CategoryController) SearchCategories() {
 		"categories": categories,
 	})
 }
+
+// GetCategoryRules 获取分类规则列表，包括系统规则与用户自己的规则
+// @router /categories/rules [get]
+func (c *CategoryController) GetCategoryRules() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析查询参数
+	req := &service.GetCategoryRulesRequest{}
+	if err := c.ParseForm(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	rules, err := c.categoryService.GetCategoryRules(userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, rules)
+}
+
+// CreateCategoryRule 创建分类规则，管理员可以创建系统规则
+// @router /categories/rules [post]
+func (c *CategoryController) CreateCategoryRule() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析请求体
+	req := &service.CategoryRuleRequest{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "请求体解析失败")
+		return
+	}
+
+	// 调用服务层
+	rule, err := c.categoryService.CreateCategoryRule(userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, rule)
+}
+
+// UpdateCategoryRule 修改分类规则
+// @router /categories/rules/:rule_id [put]
+func (c *CategoryController) UpdateCategoryRule() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	ruleID, err := strconv.Atoi(c.Ctx.Input.Param(":rule_id"))
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "规则ID格式错误")
+		return
+	}
+
+	// 解析请求体
+	req := &service.CategoryRuleRequest{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "请求体解析失败")
+		return
+	}
+
+	// 调用服务层
+	rule, err := c.categoryService.UpdateCategoryRule(userID, ruleID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, rule)
+}
+
+// DeleteCategoryRule 删除分类规则
+// @router /categories/rules/:rule_id [delete]
+func (c *CategoryController) DeleteCategoryRule() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	ruleID, err := strconv.Atoi(c.Ctx.Input.Param(":rule_id"))
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "规则ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	if err := c.categoryService.DeleteCategoryRule(userID, ruleID); err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, map[string]interface{}{
+		"message": "分类规则删除成功",
+	})
+}
@@ -0,0 +1,307 @@
+package controller
+
+import (
+	base "Backend_Lili/internal/auth/controller"
+	"Backend_Lili/internal/device/service"
+	"Backend_Lili/pkg/utils"
+	"strconv"
+)
+
+type CostController struct {
+	base.BaseController
+	costService *service.CostService
+}
+
+func NewCostController() *CostController {
+	return &CostController{}
+}
+
+func (c *CostController) Prepare() {
+	// 调用父类的Prepare方法
+	c.BaseController.Prepare()
+
+	// 初始化设备支出服务
+	c.costService = service.NewCostService()
+}
+
+// GetDeviceCosts 获取设备支出列表
+// @router /devices/:deviceId/costs [get]
+func (c *CostController) GetDeviceCosts() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	costs, err := c.costService.GetDeviceCosts(deviceID, userID, c.GetString("cost_type"))
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, map[string]interface{}{
+		"costs": costs,
+	})
+}
+
+// CreateDeviceCost 新增设备支出
+// @router /devices/:deviceId/costs [post]
+func (c *CostController) CreateDeviceCost() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.DeviceCostRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	cost, err := c.costService.CreateDeviceCost(deviceID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, cost)
+}
+
+// UpdateDeviceCost 更新设备支出
+// @router /devices/:deviceId/costs/:costId [put]
+func (c *CostController) UpdateDeviceCost() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取支出ID
+	costIDStr := c.Ctx.Input.Param(":costId")
+	costID, err := strconv.Atoi(costIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "支出ID格式错误")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.DeviceCostRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	cost, err := c.costService.UpdateDeviceCost(costID, deviceID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, cost)
+}
+
+// DeleteDeviceCost 删除设备支出
+// @router /devices/:deviceId/costs/:costId [delete]
+func (c *CostController) DeleteDeviceCost() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取支出ID
+	costIDStr := c.Ctx.Input.Param(":costId")
+	costID, err := strconv.Atoi(costIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "支出ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	err = c.costService.DeleteDeviceCost(costID, deviceID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, map[string]interface{}{
+		"message": "支出记录删除成功",
+	})
+}
+
+// GetUsageLogs 获取设备使用记录
+// @router /devices/:deviceId/usage [get]
+func (c *CostController) GetUsageLogs() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 解析查询参数
+	req := &service.GetUsageLogsRequest{}
+	if err := c.ParseForm(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	response, err := c.costService.GetUsageLogs(deviceID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, response)
+}
+
+// CreateUsageLog 记录设备使用
+// @router /devices/:deviceId/usage [post]
+func (c *CostController) CreateUsageLog() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.CreateUsageLogRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	usage, err := c.costService.CreateUsageLog(deviceID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, usage)
+}
+
+// DeleteUsageLog 删除设备使用记录
+// @router /devices/:deviceId/usage/:usageId [delete]
+func (c *CostController) DeleteUsageLog() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取使用记录ID
+	usageIDStr := c.Ctx.Input.Param(":usageId")
+	usageID, err := strconv.Atoi(usageIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "使用记录ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	err = c.costService.DeleteUsageLog(usageID, deviceID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, map[string]interface{}{
+		"message": "使用记录删除成功",
+	})
+}
@@ -4,7 +4,14 @@ import (
 	base "Backend_Lili/internal/auth/controller"
 	"Backend_Lili/internal/device/service"
 	"Backend_Lili/pkg/utils"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+
+	"github.com/beego/beego/v2/core/logs"
 )
 
 type DeviceController struct {
@@ -84,6 +91,7 @@ func (c *DeviceController) GetDeviceDetail() {
 		return
 	}
 
+	utils.SetETag(c.Ctx, device.Version)
 	utils.WriteSuccess(c.Ctx, device)
 }
 
@@ -108,7 +116,12 @@ func (c *DeviceController) CreateDevice() {
 	device, err := c.deviceService.CreateDevice(userID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
-			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+			// 疑似重复时附带重复设备列表
+			if businessErr.Details != nil {
+				utils.WriteErrorWithDetails(c.Ctx, businessErr.Code, businessErr.Message, businessErr.Details)
+			} else {
+				utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+			}
 		} else {
 			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
 		}
@@ -143,17 +156,26 @@ func (c *DeviceController) UpdateDevice() {
 		return
 	}
 
+	// 读取客户端持有的版本号
+	version, fromHeader, err := utils.ResolveVersion(c.Ctx, req.Version)
+	if err != nil {
+		utils.HandleBusinessError(c.Ctx, err)
+		return
+	}
+	req.Version = version
+
 	// 调用服务层
 	device, err := c.deviceService.UpdateDevice(deviceID, userID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
-			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+			utils.WriteVersionedError(c.Ctx, businessErr, fromHeader)
 		} else {
 			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
 		}
 		return
 	}
 
+	utils.SetETag(c.Ctx, device.Version)
 	utils.WriteSuccess(c.Ctx, device)
 }
 
@@ -214,18 +236,27 @@ func (c *DeviceController) UpdateDeviceStatus() {
 		return
 	}
 
+	// 读取客户端持有的版本号
+	version, fromHeader, err := utils.ResolveVersion(c.Ctx, req.Version)
+	if err != nil {
+		utils.HandleBusinessError(c.Ctx, err)
+		return
+	}
+	req.Version = version
+
 	// 调用服务层
-	err = c.deviceService.UpdateDeviceStatus(deviceID, userID, req)
+	device, err := c.deviceService.UpdateDeviceStatus(deviceID, userID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
-			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+			utils.WriteVersionedError(c.Ctx, businessErr, fromHeader)
 		} else {
 			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
 		}
 		return
 	}
 
-	utils.WriteSuccess(c.Ctx, nil)
+	utils.SetETag(c.Ctx, device.Version)
+	utils.WriteSuccess(c.Ctx, device)
 }
 
 // GetDeviceValuation 获取设备价值评估
@@ -260,9 +291,9 @@ func (c *DeviceController) GetDeviceValuation() {
 	utils.WriteSuccess(c.Ctx, valuation)
 }
 
-// BatchImportDevices 批量导入设备
-// @router /devices/import [post]
-func (c *DeviceController) BatchImportDevices() {
+// GetDeviceTimeline 获取设备变更时间线
+// @router /devices/:deviceId/timeline [get]
+func (c *DeviceController) GetDeviceTimeline() {
 	// 从JWT中获取用户ID
 	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
@@ -270,15 +301,23 @@ func (c *DeviceController) BatchImportDevices() {
 		return
 	}
 
-	// 解析请求参数
-	req := &service.BatchImportDevicesRequest{}
-	if err := c.BindJSON(req); err != nil {
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 解析查询参数
+	req := &service.GetDeviceTimelineRequest{}
+	if err := c.ParseForm(req); err != nil {
 		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
 		return
 	}
 
 	// 调用服务层
-	response, err := c.deviceService.BatchImportDevices(userID, req)
+	timeline, err := c.deviceService.GetDeviceTimeline(deviceID, userID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
 			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
@@ -288,12 +327,12 @@ func (c *DeviceController) BatchImportDevices() {
 		return
 	}
 
-	utils.WriteSuccess(c.Ctx, response)
+	utils.WriteSuccess(c.Ctx, timeline)
 }
 
-// GetDeviceImages 获取设备图片
-// @router /devices/:deviceId/images [get]
-func (c *DeviceController) GetDeviceImages() {
+// RestoreDeviceField 将设备字段恢复为历史值
+// @router /devices/:deviceId/timeline/restore [post]
+func (c *DeviceController) RestoreDeviceField() {
 	// 从JWT中获取用户ID
 	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
@@ -309,23 +348,39 @@ func (c *DeviceController) GetDeviceImages() {
 		return
 	}
 
+	// 解析请求参数
+	req := &service.RestoreDeviceFieldRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 读取客户端持有的版本号
+	version, fromHeader, err := utils.ResolveVersion(c.Ctx, req.Version)
+	if err != nil {
+		utils.HandleBusinessError(c.Ctx, err)
+		return
+	}
+	req.Version = version
+
 	// 调用服务层
-	images, err := c.deviceService.GetDeviceImages(deviceID, userID)
+	device, err := c.deviceService.RestoreDeviceField(deviceID, userID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
-			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+			utils.WriteVersionedError(c.Ctx, businessErr, fromHeader)
 		} else {
 			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
 		}
 		return
 	}
 
-	utils.WriteSuccess(c.Ctx, images)
+	utils.SetETag(c.Ctx, device.Version)
+	utils.WriteSuccess(c.Ctx, device)
 }
 
-// UploadDeviceImage 上传设备图片
-// @router /devices/:deviceId/images [post]
-func (c *DeviceController) UploadDeviceImage() {
+// BatchImportDevices 批量导入设备
+// @router /devices/import [post]
+func (c *DeviceController) BatchImportDevices() {
 	// 从JWT中获取用户ID
 	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
@@ -333,16 +388,39 @@ func (c *DeviceController) UploadDeviceImage() {
 		return
 	}
 
-	// 获取设备ID
-	deviceIDStr := c.Ctx.Input.Param(":deviceId")
-	deviceID, err := strconv.Atoi(deviceIDStr)
+	// 解析请求参数
+	req := &service.BatchImportDevicesRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	response, err := c.deviceService.BatchImportDevices(userID, req)
 	if err != nil {
-		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, response)
+}
+
+// ImportDevicesFile 从CSV或XLSX表格导入设备
+// @router /devices/import/file [post]
+func (c *DeviceController) ImportDevicesFile() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
 		return
 	}
 
 	// 获取上传的文件
-	file, header, err := c.GetFile("image")
+	file, header, err := c.GetFile("file")
 	if err != nil {
 		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "获取上传文件失败")
 		return
@@ -350,17 +428,36 @@ func (c *DeviceController) UploadDeviceImage() {
 	defer file.Close()
 
 	// 解析其他参数
-	imageType := c.GetString("image_type", "normal")
-	sortOrder, _ := c.GetInt("sort_order", 0)
-
-	// 构建请求对象
-	req := &service.UploadDeviceImageRequest{
-		ImageType: imageType,
-		SortOrder: int(sortOrder),
+	req := &service.ImportDevicesFileRequest{}
+	if req.DryRun, err = c.GetBool("dry_run", false); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "dry_run 参数格式错误")
+		return
+	}
+	if req.IgnoreDuplicates, err = c.GetBool("ignore_duplicates", false); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "ignore_duplicates 参数格式错误")
+		return
+	}
+	if req.HeaderRow, err = c.GetInt("header_row", 0); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "表头行号格式错误")
+		return
+	}
+	if req.DefaultCategoryID, err = c.GetInt("category_id", 0); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "分类ID格式错误")
+		return
+	}
+	if req.DefaultTemplateID, err = c.GetInt("template_id", 0); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板ID格式错误")
+		return
+	}
+	if mapping := strings.TrimSpace(c.GetString("mapping")); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &req.Mapping); err != nil {
+			utils.WriteError(c.Ctx, utils.ERROR_PARAM, "列映射格式错误")
+			return
+		}
 	}
 
 	// 调用服务层
-	response, err := c.deviceService.UploadDeviceImage(deviceID, userID, file, header, req)
+	response, err := c.deviceService.ImportDevicesFile(userID, file, header, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
 			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
@@ -373,9 +470,64 @@ func (c *DeviceController) UploadDeviceImage() {
 	utils.WriteSuccess(c.Ctx, response)
 }
 
-// DeleteDeviceImage 删除设备图片
-// @router /devices/:deviceId/images/:imageId [delete]
-func (c *DeviceController) DeleteDeviceImage() {
+// ScanDuplicates 扫描疑似重复的设备
+// @router /devices/duplicates [get]
+func (c *DeviceController) ScanDuplicates() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析查询参数
+	req := &service.ScanDuplicatesRequest{}
+	if err := c.ParseForm(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	result, err := c.deviceService.ScanDuplicates(userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, result)
+}
+
+// ReindexDeviceSpecs 重建当前用户全部设备的规格参数索引
+// @router /devices/specs/reindex [post]
+func (c *DeviceController) ReindexDeviceSpecs() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 调用服务层
+	result, err := c.deviceService.ReindexDeviceSpecs(userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, result)
+}
+
+// MergeDevices 将重复设备合并到当前设备
+// @router /devices/:deviceId/merge [post]
+func (c *DeviceController) MergeDevices() {
 	// 从JWT中获取用户ID
 	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
@@ -391,16 +543,51 @@ func (c *DeviceController) DeleteDeviceImage() {
 		return
 	}
 
-	// 获取图片ID
-	imageIDStr := c.Ctx.Input.Param(":imageId")
-	imageID, err := strconv.Atoi(imageIDStr)
+	// 解析请求参数
+	req := &service.MergeDevicesRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	device, err := c.deviceService.MergeDevices(deviceID, userID, req)
 	if err != nil {
-		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "图片ID格式错误")
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			// 版本冲突时附带当前设备
+			if businessErr.Details != nil {
+				utils.WriteErrorWithDetails(c.Ctx, businessErr.Code, businessErr.Message, businessErr.Details)
+			} else {
+				utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+			}
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, device)
+}
+
+// GetRecycleBin 获取回收站中的设备
+// @router /devices/recycle-bin [get]
+func (c *DeviceController) GetRecycleBin() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析查询参数
+	req := &service.GetRecycleBinRequest{}
+	if err := c.ParseForm(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
 		return
 	}
 
 	// 调用服务层
-	err = c.deviceService.DeleteDeviceImage(imageID, deviceID, userID)
+	result, err := c.deviceService.GetRecycleBin(userID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
 			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
@@ -410,12 +597,12 @@ func (c *DeviceController) DeleteDeviceImage() {
 		return
 	}
 
-	utils.WriteSuccess(c.Ctx, nil)
+	utils.WriteSuccess(c.Ctx, result)
 }
 
-// PredictDevicePrice 预测设备价格
-// @router /devices/:deviceId/prediction [get]
-func (c *DeviceController) PredictDevicePrice() {
+// RestoreDeletedDevice 从回收站恢复设备
+// @router /devices/recycle-bin/:deviceId/restore [post]
+func (c *DeviceController) RestoreDeletedDevice() {
 	// 从JWT中获取用户ID
 	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
@@ -424,22 +611,77 @@ func (c *DeviceController) PredictDevicePrice() {
 	}
 
 	// 获取设备ID
-	deviceIDStr := c.Ctx.Input.Param(":deviceId")
-	deviceID, err := strconv.Atoi(deviceIDStr)
+	deviceID, err := strconv.Atoi(c.Ctx.Input.Param(":deviceId"))
 	if err != nil {
 		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
 		return
 	}
 
-	// 解析查询参数
-	req := &service.PricePredictionRequest{}
-	if err := c.ParseForm(req); err != nil {
+	// 调用服务层
+	device, err := c.deviceService.RestoreDevice(deviceID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, device)
+}
+
+// PurgeDeletedDevice 彻底删除回收站中的设备，不可恢复
+// @router /devices/recycle-bin/:deviceId [delete]
+func (c *DeviceController) PurgeDeletedDevice() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceID, err := strconv.Atoi(c.Ctx.Input.Param(":deviceId"))
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	if err := c.deviceService.PurgeDeletedDevice(deviceID, userID); err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, map[string]interface{}{
+		"message": "设备已彻底删除",
+	})
+}
+
+// BulkDevices 批量修改分类、状态、成色、标签，或批量删除、恢复设备
+// @router /devices/bulk [post]
+func (c *DeviceController) BulkDevices() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.BulkDevicesRequest{}
+	if err := c.BindJSON(req); err != nil {
 		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
 		return
 	}
 
 	// 调用服务层
-	prediction, err := c.deviceService.PredictDevicePrice(deviceID, userID, req)
+	result, err := c.deviceService.BulkDevices(userID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
 			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
@@ -449,5 +691,744 @@ func (c *DeviceController) PredictDevicePrice() {
 		return
 	}
 
-	utils.WriteSuccess(c.Ctx, prediction)
+	utils.WriteSuccess(c.Ctx, result)
+}
+
+// ExportDevices 按列表筛选条件导出设备，支持 csv、xlsx、jsonl
+// @router /devices/export [get]
+func (c *DeviceController) ExportDevices() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析筛选条件、格式与导出列（逗号分隔）
+	req := &service.ExportDevicesRequest{Format: c.GetString("format")}
+	if err := c.ParseForm(&req.Filter); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+	if columns := c.GetString("columns"); columns != "" {
+		req.Columns = strings.Split(columns, ",")
+	}
+
+	// 调用服务层
+	export, err := c.deviceService.NewDeviceExport(userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	// 边查询边写出，避免将全部设备读入内存
+	header := c.Ctx.ResponseWriter.Header()
+	header.Set("Content-Type", export.ContentType)
+	header.Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(export.FileName))
+	c.Ctx.ResponseWriter.WriteHeader(http.StatusOK)
+	if err := export.Write(c.Ctx.ResponseWriter); err != nil {
+		logs.Warn("导出设备失败: user=%d, err=%v", userID, err)
+	}
+}
+
+// GetExportColumns 获取可导出的列
+// @router /devices/export/columns [get]
+func (c *DeviceController) GetExportColumns() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 调用服务层
+	result, err := c.deviceService.GetExportColumns(userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, result)
+}
+
+// GetDeviceImages 获取设备图片
+// @router /devices/:deviceId/images [get]
+func (c *DeviceController) GetDeviceImages() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	images, err := c.deviceService.GetDeviceImages(deviceID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, images)
+}
+
+// UploadDeviceImage 上传设备图片
+// @router /devices/:deviceId/images [post]
+func (c *DeviceController) UploadDeviceImage() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取上传的文件
+	file, header, err := c.GetFile("image")
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "获取上传文件失败")
+		return
+	}
+	defer file.Close()
+
+	// 解析其他参数
+	imageType := c.GetString("image_type", "normal")
+	sortOrder, _ := c.GetInt("sort_order", 0)
+
+	// 构建请求对象
+	req := &service.UploadDeviceImageRequest{
+		ImageType: imageType,
+		SortOrder: int(sortOrder),
+		Caption:   c.GetString("caption"),
+	}
+
+	// 调用服务层
+	response, err := c.deviceService.UploadDeviceImage(deviceID, userID, file, header, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, response)
+}
+
+// DeleteDeviceImage 删除设备图片
+// @router /devices/:deviceId/images/:imageId [delete]
+func (c *DeviceController) DeleteDeviceImage() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取图片ID
+	imageIDStr := c.Ctx.Input.Param(":imageId")
+	imageID, err := strconv.Atoi(imageIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "图片ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	err = c.deviceService.DeleteDeviceImage(imageID, deviceID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, nil)
+}
+
+// UploadDeviceImages 批量上传设备图片
+// @router /devices/:deviceId/images/batch [post]
+func (c *DeviceController) UploadDeviceImages() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取上传的文件
+	headers, err := c.GetFiles("images")
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "获取上传文件失败")
+		return
+	}
+
+	// 解析其他参数
+	imageType := c.GetString("image_type", "normal")
+	sortOrder, _ := c.GetInt("sort_order", 0)
+
+	req := &service.UploadDeviceImageRequest{
+		ImageType: imageType,
+		SortOrder: int(sortOrder),
+		Caption:   c.GetString("caption"),
+	}
+
+	// 调用服务层
+	response, err := c.deviceService.UploadDeviceImages(deviceID, userID, headers, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, response)
+}
+
+// UpdateDeviceImage 修改图片说明
+// @router /devices/:deviceId/images/:imageId [patch]
+func (c *DeviceController) UpdateDeviceImage() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取图片ID
+	imageIDStr := c.Ctx.Input.Param(":imageId")
+	imageID, err := strconv.Atoi(imageIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "图片ID格式错误")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.UpdateDeviceImageRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	image, err := c.deviceService.UpdateDeviceImage(imageID, deviceID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, image)
+}
+
+// ReorderDeviceImages 调整设备图片顺序
+// @router /devices/:deviceId/images/order [put]
+func (c *DeviceController) ReorderDeviceImages() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.ReorderDeviceImagesRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	images, err := c.deviceService.ReorderDeviceImages(deviceID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, images)
+}
+
+// SetDeviceCoverImage 设置设备封面图
+// @router /devices/:deviceId/images/:imageId/cover [put]
+func (c *DeviceController) SetDeviceCoverImage() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取图片ID
+	imageIDStr := c.Ctx.Input.Param(":imageId")
+	imageID, err := strconv.Atoi(imageIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "图片ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	err = c.deviceService.SetDeviceCoverImage(imageID, deviceID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, nil)
+}
+
+// DownloadDeviceImage 流式下载设备图片，可通过 size=thumb/small/medium 获取缩略图
+// @router /devices/:deviceId/images/:imageId/file [get]
+func (c *DeviceController) DownloadDeviceImage() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取图片ID
+	imageIDStr := c.Ctx.Input.Param(":imageId")
+	imageID, err := strconv.Atoi(imageIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "图片ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	size := c.GetString("size")
+	reader, info, err := c.deviceService.OpenDeviceImage(imageID, deviceID, userID, size)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+	defer reader.Close()
+
+	// 直接写入响应体，避免将整个文件读入内存
+	header := c.Ctx.ResponseWriter.Header()
+	header.Set("Content-Type", info.ContentType)
+	header.Set("Cache-Control", "private, max-age=86400")
+	if info.Size >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	if !info.ModTime.IsZero() {
+		header.Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	c.Ctx.ResponseWriter.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(c.Ctx.ResponseWriter, reader); err != nil {
+		logs.Warn("输出设备图片失败: image=%d, err=%v", imageID, err)
+	}
+}
+
+// GetDeviceImageURL 获取设备图片的限时访问地址
+// @router /devices/:deviceId/images/:imageId/url [get]
+func (c *DeviceController) GetDeviceImageURL() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取图片ID
+	imageIDStr := c.Ctx.Input.Param(":imageId")
+	imageID, err := strconv.Atoi(imageIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "图片ID格式错误")
+		return
+	}
+
+	// 有效期（秒）
+	expiresIn, _ := c.GetInt("expires_in", 900)
+
+	// 调用服务层
+	response, err := c.deviceService.GetDeviceImageURL(imageID, deviceID, userID, expiresIn)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, response)
+}
+
+// PredictDevicePrice 预测设备价格
+// @router /devices/:deviceId/prediction [get]
+func (c *DeviceController) PredictDevicePrice() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 解析查询参数
+	req := &service.PricePredictionRequest{}
+	if err := c.ParseForm(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	prediction, err := c.deviceService.PredictDevicePrice(deviceID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, prediction)
+}
+
+// SuggestCategories 按分类规则为名称、品牌、型号给出分类与模板建议
+// @router /devices/categorize/suggest [post]
+func (c *DeviceController) SuggestCategories() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.SuggestCategoriesRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	result, err := c.deviceService.SuggestCategories(userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, result)
+}
+
+// CategorizeDevices 对未设置分类或模板的设备重新运行分类规则，dry_run 为 true 时只返回结果
+// @router /devices/categorize [post]
+func (c *DeviceController) CategorizeDevices() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析请求参数，请求体可以为空
+	req := &service.CategorizeDevicesRequest{}
+	if len(c.Ctx.Input.RequestBody) > 0 {
+		if err := c.BindJSON(req); err != nil {
+			utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+			return
+		}
+	}
+
+	// 调用服务层
+	result, err := c.deviceService.CategorizeDevices(userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, result)
+}
+
+// GetDeviceQRCode 获取设备二维码的 token 与链接，首次获取时生成
+// @router /devices/:deviceId/qrcode [get]
+func (c *DeviceController) GetDeviceQRCode() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	result, err := c.deviceService.GetDeviceQRCode(deviceID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, result)
+}
+
+// ResetDeviceQRCode 重新生成设备二维码，已打印的旧标签失效
+// @router /devices/:deviceId/qrcode/reset [post]
+func (c *DeviceController) ResetDeviceQRCode() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	result, err := c.deviceService.ResetDeviceQRCode(deviceID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, result)
+}
+
+// GetDeviceQRCodeImage 下载设备二维码图片，format=png/svg，size 为边长像素
+// @router /devices/:deviceId/qrcode/image [get]
+func (c *DeviceController) GetDeviceQRCodeImage() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 解析查询参数
+	req := &service.DeviceQRCodeImageRequest{}
+	if err := c.ParseForm(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	image, err := c.deviceService.GetDeviceQRCodeImage(deviceID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	header := c.Ctx.ResponseWriter.Header()
+	header.Set("Content-Type", image.ContentType)
+	header.Set("Content-Disposition", "inline; filename*=UTF-8''"+url.PathEscape(image.FileName))
+	header.Set("Cache-Control", "private, no-cache")
+	c.Ctx.ResponseWriter.WriteHeader(http.StatusOK)
+	if _, err := c.Ctx.ResponseWriter.Write(image.Data); err != nil {
+		logs.Warn("输出设备二维码失败: device=%d, err=%v", deviceID, err)
+	}
+}
+
+// GetDeviceLabels 为选中的设备生成可打印的 A4 标签PDF
+// @router /devices/labels [post]
+func (c *DeviceController) GetDeviceLabels() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.DeviceLabelsRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	file, err := c.deviceService.GetDeviceLabels(userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	header := c.Ctx.ResponseWriter.Header()
+	header.Set("Content-Type", "application/pdf")
+	header.Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(file.FileName))
+	header.Set("Content-Length", strconv.Itoa(len(file.Data)))
+	c.Ctx.ResponseWriter.WriteHeader(http.StatusOK)
+	if _, err := c.Ctx.ResponseWriter.Write(file.Data); err != nil {
+		logs.Warn("输出设备标签失败: user=%d, err=%v", userID, err)
+	}
+}
+
+// ScanDeviceQRCode 扫码查找设备，只能查到自己的设备
+// @router /devices/scan/:token [get]
+func (c *DeviceController) ScanDeviceQRCode() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	token := c.Ctx.Input.Param(":token")
+
+	// 调用服务层
+	device, err := c.deviceService.ScanDeviceQRCode(userID, token)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, device)
 }
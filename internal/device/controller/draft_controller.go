@@ -0,0 +1,242 @@
+package controller
+
+import (
+	base "Backend_Lili/internal/auth/controller"
+	"Backend_Lili/internal/device/service"
+	"Backend_Lili/pkg/utils"
+	"strconv"
+)
+
+type DraftController struct {
+	base.BaseController
+	draftService *service.DraftService
+}
+
+func NewDraftController() *DraftController {
+	return &DraftController{}
+}
+
+func (c *DraftController) Prepare() {
+	// 调用父类的Prepare方法
+	c.BaseController.Prepare()
+
+	// 初始化设备草稿服务
+	c.draftService = service.NewDraftService()
+}
+
+// ImportOrders 导入电商订单，生成待确认的设备草稿
+// @router /devices/drafts/import [post]
+func (c *DraftController) ImportOrders() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取上传的文件
+	file, header, err := c.GetFile("file")
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "获取上传文件失败")
+		return
+	}
+	defer file.Close()
+
+	// 解析其他参数
+	req := &service.ImportOrdersRequest{Platform: c.GetString("platform")}
+	if req.IncludeAll, err = c.GetBool("include_all", false); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "include_all 参数格式错误")
+		return
+	}
+
+	// 调用服务层
+	result, err := c.draftService.ImportOrders(userID, file, header, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, result)
+}
+
+// GetDrafts 获取设备草稿列表
+// @router /devices/drafts [get]
+func (c *DraftController) GetDrafts() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析查询参数
+	req := &service.GetDraftsRequest{}
+	if err := c.ParseForm(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	result, err := c.draftService.GetDrafts(userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, result)
+}
+
+// UpdateDraft 修改设备草稿
+// @router /devices/drafts/:draftId [put]
+func (c *DraftController) UpdateDraft() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取草稿ID
+	draftID, err := strconv.Atoi(c.Ctx.Input.Param(":draftId"))
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "草稿ID格式错误")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.UpdateDraftRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	draft, err := c.draftService.UpdateDraft(draftID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, draft)
+}
+
+// AcceptDraft 确认草稿并创建设备，请求体可选，用于确认前修改字段
+// @router /devices/drafts/:draftId/accept [post]
+func (c *DraftController) AcceptDraft() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取草稿ID
+	draftID, err := strconv.Atoi(c.Ctx.Input.Param(":draftId"))
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "草稿ID格式错误")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.UpdateDraftRequest{}
+	if len(c.Ctx.Input.RequestBody) > 0 {
+		if err := c.BindJSON(req); err != nil {
+			utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+			return
+		}
+	}
+
+	// 调用服务层
+	device, err := c.draftService.AcceptDraft(draftID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			// 疑似重复时附带重复设备列表
+			if businessErr.Details != nil {
+				utils.WriteErrorWithDetails(c.Ctx, businessErr.Code, businessErr.Message, businessErr.Details)
+			} else {
+				utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+			}
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, device)
+}
+
+// RejectDraft 拒绝草稿
+// @router /devices/drafts/:draftId/reject [post]
+func (c *DraftController) RejectDraft() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取草稿ID
+	draftID, err := strconv.Atoi(c.Ctx.Input.Param(":draftId"))
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "草稿ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	if err := c.draftService.RejectDraft(draftID, userID); err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, map[string]interface{}{
+		"message": "草稿已拒绝",
+	})
+}
+
+// DeleteDraft 删除草稿
+// @router /devices/drafts/:draftId [delete]
+func (c *DraftController) DeleteDraft() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取草稿ID
+	draftID, err := strconv.Atoi(c.Ctx.Input.Param(":draftId"))
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "草稿ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	if err := c.draftService.DeleteDraft(draftID, userID); err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, map[string]interface{}{
+		"message": "草稿删除成功",
+	})
+}
@@ -0,0 +1,225 @@
+package controller
+
+import (
+	base "Backend_Lili/internal/auth/controller"
+	"Backend_Lili/internal/device/service"
+	"Backend_Lili/pkg/utils"
+	"strconv"
+)
+
+type ServiceRecordController struct {
+	base.BaseController
+	recordService *service.ServiceRecordService
+}
+
+func NewServiceRecordController() *ServiceRecordController {
+	return &ServiceRecordController{}
+}
+
+func (c *ServiceRecordController) Prepare() {
+	// 调用父类的Prepare方法
+	c.BaseController.Prepare()
+
+	// 初始化维修保养记录服务
+	c.recordService = service.NewServiceRecordService()
+}
+
+// GetServiceRecords 获取设备维修保养记录
+// @router /devices/:deviceId/service-records [get]
+func (c *ServiceRecordController) GetServiceRecords() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	response, err := c.recordService.GetServiceRecords(deviceID, userID, c.GetString("service_type"))
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, response)
+}
+
+// CreateServiceRecord 创建维修保养记录
+// @router /devices/:deviceId/service-records [post]
+func (c *ServiceRecordController) CreateServiceRecord() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.ServiceRecordRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	record, err := c.recordService.CreateServiceRecord(deviceID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, record)
+}
+
+// GetServiceRecord 获取维修保养记录详情
+// @router /devices/:deviceId/service-records/:recordId [get]
+func (c *ServiceRecordController) GetServiceRecord() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取记录ID
+	recordIDStr := c.Ctx.Input.Param(":recordId")
+	recordID, err := strconv.Atoi(recordIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "记录ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	record, err := c.recordService.GetServiceRecord(recordID, deviceID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, record)
+}
+
+// UpdateServiceRecord 更新维修保养记录
+// @router /devices/:deviceId/service-records/:recordId [put]
+func (c *ServiceRecordController) UpdateServiceRecord() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取记录ID
+	recordIDStr := c.Ctx.Input.Param(":recordId")
+	recordID, err := strconv.Atoi(recordIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "记录ID格式错误")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.ServiceRecordRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	record, err := c.recordService.UpdateServiceRecord(recordID, deviceID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, record)
+}
+
+// DeleteServiceRecord 删除维修保养记录
+// @router /devices/:deviceId/service-records/:recordId [delete]
+func (c *ServiceRecordController) DeleteServiceRecord() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取设备ID
+	deviceIDStr := c.Ctx.Input.Param(":deviceId")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "设备ID格式错误")
+		return
+	}
+
+	// 获取记录ID
+	recordIDStr := c.Ctx.Input.Param(":recordId")
+	recordID, err := strconv.Atoi(recordIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "记录ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	err = c.recordService.DeleteServiceRecord(recordID, deviceID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, map[string]interface{}{
+		"message": "维修保养记录删除成功",
+	})
+}
@@ -5,9 +5,15 @@ import (
 	"Backend_Lili/internal/device/service"
 	"Backend_Lili/pkg/utils"
 	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
 	"strconv"
 )
 
+// templateImportMaxSize 导入模板文件的大小上限
+const templateImportMaxSize = 1 << 20
+
 type TemplateController struct {
 	base.BaseController
 	templateService *service.TemplateService
@@ -28,6 +34,13 @@ func (c *TemplateController) Prepare() {
 // GetTemplatesList 获取设备模板列表
 // @router /device-templates [get]
 func (c *TemplateController) GetTemplatesList() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
 	// 解析查询参数
 	req := &service.GetTemplatesListRequest{}
 	if err := c.ParseForm(req); err != nil {
@@ -36,7 +49,7 @@ func (c *TemplateController) GetTemplatesList() {
 	}
 
 	// 调用服务层
-	response, err := c.templateService.GetTemplatesList(req)
+	response, err := c.templateService.GetTemplatesList(userID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
 			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
@@ -52,6 +65,13 @@ func (c *TemplateController) GetTemplatesList() {
 // GetTemplateDetail 获取设备模板详情
 // @router /device-templates/:template_id [get]
 func (c *TemplateController) GetTemplateDetail() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
 	// 获取路径参数
 	templateIDStr := c.Ctx.Input.Param(":template_id")
 	templateID, err := strconv.Atoi(templateIDStr)
@@ -61,7 +81,7 @@ func (c *TemplateController) GetTemplateDetail() {
 	}
 
 	// 调用服务层
-	template, err := c.templateService.GetTemplateDetail(templateID)
+	template, err := c.templateService.GetTemplateDetail(userID, templateID)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
 			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
@@ -71,12 +91,20 @@ func (c *TemplateController) GetTemplateDetail() {
 		return
 	}
 
+	utils.SetETag(c.Ctx, template.Version)
 	utils.WriteSuccess(c.Ctx, template)
 }
 
 // GetTemplateFields 获取模板字段定义
 // @router /device-templates/:template_id/fields [get]
 func (c *TemplateController) GetTemplateFields() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
 	// 获取路径参数
 	templateIDStr := c.Ctx.Input.Param(":template_id")
 	templateID, err := strconv.Atoi(templateIDStr)
@@ -86,7 +114,7 @@ func (c *TemplateController) GetTemplateFields() {
 	}
 
 	// 调用服务层
-	fields, err := c.templateService.GetTemplateFields(templateID)
+	fields, err := c.templateService.GetTemplateFields(userID, templateID)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
 			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
@@ -97,7 +125,8 @@ func (c *TemplateController) GetTemplateFields() {
 	}
 
 	utils.WriteSuccess(c.Ctx, map[string]interface{}{
-		"fields": fields,
+		"fields":      fields,
+		"field_types": service.TemplateFieldTypes,
 	})
 }
 
@@ -105,7 +134,7 @@ func (c *TemplateController) GetTemplateFields() {
 // @router /device-templates [post]
 func (c *TemplateController) CreateTemplate() {
 	// 从JWT中获取用户ID
-	userID, ok := c.Ctx.Input.GetData("userID").(int)
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
 		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
 		return
@@ -136,7 +165,7 @@ func (c *TemplateController) CreateTemplate() {
 // @router /device-templates/:template_id [put]
 func (c *TemplateController) UpdateTemplate() {
 	// 从JWT中获取用户ID
-	userID, ok := c.Ctx.Input.GetData("userID").(int)
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
 		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
 		return
@@ -157,17 +186,26 @@ func (c *TemplateController) UpdateTemplate() {
 		return
 	}
 
+	// 读取客户端持有的版本号
+	version, fromHeader, err := utils.ResolveVersion(c.Ctx, req.Version)
+	if err != nil {
+		utils.HandleBusinessError(c.Ctx, err)
+		return
+	}
+	req.Version = version
+
 	// 调用服务层
 	template, err := c.templateService.UpdateTemplate(userID, templateID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
-			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+			utils.WriteVersionedError(c.Ctx, businessErr, fromHeader)
 		} else {
 			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
 		}
 		return
 	}
 
+	utils.SetETag(c.Ctx, template.Version)
 	utils.WriteSuccess(c.Ctx, template)
 }
 
@@ -175,7 +213,7 @@ func (c *TemplateController) UpdateTemplate() {
 // @router /device-templates/:template_id [delete]
 func (c *TemplateController) DeleteTemplate() {
 	// 从JWT中获取用户ID
-	userID, ok := c.Ctx.Input.GetData("userID").(int)
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
 		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
 		return
@@ -234,6 +272,13 @@ func (c *TemplateController) GetPopularTemplates() {
 // ValidateDeviceData 根据模板验证设备数据
 // @router /device-templates/:template_id/validate [post]
 func (c *TemplateController) ValidateDeviceData() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
 	// 获取路径参数
 	templateIDStr := c.Ctx.Input.Param(":template_id")
 	templateID, err := strconv.Atoi(templateIDStr)
@@ -250,7 +295,7 @@ func (c *TemplateController) ValidateDeviceData() {
 	}
 
 	// 调用服务层
-	response, err := c.templateService.ValidateDeviceData(templateID, req)
+	response, err := c.templateService.ValidateDeviceData(userID, templateID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
 			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
@@ -267,7 +312,7 @@ func (c *TemplateController) ValidateDeviceData() {
 // @router /device-templates/recommendations [get]
 func (c *TemplateController) GetRecommendedTemplates() {
 	// 从JWT中获取用户ID
-	userID, ok := c.Ctx.Input.GetData("userID").(int)
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
 		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
 		return
@@ -292,6 +337,13 @@ func (c *TemplateController) GetRecommendedTemplates() {
 // GetTemplateStatistics 获取模板统计信息
 // @router /device-templates/:template_id/statistics [get]
 func (c *TemplateController) GetTemplateStatistics() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
 	// 获取路径参数
 	templateIDStr := c.Ctx.Input.Param(":template_id")
 	templateID, err := strconv.Atoi(templateIDStr)
@@ -301,7 +353,7 @@ func (c *TemplateController) GetTemplateStatistics() {
 	}
 
 	// 调用服务层
-	statistics, err := c.templateService.GetTemplateStatistics(templateID)
+	statistics, err := c.templateService.GetTemplateStatistics(userID, templateID)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
 			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
@@ -313,3 +365,478 @@ func (c *TemplateController) GetTemplateStatistics() {
 
 	utils.WriteSuccess(c.Ctx, statistics)
 }
+
+// GetTemplateVersions 获取模板的字段版本列表
+// @router /device-templates/:template_id/versions [get]
+func (c *TemplateController) GetTemplateVersions() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	templateIDStr := c.Ctx.Input.Param(":template_id")
+	templateID, err := strconv.Atoi(templateIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	response, err := c.templateService.GetTemplateVersions(userID, templateID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, response)
+}
+
+// GetTemplateVersion 获取模板的指定字段版本
+// @router /device-templates/:template_id/versions/:version [get]
+func (c *TemplateController) GetTemplateVersion() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	templateIDStr := c.Ctx.Input.Param(":template_id")
+	templateID, err := strconv.Atoi(templateIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板ID格式错误")
+		return
+	}
+	version, err := strconv.Atoi(c.Ctx.Input.Param(":version"))
+	if err != nil || version <= 0 {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板版本格式错误")
+		return
+	}
+
+	// 调用服务层
+	info, err := c.templateService.GetTemplateVersion(userID, templateID, version)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, info)
+}
+
+// DiffTemplateVersions 对比模板的两个字段版本
+// @router /device-templates/:template_id/diff [get]
+func (c *TemplateController) DiffTemplateVersions() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	templateIDStr := c.Ctx.Input.Param(":template_id")
+	templateID, err := strconv.Atoi(templateIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板ID格式错误")
+		return
+	}
+
+	// 解析查询参数
+	req := &service.DiffTemplateVersionsRequest{}
+	if err := c.ParseForm(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	diff, err := c.templateService.DiffTemplateVersions(userID, templateID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, diff)
+}
+
+// MigrateTemplateDevices 把使用旧字段版本的设备迁移到目标版本（管理员），默认只预览
+// @router /device-templates/:template_id/migrate [post]
+func (c *TemplateController) MigrateTemplateDevices() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	templateIDStr := c.Ctx.Input.Param(":template_id")
+	templateID, err := strconv.Atoi(templateIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板ID格式错误")
+		return
+	}
+
+	// 解析请求体
+	req := &service.MigrateTemplateDevicesRequest{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "请求体解析失败")
+		return
+	}
+
+	// 调用服务层
+	response, err := c.templateService.MigrateTemplateDevices(userID, templateID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, response)
+}
+
+// SetTemplateVisibility 修改模板的可见范围
+// @router /device-templates/:template_id/visibility [put]
+func (c *TemplateController) SetTemplateVisibility() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	templateIDStr := c.Ctx.Input.Param(":template_id")
+	templateID, err := strconv.Atoi(templateIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板ID格式错误")
+		return
+	}
+
+	// 解析请求体
+	req := &service.SetTemplateVisibilityRequest{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "请求体解析失败")
+		return
+	}
+
+	// 读取客户端持有的版本号
+	version, fromHeader, err := utils.ResolveVersion(c.Ctx, req.Version)
+	if err != nil {
+		utils.HandleBusinessError(c.Ctx, err)
+		return
+	}
+	req.Version = version
+
+	// 调用服务层
+	template, err := c.templateService.SetTemplateVisibility(userID, templateID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteVersionedError(c.Ctx, businessErr, fromHeader)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.SetETag(c.Ctx, template.Version)
+	utils.WriteSuccess(c.Ctx, template)
+}
+
+// ForkTemplate 复制模板到自己名下
+// @router /device-templates/:template_id/fork [post]
+func (c *TemplateController) ForkTemplate() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	templateIDStr := c.Ctx.Input.Param(":template_id")
+	templateID, err := strconv.Atoi(templateIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板ID格式错误")
+		return
+	}
+
+	// 解析请求体，请求体可以为空
+	req := &service.ForkTemplateRequest{}
+	if len(c.Ctx.Input.RequestBody) > 0 {
+		if err := json.Unmarshal(c.Ctx.Input.RequestBody, req); err != nil {
+			utils.WriteError(c.Ctx, utils.ERROR_PARAM, "请求体解析失败")
+			return
+		}
+	}
+
+	// 调用服务层
+	template, err := c.templateService.ForkTemplate(userID, templateID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, template)
+}
+
+// RateTemplate 为模板评分，重复评分时覆盖之前的评分
+// @router /device-templates/:template_id/ratings [post]
+func (c *TemplateController) RateTemplate() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	templateIDStr := c.Ctx.Input.Param(":template_id")
+	templateID, err := strconv.Atoi(templateIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板ID格式错误")
+		return
+	}
+
+	// 解析请求体
+	req := &service.RateTemplateRequest{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "请求体解析失败")
+		return
+	}
+
+	// 调用服务层
+	rating, err := c.templateService.RateTemplate(userID, templateID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, rating)
+}
+
+// GetTemplateRatings 获取模板的评分列表
+// @router /device-templates/:template_id/ratings [get]
+func (c *TemplateController) GetTemplateRatings() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	templateIDStr := c.Ctx.Input.Param(":template_id")
+	templateID, err := strconv.Atoi(templateIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板ID格式错误")
+		return
+	}
+
+	// 解析查询参数
+	req := &service.GetTemplateRatingsRequest{}
+	if err := c.ParseForm(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	response, err := c.templateService.GetTemplateRatings(userID, templateID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, response)
+}
+
+// ExportTemplate 把模板导出为JSON文件
+// @router /device-templates/:template_id/export [get]
+func (c *TemplateController) ExportTemplate() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	templateIDStr := c.Ctx.Input.Param(":template_id")
+	templateID, err := strconv.Atoi(templateIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	file, err := c.templateService.ExportTemplate(userID, templateID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_SERVER, "导出模板失败")
+		return
+	}
+	header := c.Ctx.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(file.Name+".template.json"))
+	c.Ctx.ResponseWriter.WriteHeader(http.StatusOK)
+	c.Ctx.ResponseWriter.Write(content)
+}
+
+// ImportTemplate 从导出的JSON文件导入模板，支持 multipart 上传 file 或直接提交文件内容
+// @router /device-templates/import [post]
+func (c *TemplateController) ImportTemplate() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 读取模板文件
+	data := c.Ctx.Input.RequestBody
+	if file, _, err := c.GetFile("file"); err == nil {
+		defer file.Close()
+		if data, err = io.ReadAll(io.LimitReader(file, templateImportMaxSize+1)); err != nil {
+			utils.WriteError(c.Ctx, utils.ERROR_PARAM, "读取上传文件失败")
+			return
+		}
+	}
+	if len(data) == 0 {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "请上传模板文件")
+		return
+	}
+	if len(data) > templateImportMaxSize {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板文件过大")
+		return
+	}
+
+	// 解析其他参数
+	req := &service.ImportTemplateRequest{Name: c.GetString("name")}
+	var err error
+	if req.CategoryID, err = c.GetInt("category_id", 0); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "分类ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	template, err := c.templateService.ImportTemplate(userID, data, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, template)
+}
+
+// GetReviewTemplates 获取待审核的公开模板（管理员）
+// @router /device-templates/reviews [get]
+func (c *TemplateController) GetReviewTemplates() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析查询参数
+	req := &service.GetReviewTemplatesRequest{}
+	if err := c.ParseForm(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	response, err := c.templateService.GetReviewTemplates(userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, response)
+}
+
+// ReviewTemplate 审核公开模板（管理员）
+// @router /device-templates/:template_id/review [post]
+func (c *TemplateController) ReviewTemplate() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取路径参数
+	templateIDStr := c.Ctx.Input.Param(":template_id")
+	templateID, err := strconv.Atoi(templateIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "模板ID格式错误")
+		return
+	}
+
+	// 解析请求体
+	req := &service.ReviewTemplateRequest{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "请求体解析失败")
+		return
+	}
+
+	// 调用服务层
+	template, err := c.templateService.ReviewTemplate(userID, templateID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, template)
+}
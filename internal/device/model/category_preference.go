@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// CategoryPreference 用户对系统分类的个人设置（隐藏、排序），只影响该用户看到的分类列表
+type CategoryPreference struct {
+	ID         int       `orm:"column(id);auto;pk" json:"id"`
+	UserID     int       `orm:"column(user_id)" json:"user_id"`
+	CategoryID int       `orm:"column(category_id)" json:"category_id"`
+	Hidden     bool      `orm:"column(hidden);default(false)" json:"hidden"`
+	SortOrder  *int      `orm:"column(sort_order);null" json:"sort_order"` // 为空时使用分类自身的排序
+	CreatedAt  time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+	UpdatedAt  time.Time `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
+}
+
+func (cp *CategoryPreference) TableName() string {
+	return "user_category_prefs"
+}
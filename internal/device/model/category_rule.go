@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// CategoryRule 自动分类规则：按品牌、型号、名称关键字与正则匹配设备，给出分类与模板建议；
+// user_id 为空的是系统规则，只有管理员可以维护
+type CategoryRule struct {
+	ID            int       `orm:"column(id);auto;pk" json:"id"`
+	UserID        *int      `orm:"column(user_id);null" json:"user_id"`
+	Name          string    `orm:"column(name);size(100)" json:"name"`
+	Brands        string    `orm:"column(brands);size(500);null" json:"brands"`                 // 品牌，多个以逗号分隔，不区分大小写完全匹配
+	ModelKeywords string    `orm:"column(model_keywords);size(500);null" json:"model_keywords"` // 型号关键字，多个以逗号分隔，包含任一即可
+	NameKeywords  string    `orm:"column(name_keywords);size(500);null" json:"name_keywords"`   // 名称关键字，多个以逗号分隔，包含任一即可
+	Pattern       string    `orm:"column(pattern);size(255);null" json:"pattern"`               // 正则，匹配「品牌 型号 名称」，不区分大小写
+	CategoryID    *int      `orm:"column(category_id);null" json:"category_id"`
+	TemplateID    *int      `orm:"column(template_id);null" json:"template_id"`
+	Priority      int       `orm:"column(priority);default(0)" json:"priority"` // 多条规则匹配时优先级高的在前
+	IsActive      bool      `orm:"column(is_active);default(true)" json:"is_active"`
+	CreatedAt     time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+	UpdatedAt     time.Time `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
+}
+
+func (cr *CategoryRule) TableName() string {
+	return "category_rules"
+}
+
+// CategorySuggestion 分类规则给出的分类与模板建议，不存储在数据库
+type CategorySuggestion struct {
+	RuleID       int      `json:"rule_id"`
+	RuleName     string   `json:"rule_name"`
+	System       bool     `json:"system"` // 是否为系统规则
+	CategoryID   int      `json:"category_id,omitempty"`
+	CategoryName string   `json:"category_name,omitempty"`
+	TemplateID   int      `json:"template_id,omitempty"`
+	TemplateName string   `json:"template_name,omitempty"`
+	Confidence   float64  `json:"confidence"` // 0-1，由命中的条件计算
+	Matched      []string `json:"matched"`    // 命中的条件：brand/model/name/pattern
+	Applied      bool     `json:"applied"`    // 是否已用于填充分类或模板
+}
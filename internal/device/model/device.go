@@ -1,40 +1,67 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 )
 
 // Device 设备信息表
 type Device struct {
-	ID             int       `orm:"column(id);auto;pk" json:"id"`
-	UserID         int       `orm:"column(user_id)" json:"user_id"`
-	TemplateID     *int      `orm:"column(template_id);null" json:"template_id"`
-	CategoryID     *int      `orm:"column(category_id);null" json:"category_id"`
-	Name           string    `orm:"column(name);size(200)" json:"name"`
-	Brand          string    `orm:"column(brand);size(100)" json:"brand"`
-	Model          string    `orm:"column(model);size(100)" json:"model"`
-	SerialNumber   string    `orm:"column(serial_number);size(200);null" json:"serial_number"`
-	Color          string    `orm:"column(color);size(50);null" json:"color"`
-	Storage        string    `orm:"column(storage);size(50);null" json:"storage"`
-	Memory         string    `orm:"column(memory);size(50);null" json:"memory"`
-	Processor      string    `orm:"column(processor);size(100);null" json:"processor"`
-	ScreenSize     string    `orm:"column(screen_size);size(50);null" json:"screen_size"`
-	PurchasePrice  float64   `orm:"column(purchase_price);digits(10);decimals(2)" json:"purchase_price"`
-	CurrentValue   float64   `orm:"column(current_value);digits(10);decimals(2);default(0)" json:"current_value"`
-	PurchaseDate   time.Time `orm:"column(purchase_date);type(date)" json:"purchase_date"`
-	WarrantyDate   time.Time `orm:"column(warranty_date);type(date);null" json:"warranty_date"`
-	Condition      string    `orm:"column(condition);size(20);default(new)" json:"condition"` // new/good/fair/poor
-	Status         string    `orm:"column(status);size(20);default(active)" json:"status"`    // active/sold/broken/lost
-	SalePrice      float64   `orm:"column(sale_price);digits(10);decimals(2);null" json:"sale_price"`
-	SaleDate       time.Time `orm:"column(sale_date);type(date);null" json:"sale_date"`
-	Notes          string    `orm:"column(notes);type(text);null" json:"notes"`
-	Specifications string    `orm:"column(specifications);type(json);null" json:"specifications"` // JSON格式存储其他规格
-	CreatedAt      time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
-	UpdatedAt      time.Time `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
-	DeletedAt      time.Time `orm:"column(deleted_at);null;type(datetime)" json:"-"`
+	ID              int       `orm:"column(id);auto;pk" json:"id"`
+	UserID          int       `orm:"column(user_id)" json:"user_id"`
+	TemplateID      *int      `orm:"column(template_id);null" json:"template_id"`
+	CategoryID      *int      `orm:"column(category_id);null" json:"category_id"`
+	ParentID        *int      `orm:"column(parent_id);null" json:"parent_id"` // 所属套装的主设备，为空表示独立设备或主设备
+	Name            string    `orm:"column(name);size(200)" json:"name"`
+	Brand           string    `orm:"column(brand);size(100)" json:"brand"`
+	Model           string    `orm:"column(model);size(100)" json:"model"`
+	SerialNumber    string    `orm:"column(serial_number);size(200);null" json:"serial_number"`
+	Color           string    `orm:"column(color);size(50);null" json:"color"`
+	Storage         string    `orm:"column(storage);size(50);null" json:"storage"`
+	Memory          string    `orm:"column(memory);size(50);null" json:"memory"`
+	Processor       string    `orm:"column(processor);size(100);null" json:"processor"`
+	ScreenSize      string    `orm:"column(screen_size);size(50);null" json:"screen_size"`
+	PurchasePrice   float64   `orm:"column(purchase_price);digits(10);decimals(2)" json:"purchase_price"`
+	CurrentValue    float64   `orm:"column(current_value);digits(10);decimals(2);default(0)" json:"current_value"`
+	PurchaseDate    time.Time `orm:"column(purchase_date);type(date)" json:"purchase_date"`
+	WarrantyDate    time.Time `orm:"column(warranty_date);type(date);null" json:"warranty_date"`
+	Condition       string    `orm:"column(condition);size(20);default(new)" json:"condition"` // new/good/fair/poor
+	Status          string    `orm:"column(status);size(20);default(active)" json:"status"`    // active/sold/broken/lost
+	SalePrice       float64   `orm:"column(sale_price);digits(10);decimals(2);null" json:"sale_price"`
+	SaleDate        time.Time `orm:"column(sale_date);type(date);null" json:"sale_date"`
+	Notes           string    `orm:"column(notes);type(text);null" json:"notes"`
+	Specifications  string    `orm:"column(specifications);type(json);null" json:"specifications"` // JSON格式存储其他规格
+	TemplateVersion int       `orm:"column(template_version);default(0)" json:"template_version"`  // 规格参数对应的模板字段版本，0表示未知
+	Version         int       `orm:"column(version);default(1)" json:"version"`                    // 乐观锁版本号，每次修改加1
+	CreatedAt       time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+	UpdatedAt       time.Time `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
+	DeletedAt       time.Time `orm:"column(deleted_at);null;type(datetime)" json:"-"`
 
 	// 关联字段 (不使用ORM自动关联，在代码中手动加载)
-	Images []*DeviceImage `orm:"-" json:"images,omitempty"`
+	Images      []*DeviceImage        `orm:"-" json:"images,omitempty"`
+	CoverImage  *DeviceImage          `orm:"-" json:"cover_image,omitempty"` // 封面图，列表中使用
+	Attachments []*DeviceAttachment   `orm:"-" json:"attachments,omitempty"`
+	Ownership   *OwnershipCost        `orm:"-" json:"ownership_cost,omitempty"`       // 持有总成本，详情中使用
+	Accessories []*Device             `orm:"-" json:"accessories,omitempty"`          // 套装配件，详情中使用
+	Kit         *KitSummary           `orm:"-" json:"kit,omitempty"`                  // 套装汇总，仅含配件的主设备
+	TagIDs      []int                 `orm:"-" json:"tag_ids,omitempty"`              // 设备标签，详情中使用
+	Suggestions []*CategorySuggestion `orm:"-" json:"category_suggestions,omitempty"` // 分类规则的建议，创建时未指定分类或模板时返回
+}
+
+// KitSummary 套装汇总（主设备+配件），不存储在数据库
+type KitSummary struct {
+	AccessoryCount     int     `json:"accessory_count"`
+	TotalPurchasePrice float64 `json:"total_purchase_price"` // 主设备与全部配件的购买价格合计
+	TotalCurrentValue  float64 `json:"total_current_value"`  // 主设备与未出售、未丢失配件的当前估值合计
+}
+
+// AddAccessory 将配件计入套装汇总
+func (k *KitSummary) AddAccessory(accessory *Device) {
+	k.AccessoryCount++
+	k.TotalPurchasePrice += accessory.PurchasePrice
+	if accessory.Status != "sold" && accessory.Status != "lost" {
+		k.TotalCurrentValue += accessory.CurrentValue
+	}
 }
 
 func (d *Device) TableName() string {
@@ -43,36 +70,69 @@ func (d *Device) TableName() string {
 
 // DeviceTemplate 设备模板表
 type DeviceTemplate struct {
-	ID          int       `orm:"column(id);auto;pk" json:"id"`
-	Name        string    `orm:"column(name);size(100)" json:"name"`
-	Description string    `orm:"column(description);type(text);null" json:"description"`
-	Icon        string    `orm:"column(icon);size(500);null" json:"icon"`
-	Fields      string    `orm:"column(fields);type(json)" json:"fields"` // JSON格式定义字段模板
-	CategoryID  *int      `orm:"column(category_id);null" json:"category_id"`
-	IsActive    bool      `orm:"column(is_active);default(true)" json:"is_active"`
-	UseCount    int       `orm:"column(use_count);default(0)" json:"use_count"` // 使用次数，用于热门模板统计
-	CreatedAt   time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
-	UpdatedAt   time.Time `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
-	DeletedAt   time.Time `orm:"column(deleted_at);null;type(datetime)" json:"-"`
-
-
+	ID            int        `orm:"column(id);auto;pk" json:"id"`
+	Name          string     `orm:"column(name);size(100)" json:"name"`
+	Description   string     `orm:"column(description);type(text);null" json:"description"`
+	Icon          string     `orm:"column(icon);size(500);null" json:"icon"`
+	Fields        string     `orm:"column(fields);type(json)" json:"fields"` // JSON格式定义字段模板
+	CategoryID    *int       `orm:"column(category_id);null" json:"category_id"`
+	IsActive      bool       `orm:"column(is_active);default(true)" json:"is_active"`
+	UseCount      int        `orm:"column(use_count);default(0)" json:"use_count"`                         // 使用次数，用于热门模板统计
+	OwnerID       *int       `orm:"column(owner_id);null" json:"owner_id"`                                 // 创建者，为空表示系统模板
+	Visibility    string     `orm:"column(visibility);size(20);default(public)" json:"visibility"`         // private/unlisted/public
+	ReviewStatus  string     `orm:"column(review_status);size(20);default(approved)" json:"review_status"` // none/pending/approved/rejected，公开模板审核通过后进入模板库
+	ReviewNote    string     `orm:"column(review_note);size(500);null" json:"review_note"`                 // 审核意见
+	ReviewedAt    *time.Time `orm:"column(reviewed_at);type(datetime);null" json:"reviewed_at,omitempty"`
+	ForkedFromID  *int       `orm:"column(forked_from_id);null" json:"forked_from_id"` // 复制来源模板
+	ForkCount     int        `orm:"column(fork_count);default(0)" json:"fork_count"`
+	RatingCount   int        `orm:"column(rating_count);default(0)" json:"rating_count"`
+	RatingAvg     float64    `orm:"column(rating_avg);digits(3);decimals(2);default(0)" json:"rating_avg"` // 平均评分(1-5)
+	FieldsVersion int        `orm:"column(fields_version);default(1)" json:"fields_version"`               // 当前字段定义的版本号，修改字段时递增
+	Version       int        `orm:"column(version);default(1)" json:"version"`                             // 乐观锁版本号，每次修改加1
+	CreatedAt     time.Time  `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+	UpdatedAt     time.Time  `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
+	DeletedAt     time.Time  `orm:"column(deleted_at);null;type(datetime)" json:"-"`
 }
 
 func (dt *DeviceTemplate) TableName() string {
 	return "device_templates"
 }
 
+// 模板可见范围
+const (
+	TemplateVisibilityPrivate  = "private"  // 仅创建者可见
+	TemplateVisibilityUnlisted = "unlisted" // 知道模板ID即可查看与使用，不出现在模板库
+	TemplateVisibilityPublic   = "public"   // 审核通过后出现在模板库
+)
+
+// 模板审核状态
+const (
+	TemplateReviewNone     = "none"     // 未公开，无需审核
+	TemplateReviewPending  = "pending"  // 等待管理员审核
+	TemplateReviewApproved = "approved" // 审核通过
+	TemplateReviewRejected = "rejected" // 审核未通过
+)
+
+// 字段验证规则的语法版本，记录在 device_templates 与 device_template_versions 的 rule_syntax 列；
+// 该列不映射到模型，新记录使用列默认值（当前语法）
+const (
+	RuleSyntaxLegacy  = 1 // 旧语法：只有 min/max/regex，min/max 对所有字段类型都表示长度
+	RuleSyntaxCurrent = 2
+)
+
 // TemplateField 模板字段定义结构
 type TemplateField struct {
-	FieldName       string   `json:"field_name"`       // 字段名称
-	FieldLabel      string   `json:"field_label"`      // 字段显示名称
-	FieldType       string   `json:"field_type"`       // 字段类型：text/number/select/date/textarea
-	Required        bool     `json:"required"`         // 是否必填
-	DefaultValue    string   `json:"default_value"`    // 默认值
-	ValidationRules string   `json:"validation_rules"` // 验证规则
-	Options         []string `json:"options"`          // 选项列表(仅select类型)
-	Placeholder     string   `json:"placeholder"`      // 占位符
-	HelpText        string   `json:"help_text"`        // 帮助文本
+	FieldName       string           `json:"field_name"`         // 字段名称
+	FieldLabel      string           `json:"field_label"`        // 字段显示名称
+	FieldType       string           `json:"field_type"`         // 字段类型：text/number/select/date/textarea/multiselect/boolean/money/url/image/group
+	Required        bool             `json:"required"`           // 是否必填
+	DefaultValue    string           `json:"default_value"`      // 默认值
+	ValidationRules string           `json:"validation_rules"`   // 验证规则
+	Options         []string         `json:"options"`            // 选项列表(select/multiselect的选项，money的可选货币)
+	Currency        string           `json:"currency,omitempty"` // 默认货币(仅money类型)，ISO 4217代码，默认CNY
+	Fields          []*TemplateField `json:"fields,omitempty"`   // 子字段(仅group类型)，每一项按子字段填写
+	Placeholder     string           `json:"placeholder"`        // 占位符
+	HelpText        string           `json:"help_text"`          // 帮助文本
 }
 
 // Category 设备分类表
@@ -87,7 +147,9 @@ type Category struct {
 	Type        string    `orm:"column(type);size(20);default(system)" json:"type"` // system/custom
 	UserID      int       `orm:"column(user_id);null" json:"user_id"`               // 自定义分类的用户ID
 	IsActive    bool      `orm:"column(is_active);default(true)" json:"is_active"`
-	DeviceCount int       `orm:"-" json:"device_count,omitempty"` // 设备数量，不存储在数据库
+	Version     int       `orm:"column(version);default(1)" json:"version"` // 乐观锁版本号，每次修改加1
+	DeviceCount int       `orm:"-" json:"device_count,omitempty"`           // 设备数量，不存储在数据库
+	Hidden      bool      `orm:"-" json:"hidden,omitempty"`                 // 当前用户是否隐藏了此系统分类，不存储在数据库
 	CreatedAt   time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
 	UpdatedAt   time.Time `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
 	DeletedAt   time.Time `orm:"column(deleted_at);null;type(datetime)" json:"-"`
@@ -102,18 +164,115 @@ func (c *Category) TableName() string {
 
 // DeviceImage 设备图片表
 type DeviceImage struct {
-	ID        int       `orm:"column(id);auto;pk" json:"id"`
-	DeviceID  int       `orm:"column(device_id)" json:"device_id"`
-	ImageURL  string    `orm:"column(image_url);size(500)" json:"image_url"`
-	ImageType string    `orm:"column(image_type);size(20);default(normal)" json:"image_type"` // normal/cover
-	SortOrder int       `orm:"column(sort_order);default(0)" json:"sort_order"`
-	CreatedAt time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
-
+	ID          int       `orm:"column(id);auto;pk" json:"id"`
+	DeviceID    int       `orm:"column(device_id)" json:"device_id"`
+	ImageURL    string    `orm:"column(image_url);size(500)" json:"image_url"`
+	StorageKey  string    `orm:"column(storage_key);size(500);null" json:"-"`            // 对象存储中的键
+	ContentHash string    `orm:"column(content_hash);size(64);null" json:"content_hash"` // 原始文件SHA-256，用于去重
+	ContentType string    `orm:"column(content_type);size(50);null" json:"content_type"`
+	Width       int       `orm:"column(width);default(0)" json:"width"`
+	Height      int       `orm:"column(height);default(0)" json:"height"`
+	ByteSize    int64     `orm:"column(byte_size);default(0)" json:"byte_size"`
+	Variants    string    `orm:"column(variants);type(json);null" json:"-"` // 缩略图信息，JSON格式
+	Caption     string    `orm:"column(caption);size(500);null" json:"caption"`
+	ImageType   string    `orm:"column(image_type);size(20);default(normal)" json:"image_type"` // normal/cover
+	SortOrder   int       `orm:"column(sort_order);default(0)" json:"sort_order"`
+	CreatedAt   time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
 
+	// 各尺寸访问地址（original/thumb/small/medium），不存储在数据库
+	URLs map[string]string `orm:"-" json:"urls,omitempty"`
 }
 
 func (di *DeviceImage) TableName() string {
 	return "device_images"
 }
 
+// ImageVariant 图片衍生尺寸
+type ImageVariant struct {
+	Key      string `json:"key"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	ByteSize int64  `json:"byte_size"`
+}
+
+// VariantMap 解析缩略图信息，键为尺寸名称
+func (di *DeviceImage) VariantMap() map[string]*ImageVariant {
+	variants := make(map[string]*ImageVariant)
+	if di.Variants != "" {
+		_ = json.Unmarshal([]byte(di.Variants), &variants)
+	}
+	return variants
+}
+
+// StorageKeys 返回原图及全部缩略图的存储键
+func (di *DeviceImage) StorageKeys() []string {
+	var keys []string
+	if di.StorageKey != "" {
+		keys = append(keys, di.StorageKey)
+	}
+	for _, v := range di.VariantMap() {
+		if v.Key != "" && v.Key != di.StorageKey {
+			keys = append(keys, v.Key)
+		}
+	}
+	return keys
+}
+
+// DeviceAttachment 设备附件表（购物小票、电子发票、保修卡、说明书、维修单据）
+type DeviceAttachment struct {
+	ID              int        `orm:"column(id);auto;pk" json:"id"`
+	DeviceID        int        `orm:"column(device_id)" json:"device_id"`
+	UserID          int        `orm:"column(user_id)" json:"user_id"`
+	AttachmentType  string     `orm:"column(attachment_type);size(20)" json:"attachment_type"`           // receipt/invoice/warranty/manual/service
+	ServiceRecordID *int       `orm:"column(service_record_id);null" json:"service_record_id,omitempty"` // 关联的维修保养记录
+	Title           string     `orm:"column(title);size(200)" json:"title"`
+	FileName        string     `orm:"column(file_name);size(255)" json:"file_name"` // 上传时的原始文件名
+	MimeType        string     `orm:"column(mime_type);size(100)" json:"mime_type"`
+	ByteSize        int64      `orm:"column(byte_size);default(0)" json:"byte_size"`
+	StorageKey      string     `orm:"column(storage_key);size(500)" json:"-"`
+	InvoiceNumber   string     `orm:"column(invoice_number);size(50);null" json:"invoice_number,omitempty"`
+	InvoiceAmount   *float64   `orm:"column(invoice_amount);digits(10);decimals(2);null" json:"invoice_amount,omitempty"`
+	InvoiceDate     *time.Time `orm:"column(invoice_date);type(date);null" json:"invoice_date,omitempty"`
+	CreatedAt       time.Time  `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+
+	// 下载地址，不存储在数据库
+	FileURL string `orm:"-" json:"file_url"`
+}
+
+func (da *DeviceAttachment) TableName() string {
+	return "device_attachments"
+}
+
+// DeviceServiceRecord 设备维修保养记录表
+type DeviceServiceRecord struct {
+	ID              int       `orm:"column(id);auto;pk" json:"id"`
+	DeviceID        int       `orm:"column(device_id)" json:"device_id"`
+	UserID          int       `orm:"column(user_id)" json:"user_id"`
+	ServiceDate     time.Time `orm:"column(service_date);type(date)" json:"service_date"`
+	ServiceType     string    `orm:"column(service_type);size(20)" json:"service_type"` // repair/battery_swap/cleaning/upgrade
+	Vendor          string    `orm:"column(vendor);size(200);null" json:"vendor"`       // 维修商/服务网点
+	Cost            float64   `orm:"column(cost);digits(10);decimals(2);default(0)" json:"cost"`
+	WarrantyCovered bool      `orm:"column(warranty_covered);default(false)" json:"warranty_covered"` // 保修范围内，费用不计入支出
+	Description     string    `orm:"column(description);type(text);null" json:"description"`
+	CreatedAt       time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+	UpdatedAt       time.Time `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
+
+	// 关联的单据附件，不存储在数据库
+	Attachments []*DeviceAttachment `orm:"-" json:"attachments,omitempty"`
+}
+
+func (dsr *DeviceServiceRecord) TableName() string {
+	return "device_service_records"
+}
 
+// DeviceTag 设备与标签的关联表，标签来自 tags 表（系统标签或用户自定义标签）
+type DeviceTag struct {
+	ID        int       `orm:"column(id);auto;pk" json:"id"`
+	DeviceID  int       `orm:"column(device_id)" json:"device_id"`
+	TagID     int       `orm:"column(tag_id)" json:"tag_id"`
+	CreatedAt time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+}
+
+func (dt *DeviceTag) TableName() string {
+	return "device_tags"
+}
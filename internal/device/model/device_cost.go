@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+// DeviceCost 设备持有期间的其他支出（配件、订阅服务、平台费用）
+type DeviceCost struct {
+	ID           int        `orm:"column(id);auto;pk" json:"id"`
+	DeviceID     int        `orm:"column(device_id)" json:"device_id"`
+	UserID       int        `orm:"column(user_id)" json:"user_id"`
+	CostType     string     `orm:"column(cost_type);size(20)" json:"cost_type"` // accessory/subscription/fee
+	Title        string     `orm:"column(title);size(200)" json:"title"`
+	Amount       float64    `orm:"column(amount);digits(10);decimals(2)" json:"amount"`                // 单次金额或每期金额
+	CostDate     time.Time  `orm:"column(cost_date);type(date)" json:"cost_date"`                      // 支出日期，订阅为开始日期
+	BillingCycle string     `orm:"column(billing_cycle);size(20);null" json:"billing_cycle,omitempty"` // 订阅周期 monthly/yearly
+	EndDate      *time.Time `orm:"column(end_date);type(date);null" json:"end_date,omitempty"`         // 订阅结束日期，为空表示持续中
+	Notes        string     `orm:"column(notes);type(text);null" json:"notes"`
+	CreatedAt    time.Time  `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+	UpdatedAt    time.Time  `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
+}
+
+func (dc *DeviceCost) TableName() string {
+	return "device_costs"
+}
+
+// DeviceUsageLog 设备使用记录，用于计算单次使用成本
+type DeviceUsageLog struct {
+	ID        int       `orm:"column(id);auto;pk" json:"id"`
+	DeviceID  int       `orm:"column(device_id)" json:"device_id"`
+	UserID    int       `orm:"column(user_id)" json:"user_id"`
+	UsedOn    time.Time `orm:"column(used_on);type(date)" json:"used_on"`
+	UseCount  int       `orm:"column(use_count);default(1)" json:"use_count"` // 当天使用次数
+	Note      string    `orm:"column(note);size(200);null" json:"note"`
+	CreatedAt time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+}
+
+func (dul *DeviceUsageLog) TableName() string {
+	return "device_usage_logs"
+}
+
+// OwnershipCost 设备持有总成本，不存储在数据库
+type OwnershipCost struct {
+	PurchasePrice    float64  `json:"purchase_price"`
+	AccessoryCost    float64  `json:"accessory_cost"`
+	RepairCost       float64  `json:"repair_cost"`       // 自付维修保养费用
+	SubscriptionCost float64  `json:"subscription_cost"` // 按周期累计的订阅费用
+	FeeCost          float64  `json:"fee_cost"`          // 平台费用等
+	ResidualValue    float64  `json:"residual_value"`    // 出售所得或当前估值
+	ResidualSource   string   `json:"residual_source"`   // sale/current_value/none
+	TotalCost        float64  `json:"total_cost"`        // 总支出-残值
+	HoldingDays      int      `json:"holding_days"`
+	CostPerDay       float64  `json:"cost_per_day"`
+	CostPerMonth     float64  `json:"cost_per_month"`
+	UsageCount       int      `json:"usage_count"`
+	CostPerUse       *float64 `json:"cost_per_use"` // 未记录使用时为null
+}
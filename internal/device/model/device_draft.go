@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// 草稿状态
+const (
+	DraftStatusPending  = "pending"  // 待确认
+	DraftStatusAccepted = "accepted" // 已创建设备
+	DraftStatusRejected = "rejected" // 已忽略
+)
+
+// DeviceDraft 从电商订单导入、等待用户确认的设备草稿
+type DeviceDraft struct {
+	ID            int        `orm:"column(id);auto;pk" json:"id"`
+	UserID        int        `orm:"column(user_id)" json:"user_id"`
+	Source        string     `orm:"column(source);size(20)" json:"source"`                       // 订单来源 jd/taobao
+	OrderNo       string     `orm:"column(order_no);size(64);null" json:"order_no"`              // 平台订单号
+	OrderDate     *time.Time `orm:"column(order_date);type(date);null" json:"order_date"`        // 下单日期
+	Shop          string     `orm:"column(shop);size(200);null" json:"shop"`                     // 店铺名称
+	Title         string     `orm:"column(title);size(500)" json:"title"`                        // 商品标题原文
+	Quantity      int        `orm:"column(quantity);default(1)" json:"quantity"`                 // 购买数量
+	PricePaid     float64    `orm:"column(price_paid);digits(10);decimals(2)" json:"price_paid"` // 该行实付金额
+	Name          string     `orm:"column(name);size(200)" json:"name"`
+	Brand         string     `orm:"column(brand);size(100);null" json:"brand"`
+	Model         string     `orm:"column(model);size(100);null" json:"model"`
+	CategoryID    *int       `orm:"column(category_id);null" json:"category_id"`
+	TemplateID    *int       `orm:"column(template_id);null" json:"template_id"`
+	PurchasePrice float64    `orm:"column(purchase_price);digits(10);decimals(2)" json:"purchase_price"` // 单件价格，默认按数量折算
+	SerialNumber  string     `orm:"column(serial_number);size(200);null" json:"serial_number"`
+	Notes         string     `orm:"column(notes);type(text);null" json:"notes"`
+	Status        string     `orm:"column(status);size(20);default(pending)" json:"status"`
+	DeviceID      *int       `orm:"column(device_id);null" json:"device_id"` // 确认后创建的设备
+	CreatedAt     time.Time  `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+	UpdatedAt     time.Time  `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
+}
+
+func (dd *DeviceDraft) TableName() string {
+	return "device_drafts"
+}
@@ -0,0 +1,147 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// 设备事件类型
+const (
+	DeviceEventCreated       = "created"        // 创建设备
+	DeviceEventUpdated       = "updated"        // 编辑设备信息
+	DeviceEventStatusChanged = "status_changed" // 状态变更
+	DeviceEventImageAdded    = "image_added"    // 上传图片
+	DeviceEventImageRemoved  = "image_removed"  // 删除图片
+	DeviceEventImageUpdated  = "image_updated"  // 修改图片说明、顺序或封面
+	DeviceEventValueChanged  = "value_changed"  // 估值变化
+	DeviceEventDeleted       = "deleted"        // 删除设备
+	DeviceEventFieldRestored = "field_restored" // 从历史恢复字段
+	DeviceEventMerged        = "merged"         // 合并了其他重复设备
+	DeviceEventRestored      = "restored"       // 从回收站恢复
+	DeviceEventSpecsMigrated = "specs_migrated" // 规格参数迁移到模板的新字段版本
+)
+
+// DeviceEvent 设备变更事件表，只追加不修改
+type DeviceEvent struct {
+	ID        int       `orm:"column(id);auto;pk" json:"id"`
+	DeviceID  int       `orm:"column(device_id)" json:"device_id"`
+	UserID    int       `orm:"column(user_id)" json:"user_id"`   // 设备所有者
+	ActorID   int       `orm:"column(actor_id)" json:"actor_id"` // 操作人，0表示系统
+	EventType string    `orm:"column(event_type);size(30)" json:"event_type"`
+	Changes   string    `orm:"column(changes);type(json);null" json:"-"`  // 字段变化 {字段: {old, new}}
+	Metadata  string    `orm:"column(metadata);type(json);null" json:"-"` // 附加信息，如图片ID
+	CreatedAt time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+
+	// 解析后的字段变化与附加信息，不存储在数据库
+	Diff map[string]*FieldChange `orm:"-" json:"changes"`
+	Meta map[string]interface{}  `orm:"-" json:"metadata,omitempty"`
+}
+
+func (de *DeviceEvent) TableName() string {
+	return "device_events"
+}
+
+// FieldChange 单个字段的变化
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// NewDeviceEvent 构造设备事件，字段变化与附加信息序列化为JSON
+func NewDeviceEvent(deviceID, userID, actorID int, eventType string, changes map[string]*FieldChange, meta map[string]interface{}) *DeviceEvent {
+	event := &DeviceEvent{
+		DeviceID:  deviceID,
+		UserID:    userID,
+		ActorID:   actorID,
+		EventType: eventType,
+		Diff:      changes,
+		Meta:      meta,
+	}
+	if len(changes) > 0 {
+		if data, err := json.Marshal(changes); err == nil {
+			event.Changes = string(data)
+		}
+	}
+	if len(meta) > 0 {
+		if data, err := json.Marshal(meta); err == nil {
+			event.Metadata = string(data)
+		}
+	}
+	return event
+}
+
+// Decode 解析数据库中的JSON字段
+func (de *DeviceEvent) Decode() {
+	de.Diff = map[string]*FieldChange{}
+	if de.Changes != "" {
+		json.Unmarshal([]byte(de.Changes), &de.Diff)
+	}
+	if de.Metadata != "" {
+		json.Unmarshal([]byte(de.Metadata), &de.Meta)
+	}
+}
+
+// Snapshot 返回设备可追踪字段的快照，键为JSON字段名，日期格式化为YYYY-MM-DD，空日期为nil
+func (d *Device) Snapshot() map[string]interface{} {
+	return map[string]interface{}{
+		"template_id":    intPtrValue(d.TemplateID),
+		"category_id":    intPtrValue(d.CategoryID),
+		"parent_id":      intPtrValue(d.ParentID),
+		"name":           d.Name,
+		"brand":          d.Brand,
+		"model":          d.Model,
+		"serial_number":  d.SerialNumber,
+		"color":          d.Color,
+		"storage":        d.Storage,
+		"memory":         d.Memory,
+		"processor":      d.Processor,
+		"screen_size":    d.ScreenSize,
+		"purchase_price": d.PurchasePrice,
+		"current_value":  d.CurrentValue,
+		"purchase_date":  dateValue(d.PurchaseDate),
+		"warranty_date":  dateValue(d.WarrantyDate),
+		"condition":      d.Condition,
+		"status":         d.Status,
+		"sale_price":     d.SalePrice,
+		"sale_date":      dateValue(d.SaleDate),
+		"notes":          d.Notes,
+		"specifications": d.Specifications,
+	}
+}
+
+// DiffDevices 比较两个设备快照，返回发生变化的字段；before 为nil时返回after的全部非空字段
+func DiffDevices(before, after *Device) map[string]*FieldChange {
+	changes := map[string]*FieldChange{}
+	newValues := after.Snapshot()
+	if before == nil {
+		for field, value := range newValues {
+			if value != nil && !reflect.ValueOf(value).IsZero() {
+				changes[field] = &FieldChange{New: value}
+			}
+		}
+		return changes
+	}
+
+	oldValues := before.Snapshot()
+	for field, value := range newValues {
+		if !reflect.DeepEqual(oldValues[field], value) {
+			changes[field] = &FieldChange{Old: oldValues[field], New: value}
+		}
+	}
+	return changes
+}
+
+func intPtrValue(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func dateValue(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
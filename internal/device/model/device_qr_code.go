@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// DeviceQRCode 设备二维码，扫码时按 token 查找设备；重置 token 后已打印的旧标签失效
+type DeviceQRCode struct {
+	ID        int       `orm:"column(id);auto;pk" json:"id"`
+	DeviceID  int       `orm:"column(device_id);unique" json:"device_id"`
+	UserID    int       `orm:"column(user_id)" json:"user_id"`
+	Token     string    `orm:"column(token);size(64);unique" json:"token"`
+	CreatedAt time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+	UpdatedAt time.Time `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
+}
+
+func (q *DeviceQRCode) TableName() string {
+	return "device_qr_codes"
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// DeviceSpecValue 设备规格参数的类型化索引，每个字段值一行（多选字段每个选项一行，字段组子字段按 组.子字段 命名），
+// 由规格参数写入时重建，用于按规格筛选与分组统计
+type DeviceSpecValue struct {
+	ID         int        `orm:"column(id);auto;pk" json:"id"`
+	DeviceID   int        `orm:"column(device_id)" json:"device_id"`
+	UserID     int        `orm:"column(user_id)" json:"user_id"`
+	TemplateID int        `orm:"column(template_id);null" json:"template_id"`
+	FieldName  string     `orm:"column(field_name);size(100)" json:"field_name"`
+	FieldType  string     `orm:"column(field_type);size(20)" json:"field_type"`
+	ValueText  string     `orm:"column(value_text);size(255)" json:"value_text"`
+	ValueNum   *float64   `orm:"column(value_num);null" json:"value_num"`              // 数值，带单位时换算为量纲的基准单位
+	Dimension  string     `orm:"column(dimension);size(20)" json:"dimension"`          // 单位量纲，金额为币种，无单位时为空
+	UnitFactor float64    `orm:"column(unit_factor);default(1)" json:"unit_factor"`    // 字段单位相对基准单位的倍数，筛选值不带单位时按字段单位换算
+	ValueDate  *time.Time `orm:"column(value_date);type(date);null" json:"value_date"` // 日期字段的值
+}
+
+func (sv *DeviceSpecValue) TableName() string {
+	return "device_spec_values"
+}
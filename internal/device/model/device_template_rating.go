@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// TemplateRating 用户对模板的评分，每个用户对每个模板只保留一条
+type TemplateRating struct {
+	ID         int       `orm:"column(id);auto;pk" json:"id"`
+	TemplateID int       `orm:"column(template_id)" json:"template_id"`
+	UserID     int       `orm:"column(user_id)" json:"user_id"`
+	Score      int       `orm:"column(score)" json:"score"` // 1-5
+	Comment    string    `orm:"column(comment);size(500);null" json:"comment"`
+	CreatedAt  time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+	UpdatedAt  time.Time `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
+}
+
+func (tr *TemplateRating) TableName() string {
+	return "template_ratings"
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// DeviceTemplateVersion 模板字段定义的历史版本，创建后不再修改
+type DeviceTemplateVersion struct {
+	ID         int       `orm:"column(id);auto;pk" json:"id"`
+	TemplateID int       `orm:"column(template_id)" json:"template_id"`
+	Version    int       `orm:"column(version)" json:"version"` // 模板内从1开始递增
+	Fields     string    `orm:"column(fields);type(json)" json:"fields"`
+	CreatedBy  int       `orm:"column(created_by);null" json:"created_by"`
+	CreatedAt  time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+}
+
+func (tv *DeviceTemplateVersion) TableName() string {
+	return "device_template_versions"
+}
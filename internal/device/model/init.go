@@ -9,7 +9,20 @@ func Init() {
 	orm.RegisterModel(
 		new(Device),
 		new(DeviceTemplate),
+		new(DeviceTemplateVersion),
+		new(TemplateRating),
 		new(Category),
+		new(CategoryPreference),
+		new(CategoryRule),
 		new(DeviceImage),
+		new(DeviceAttachment),
+		new(DeviceServiceRecord),
+		new(DeviceEvent),
+		new(DeviceCost),
+		new(DeviceUsageLog),
+		new(DeviceDraft),
+		new(DeviceTag),
+		new(DeviceSpecValue),
+		new(DeviceQRCode),
 	)
 }
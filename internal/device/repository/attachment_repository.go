@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"Backend_Lili/internal/device/model"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+type AttachmentRepository struct{}
+
+func NewAttachmentRepository() *AttachmentRepository {
+	return &AttachmentRepository{}
+}
+
+// GetDeviceAttachments 获取设备附件列表，attachmentType 为空时返回全部类型
+func (r *AttachmentRepository) GetDeviceAttachments(deviceID, userID int, attachmentType string) ([]*model.DeviceAttachment, error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable("device_attachments").
+		Filter("device_id", deviceID).
+		Filter("user_id", userID)
+	if attachmentType != "" {
+		qs = qs.Filter("attachment_type", attachmentType)
+	}
+
+	var attachments []*model.DeviceAttachment
+	_, err := qs.OrderBy("attachment_type", "-created_at").All(&attachments)
+	return attachments, err
+}
+
+// GetAttachmentByID 获取单个附件（校验设备与用户归属）
+func (r *AttachmentRepository) GetAttachmentByID(attachmentID, deviceID, userID int) (*model.DeviceAttachment, error) {
+	o := orm.NewOrm()
+	attachment := &model.DeviceAttachment{}
+	err := o.QueryTable("device_attachments").
+		Filter("id", attachmentID).
+		Filter("device_id", deviceID).
+		Filter("user_id", userID).
+		One(attachment)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// CreateAttachment 创建附件记录
+func (r *AttachmentRepository) CreateAttachment(attachment *model.DeviceAttachment) error {
+	o := orm.NewOrm()
+	attachment.CreatedAt = time.Now()
+	_, err := o.Insert(attachment)
+	return err
+}
+
+// DeleteAttachment 删除附件记录
+func (r *AttachmentRepository) DeleteAttachment(attachmentID, userID int) error {
+	o := orm.NewOrm()
+	_, err := o.QueryTable("device_attachments").
+		Filter("id", attachmentID).
+		Filter("user_id", userID).
+		Delete()
+	return err
+}
@@ -49,16 +49,60 @@ func (r *CategoryRepository) CreateCategory(category *model.Category) error {
 	o := orm.NewOrm()
 	category.CreatedAt = time.Now()
 	category.UpdatedAt = time.Now()
+	category.Version = 1
 	_, err := o.Insert(category)
 	return err
 }
 
-// UpdateCategory 更新分类
+// UpdateCategory 更新分类，category.Version 为读取时的版本号，已被修改时返回 ErrVersionConflict
 func (r *CategoryRepository) UpdateCategory(category *model.Category) error {
 	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
 	category.UpdatedAt = time.Now()
-	_, err := o.Update(category)
-	return err
+	if err := UpdateWithVersion(tx, "categories", category.ID, &category.Version, category); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateCategoryParent 在同一事务中锁定用户的全部自定义分类，把锁定后读到的可见分类（系统分类与自定义分类）
+// 交给 check 校验层级关系，通过后再写入分类；同一用户的并发移动依次执行，校验不会基于过期的分类树
+func (r *CategoryRepository) UpdateCategoryParent(category *model.Category, check func([]*model.Category) error) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+
+	var categories []*model.Category
+	_, err = tx.Raw("SELECT * FROM categories WHERE type = 'custom' AND user_id = ? AND is_active = 1 AND deleted_at IS NULL ORDER BY id FOR UPDATE",
+		category.UserID).QueryRows(&categories)
+	if err == nil {
+		// 系统分类不能被用户移动，无需加锁
+		var systemCategories []*model.Category
+		_, err = tx.QueryTable("categories").
+			Filter("type", "system").
+			Filter("is_active", true).
+			Filter("deleted_at__isnull", true).
+			All(&systemCategories)
+		categories = append(systemCategories, categories...)
+	}
+	if err == nil {
+		err = check(categories)
+	}
+	if err == nil {
+		category.UpdatedAt = time.Now()
+		err = UpdateWithVersion(tx, "categories", category.ID, &category.Version, category)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetCategoriesByType 根据类型获取分类
@@ -178,13 +222,18 @@ func (r *CategoryRepository) DeleteCategory(categoryID int, userID int) error {
 
 	category.DeletedAt = time.Now()
 	category.IsActive = false
-	_, err = o.Update(category, "deleted_at", "is_active")
+	category.Version++
+	_, err = o.Update(category, "deleted_at", "is_active", "version")
 	return err
 }
 
-// UpdateCategoriesSort 批量更新分类排序
+// UpdateCategoriesSort 批量更新分类排序：自定义分类直接修改排序，系统分类写入用户的个人排序，不影响其他用户
 func (r *CategoryRepository) UpdateCategoriesSort(userID int, categoryOrders []map[string]int) error {
 	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
 
 	for _, order := range categoryOrders {
 		categoryID := order["category_id"]
@@ -192,25 +241,39 @@ func (r *CategoryRepository) UpdateCategoriesSort(userID int, categoryOrders []m
 
 		// 验证分类归属权
 		category := &model.Category{}
-		err := o.QueryTable("categories").
+		err := tx.QueryTable("categories").
+			SetCond(orm.NewCondition().AndCond(orm.NewCondition().
+				Or("type", "system").
+				OrCond(orm.NewCondition().And("type", "custom").And("user_id", userID)))).
 			Filter("id", categoryID).
-			Filter("user_id", userID).
-			Filter("type", "custom").
+			Filter("is_active", true).
+			Filter("deleted_at__isnull", true).
 			One(category)
 		if err != nil {
+			tx.Rollback()
 			return err
 		}
 
-		// 更新排序
-		category.SortOrder = sortOrder
-		category.UpdatedAt = time.Now()
-		_, err = o.Update(category, "sort_order", "updated_at")
+		if category.Type == "system" {
+			err = saveCategoryPreference(tx, &model.CategoryPreference{
+				UserID:     userID,
+				CategoryID: categoryID,
+				SortOrder:  &sortOrder,
+			}, "sort_order")
+		} else {
+			// 更新排序
+			category.SortOrder = sortOrder
+			category.UpdatedAt = time.Now()
+			category.Version++
+			_, err = tx.Update(category, "sort_order", "updated_at", "version")
+		}
 		if err != nil {
+			tx.Rollback()
 			return err
 		}
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // SearchCategories 搜索分类
@@ -360,3 +423,134 @@ func (r *CategoryRepository) GetCategoryWithChildren(categoryID int) (*model.Cat
 
 	return category, nil
 }
+
+// CategoryReassignResult 删除或合并分类时转移的数据
+type CategoryReassignResult struct {
+	DeviceIDs     []int // 转移的设备，含回收站中的设备
+	TemplateCount int64 // 转移的模板数量
+	DraftCount    int64 // 转移的设备草稿数量
+	ChildCount    int64 // 转移的子分类数量
+}
+
+// RemoveCategory 在同一事务中转移自定义分类的子分类、设备、模板与草稿后软删除该分类；
+// childParentID 为子分类的新父分类（0为顶级），deviceCategoryID 为设备、模板与草稿的新分类，为空时清除分类
+func (r *CategoryRepository) RemoveCategory(categoryID, userID, childParentID int, deviceCategoryID *int) (*CategoryReassignResult, error) {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var newCategory interface{}
+	if deviceCategoryID != nil {
+		newCategory = *deviceCategoryID
+	}
+	result := &CategoryReassignResult{}
+
+	// 设备（含回收站），版本号自增
+	_, err = tx.Raw("SELECT id FROM devices WHERE category_id = ? AND user_id = ?", categoryID, userID).QueryRows(&result.DeviceIDs)
+	if err == nil && len(result.DeviceIDs) > 0 {
+		_, err = tx.QueryTable("devices").
+			Filter("id__in", result.DeviceIDs).
+			Update(bumpVersion(orm.Params{"category_id": newCategory, "updated_at": now}))
+	}
+	// 模板与设备草稿
+	if err == nil {
+		result.TemplateCount, err = tx.QueryTable("device_templates").
+			Filter("category_id", categoryID).
+			Update(bumpVersion(orm.Params{"category_id": newCategory, "updated_at": now}))
+	}
+	if err == nil {
+		result.DraftCount, err = tx.QueryTable("device_drafts").
+			Filter("category_id", categoryID).
+			Filter("user_id", userID).
+			Update(orm.Params{"category_id": newCategory, "updated_at": now})
+	}
+	// 子分类
+	if err == nil {
+		result.ChildCount, err = tx.QueryTable("categories").
+			Filter("parent_id", categoryID).
+			Filter("is_active", true).
+			Filter("deleted_at__isnull", true).
+			Update(bumpVersion(orm.Params{"parent_id": childParentID, "updated_at": now}))
+	}
+	if err == nil {
+		var deleted int64
+		deleted, err = tx.QueryTable("categories").
+			Filter("id", categoryID).
+			Filter("type", "custom").
+			Filter("user_id", userID).
+			Filter("deleted_at__isnull", true).
+			Update(bumpVersion(orm.Params{"is_active": false, "deleted_at": now, "updated_at": now}))
+		if err == nil && deleted == 0 {
+			err = orm.ErrNoRows
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return result, tx.Commit()
+}
+
+// GetCategoryPreferences 获取用户对系统分类的个人设置，按分类ID索引
+func (r *CategoryRepository) GetCategoryPreferences(userID int) (map[int]*model.CategoryPreference, error) {
+	o := orm.NewOrm()
+	var prefs []*model.CategoryPreference
+	_, err := o.QueryTable("user_category_prefs").Filter("user_id", userID).All(&prefs)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int]*model.CategoryPreference, len(prefs))
+	for _, pref := range prefs {
+		result[pref.CategoryID] = pref
+	}
+	return result, nil
+}
+
+// SetCategoryHidden 设置用户是否隐藏系统分类
+func (r *CategoryRepository) SetCategoryHidden(userID, categoryID int, hidden bool) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	err = saveCategoryPreference(tx, &model.CategoryPreference{
+		UserID:     userID,
+		CategoryID: categoryID,
+		Hidden:     hidden,
+	}, "hidden")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ResetCategoryPreferences 清除用户对系统分类的全部个人设置
+func (r *CategoryRepository) ResetCategoryPreferences(userID int) error {
+	o := orm.NewOrm()
+	_, err := o.QueryTable("user_category_prefs").Filter("user_id", userID).Delete()
+	return err
+}
+
+// saveCategoryPreference 新增或更新个人设置中 columns 指定的字段，其余字段保持不变
+func saveCategoryPreference(tx orm.TxOrmer, pref *model.CategoryPreference, columns ...string) error {
+	existing := &model.CategoryPreference{}
+	err := tx.QueryTable("user_category_prefs").
+		Filter("user_id", pref.UserID).
+		Filter("category_id", pref.CategoryID).
+		ForUpdate().
+		One(existing)
+	pref.UpdatedAt = time.Now()
+	switch err {
+	case orm.ErrNoRows:
+		pref.CreatedAt = pref.UpdatedAt
+		_, err = tx.Insert(pref)
+	case nil:
+		pref.ID = existing.ID
+		_, err = tx.Update(pref, append(columns, "updated_at")...)
+	}
+	return err
+}
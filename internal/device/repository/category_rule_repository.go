@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"Backend_Lili/internal/device/model"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+type CategoryRuleRepository struct{}
+
+func NewCategoryRuleRepository() *CategoryRuleRepository {
+	return &CategoryRuleRepository{}
+}
+
+// GetActiveRules 获取对用户生效的规则：启用的系统规则与用户自己的规则，按优先级从高到低
+func (r *CategoryRuleRepository) GetActiveRules(userID int) ([]*model.CategoryRule, error) {
+	o := orm.NewOrm()
+	var rules []*model.CategoryRule
+	_, err := o.QueryTable("category_rules").
+		SetCond(ruleOwnerCond(userID)).
+		Filter("is_active", true).
+		OrderBy("-priority", "id").
+		All(&rules)
+	return rules, err
+}
+
+// GetRules 列出规则，scope 为 system 只列系统规则，mine 只列用户自己的规则，为空时两者都列
+func (r *CategoryRuleRepository) GetRules(userID int, scope string) ([]*model.CategoryRule, error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable("category_rules")
+	switch scope {
+	case "system":
+		qs = qs.Filter("user_id__isnull", true)
+	case "mine":
+		qs = qs.Filter("user_id", userID)
+	default:
+		qs = qs.SetCond(ruleOwnerCond(userID))
+	}
+	var rules []*model.CategoryRule
+	_, err := qs.OrderBy("-priority", "id").All(&rules)
+	return rules, err
+}
+
+// ruleOwnerCond 系统规则与用户自己的规则
+func ruleOwnerCond(userID int) *orm.Condition {
+	return orm.NewCondition().AndCond(orm.NewCondition().Or("user_id__isnull", true).Or("user_id", userID))
+}
+
+// GetRuleByID 根据ID获取规则
+func (r *CategoryRuleRepository) GetRuleByID(ruleID int) (*model.CategoryRule, error) {
+	o := orm.NewOrm()
+	rule := &model.CategoryRule{}
+	err := o.QueryTable("category_rules").Filter("id", ruleID).One(rule)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	return rule, err
+}
+
+// CreateRule 创建规则
+func (r *CategoryRuleRepository) CreateRule(rule *model.CategoryRule) error {
+	o := orm.NewOrm()
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = rule.CreatedAt
+	_, err := o.Insert(rule)
+	return err
+}
+
+// UpdateRule 更新规则
+func (r *CategoryRuleRepository) UpdateRule(rule *model.CategoryRule) error {
+	o := orm.NewOrm()
+	rule.UpdatedAt = time.Now()
+	_, err := o.Update(rule, "name", "brands", "model_keywords", "name_keywords", "pattern",
+		"category_id", "template_id", "priority", "is_active", "updated_at")
+	return err
+}
+
+// DeleteRule 删除规则
+func (r *CategoryRuleRepository) DeleteRule(ruleID int) error {
+	o := orm.NewOrm()
+	_, err := o.QueryTable("category_rules").Filter("id", ruleID).Delete()
+	return err
+}
+
+// GetUncategorizedDevices 按ID升序获取未设置分类或模板的设备（不含回收站）
+func (r *DeviceRepository) GetUncategorizedDevices(userID, limit int) ([]*model.Device, error) {
+	o := orm.NewOrm()
+	var devices []*model.Device
+	_, err := o.QueryTable("devices").
+		SetCond(orm.NewCondition().AndCond(orm.NewCondition().Or("category_id__isnull", true).Or("template_id__isnull", true))).
+		Filter("user_id", userID).
+		Filter("deleted_at__isnull", true).
+		OrderBy("id").
+		Limit(limit).
+		All(&devices)
+	return devices, err
+}
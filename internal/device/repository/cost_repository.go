@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"Backend_Lili/internal/device/model"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+type CostRepository struct{}
+
+func NewCostRepository() *CostRepository {
+	return &CostRepository{}
+}
+
+// GetDeviceCosts 获取设备的其他支出，可按类型筛选
+func (r *CostRepository) GetDeviceCosts(deviceID, userID int, costType string) ([]*model.DeviceCost, error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable("device_costs").Filter("device_id", deviceID).Filter("user_id", userID)
+	if costType != "" {
+		qs = qs.Filter("cost_type", costType)
+	}
+	var costs []*model.DeviceCost
+	_, err := qs.OrderBy("-cost_date", "-id").All(&costs)
+	return costs, err
+}
+
+// GetUserCosts 获取用户全部设备的其他支出，按设备分组
+func (r *CostRepository) GetUserCosts(userID int) (map[int][]*model.DeviceCost, error) {
+	o := orm.NewOrm()
+	var costs []*model.DeviceCost
+	if _, err := o.QueryTable("device_costs").Filter("user_id", userID).All(&costs); err != nil {
+		return nil, err
+	}
+	result := make(map[int][]*model.DeviceCost)
+	for _, cost := range costs {
+		result[cost.DeviceID] = append(result[cost.DeviceID], cost)
+	}
+	return result, nil
+}
+
+// GetCostByID 获取单条支出
+func (r *CostRepository) GetCostByID(costID, deviceID, userID int) (*model.DeviceCost, error) {
+	o := orm.NewOrm()
+	cost := &model.DeviceCost{}
+	err := o.QueryTable("device_costs").
+		Filter("id", costID).
+		Filter("device_id", deviceID).
+		Filter("user_id", userID).
+		One(cost)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	return cost, err
+}
+
+// CreateCost 创建支出记录
+func (r *CostRepository) CreateCost(cost *model.DeviceCost) error {
+	o := orm.NewOrm()
+	cost.CreatedAt = time.Now()
+	cost.UpdatedAt = time.Now()
+	_, err := o.Insert(cost)
+	return err
+}
+
+// UpdateCost 更新支出记录
+func (r *CostRepository) UpdateCost(cost *model.DeviceCost) error {
+	o := orm.NewOrm()
+	cost.UpdatedAt = time.Now()
+	_, err := o.Update(cost)
+	return err
+}
+
+// DeleteCost 删除支出记录
+func (r *CostRepository) DeleteCost(costID, userID int) error {
+	o := orm.NewOrm()
+	_, err := o.QueryTable("device_costs").Filter("id", costID).Filter("user_id", userID).Delete()
+	return err
+}
+
+// GetUsageLogs 分页获取设备使用记录
+func (r *CostRepository) GetUsageLogs(deviceID, userID, page, limit int) ([]*model.DeviceUsageLog, int64, error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable("device_usage_logs").Filter("device_id", deviceID).Filter("user_id", userID)
+	total, err := qs.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	var logs []*model.DeviceUsageLog
+	_, err = qs.OrderBy("-used_on", "-id").Limit(limit, (page-1)*limit).All(&logs)
+	return logs, total, err
+}
+
+// GetUsageLogByID 获取单条使用记录
+func (r *CostRepository) GetUsageLogByID(logID, deviceID, userID int) (*model.DeviceUsageLog, error) {
+	o := orm.NewOrm()
+	usage := &model.DeviceUsageLog{}
+	err := o.QueryTable("device_usage_logs").
+		Filter("id", logID).
+		Filter("device_id", deviceID).
+		Filter("user_id", userID).
+		One(usage)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	return usage, err
+}
+
+// CreateUsageLog 记录设备使用
+func (r *CostRepository) CreateUsageLog(usage *model.DeviceUsageLog) error {
+	o := orm.NewOrm()
+	usage.CreatedAt = time.Now()
+	_, err := o.Insert(usage)
+	return err
+}
+
+// DeleteUsageLog 删除使用记录
+func (r *CostRepository) DeleteUsageLog(logID, userID int) error {
+	o := orm.NewOrm()
+	_, err := o.QueryTable("device_usage_logs").Filter("id", logID).Filter("user_id", userID).Delete()
+	return err
+}
+
+// GetUsageCount 统计设备累计使用次数
+func (r *CostRepository) GetUsageCount(deviceID, userID int) (int, error) {
+	o := orm.NewOrm()
+	var row struct{ Total int }
+	err := o.Raw("SELECT COALESCE(SUM(use_count), 0) AS total FROM device_usage_logs WHERE device_id = ? AND user_id = ?", deviceID, userID).QueryRow(&row)
+	return row.Total, err
+}
+
+// GetUsageCounts 按设备统计用户全部设备的累计使用次数
+func (r *CostRepository) GetUsageCounts(userID int) (map[int]int, error) {
+	o := orm.NewOrm()
+	var rows []struct {
+		DeviceID int `orm:"column(device_id)"`
+		Total    int `orm:"column(total)"`
+	}
+	_, err := o.Raw("SELECT device_id, COALESCE(SUM(use_count), 0) AS total FROM device_usage_logs WHERE user_id = ? GROUP BY device_id", userID).QueryRows(&rows)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.DeviceID] = row.Total
+	}
+	return counts, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"Backend_Lili/internal/device/model"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+// DeviceEventRepository 设备事件流，只提供追加与查询，事件写入后不再修改
+type DeviceEventRepository struct{}
+
+func NewDeviceEventRepository() *DeviceEventRepository {
+	return &DeviceEventRepository{}
+}
+
+// CreateEvent 追加设备事件
+func (r *DeviceEventRepository) CreateEvent(event *model.DeviceEvent) error {
+	o := orm.NewOrm()
+	event.CreatedAt = time.Now()
+	_, err := o.Insert(event)
+	return err
+}
+
+// GetDeviceEvents 分页获取设备事件，按时间倒序
+func (r *DeviceEventRepository) GetDeviceEvents(deviceID, userID int, eventType string, page, limit int) ([]*model.DeviceEvent, int64, error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable("device_events").Filter("device_id", deviceID).Filter("user_id", userID)
+	if eventType != "" {
+		qs = qs.Filter("event_type", eventType)
+	}
+
+	total, err := qs.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var events []*model.DeviceEvent
+	_, err = qs.OrderBy("-created_at", "-id").Limit(limit, (page-1)*limit).All(&events)
+	return events, total, err
+}
+
+// GetEventByID 获取单个设备事件
+func (r *DeviceEventRepository) GetEventByID(eventID, deviceID, userID int) (*model.DeviceEvent, error) {
+	o := orm.NewOrm()
+	event := &model.DeviceEvent{}
+	err := o.QueryTable("device_events").
+		Filter("id", eventID).
+		Filter("device_id", deviceID).
+		Filter("user_id", userID).
+		One(event)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	return event, err
+}
+
+// GetEventsAfter 获取指定事件之后发生的设备事件，按发生顺序
+func (r *DeviceEventRepository) GetEventsAfter(eventID, deviceID, userID int) ([]*model.DeviceEvent, error) {
+	o := orm.NewOrm()
+	var events []*model.DeviceEvent
+	_, err := o.QueryTable("device_events").
+		Filter("device_id", deviceID).
+		Filter("user_id", userID).
+		Filter("id__gt", eventID).
+		OrderBy("id").
+		All(&events)
+	return events, err
+}
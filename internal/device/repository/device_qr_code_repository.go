@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"Backend_Lili/internal/device/model"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+type DeviceQRCodeRepository struct{}
+
+func NewDeviceQRCodeRepository() *DeviceQRCodeRepository {
+	return &DeviceQRCodeRepository{}
+}
+
+// GetQRCodeByToken 根据 token 获取二维码，不存在时返回 nil
+func (r *DeviceQRCodeRepository) GetQRCodeByToken(token string) (*model.DeviceQRCode, error) {
+	o := orm.NewOrm()
+	code := &model.DeviceQRCode{}
+	err := o.QueryTable("device_qr_codes").Filter("token", token).One(code)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	return code, err
+}
+
+// GetQRCodesByDeviceIDs 批量获取设备的二维码，按设备ID索引
+func (r *DeviceQRCodeRepository) GetQRCodesByDeviceIDs(userID int, deviceIDs []int) (map[int]*model.DeviceQRCode, error) {
+	result := make(map[int]*model.DeviceQRCode)
+	if len(deviceIDs) == 0 {
+		return result, nil
+	}
+	o := orm.NewOrm()
+	var codes []*model.DeviceQRCode
+	if _, err := o.QueryTable("device_qr_codes").
+		Filter("user_id", userID).
+		Filter("device_id__in", deviceIDs).
+		All(&codes); err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		result[code.DeviceID] = code
+	}
+	return result, nil
+}
+
+// CreateQRCode 创建二维码，同一设备只能有一条
+func (r *DeviceQRCodeRepository) CreateQRCode(code *model.DeviceQRCode) error {
+	o := orm.NewOrm()
+	code.CreatedAt = time.Now()
+	code.UpdatedAt = code.CreatedAt
+	_, err := o.Insert(code)
+	return err
+}
+
+// UpdateQRCodeToken 更换二维码的 token
+func (r *DeviceQRCodeRepository) UpdateQRCodeToken(code *model.DeviceQRCode) error {
+	o := orm.NewOrm()
+	code.UpdatedAt = time.Now()
+	_, err := o.Update(code, "token", "updated_at")
+	return err
+}
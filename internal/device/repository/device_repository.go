@@ -2,12 +2,18 @@ package repository
 
 import (
 	"Backend_Lili/internal/device/model"
+	"encoding/json"
 	"errors"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/beego/beego/v2/client/orm"
 )
 
+// ErrImageSetMismatch 重排时提交的图片与设备现有图片不一致
+var ErrImageSetMismatch = errors.New("图片列表与设备现有图片不一致")
+
 type DeviceRepository struct{}
 
 func NewDeviceRepository() *DeviceRepository {
@@ -17,18 +23,92 @@ func NewDeviceRepository() *DeviceRepository {
 // GetDevicesList 获取设备列表   键为字符串类型 值为任意类型的映射 处理结构不固定 类型不确定的数据（动态数据）
 func (r *DeviceRepository) GetDevicesList(userID int, params map[string]interface{}) ([]*model.Device, int64, error) {
 	o := orm.NewOrm()
-	qs := o.QueryTable("devices").Filter("user_id", userID).Filter("deleted_at__isnull", true)
+	qs, err := deviceListQuery(o, userID, params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 获取总数
+	total, err := qs.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 分页
+	if page, ok := params["page"].(int); ok && page > 0 {
+		limit := 10
+		if limitParam, ok := params["limit"].(int); ok && limitParam > 0 {
+			limit = limitParam
+		}
+		offset := (page - 1) * limit
+		qs = qs.Limit(limit, offset)
+	}
+
+	var devices []*model.Device
+	_, err = qs.RelatedSel().All(&devices)
+	return devices, total, err
+}
+
+// IterateDevices 按设备列表的筛选与排序分批读取设备，用于导出等需要遍历全部结果的场景
+func (r *DeviceRepository) IterateDevices(userID int, params map[string]interface{}, batchSize int, fn func([]*model.Device) error) error {
+	o := orm.NewOrm()
+	qs, err := deviceListQuery(o, userID, params)
+	if err != nil {
+		return err
+	}
+	for offset := 0; ; offset += batchSize {
+		var devices []*model.Device
+		if _, err := qs.Limit(batchSize, offset).All(&devices); err != nil {
+			return err
+		}
+		if len(devices) == 0 {
+			return nil
+		}
+		if err := fn(devices); err != nil {
+			return err
+		}
+		if len(devices) < batchSize {
+			return nil
+		}
+	}
+}
+
+// deviceListQuery 构建设备列表的筛选与排序，排序值相同时按ID保证分页稳定；deleted 为 true 时只查已删除的设备
+func deviceListQuery(o orm.Ormer, userID int, params map[string]interface{}) (orm.QuerySeter, error) {
+	qs := o.QueryTable("devices").Filter("user_id", userID)
+	if deleted, ok := params["deleted"].(bool); ok && deleted {
+		qs = qs.Filter("deleted_at__isnull", false)
+	} else {
+		qs = qs.Filter("deleted_at__isnull", true)
+	}
 
 	// 应用筛选条件
 	if categoryID, ok := params["category_id"].(int); ok && categoryID > 0 {
 		qs = qs.Filter("category_id", categoryID)
 	}
+	if parentID, ok := params["parent_id"].(int); ok && parentID > 0 {
+		qs = qs.Filter("parent_id", parentID)
+	}
+	if topLevel, ok := params["top_level"].(bool); ok && topLevel {
+		qs = qs.Filter("parent_id__isnull", true)
+	}
 	if status, ok := params["status"].(string); ok && status != "" {
 		qs = qs.Filter("status", status)
 	}
 	if search, ok := params["search"].(string); ok && search != "" {
 		qs = qs.Filter("name__icontains", search).Filter("brand__icontains", search).Filter("model__icontains", search)
 	}
+	if filters, ok := params["spec_filters"].([]*SpecFilter); ok && len(filters) > 0 {
+		deviceIDs, err := specFilterDeviceIDs(o, userID, filters)
+		if err != nil {
+			return nil, err
+		}
+		if len(deviceIDs) == 0 {
+			// 没有设备满足规格条件，ID从1开始
+			deviceIDs = []int{0}
+		}
+		qs = qs.Filter("id__in", deviceIDs)
+	}
 
 	// 应用排序
 	if sort, ok := params["sort"].(string); ok && sort != "" {
@@ -37,33 +117,14 @@ func (r *DeviceRepository) GetDevicesList(userID int, params map[string]interfac
 			order = "asc"
 		}
 		if order == "asc" {
-			qs = qs.OrderBy(sort)
+			qs = qs.OrderBy(sort, "id")
 		} else {
-			qs = qs.OrderBy("-" + sort)
+			qs = qs.OrderBy("-"+sort, "-id")
 		}
 	} else {
-		qs = qs.OrderBy("-created_at")
+		qs = qs.OrderBy("-created_at", "-id")
 	}
-
-	// 获取总数
-	total, err := qs.Count()
-	if err != nil {
-		return nil, 0, err
-	}
-
-	// 分页
-	if page, ok := params["page"].(int); ok && page > 0 {
-		limit := 10
-		if limitParam, ok := params["limit"].(int); ok && limitParam > 0 {
-			limit = limitParam
-		}
-		offset := (page - 1) * limit
-		qs = qs.Limit(limit, offset)
-	}
-
-	var devices []*model.Device
-	_, err = qs.RelatedSel().All(&devices)
-	return devices, total, err
+	return qs, nil
 }
 
 // GetDeviceByID 根据ID获取设备详情
@@ -94,6 +155,33 @@ func (r *DeviceRepository) GetDeviceByID(deviceID, userID int) (*model.Device, e
 		device.Images = images
 	}
 
+	// 加载套装配件
+	var accessories []*model.Device
+	_, err = o.QueryTable("devices").
+		Filter("parent_id", deviceID).
+		Filter("user_id", userID).
+		Filter("deleted_at__isnull", true).
+		OrderBy("created_at").
+		All(&accessories)
+	if err == nil {
+		device.Accessories = accessories
+	}
+
+	// 加载设备附件
+	var attachments []*model.DeviceAttachment
+	_, err = o.QueryTable("device_attachments").
+		Filter("device_id", deviceID).
+		OrderBy("attachment_type", "-created_at").
+		All(&attachments)
+	if err == nil {
+		device.Attachments = attachments
+	}
+
+	// 加载设备标签
+	if tagIDs, err := r.GetDeviceTagIDs(deviceID); err == nil {
+		device.TagIDs = tagIDs
+	}
+
 	return device, nil
 }
 
@@ -102,33 +190,247 @@ func (r *DeviceRepository) CreateDevice(device *model.Device) error {
 	o := orm.NewOrm()
 	device.CreatedAt = time.Now()
 	device.UpdatedAt = time.Now()
+	device.Version = 1
 	_, err := o.Insert(device)
 	return err
 }
 
-// UpdateDevice 更新设备
+// UpdateDevice 更新设备，device.Version 为读取时的版本号，已被修改时返回 ErrVersionConflict
 func (r *DeviceRepository) UpdateDevice(device *model.Device) error {
 	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
 	device.UpdatedAt = time.Now()
-	_, err := o.Update(device)
-	return err
+	if err := UpdateWithVersion(tx, "devices", device.ID, &device.Version, device); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
-// SoftDeleteDevice 软删除设备
+// SoftDeleteDevice 软删除设备（移入回收站），同时暂停设备的价格预警
 func (r *DeviceRepository) SoftDeleteDevice(deviceID, userID int) error {
 	o := orm.NewOrm()
-	_, err := o.QueryTable("devices").
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.QueryTable("devices").
 		Filter("id", deviceID).
 		Filter("user_id", userID).
-		Update(orm.Params{
+		Update(bumpVersion(orm.Params{
 			"deleted_at": time.Now(),
 			"updated_at": time.Now(),
-		})
+		})); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := suspendPriceAlerts(tx, userID, []int{deviceID}, true); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// RestoreDevice 从回收站恢复设备，同时恢复被暂停的价格预警
+func (r *DeviceRepository) RestoreDevice(deviceID, userID int) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.QueryTable("devices").
+		Filter("id", deviceID).
+		Filter("user_id", userID).
+		Update(bumpVersion(orm.Params{
+			"deleted_at": nil,
+			"updated_at": time.Now(),
+		})); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := suspendPriceAlerts(tx, userID, []int{deviceID}, false); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// suspendPriceAlerts 暂停或恢复设备的价格预警：暂停只处理生效中的预警，恢复只处理被暂停的预警
+func suspendPriceAlerts(tx orm.TxOrmer, userID int, deviceIDs []int, suspend bool) error {
+	from, to := "active", "suspended"
+	if !suspend {
+		from, to = "suspended", "active"
+	}
+	_, err := tx.QueryTable("price_alerts").
+		Filter("device_id__in", deviceIDs).
+		Filter("user_id", userID).
+		Filter("status", from).
+		Update(bumpVersion(orm.Params{
+			"status":     to,
+			"updated_at": time.Now(),
+		}))
 	return err
 }
 
-// UpdateDeviceStatus 更新设备状态
-func (r *DeviceRepository) UpdateDeviceStatus(deviceID, userID int, status string, salePrice *float64, saleDate *time.Time, notes string) error {
+// GetDeletedDevicesBefore 获取删除时间早于 before 的设备，按ID升序分批读取
+func (r *DeviceRepository) GetDeletedDevicesBefore(before time.Time, afterID, limit int) ([]*model.Device, error) {
+	o := orm.NewOrm()
+	var devices []*model.Device
+	_, err := o.QueryTable("devices").
+		Filter("deleted_at__isnull", false).
+		Filter("deleted_at__lt", before).
+		Filter("id__gt", afterID).
+		OrderBy("id").
+		Limit(limit).
+		All(&devices, "id", "user_id", "deleted_at")
+	return devices, err
+}
+
+// GetTemplateDevices 获取使用模板但字段版本不是 toVersion 的设备（含回收站中的设备），按ID升序分批读取；
+// userID 大于0时只取该用户的设备，fromVersion 大于0时只取该版本的设备
+func (r *DeviceRepository) GetTemplateDevices(templateID, userID, fromVersion, toVersion, afterID, limit int) ([]*model.Device, error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable("devices").
+		Filter("template_id", templateID).
+		Exclude("template_version", toVersion).
+		Filter("id__gt", afterID)
+	if userID > 0 {
+		qs = qs.Filter("user_id", userID)
+	}
+	if fromVersion > 0 {
+		qs = qs.Filter("template_version", fromVersion)
+	}
+	var devices []*model.Device
+	_, err := qs.OrderBy("id").Limit(limit).All(&devices, "id", "user_id", "specifications", "template_version", "version")
+	return devices, err
+}
+
+// MigrateDeviceSpecs 把设备规格参数迁移到新的模板字段版本；设备在读取后已被修改时不做修改并返回 false
+func (r *DeviceRepository) MigrateDeviceSpecs(device *model.Device, toVersion int, specifications string) (bool, error) {
+	o := orm.NewOrm()
+	var specs interface{} = specifications
+	if specifications == "" {
+		specs = nil
+	}
+	num, err := o.QueryTable("devices").
+		Filter("id", device.ID).
+		Filter("version", device.Version).
+		Update(bumpVersion(orm.Params{
+			"specifications":   specs,
+			"template_version": toVersion,
+			"updated_at":       time.Now(),
+		}))
+	return num > 0, err
+}
+
+// GetKitSummaries 批量汇总主设备的配件数量与价值，返回的汇总已包含主设备自身
+func (r *DeviceRepository) GetKitSummaries(userID int, parents []*model.Device) (map[int]*model.KitSummary, error) {
+	summaries := make(map[int]*model.KitSummary)
+	if len(parents) == 0 {
+		return summaries, nil
+	}
+
+	parentIDs := make([]int, 0, len(parents))
+	for _, parent := range parents {
+		parentIDs = append(parentIDs, parent.ID)
+	}
+
+	o := orm.NewOrm()
+	var accessories []*model.Device
+	_, err := o.QueryTable("devices").
+		Filter("parent_id__in", parentIDs).
+		Filter("user_id", userID).
+		Filter("deleted_at__isnull", true).
+		All(&accessories, "id", "parent_id", "purchase_price", "current_value", "status")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, accessory := range accessories {
+		summary, ok := summaries[*accessory.ParentID]
+		if !ok {
+			summary = &model.KitSummary{}
+			summaries[*accessory.ParentID] = summary
+		}
+		summary.AddAccessory(accessory)
+	}
+	for _, parent := range parents {
+		if summary, ok := summaries[parent.ID]; ok {
+			summary.TotalPurchasePrice += parent.PurchasePrice
+			summary.TotalCurrentValue += parent.CurrentValue
+		}
+	}
+	return summaries, nil
+}
+
+// CountAccessories 统计设备的配件数量
+func (r *DeviceRepository) CountAccessories(deviceID, userID int) (int64, error) {
+	o := orm.NewOrm()
+	return o.QueryTable("devices").
+		Filter("parent_id", deviceID).
+		Filter("user_id", userID).
+		Filter("deleted_at__isnull", true).
+		Count()
+}
+
+// UpdateKitStatus 在同一事务中更新主设备与指定配件的状态，配件不记录出售价格；
+// 主设备版本号与 version 不一致时返回 ErrVersionConflict
+func (r *DeviceRepository) UpdateKitStatus(deviceID, userID, version int, status string, salePrice *float64, saleDate *time.Time, notes string, accessoryIDs []int) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+
+	params := orm.Params{
+		"status":     status,
+		"updated_at": time.Now(),
+	}
+	if notes != "" {
+		params["notes"] = notes
+	}
+	if salePrice != nil {
+		params["sale_price"] = *salePrice
+	}
+	if saleDate != nil {
+		params["sale_date"] = *saleDate
+	}
+	num, err := tx.QueryTable("devices").Filter("id", deviceID).Filter("user_id", userID).Filter("version", version).Update(bumpVersion(params))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if num == 0 {
+		tx.Rollback()
+		return ErrVersionConflict
+	}
+
+	if len(accessoryIDs) > 0 {
+		accessoryParams := orm.Params{
+			"status":     status,
+			"updated_at": time.Now(),
+		}
+		if saleDate != nil {
+			accessoryParams["sale_date"] = *saleDate
+		}
+		if _, err := tx.QueryTable("devices").
+			Filter("id__in", accessoryIDs).
+			Filter("parent_id", deviceID).
+			Filter("user_id", userID).
+			Update(bumpVersion(accessoryParams)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpdateDeviceStatus 更新设备状态，版本号与 version 不一致时返回 ErrVersionConflict
+func (r *DeviceRepository) UpdateDeviceStatus(deviceID, userID, version int, status string, salePrice *float64, saleDate *time.Time, notes string) error {
 	o := orm.NewOrm()
 	params := orm.Params{
 		"status":     status,
@@ -145,11 +447,18 @@ func (r *DeviceRepository) UpdateDeviceStatus(deviceID, userID int, status strin
 		params["sale_date"] = *saleDate
 	}
 
-	_, err := o.QueryTable("devices").
+	num, err := o.QueryTable("devices").
 		Filter("id", deviceID).
 		Filter("user_id", userID).
-		Update(params)
-	return err
+		Filter("version", version).
+		Update(bumpVersion(params))
+	if err != nil {
+		return err
+	}
+	if num == 0 {
+		return ErrVersionConflict
+	}
+	return nil
 }
 
 // GetDeviceImages 获取设备图片
@@ -174,11 +483,112 @@ func (r *DeviceRepository) GetDeviceImages(deviceID, userID int) ([]*model.Devic
 	return images, err
 }
 
-// AddDeviceImage 添加设备图片
-func (r *DeviceRepository) AddDeviceImage(image *model.DeviceImage) error {
+// GetDeviceImageByID 获取设备的单张图片（校验设备归属）
+func (r *DeviceRepository) GetDeviceImageByID(imageID, deviceID, userID int) (*model.DeviceImage, error) {
 	o := orm.NewOrm()
-	image.CreatedAt = time.Now()
-	_, err := o.Insert(image)
+
+	exists := o.QueryTable("devices").
+		Filter("id", deviceID).
+		Filter("user_id", userID).
+		Filter("deleted_at__isnull", true).
+		Exist()
+	if !exists {
+		return nil, nil
+	}
+
+	image := &model.DeviceImage{}
+	err := o.QueryTable("device_images").
+		Filter("id", imageID).
+		Filter("device_id", deviceID).
+		One(image)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+// GetDeviceImageByHash 查找设备下内容相同的图片
+func (r *DeviceRepository) GetDeviceImageByHash(deviceID int, contentHash string) (*model.DeviceImage, error) {
+	o := orm.NewOrm()
+	image := &model.DeviceImage{}
+	err := o.QueryTable("device_images").
+		Filter("device_id", deviceID).
+		Filter("content_hash", contentHash).
+		One(image)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+// AddSharedDeviceImage 保存按内容去重的图片：在同一事务中锁定一条内容相同的图片记录并复用其文件，
+// 没有可复用的记录时调用 store 写入新文件；被锁定的记录在插入提交前不能删除，复用的文件不会被同时释放
+func (r *DeviceRepository) AddSharedDeviceImage(image *model.DeviceImage, store func() error) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+
+	var shared []*model.DeviceImage
+	_, err = tx.Raw("SELECT * FROM device_images WHERE content_hash = ? AND storage_key IS NOT NULL ORDER BY id LIMIT 1 FOR UPDATE",
+		image.ContentHash).QueryRows(&shared)
+	if err == nil && len(shared) > 0 {
+		image.StorageKey = shared[0].StorageKey
+		image.ContentType = shared[0].ContentType
+		image.Width = shared[0].Width
+		image.Height = shared[0].Height
+		image.ByteSize = shared[0].ByteSize
+		image.Variants = shared[0].Variants
+	} else if err == nil {
+		err = store()
+	}
+	if err == nil {
+		image.CreatedAt = time.Now()
+		_, err = tx.Insert(image)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReleaseSharedImage 在锁定引用同一文件的图片记录的事务中判断文件是否仍被引用，没有引用时调用 remove 删除文件
+func (r *DeviceRepository) ReleaseSharedImage(contentHash, storageKey string, remove func()) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	_, err = tx.Raw("SELECT id FROM device_images WHERE content_hash = ? AND storage_key = ? FOR UPDATE",
+		contentHash, storageKey).QueryRows(&ids)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(ids) == 0 {
+		remove()
+	}
+	return tx.Commit()
+}
+
+// UpdateDeviceImageURL 更新图片访问地址
+func (r *DeviceRepository) UpdateDeviceImageURL(imageID int, imageURL string) error {
+	o := orm.NewOrm()
+	_, err := o.QueryTable("device_images").
+		Filter("id", imageID).
+		Update(orm.Params{
+			"image_url": imageURL,
+		})
 	return err
 }
 
@@ -209,6 +619,199 @@ func (r *DeviceRepository) DeleteDeviceImage(imageID, deviceID, userID int) erro
 	return err
 }
 
+// UpdateDeviceImageCaption 更新图片说明
+func (r *DeviceRepository) UpdateDeviceImageCaption(imageID int, caption string) error {
+	o := orm.NewOrm()
+	_, err := o.QueryTable("device_images").
+		Filter("id", imageID).
+		Update(orm.Params{
+			"caption": caption,
+		})
+	return err
+}
+
+// ReorderDeviceImages 按给定顺序重排设备图片，imageIDs 必须包含设备的全部图片
+func (r *DeviceRepository) ReorderDeviceImages(deviceID int, imageIDs []int) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+
+	var images []*model.DeviceImage
+	if _, err := tx.QueryTable("device_images").Filter("device_id", deviceID).All(&images, "id"); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(images) != len(imageIDs) {
+		tx.Rollback()
+		return ErrImageSetMismatch
+	}
+	owned := make(map[int]bool, len(images))
+	for _, image := range images {
+		owned[image.ID] = true
+	}
+	for _, id := range imageIDs {
+		if !owned[id] {
+			tx.Rollback()
+			return ErrImageSetMismatch
+		}
+		delete(owned, id) // 防止重复ID
+	}
+
+	for i, id := range imageIDs {
+		if _, err := tx.QueryTable("device_images").
+			Filter("id", id).
+			Update(orm.Params{"sort_order": i}); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetDeviceCoverImage 将指定图片设为封面，同一设备的其他图片恢复为普通图片
+func (r *DeviceRepository) SetDeviceCoverImage(deviceID, imageID int) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.QueryTable("device_images").
+		Filter("device_id", deviceID).
+		Filter("image_type", "cover").
+		Exclude("id", imageID).
+		Update(orm.Params{"image_type": "normal"}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	num, err := tx.QueryTable("device_images").
+		Filter("id", imageID).
+		Filter("device_id", deviceID).
+		Update(orm.Params{"image_type": "cover"})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if num == 0 && !tx.QueryTable("device_images").Filter("id", imageID).Filter("device_id", deviceID).Exist() {
+		tx.Rollback()
+		return errors.New("图片不存在")
+	}
+	return tx.Commit()
+}
+
+// GetCoverImages 批量获取设备封面图，没有封面时取排序最靠前的图片
+func (r *DeviceRepository) GetCoverImages(deviceIDs []int) (map[int]*model.DeviceImage, error) {
+	covers := make(map[int]*model.DeviceImage)
+	if len(deviceIDs) == 0 {
+		return covers, nil
+	}
+
+	o := orm.NewOrm()
+	var images []*model.DeviceImage
+	_, err := o.QueryTable("device_images").
+		Filter("device_id__in", deviceIDs).
+		OrderBy("device_id", "sort_order", "created_at").
+		All(&images)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range images {
+		current, ok := covers[image.DeviceID]
+		if !ok || (image.ImageType == "cover" && current.ImageType != "cover") {
+			covers[image.DeviceID] = image
+		}
+	}
+	return covers, nil
+}
+
+// HardDeleteDevice 彻底删除设备及其图片记录，返回被删除的图片记录
+func (r *DeviceRepository) HardDeleteDevice(deviceID, userID int) ([]*model.DeviceImage, error) {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	exists := tx.QueryTable("devices").
+		Filter("id", deviceID).
+		Filter("user_id", userID).
+		Exist()
+	if !exists {
+		tx.Rollback()
+		return nil, errors.New("设备不存在或无权限")
+	}
+
+	var images []*model.DeviceImage
+	if _, err := tx.QueryTable("device_images").Filter("device_id", deviceID).All(&images); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.QueryTable("device_images").Filter("device_id", deviceID).Delete(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.QueryTable("device_attachments").Filter("device_id", deviceID).Delete(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.QueryTable("device_service_records").Filter("device_id", deviceID).Delete(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.Raw("DELETE FROM reminders WHERE device_id = ?", deviceID).Exec(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.QueryTable("device_events").Filter("device_id", deviceID).Delete(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.QueryTable("device_costs").Filter("device_id", deviceID).Delete(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.QueryTable("device_usage_logs").Filter("device_id", deviceID).Delete(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.QueryTable("device_tags").Filter("device_id", deviceID).Delete(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.QueryTable("device_spec_values").Filter("device_id", deviceID).Delete(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.QueryTable("device_qr_codes").Filter("device_id", deviceID).Delete(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// 价格数据按设备与用户删除
+	for _, table := range []string{"prices", "price_histories", "price_alerts", "price_predictions"} {
+		if _, err := tx.Raw("DELETE FROM "+table+" WHERE device_id = ? AND user_id = ?", deviceID, userID).Exec(); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	// 配件保留，解除与主设备的关联
+	if _, err := tx.QueryTable("devices").Filter("parent_id", deviceID).Update(bumpVersion(orm.Params{"parent_id": nil, "updated_at": time.Now()})); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.QueryTable("devices").Filter("id", deviceID).Filter("user_id", userID).Delete(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
 // GetPriceHistory 获取设备价格历史
 // 注意：价格历史功能应该在价格模块中实现
 func (r *DeviceRepository) GetPriceHistory(deviceID, userID int, limit int) ([]interface{}, error) {
@@ -230,27 +833,452 @@ func (r *DeviceRepository) UpdateDeviceCurrentValue(deviceID int, currentValue f
 	o := orm.NewOrm()
 	_, err := o.QueryTable("devices").
 		Filter("id", deviceID).
-		Update(orm.Params{
+		Update(bumpVersion(orm.Params{
 			"current_value": currentValue,
 			"updated_at":    time.Now(),
-		})
+		}))
 	return err
 }
 
 // BatchImportDevices 批量导入设备
 func (r *DeviceRepository) BatchImportDevices(devices []*model.Device) (int, error) {
+	if len(devices) == 0 {
+		return 0, nil
+	}
+
 	o := orm.NewOrm()
-	successCount := 0
+	tx, err := o.Begin()
+	if err != nil {
+		return 0, err
+	}
 
+	// 同一事务写入，任一失败则全部回滚
+	now := time.Now()
 	for _, device := range devices {
-		device.CreatedAt = time.Now()
-		device.UpdatedAt = time.Now()
-		_, err := o.Insert(device)
-		if err != nil {
-			continue // 跳过失败的记录
+		device.CreatedAt = now
+		device.UpdatedAt = now
+		device.Version = 1
+		if _, err := tx.Insert(device); err != nil {
+			tx.Rollback()
+			for _, d := range devices {
+				d.ID = 0
+			}
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(devices), nil
+}
+
+// GetDeviceIDsBySerialNumbers 按序列号查找用户未删除的设备，返回 大写序列号 -> 设备ID
+func (r *DeviceRepository) GetDeviceIDsBySerialNumbers(userID int, serialNumbers []string) (map[string]int, error) {
+	result := make(map[string]int)
+	if len(serialNumbers) == 0 {
+		return result, nil
+	}
+
+	o := orm.NewOrm()
+	var devices []*model.Device
+	_, err := o.QueryTable("devices").
+		Filter("user_id", userID).
+		Filter("serial_number__in", serialNumbers).
+		Filter("deleted_at__isnull", true).
+		All(&devices, "id", "serial_number")
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		result[strings.ToUpper(strings.TrimSpace(device.SerialNumber))] = device.ID
+	}
+	return result, nil
+}
+
+// GetDeviceTagIDs 获取设备的标签ID
+func (r *DeviceRepository) GetDeviceTagIDs(deviceID int) ([]int, error) {
+	o := orm.NewOrm()
+	var deviceTags []*model.DeviceTag
+	_, err := o.QueryTable("device_tags").Filter("device_id", deviceID).OrderBy("id").All(&deviceTags, "tag_id")
+	if err != nil {
+		return nil, err
+	}
+	tagIDs := make([]int, 0, len(deviceTags))
+	for _, deviceTag := range deviceTags {
+		tagIDs = append(tagIDs, deviceTag.TagID)
+	}
+	return tagIDs, nil
+}
+
+// SetDeviceTags 用给定标签替换设备现有标签
+func (r *DeviceRepository) SetDeviceTags(deviceID int, tagIDs []int) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.QueryTable("device_tags").Filter("device_id", deviceID).Delete(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	now := time.Now()
+	for _, tagID := range tagIDs {
+		if _, err := tx.Insert(&model.DeviceTag{DeviceID: deviceID, TagID: tagID, CreatedAt: now}); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetAvailableTagIDs 过滤出用户可用的启用标签（系统标签或本人的自定义标签）
+func (r *DeviceRepository) GetAvailableTagIDs(userID int, tagIDs []int) (map[int]bool, error) {
+	available := make(map[int]bool)
+	if len(tagIDs) == 0 {
+		return available, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tagIDs)), ",")
+	args := make([]interface{}, 0, len(tagIDs)+1)
+	for _, tagID := range tagIDs {
+		args = append(args, tagID)
+	}
+	args = append(args, userID)
+
+	o := orm.NewOrm()
+	var ids []int
+	_, err := o.Raw("SELECT id FROM tags WHERE id IN ("+placeholders+") AND active = 1 AND (type = 'system' OR owner_id = ?)", args...).QueryRows(&ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		available[id] = true
+	}
+	return available, nil
+}
+
+// GetDuplicateCandidates 获取可能与给定设备重复的已有设备：序列号相同或品牌相同
+func (r *DeviceRepository) GetDuplicateCandidates(userID int, serialNumber, brand string) ([]*model.Device, error) {
+	cond := orm.NewCondition()
+	if serialNumber != "" {
+		cond = cond.Or("serial_number", serialNumber)
+	}
+	if brand != "" {
+		cond = cond.Or("brand__iexact", brand)
+	}
+	if cond.IsEmpty() {
+		return nil, nil
+	}
+
+	o := orm.NewOrm()
+	var devices []*model.Device
+	_, err := o.QueryTable("devices").
+		SetCond(orm.NewCondition().AndCond(cond)).
+		Filter("user_id", userID).
+		Filter("deleted_at__isnull", true).
+		OrderBy("id").
+		All(&devices)
+	return devices, err
+}
+
+// GetAllDevices 获取用户全部未删除设备，用于重复设备扫描
+func (r *DeviceRepository) GetAllDevices(userID int) ([]*model.Device, error) {
+	o := orm.NewOrm()
+	var devices []*model.Device
+	_, err := o.QueryTable("devices").
+		Filter("user_id", userID).
+		Filter("deleted_at__isnull", true).
+		OrderBy("id").
+		All(&devices)
+	return devices, err
+}
+
+// MergeDevices 在同一事务中把来源设备的数据并入目标设备并软删除来源设备：
+// 更新目标设备字段，迁移图片（dropImageIDs 为与目标重复的图片，直接删除记录）、附件、维修记录、支出、使用记录、
+// 价格历史、价格预警、标签与配件
+func (r *DeviceRepository) MergeDevices(target *model.Device, sourceIDs []int, movedImages []*model.DeviceImage, dropImageIDs []int) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	target.UpdatedAt = now
+	if err := UpdateWithVersion(tx, "devices", target.ID, &target.Version, target); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if len(dropImageIDs) > 0 {
+		if _, err := tx.QueryTable("device_images").Filter("id__in", dropImageIDs).Filter("device_id__in", sourceIDs).Delete(); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	for _, image := range movedImages {
+		if _, err := tx.QueryTable("device_images").
+			Filter("id", image.ID).
+			Filter("device_id__in", sourceIDs).
+			Update(orm.Params{
+				"device_id":  target.ID,
+				"sort_order": image.SortOrder,
+				"image_type": image.ImageType,
+			}); err != nil {
+			tx.Rollback()
+			return err
+		}
+		image.DeviceID = target.ID
+	}
+
+	for _, table := range []string{"device_attachments", "device_service_records", "device_costs", "device_usage_logs", "price_histories", "price_alerts"} {
+		if _, err := tx.QueryTable(table).
+			Filter("device_id__in", sourceIDs).
+			Filter("user_id", target.UserID).
+			Update(orm.Params{"device_id": target.ID}); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// 标签取并集
+	var sourceTags []*model.DeviceTag
+	if _, err := tx.QueryTable("device_tags").Filter("device_id__in", sourceIDs).All(&sourceTags); err != nil {
+		tx.Rollback()
+		return err
+	}
+	var targetTags []*model.DeviceTag
+	if _, err := tx.QueryTable("device_tags").Filter("device_id", target.ID).All(&targetTags); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tagSet := make(map[int]bool, len(targetTags))
+	for _, tag := range targetTags {
+		tagSet[tag.TagID] = true
+	}
+	for _, tag := range sourceTags {
+		if tagSet[tag.TagID] {
+			continue
+		}
+		tagSet[tag.TagID] = true
+		if _, err := tx.Insert(&model.DeviceTag{DeviceID: target.ID, TagID: tag.TagID, CreatedAt: now}); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.QueryTable("device_tags").Filter("device_id__in", sourceIDs).Delete(); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 来源设备的配件改挂到目标设备
+	if _, err := tx.QueryTable("devices").
+		Filter("parent_id__in", sourceIDs).
+		Filter("user_id", target.UserID).
+		Exclude("id", target.ID).
+		Update(bumpVersion(orm.Params{"parent_id": target.ID, "updated_at": now})); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.QueryTable("devices").
+		Filter("id__in", sourceIDs).
+		Filter("user_id", target.UserID).
+		Update(bumpVersion(orm.Params{"deleted_at": now, "updated_at": now})); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetDeviceTagNames 批量获取设备的标签名称，返回 设备ID -> 标签名称
+func (r *DeviceRepository) GetDeviceTagNames(deviceIDs []int) (map[int][]string, error) {
+	result := make(map[int][]string)
+	if len(deviceIDs) == 0 {
+		return result, nil
+	}
+
+	type deviceTagName struct {
+		DeviceID int    `orm:"column(device_id)"`
+		Name     string `orm:"column(name)"`
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(deviceIDs)), ",")
+	args := make([]interface{}, 0, len(deviceIDs))
+	for _, id := range deviceIDs {
+		args = append(args, id)
+	}
+
+	o := orm.NewOrm()
+	var rows []deviceTagName
+	_, err := o.Raw("SELECT dt.device_id, t.name FROM device_tags dt INNER JOIN tags t ON t.id = dt.tag_id WHERE dt.device_id IN ("+placeholders+") ORDER BY dt.id", args...).QueryRows(&rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.DeviceID] = append(result[row.DeviceID], row.Name)
+	}
+	return result, nil
+}
+
+// GetCurrentPrices 批量获取价格模块记录的设备当前市场价，返回 设备ID -> 价格
+func (r *DeviceRepository) GetCurrentPrices(deviceIDs []int) (map[int]float64, error) {
+	result := make(map[int]float64)
+	if len(deviceIDs) == 0 {
+		return result, nil
+	}
+
+	type devicePrice struct {
+		DeviceID     int     `orm:"column(device_id)"`
+		CurrentPrice float64 `orm:"column(current_price)"`
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(deviceIDs)), ",")
+	args := make([]interface{}, 0, len(deviceIDs))
+	for _, id := range deviceIDs {
+		args = append(args, id)
+	}
+
+	o := orm.NewOrm()
+	var rows []devicePrice
+	_, err := o.Raw("SELECT device_id, current_price FROM prices WHERE device_id IN ("+placeholders+") ORDER BY id", args...).QueryRows(&rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.DeviceID] = row.CurrentPrice
+	}
+	return result, nil
+}
+
+// GetSpecificationKeys 获取用户设备规格参数中出现过的全部键
+func (r *DeviceRepository) GetSpecificationKeys(userID int) ([]string, error) {
+	o := orm.NewOrm()
+	var specs []string
+	_, err := o.Raw("SELECT specifications FROM devices WHERE user_id = ? AND deleted_at IS NULL AND specifications IS NOT NULL", userID).QueryRows(&specs)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+	for _, spec := range specs {
+		values := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(spec), &values); err != nil {
+			continue
+		}
+		for key := range values {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// GetDeviceIDsByFilter 按列表筛选条件获取设备ID，最多返回 limit 个
+func (r *DeviceRepository) GetDeviceIDsByFilter(userID int, params map[string]interface{}, limit int) ([]int, error) {
+	o := orm.NewOrm()
+	qs, err := deviceListQuery(o, userID, params)
+	if err != nil {
+		return nil, err
+	}
+	var devices []*model.Device
+	if _, err := qs.Limit(limit).All(&devices, "id"); err != nil {
+		return nil, err
+	}
+	deviceIDs := make([]int, 0, len(devices))
+	for _, device := range devices {
+		deviceIDs = append(deviceIDs, device.ID)
+	}
+	return deviceIDs, nil
+}
+
+// GetDevicesByIDs 批量获取用户的设备，包含已删除的设备
+func (r *DeviceRepository) GetDevicesByIDs(userID int, deviceIDs []int) ([]*model.Device, error) {
+	if len(deviceIDs) == 0 {
+		return nil, nil
+	}
+	o := orm.NewOrm()
+	var devices []*model.Device
+	_, err := o.QueryTable("devices").
+		Filter("id__in", deviceIDs).
+		Filter("user_id", userID).
+		All(&devices)
+	return devices, err
+}
+
+// GetDevicesTagIDs 批量获取设备的标签ID
+func (r *DeviceRepository) GetDevicesTagIDs(deviceIDs []int) (map[int][]int, error) {
+	result := make(map[int][]int)
+	if len(deviceIDs) == 0 {
+		return result, nil
+	}
+	o := orm.NewOrm()
+	var deviceTags []*model.DeviceTag
+	_, err := o.QueryTable("device_tags").Filter("device_id__in", deviceIDs).OrderBy("id").All(&deviceTags)
+	if err != nil {
+		return nil, err
+	}
+	for _, deviceTag := range deviceTags {
+		result[deviceTag.DeviceID] = append(result[deviceTag.DeviceID], deviceTag.TagID)
+	}
+	return result, nil
+}
+
+// BulkUpdateDevices 在同一事务中批量修改设备：params 为要更新的字段（可为空），
+// addTags 为各设备要新增的标签，removeTagIDs 为要从全部设备移除的标签
+func (r *DeviceRepository) BulkUpdateDevices(userID int, deviceIDs []int, params orm.Params, addTags map[int][]int, removeTagIDs []int) error {
+	if len(deviceIDs) == 0 {
+		return nil
+	}
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+
+	// 标签变化同样视为修改设备，版本号一并自增
+	if params == nil {
+		params = orm.Params{}
+	}
+	deletedAt, deleting := params["deleted_at"]
+	params["updated_at"] = time.Now()
+	if _, err := tx.QueryTable("devices").
+		Filter("id__in", deviceIDs).
+		Filter("user_id", userID).
+		Update(bumpVersion(params)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	// 移入回收站时暂停价格预警，恢复时重新启用
+	if deleting {
+		if err := suspendPriceAlerts(tx, userID, deviceIDs, deletedAt != nil); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	now := time.Now()
+	for deviceID, tagIDs := range addTags {
+		for _, tagID := range tagIDs {
+			if _, err := tx.Insert(&model.DeviceTag{DeviceID: deviceID, TagID: tagID, CreatedAt: now}); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	if len(removeTagIDs) > 0 {
+		if _, err := tx.QueryTable("device_tags").
+			Filter("device_id__in", deviceIDs).
+			Filter("tag_id__in", removeTagIDs).
+			Delete(); err != nil {
+			tx.Rollback()
+			return err
 		}
-		successCount++
 	}
 
-	return successCount, nil
+	return tx.Commit()
 }
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"Backend_Lili/internal/device/model"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+// 规格筛选的比较方式
+const (
+	SpecOpEq       = "eq"
+	SpecOpNe       = "ne"
+	SpecOpGt       = "gt"
+	SpecOpGte      = "gte"
+	SpecOpLt       = "lt"
+	SpecOpLte      = "lte"
+	SpecOpContains = "contains"
+)
+
+// specOpSQL 范围比较对应的SQL运算符
+var specOpSQL = map[string]string{
+	SpecOpGt:  ">",
+	SpecOpGte: ">=",
+	SpecOpLt:  "<",
+	SpecOpLte: "<=",
+}
+
+// specNumberTolerance 数值相等比较的相对误差，换算单位时可能产生浮点误差
+const specNumberTolerance = 1e-9
+
+// SpecFilterValue 规格筛选的比较值
+type SpecFilterValue struct {
+	Text      string
+	Number    *float64   // 带单位时已换算为量纲的基准单位
+	Dimension string     // 数值的量纲或币种，为空时按各字段自身的单位比较
+	Date      *time.Time // 日期值
+}
+
+// SpecFilter 规格参数筛选条件，eq/ne 时任一值匹配即可，其他比较方式只有一个值
+type SpecFilter struct {
+	Field  string
+	Op     string
+	Values []*SpecFilterValue
+}
+
+// ReplaceDeviceSpecValues 重建设备的规格参数索引
+func (r *DeviceRepository) ReplaceDeviceSpecValues(deviceID int, values []*model.DeviceSpecValue) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.QueryTable("device_spec_values").Filter("device_id", deviceID).Delete(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(values) > 0 {
+		if _, err := tx.InsertMulti(100, values); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDevicesForSpecIndex 按ID升序分批获取用户的设备（含回收站中的设备），用于重建规格参数索引
+func (r *DeviceRepository) GetDevicesForSpecIndex(userID, afterID, limit int) ([]*model.Device, error) {
+	o := orm.NewOrm()
+	var devices []*model.Device
+	_, err := o.QueryTable("devices").
+		Filter("user_id", userID).
+		Filter("id__gt", afterID).
+		OrderBy("id").
+		Limit(limit).
+		All(&devices, "id", "user_id", "template_id", "template_version", "specifications")
+	return devices, err
+}
+
+// specFilterDeviceIDs 获取同时满足全部规格筛选条件的设备ID
+func specFilterDeviceIDs(o orm.Ormer, userID int, filters []*SpecFilter) ([]int, error) {
+	query := "SELECT d.id FROM devices d WHERE d.user_id = ?"
+	args := []interface{}{userID}
+	for _, filter := range filters {
+		subquery := "SELECT device_id FROM device_spec_values WHERE user_id = ? AND field_name = ?"
+		subArgs := []interface{}{userID, filter.Field}
+
+		if filter.Op == SpecOpNe {
+			// 填写了该字段且值不等于任一比较值
+			cond, condArgs := specEqualCond(filter.Values)
+			query += " AND d.id IN (" + subquery + ") AND d.id NOT IN (" + subquery + " AND (" + cond + "))"
+			args = append(args, subArgs...)
+			args = append(args, subArgs...)
+			args = append(args, condArgs...)
+			continue
+		}
+
+		var cond string
+		var condArgs []interface{}
+		switch filter.Op {
+		case SpecOpEq:
+			cond, condArgs = specEqualCond(filter.Values)
+		case SpecOpContains:
+			cond, condArgs = "value_text LIKE ?", []interface{}{"%" + escapeLike(filter.Values[0].Text) + "%"}
+		default:
+			cond, condArgs = specRangeCond(specOpSQL[filter.Op], filter.Values[0])
+		}
+		query += " AND d.id IN (" + subquery + " AND (" + cond + "))"
+		args = append(args, subArgs...)
+		args = append(args, condArgs...)
+	}
+
+	var deviceIDs []int
+	_, err := o.Raw(query, args...).QueryRows(&deviceIDs)
+	return deviceIDs, err
+}
+
+// specEqualCond 等于任一比较值：文本相同，或数值、日期相等
+func specEqualCond(values []*SpecFilterValue) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	for _, value := range values {
+		conds = append(conds, "value_text = ?")
+		args = append(args, value.Text)
+		if value.Number != nil {
+			if value.Dimension != "" {
+				conds = append(conds, "(dimension = ? AND ABS(value_num - ?) <= ?)")
+				args = append(args, value.Dimension, *value.Number, math.Abs(*value.Number)*specNumberTolerance)
+			} else {
+				conds = append(conds, "ABS(value_num - ? * unit_factor) <= ABS(? * unit_factor) * ?")
+				args = append(args, *value.Number, *value.Number, specNumberTolerance)
+			}
+		}
+		if value.Date != nil {
+			conds = append(conds, "value_date = ?")
+			args = append(args, value.Date.Format("2006-01-02"))
+		}
+	}
+	return strings.Join(conds, " OR "), args
+}
+
+// specRangeCond 数值或日期的范围比较，数值不带单位时按各字段自身的单位换算
+func specRangeCond(operator string, value *SpecFilterValue) (string, []interface{}) {
+	if value.Date != nil {
+		return "value_date " + operator + " ?", []interface{}{value.Date.Format("2006-01-02")}
+	}
+	if value.Dimension != "" {
+		return "dimension = ? AND value_num " + operator + " ?", []interface{}{value.Dimension, *value.Number}
+	}
+	return "value_num " + operator + " ? * unit_factor", []interface{}{*value.Number}
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
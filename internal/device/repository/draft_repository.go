@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"Backend_Lili/internal/device/model"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+type DraftRepository struct{}
+
+func NewDraftRepository() *DraftRepository {
+	return &DraftRepository{}
+}
+
+// GetDrafts 分页获取设备草稿，可按状态筛选
+func (r *DraftRepository) GetDrafts(userID int, status string, page, limit int) ([]*model.DeviceDraft, int64, error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable("device_drafts").Filter("user_id", userID)
+	if status != "" {
+		qs = qs.Filter("status", status)
+	}
+	total, err := qs.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	var drafts []*model.DeviceDraft
+	_, err = qs.OrderBy("-order_date", "-id").Limit(limit, (page-1)*limit).All(&drafts)
+	return drafts, total, err
+}
+
+// GetDraftByID 获取单条草稿
+func (r *DraftRepository) GetDraftByID(draftID, userID int) (*model.DeviceDraft, error) {
+	o := orm.NewOrm()
+	draft := &model.DeviceDraft{}
+	err := o.QueryTable("device_drafts").Filter("id", draftID).Filter("user_id", userID).One(draft)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	return draft, err
+}
+
+// GetImportedOrderKeys 获取已导入过的订单商品，返回 订单号+标题 集合，用于重复导入时跳过
+func (r *DraftRepository) GetImportedOrderKeys(userID int, source string, orderNos []string) (map[string]bool, error) {
+	keys := make(map[string]bool)
+	if len(orderNos) == 0 {
+		return keys, nil
+	}
+
+	o := orm.NewOrm()
+	var drafts []*model.DeviceDraft
+	_, err := o.QueryTable("device_drafts").
+		Filter("user_id", userID).
+		Filter("source", source).
+		Filter("order_no__in", orderNos).
+		All(&drafts, "order_no", "title")
+	if err != nil {
+		return nil, err
+	}
+	for _, draft := range drafts {
+		keys[DraftOrderKey(draft.OrderNo, draft.Title)] = true
+	}
+	return keys, nil
+}
+
+// DraftOrderKey 订单商品的去重键
+func DraftOrderKey(orderNo, title string) string {
+	return orderNo + "\x00" + title
+}
+
+// CreateDrafts 在同一事务中批量创建草稿
+func (r *DraftRepository) CreateDrafts(drafts []*model.DeviceDraft) error {
+	if len(drafts) == 0 {
+		return nil
+	}
+
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, draft := range drafts {
+		draft.CreatedAt = now
+		draft.UpdatedAt = now
+		if _, err := tx.Insert(draft); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UpdateDraft 更新草稿
+func (r *DraftRepository) UpdateDraft(draft *model.DeviceDraft) error {
+	o := orm.NewOrm()
+	draft.UpdatedAt = time.Now()
+	_, err := o.Update(draft)
+	return err
+}
+
+// DeleteDraft 删除草稿
+func (r *DraftRepository) DeleteDraft(draftID, userID int) error {
+	o := orm.NewOrm()
+	_, err := o.QueryTable("device_drafts").Filter("id", draftID).Filter("user_id", userID).Delete()
+	return err
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"Backend_Lili/internal/device/model"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+type ServiceRecordRepository struct{}
+
+func NewServiceRecordRepository() *ServiceRecordRepository {
+	return &ServiceRecordRepository{}
+}
+
+// GetServiceRecords 获取设备维修保养记录，按服务日期倒序
+func (r *ServiceRecordRepository) GetServiceRecords(deviceID, userID int, serviceType string) ([]*model.DeviceServiceRecord, error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable("device_service_records").
+		Filter("device_id", deviceID).
+		Filter("user_id", userID)
+	if serviceType != "" {
+		qs = qs.Filter("service_type", serviceType)
+	}
+
+	var records []*model.DeviceServiceRecord
+	_, err := qs.OrderBy("-service_date", "-id").All(&records)
+	return records, err
+}
+
+// GetServiceRecordByID 获取单条维修保养记录（校验设备与用户归属）
+func (r *ServiceRecordRepository) GetServiceRecordByID(recordID, deviceID, userID int) (*model.DeviceServiceRecord, error) {
+	o := orm.NewOrm()
+	record := &model.DeviceServiceRecord{}
+	err := o.QueryTable("device_service_records").
+		Filter("id", recordID).
+		Filter("device_id", deviceID).
+		Filter("user_id", userID).
+		One(record)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// CreateServiceRecord 创建维修保养记录
+func (r *ServiceRecordRepository) CreateServiceRecord(record *model.DeviceServiceRecord) error {
+	o := orm.NewOrm()
+	record.CreatedAt = time.Now()
+	record.UpdatedAt = time.Now()
+	_, err := o.Insert(record)
+	return err
+}
+
+// UpdateServiceRecord 更新维修保养记录
+func (r *ServiceRecordRepository) UpdateServiceRecord(record *model.DeviceServiceRecord) error {
+	o := orm.NewOrm()
+	record.UpdatedAt = time.Now()
+	_, err := o.Update(record)
+	return err
+}
+
+// DeleteServiceRecord 删除维修保养记录及其附件记录，返回被删除的附件
+func (r *ServiceRecordRepository) DeleteServiceRecord(recordID, userID int) ([]*model.DeviceAttachment, error) {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments []*model.DeviceAttachment
+	if _, err := tx.QueryTable("device_attachments").Filter("service_record_id", recordID).All(&attachments); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.QueryTable("device_attachments").Filter("service_record_id", recordID).Delete(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.QueryTable("device_service_records").Filter("id", recordID).Filter("user_id", userID).Delete(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// ServiceCostSummary 维修保养费用汇总
+type ServiceCostSummary struct {
+	RecordCount  int     // 记录数
+	TotalCost    float64 // 自付费用（不含保修范围内）
+	CoveredCost  float64 // 保修范围内的费用
+	CoveredCount int     // 保修范围内的记录数
+}
+
+// GetServiceCostSummary 汇总设备的维修保养费用
+func (r *ServiceRecordRepository) GetServiceCostSummary(deviceID, userID int) (*ServiceCostSummary, error) {
+	o := orm.NewOrm()
+	summary := &ServiceCostSummary{}
+	err := o.Raw(`SELECT COUNT(*) AS record_count,
+		COALESCE(SUM(CASE WHEN warranty_covered = 0 THEN cost ELSE 0 END), 0) AS total_cost,
+		COALESCE(SUM(CASE WHEN warranty_covered = 1 THEN cost ELSE 0 END), 0) AS covered_cost,
+		COALESCE(SUM(CASE WHEN warranty_covered = 1 THEN 1 ELSE 0 END), 0) AS covered_count
+		FROM device_service_records WHERE device_id = ? AND user_id = ?`, deviceID, userID).QueryRow(summary)
+	return summary, err
+}
+
+// GetRecordAttachments 批量获取记录关联的附件
+func (r *ServiceRecordRepository) GetRecordAttachments(recordIDs []int) (map[int][]*model.DeviceAttachment, error) {
+	result := make(map[int][]*model.DeviceAttachment)
+	if len(recordIDs) == 0 {
+		return result, nil
+	}
+
+	o := orm.NewOrm()
+	var attachments []*model.DeviceAttachment
+	_, err := o.QueryTable("device_attachments").
+		Filter("service_record_id__in", recordIDs).
+		OrderBy("created_at").
+		All(&attachments)
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		result[*attachment.ServiceRecordID] = append(result[*attachment.ServiceRecordID], attachment)
+	}
+	return result, nil
+}
+
+// GetServiceCostTotals 按设备汇总用户全部设备的自付维修保养费用
+func (r *ServiceRecordRepository) GetServiceCostTotals(userID int) (map[int]float64, error) {
+	o := orm.NewOrm()
+	var rows []struct {
+		DeviceID  int     `orm:"column(device_id)"`
+		TotalCost float64 `orm:"column(total_cost)"`
+	}
+	_, err := o.Raw(`SELECT device_id, COALESCE(SUM(cost), 0) AS total_cost
+		FROM device_service_records WHERE user_id = ? AND warranty_covered = 0
+		GROUP BY device_id`, userID).QueryRows(&rows)
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[int]float64, len(rows))
+	for _, row := range rows {
+		totals[row.DeviceID] = row.TotalCost
+	}
+	return totals, nil
+}
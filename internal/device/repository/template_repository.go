@@ -53,28 +53,201 @@ func (r *TemplateRepository) GetTemplateByID(templateID int) (*model.DeviceTempl
 	return template, err
 }
 
-// CreateTemplate 创建模板
-func (r *TemplateRepository) CreateTemplate(template *model.DeviceTemplate) error {
+// templateEditableColumns 编辑模板时更新的列，使用次数、复制次数与评分由各自的操作单独维护
+var templateEditableColumns = []string{
+	"name", "description", "icon", "fields", "category_id", "is_active",
+	"visibility", "review_status", "review_note", "reviewed_at", "fields_version", "updated_at",
+}
+
+// CreateTemplate 创建模板，同时记录字段定义的第1版
+func (r *TemplateRepository) CreateTemplate(template *model.DeviceTemplate, createdBy int) error {
 	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	if err := insertTemplate(tx, template, createdBy); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ForkTemplate 创建复制的模板，并把来源模板的复制次数加一
+func (r *TemplateRepository) ForkTemplate(template *model.DeviceTemplate, createdBy int) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	if err := insertTemplate(tx, template, createdBy); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Raw("UPDATE device_templates SET fork_count = fork_count + 1 WHERE id = ?", *template.ForkedFromID).Exec(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertTemplate 插入模板并记录字段定义的第1版
+func insertTemplate(tx orm.TxOrmer, template *model.DeviceTemplate, createdBy int) error {
 	template.CreatedAt = time.Now()
 	template.UpdatedAt = time.Now()
-	_, err := o.Insert(template)
-	return err
+	template.Version = 1
+	template.FieldsVersion = 1
+	if _, err := tx.Insert(template); err != nil {
+		return err
+	}
+	return insertTemplateVersion(tx, template, createdBy)
 }
 
-// UpdateTemplate 更新模板
-func (r *TemplateRepository) UpdateTemplate(template *model.DeviceTemplate) error {
+// UpdateTemplate 更新模板，template.Version 为读取时的版本号，已被修改时返回 ErrVersionConflict；
+// fieldsChanged 为 true 时字段定义版本号加一并记录新版本
+func (r *TemplateRepository) UpdateTemplate(template *model.DeviceTemplate, fieldsChanged bool, updatedBy int) error {
 	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
 	template.UpdatedAt = time.Now()
-	_, err := o.Update(template)
+	if fieldsChanged {
+		template.FieldsVersion++
+	}
+	if err := UpdateWithVersion(tx, "device_templates", template.ID, &template.Version, template, templateEditableColumns...); err != nil {
+		tx.Rollback()
+		if fieldsChanged {
+			template.FieldsVersion--
+		}
+		return err
+	}
+	if fieldsChanged {
+		if err := insertTemplateVersion(tx, template, updatedBy); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// insertTemplateVersion 把模板当前的字段定义记为新版本
+func insertTemplateVersion(tx orm.TxOrmer, template *model.DeviceTemplate, createdBy int) error {
+	_, err := tx.Insert(&model.DeviceTemplateVersion{
+		TemplateID: template.ID,
+		Version:    template.FieldsVersion,
+		Fields:     template.Fields,
+		CreatedBy:  createdBy,
+		CreatedAt:  time.Now(),
+	})
 	return err
 }
 
-// GetTemplatesWithPagination 分页获取模板列表
-func (r *TemplateRepository) GetTemplatesWithPagination(categoryID int, active bool, page, limit int) ([]*model.DeviceTemplate, int, error) {
+// GetLegacyRuleTemplates 获取验证规则仍为旧语法的模板与模板字段版本（只读取字段定义）
+func (r *TemplateRepository) GetLegacyRuleTemplates() ([]*model.DeviceTemplate, []*model.DeviceTemplateVersion, error) {
+	o := orm.NewOrm()
+	var templates []*model.DeviceTemplate
+	if _, err := o.Raw("SELECT id, name, fields FROM device_templates WHERE rule_syntax = ?", model.RuleSyntaxLegacy).QueryRows(&templates); err != nil {
+		return nil, nil, err
+	}
+	var versions []*model.DeviceTemplateVersion
+	if _, err := o.Raw("SELECT id, template_id, version, fields FROM device_template_versions WHERE rule_syntax = ?", model.RuleSyntaxLegacy).QueryRows(&versions); err != nil {
+		return nil, nil, err
+	}
+	return templates, versions, nil
+}
+
+// UpgradeTemplateRules 写入改写为当前语法的模板字段定义，乐观锁版本号加一，
+// 改写前读取模板的编辑请求会返回冲突
+func (r *TemplateRepository) UpgradeTemplateRules(templateID int, fields string) error {
+	o := orm.NewOrm()
+	_, err := o.Raw("UPDATE device_templates SET fields = ?, rule_syntax = ?, version = version + 1 WHERE id = ? AND rule_syntax = ?",
+		fields, model.RuleSyntaxCurrent, templateID, model.RuleSyntaxLegacy).Exec()
+	return err
+}
+
+// UpgradeTemplateVersionRules 写入改写为当前语法的模板字段版本
+func (r *TemplateRepository) UpgradeTemplateVersionRules(versionID int, fields string) error {
+	o := orm.NewOrm()
+	_, err := o.Raw("UPDATE device_template_versions SET fields = ?, rule_syntax = ? WHERE id = ? AND rule_syntax = ?",
+		fields, model.RuleSyntaxCurrent, versionID, model.RuleSyntaxLegacy).Exec()
+	return err
+}
+
+// GetTemplateVersions 获取模板的全部字段版本，新版本在前
+func (r *TemplateRepository) GetTemplateVersions(templateID int) ([]*model.DeviceTemplateVersion, error) {
+	o := orm.NewOrm()
+	var versions []*model.DeviceTemplateVersion
+	_, err := o.QueryTable("device_template_versions").
+		Filter("template_id", templateID).
+		OrderBy("-version").
+		All(&versions)
+	return versions, err
+}
+
+// GetTemplateVersion 获取模板的指定字段版本
+func (r *TemplateRepository) GetTemplateVersion(templateID, version int) (*model.DeviceTemplateVersion, error) {
+	o := orm.NewOrm()
+	templateVersion := &model.DeviceTemplateVersion{}
+	err := o.QueryTable("device_template_versions").
+		Filter("template_id", templateID).
+		Filter("version", version).
+		One(templateVersion)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	return templateVersion, err
+}
+
+// CountDevicesByTemplateVersion 统计使用模板各字段版本的设备数量（含回收站中的设备）
+func (r *TemplateRepository) CountDevicesByTemplateVersion(templateID int) (map[int]int64, error) {
+	o := orm.NewOrm()
+	var rows []struct {
+		TemplateVersion int   `orm:"column(template_version)"`
+		Total           int64 `orm:"column(total)"`
+	}
+	_, err := o.Raw("SELECT template_version, COUNT(*) AS total FROM devices WHERE template_id = ? GROUP BY template_version", templateID).QueryRows(&rows)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.TemplateVersion] = row.Total
+	}
+	return counts, nil
+}
+
+// libraryTemplateCond 模板库中的模板：公开且审核通过（系统模板默认如此）
+func libraryTemplateCond() *orm.Condition {
+	return orm.NewCondition().
+		And("visibility", model.TemplateVisibilityPublic).
+		And("review_status", model.TemplateReviewApproved)
+}
+
+// availableTemplateCond 用户可以浏览的模板：系统模板、自己的模板与模板库中的模板；
+// 整体作为一个分组，之后追加的 Filter 对全部模板生效
+func availableTemplateCond(userID int) *orm.Condition {
+	return orm.NewCondition().AndCond(orm.NewCondition().
+		Or("owner_id__isnull", true).
+		Or("owner_id", userID).
+		OrCond(libraryTemplateCond()))
+}
+
+// GetTemplatesWithPagination 分页获取模板列表；scope 为 mine 时只返回自己的模板，
+// 为 library 时只返回模板库中的模板，其余返回用户可以浏览的全部模板；sort 为 rating/newest/popular
+func (r *TemplateRepository) GetTemplatesWithPagination(userID, categoryID int, active bool, scope, sort string, page, limit int) ([]*model.DeviceTemplate, int, error) {
 	o := orm.NewOrm()
 	qs := o.QueryTable("device_templates")
 
+	switch scope {
+	case "mine":
+		qs = qs.Filter("owner_id", userID)
+	case "library":
+		qs = qs.SetCond(libraryTemplateCond())
+	default:
+		qs = qs.SetCond(availableTemplateCond(userID))
+	}
+
 	// 添加条件
 	if categoryID > 0 {
 		qs = qs.Filter("category_id", categoryID)
@@ -91,21 +264,60 @@ func (r *TemplateRepository) GetTemplatesWithPagination(categoryID int, active b
 	}
 
 	// 分页查询
+	orderBy := []string{"-use_count", "created_at"}
+	switch sort {
+	case "rating":
+		orderBy = []string{"-rating_avg", "-rating_count", "-use_count"}
+	case "newest":
+		orderBy = []string{"-created_at"}
+	}
 	var templates []*model.DeviceTemplate
 	offset := (page - 1) * limit
 	_, err = qs.RelatedSel().
-		OrderBy("-use_count", "created_at").
+		OrderBy(orderBy...).
 		Limit(limit, offset).
 		All(&templates)
 
 	return templates, int(total), err
 }
 
+// GetAvailableTemplates 获取用户可以浏览的全部启用模板
+func (r *TemplateRepository) GetAvailableTemplates(userID int) ([]*model.DeviceTemplate, error) {
+	o := orm.NewOrm()
+	var templates []*model.DeviceTemplate
+	_, err := o.QueryTable("device_templates").
+		SetCond(availableTemplateCond(userID)).
+		Filter("is_active", true).
+		Filter("deleted_at__isnull", true).
+		OrderBy("category_id", "created_at").
+		All(&templates)
+	return templates, err
+}
+
+// GetTemplatesByReviewStatus 按审核状态分页获取公开模板，先提交的在前
+func (r *TemplateRepository) GetTemplatesByReviewStatus(status string, page, limit int) ([]*model.DeviceTemplate, int, error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable("device_templates").
+		Filter("visibility", model.TemplateVisibilityPublic).
+		Filter("review_status", status).
+		Filter("deleted_at__isnull", true)
+
+	total, err := qs.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var templates []*model.DeviceTemplate
+	_, err = qs.OrderBy("updated_at").Limit(limit, (page-1)*limit).All(&templates)
+	return templates, int(total), err
+}
+
 // GetPopularTemplates 获取热门模板
 func (r *TemplateRepository) GetPopularTemplates(limit int) ([]*model.DeviceTemplate, error) {
 	o := orm.NewOrm()
 	var templates []*model.DeviceTemplate
 	_, err := o.QueryTable("device_templates").
+		SetCond(libraryTemplateCond()).
 		Filter("is_active", true).
 		Filter("deleted_at__isnull", true).
 		RelatedSel().
@@ -126,7 +338,8 @@ func (r *TemplateRepository) DeleteTemplate(templateID int) error {
 
 	template.DeletedAt = time.Now()
 	template.IsActive = false
-	_, err = o.Update(template, "deleted_at", "is_active")
+	template.Version++
+	_, err = o.Update(template, "deleted_at", "is_active", "version")
 	return err
 }
 
@@ -164,19 +377,20 @@ func (r *TemplateRepository) GetTemplateStatistics(templateID int) (map[string]i
 func (r *TemplateRepository) GetRecommendedTemplates(userID int, limit int) ([]*model.DeviceTemplate, error) {
 	o := orm.NewOrm()
 
-	// 根据用户历史创建的设备分类推荐模板
+	// 根据用户历史创建的设备分类推荐模板，只推荐模板库中与自己的模板
 	var templates []*model.DeviceTemplate
 	_, err := o.Raw(`
 		SELECT DISTINCT dt.* FROM device_templates dt
 		INNER JOIN categories c ON dt.category_id = c.id
 		WHERE dt.is_active = 1 AND dt.deleted_at IS NULL
+		AND ((dt.visibility = ? AND dt.review_status = ?) OR dt.owner_id = ?)
 		AND c.id IN (
 			SELECT DISTINCT d.category_id FROM devices d 
 			WHERE d.user_id = ? AND d.deleted_at IS NULL
 		)
 		ORDER BY dt.use_count DESC, dt.created_at DESC
 		LIMIT ?
-	`, userID, limit).QueryRows(&templates)
+	`, model.TemplateVisibilityPublic, model.TemplateReviewApproved, userID, userID, limit).QueryRows(&templates)
 
 	return templates, err
 }
@@ -199,3 +413,60 @@ func (r *TemplateRepository) SearchTemplates(keyword string, categoryID int) ([]
 	_, err := qs.RelatedSel().OrderBy("-use_count", "name").All(&templates)
 	return templates, err
 }
+
+// GetTemplateRatings 分页获取模板的评分，最近更新的在前
+func (r *TemplateRepository) GetTemplateRatings(templateID, page, limit int) ([]*model.TemplateRating, int, error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable("template_ratings").Filter("template_id", templateID)
+
+	total, err := qs.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ratings []*model.TemplateRating
+	_, err = qs.OrderBy("-updated_at").Limit(limit, (page-1)*limit).All(&ratings)
+	return ratings, int(total), err
+}
+
+// SaveTemplateRating 新增或覆盖用户对模板的评分，并重新计算模板的评分人数与平均分
+func (r *TemplateRepository) SaveTemplateRating(rating *model.TemplateRating) error {
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+
+	existing := &model.TemplateRating{}
+	err = tx.QueryTable("template_ratings").
+		Filter("template_id", rating.TemplateID).
+		Filter("user_id", rating.UserID).
+		ForUpdate().
+		One(existing)
+	rating.UpdatedAt = time.Now()
+	switch err {
+	case orm.ErrNoRows:
+		rating.CreatedAt = rating.UpdatedAt
+		_, err = tx.Insert(rating)
+	case nil:
+		rating.ID = existing.ID
+		rating.CreatedAt = existing.CreatedAt
+		_, err = tx.Update(rating, "score", "comment", "updated_at")
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Raw(`
+		UPDATE device_templates t SET
+			rating_count = (SELECT COUNT(*) FROM template_ratings r WHERE r.template_id = t.id),
+			rating_avg = (SELECT IFNULL(ROUND(AVG(r.score), 2), 0) FROM template_ratings r WHERE r.template_id = t.id)
+		WHERE t.id = ?
+	`, rating.TemplateID).Exec()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
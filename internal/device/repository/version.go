@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+// ErrVersionConflict 记录在读取后已被其他请求修改
+var ErrVersionConflict = errors.New("记录已被其他请求修改")
+
+// UpdateWithVersion 在事务中锁定记录并核对版本号，一致时更新并把版本号加一；
+// version 指向模型的 Version 字段，成功后即为新版本号；cols 为空时整行更新，否则只更新指定列（自动包含 version）
+func UpdateWithVersion(tx orm.TxOrmer, table string, id int, version *int, md interface{}, cols ...string) error {
+	var current int
+	if err := tx.Raw("SELECT version FROM "+table+" WHERE id = ? FOR UPDATE", id).QueryRow(&current); err != nil {
+		if err == orm.ErrNoRows {
+			return ErrVersionConflict
+		}
+		return err
+	}
+	if current != *version {
+		return ErrVersionConflict
+	}
+	*version = current + 1
+	if len(cols) > 0 {
+		cols = append(cols, "version")
+	}
+	if _, err := tx.Update(md, cols...); err != nil {
+		*version = current
+		return err
+	}
+	return nil
+}
+
+// bumpVersion 批量更新时附带的版本号自增
+func bumpVersion(params orm.Params) orm.Params {
+	params["version"] = orm.ColValue(orm.ColAdd, 1)
+	return params
+}
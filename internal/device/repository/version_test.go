@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+// versionedRecord 带版本号的记录
+type versionedRecord struct {
+	ID      int
+	Name    string
+	Version int
+}
+
+// memoryTable 内存中只有一行的表；读取版本号时加行锁，事务提交或回滚时释放，模拟 SELECT ... FOR UPDATE
+type memoryTable struct {
+	lock    sync.Mutex
+	exists  bool
+	row     versionedRecord
+	updates int
+	cols    []string
+}
+
+// fakeTx 只实现 UpdateWithVersion 用到的方法，其余方法未实现
+type fakeTx struct {
+	orm.TxOrmer
+	table  *memoryTable
+	locked bool
+}
+
+type fakeRawSeter struct {
+	orm.RawSeter
+	tx *fakeTx
+}
+
+func (tx *fakeTx) Raw(query string, args ...interface{}) orm.RawSeter {
+	return &fakeRawSeter{tx: tx}
+}
+
+func (r *fakeRawSeter) QueryRow(containers ...interface{}) error {
+	table := r.tx.table
+	table.lock.Lock()
+	r.tx.locked = true
+	if !table.exists {
+		return orm.ErrNoRows
+	}
+	*containers[0].(*int) = table.row.Version
+	return nil
+}
+
+func (tx *fakeTx) Update(md interface{}, cols ...string) (int64, error) {
+	tx.table.row = *md.(*versionedRecord)
+	tx.table.updates++
+	tx.table.cols = cols
+	return 1, nil
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.release()
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.release()
+	return nil
+}
+
+func (tx *fakeTx) release() {
+	if tx.locked {
+		tx.locked = false
+		tx.table.lock.Unlock()
+	}
+}
+
+// write 以读取时的版本号在一个事务中更新记录
+func (table *memoryTable) write(read versionedRecord, name string, cols ...string) (versionedRecord, error) {
+	tx := &fakeTx{table: table}
+	record := read
+	record.Name = name
+	if err := UpdateWithVersion(tx, "records", record.ID, &record.Version, &record, cols...); err != nil {
+		tx.Rollback()
+		return record, err
+	}
+	return record, tx.Commit()
+}
+
+func TestUpdateWithVersionSecondWriterConflicts(t *testing.T) {
+	table := &memoryTable{exists: true, row: versionedRecord{ID: 1, Name: "原名", Version: 3}}
+
+	// 两个请求读取到同一版本
+	first, second := table.row, table.row
+
+	updated, err := table.write(first, "第一次修改")
+	if err != nil {
+		t.Fatalf("第一个写入返回错误: %v", err)
+	}
+	if updated.Version != 4 || table.row.Version != 4 {
+		t.Fatalf("第一个写入后版本号为 %d/%d，期望 4", updated.Version, table.row.Version)
+	}
+
+	stale, err := table.write(second, "第二次修改")
+	if err != ErrVersionConflict {
+		t.Fatalf("第二个写入返回 %v，期望 ErrVersionConflict", err)
+	}
+	if stale.Version != 3 {
+		t.Errorf("冲突后调用方的版本号被改为 %d，期望保持 3", stale.Version)
+	}
+	if table.row.Name != "第一次修改" || table.updates != 1 {
+		t.Errorf("冲突的写入覆盖了记录: name=%s, updates=%d", table.row.Name, table.updates)
+	}
+
+	// 重新读取后可以写入
+	if _, err := table.write(table.row, "重新读取后修改"); err != nil {
+		t.Fatalf("重新读取后写入返回错误: %v", err)
+	}
+	if table.row.Version != 5 {
+		t.Errorf("版本号为 %d，期望 5", table.row.Version)
+	}
+}
+
+func TestUpdateWithVersionConcurrentWriters(t *testing.T) {
+	const writers = 8
+	table := &memoryTable{exists: true, row: versionedRecord{ID: 1, Name: "原名", Version: 1}}
+	read := table.row
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, errs[i] = table.write(read, "并发修改")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded, conflicts := 0, 0
+	for _, err := range errs {
+		switch err {
+		case nil:
+			succeeded++
+		case ErrVersionConflict:
+			conflicts++
+		default:
+			t.Fatalf("写入返回意外的错误: %v", err)
+		}
+	}
+	if succeeded != 1 || conflicts != writers-1 {
+		t.Errorf("成功 %d 个、冲突 %d 个，期望成功 1 个、冲突 %d 个", succeeded, conflicts, writers-1)
+	}
+	if table.row.Version != 2 || table.updates != 1 {
+		t.Errorf("版本号为 %d、更新 %d 次，期望版本号 2、更新 1 次", table.row.Version, table.updates)
+	}
+}
+
+func TestUpdateWithVersionMissingRecord(t *testing.T) {
+	table := &memoryTable{}
+	if _, err := table.write(versionedRecord{ID: 1, Version: 1}, "修改"); err != ErrVersionConflict {
+		t.Fatalf("记录不存在时返回 %v，期望 ErrVersionConflict", err)
+	}
+}
+
+func TestUpdateWithVersionColumns(t *testing.T) {
+	table := &memoryTable{exists: true, row: versionedRecord{ID: 1, Version: 1}}
+	if _, err := table.write(table.row, "修改", "name"); err != nil {
+		t.Fatalf("写入返回错误: %v", err)
+	}
+	if len(table.cols) != 2 || table.cols[0] != "name" || table.cols[1] != "version" {
+		t.Errorf("更新的列为 %v，期望 [name version]", table.cols)
+	}
+}
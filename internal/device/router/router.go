@@ -12,6 +12,10 @@ func InitDeviceRoutes() {
 	deviceController := controller.NewDeviceController()
 	categoryController := controller.NewCategoryController()
 	templateController := controller.NewTemplateController()
+	attachmentController := controller.NewAttachmentController()
+	serviceRecordController := controller.NewServiceRecordController()
+	costController := controller.NewCostController()
+	draftController := controller.NewDraftController()
 
 	// 设备模块路由
 	ns := web.NewNamespace("/api/v1",
@@ -28,16 +32,72 @@ func InitDeviceRoutes() {
 
 			// 设备价值评估
 			web.NSRouter("/:deviceId/valuation", deviceController, "get:GetDeviceValuation"),
+			web.NSRouter("/:deviceId/timeline", deviceController, "get:GetDeviceTimeline"),
+			web.NSRouter("/:deviceId/timeline/restore", deviceController, "post:RestoreDeviceField"),
+
+			// 重复设备检测与合并
+			web.NSRouter("/duplicates", deviceController, "get:ScanDuplicates"),
+			web.NSRouter("/:deviceId/merge", deviceController, "post:MergeDevices"),
 
 			// 设备价格预测
 			web.NSRouter("/:deviceId/prediction", deviceController, "get:PredictDevicePrice"),
 
 			// 批量导入设备
 			web.NSRouter("/import", deviceController, "post:BatchImportDevices"),
+			web.NSRouter("/import/file", deviceController, "post:ImportDevicesFile"),
+
+			// 规格参数索引
+			web.NSRouter("/specs/reindex", deviceController, "post:ReindexDeviceSpecs"),
+
+			// 回收站
+			web.NSRouter("/recycle-bin", deviceController, "get:GetRecycleBin"),
+			web.NSRouter("/recycle-bin/:deviceId", deviceController, "delete:PurgeDeletedDevice"),
+			web.NSRouter("/recycle-bin/:deviceId/restore", deviceController, "post:RestoreDeletedDevice"),
+
+			// 批量操作设备
+			web.NSRouter("/bulk", deviceController, "post:BulkDevices"),
+
+			// 按分类规则自动分类
+			web.NSRouter("/categorize", deviceController, "post:CategorizeDevices"),
+			web.NSRouter("/categorize/suggest", deviceController, "post:SuggestCategories"),
+
+			// 导出设备
+			web.NSRouter("/export", deviceController, "get:ExportDevices"),
+			web.NSRouter("/export/columns", deviceController, "get:GetExportColumns"),
+
+			// 二维码标签与扫码查找
+			web.NSRouter("/labels", deviceController, "post:GetDeviceLabels"),
+			web.NSRouter("/scan/:token", deviceController, "get:ScanDeviceQRCode"),
+			web.NSRouter("/:deviceId/qrcode", deviceController, "get:GetDeviceQRCode"),
+			web.NSRouter("/:deviceId/qrcode/image", deviceController, "get:GetDeviceQRCodeImage"),
+			web.NSRouter("/:deviceId/qrcode/reset", deviceController, "post:ResetDeviceQRCode"),
+
+			// 电商订单导入与设备草稿
+			web.NSRouter("/drafts", draftController, "get:GetDrafts"),
+			web.NSRouter("/drafts/import", draftController, "post:ImportOrders"),
+			web.NSRouter("/drafts/:draftId", draftController, "put:UpdateDraft;delete:DeleteDraft"),
+			web.NSRouter("/drafts/:draftId/accept", draftController, "post:AcceptDraft"),
+			web.NSRouter("/drafts/:draftId/reject", draftController, "post:RejectDraft"),
 
 			// 设备图片管理
 			web.NSRouter("/:deviceId/images", deviceController, "get:GetDeviceImages;post:UploadDeviceImage"),
-			web.NSRouter("/:deviceId/images/:imageId", deviceController, "delete:DeleteDeviceImage"),
+			web.NSRouter("/:deviceId/images/batch", deviceController, "post:UploadDeviceImages"),
+			web.NSRouter("/:deviceId/images/order", deviceController, "put:ReorderDeviceImages"),
+			web.NSRouter("/:deviceId/images/:imageId", deviceController, "patch:UpdateDeviceImage;delete:DeleteDeviceImage"),
+			web.NSRouter("/:deviceId/images/:imageId/cover", deviceController, "put:SetDeviceCoverImage"),
+			web.NSRouter("/:deviceId/images/:imageId/file", deviceController, "get:DownloadDeviceImage"),
+			web.NSRouter("/:deviceId/images/:imageId/url", deviceController, "get:GetDeviceImageURL"),
+
+			// 设备附件管理（小票、发票、保修卡、说明书）
+			web.NSRouter("/:deviceId/attachments", attachmentController, "get:GetDeviceAttachments;post:UploadAttachment"),
+			web.NSRouter("/:deviceId/attachments/:attachmentId", attachmentController, "delete:DeleteAttachment"),
+			web.NSRouter("/:deviceId/attachments/:attachmentId/file", attachmentController, "get:DownloadAttachment"),
+			web.NSRouter("/:deviceId/service-records", serviceRecordController, "get:GetServiceRecords;post:CreateServiceRecord"),
+			web.NSRouter("/:deviceId/service-records/:recordId", serviceRecordController, "get:GetServiceRecord;put:UpdateServiceRecord;delete:DeleteServiceRecord"),
+			web.NSRouter("/:deviceId/costs", costController, "get:GetDeviceCosts;post:CreateDeviceCost"),
+			web.NSRouter("/:deviceId/costs/:costId", costController, "put:UpdateDeviceCost;delete:DeleteDeviceCost"),
+			web.NSRouter("/:deviceId/usage", costController, "get:GetUsageLogs;post:CreateUsageLog"),
+			web.NSRouter("/:deviceId/usage/:usageId", costController, "delete:DeleteUsageLog"),
 		),
 
 		// 设备模板相关路由 - 需要JWT认证
@@ -48,6 +108,9 @@ func InitDeviceRoutes() {
 			web.NSRouter("/popular", templateController, "get:GetPopularTemplates"),
 			// 获取推荐模板
 			web.NSRouter("/recommendations", templateController, "get:GetRecommendedTemplates"),
+			// 模板导入与审核列表
+			web.NSRouter("/import", templateController, "post:ImportTemplate"),
+			web.NSRouter("/reviews", templateController, "get:GetReviewTemplates"),
 
 			// 基本CRUD操作
 			web.NSRouter("/", templateController, "get:GetTemplatesList;post:CreateTemplate"),
@@ -61,6 +124,19 @@ func InitDeviceRoutes() {
 
 			// 模板统计
 			web.NSRouter("/:template_id/statistics", templateController, "get:GetTemplateStatistics"),
+
+			// 字段版本与设备迁移
+			web.NSRouter("/:template_id/versions", templateController, "get:GetTemplateVersions"),
+			web.NSRouter("/:template_id/versions/:version", templateController, "get:GetTemplateVersion"),
+			web.NSRouter("/:template_id/diff", templateController, "get:DiffTemplateVersions"),
+			web.NSRouter("/:template_id/migrate", templateController, "post:MigrateTemplateDevices"),
+
+			// 共享、复制、评分、导出与审核
+			web.NSRouter("/:template_id/visibility", templateController, "put:SetTemplateVisibility"),
+			web.NSRouter("/:template_id/fork", templateController, "post:ForkTemplate"),
+			web.NSRouter("/:template_id/ratings", templateController, "get:GetTemplateRatings;post:RateTemplate"),
+			web.NSRouter("/:template_id/export", templateController, "get:ExportTemplate"),
+			web.NSRouter("/:template_id/review", templateController, "post:ReviewTemplate"),
 		),
 
 		// 设备分类相关路由 - 需要JWT认证
@@ -73,10 +149,16 @@ func InitDeviceRoutes() {
 			web.NSRouter("/sort", categoryController, "put:SortCategories"),
 			web.NSRouter("/statistics", categoryController, "get:GetCategoryStatistics"),
 			web.NSRouter("/search", categoryController, "get:SearchCategories"),
+			web.NSRouter("/preferences", categoryController, "delete:ResetCategoryPreferences"),
+			web.NSRouter("/rules", categoryController, "get:GetCategoryRules;post:CreateCategoryRule"),
+			web.NSRouter("/rules/:rule_id", categoryController, "put:UpdateCategoryRule;delete:DeleteCategoryRule"),
 
 			// 基本CRUD操作
 			web.NSRouter("/", categoryController, "get:GetCategoriesList;post:CreateCustomCategory"),
 			web.NSRouter("/:category_id", categoryController, "get:GetCategoryDetail;put:UpdateCustomCategory;delete:DeleteCustomCategory"),
+			web.NSRouter("/:category_id/move", categoryController, "post:MoveCategory"),
+			web.NSRouter("/:category_id/merge", categoryController, "post:MergeCategory"),
+			web.NSRouter("/:category_id/hidden", categoryController, "put:SetCategoryHidden"),
 		),
 	)
 
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/device/repository"
+	"Backend_Lili/pkg/storage"
+	"Backend_Lili/pkg/utils"
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// attachmentRule 各附件类型允许的文件格式与大小
+type attachmentRule struct {
+	Name         string
+	Formats      []string
+	MaxSize      int64
+	AllowInvoice bool // 是否允许填写发票号码
+	AllowAmount  bool // 是否允许填写金额与开票/购买日期
+}
+
+var attachmentRules = map[string]*attachmentRule{
+	"receipt":  {Name: "购物小票", Formats: []string{"jpeg", "png", "pdf"}, MaxSize: 10 * 1024 * 1024, AllowAmount: true},
+	"invoice":  {Name: "发票", Formats: []string{"pdf", "ofd", "jpeg", "png"}, MaxSize: 10 * 1024 * 1024, AllowInvoice: true, AllowAmount: true},
+	"warranty": {Name: "保修卡", Formats: []string{"jpeg", "png", "pdf"}, MaxSize: 10 * 1024 * 1024},
+	"manual":   {Name: "说明书", Formats: []string{"pdf"}, MaxSize: 50 * 1024 * 1024},
+	"service":  {Name: "维修单据", Formats: []string{"jpeg", "png", "pdf"}, MaxSize: 10 * 1024 * 1024, AllowAmount: true},
+}
+
+// attachmentFormats 文件格式对应的扩展名与MIME类型
+var attachmentFormats = map[string]struct {
+	Exts     []string
+	MimeType string
+}{
+	"pdf":  {Exts: []string{".pdf"}, MimeType: "application/pdf"},
+	"ofd":  {Exts: []string{".ofd"}, MimeType: "application/ofd"},
+	"jpeg": {Exts: []string{".jpg", ".jpeg"}, MimeType: "image/jpeg"},
+	"png":  {Exts: []string{".png"}, MimeType: "image/png"},
+}
+
+type AttachmentService struct {
+	attachmentRepo *repository.AttachmentRepository
+	deviceRepo     *repository.DeviceRepository
+	recordRepo     *repository.ServiceRecordRepository
+	blobStore      storage.BlobStore
+}
+
+func NewAttachmentService() *AttachmentService {
+	return &AttachmentService{
+		attachmentRepo: repository.NewAttachmentRepository(),
+		deviceRepo:     repository.NewDeviceRepository(),
+		recordRepo:     repository.NewServiceRecordRepository(),
+		blobStore:      storage.Default(),
+	}
+}
+
+// GetDeviceAttachments 获取设备附件列表
+func (s *AttachmentService) GetDeviceAttachments(deviceID, userID int, attachmentType string) ([]*model.DeviceAttachment, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if attachmentType != "" && attachmentRules[attachmentType] == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "附件类型无效")
+	}
+
+	device, err := s.deviceRepo.GetDeviceByID(deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	if device == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+	}
+
+	attachments, err := s.attachmentRepo.GetDeviceAttachments(deviceID, userID, attachmentType)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备附件失败")
+	}
+	fillAttachmentURLs(attachments...)
+
+	return attachments, nil
+}
+
+// UploadAttachment 上传设备附件
+func (s *AttachmentService) UploadAttachment(deviceID, userID int, file multipart.File, fileHeader *multipart.FileHeader, req *UploadAttachmentRequest) (*model.DeviceAttachment, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+
+	rule := attachmentRules[req.AttachmentType]
+	if rule == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "附件类型无效，仅支持 receipt、invoice、warranty、manual、service")
+	}
+
+	device, err := s.deviceRepo.GetDeviceByID(deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	if device == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+	}
+
+	attachment := &model.DeviceAttachment{
+		DeviceID:       deviceID,
+		UserID:         userID,
+		AttachmentType: req.AttachmentType,
+		FileName:       filepath.Base(fileHeader.Filename),
+		ByteSize:       fileHeader.Size,
+	}
+
+	// 关联维修保养记录
+	if req.ServiceRecordID != nil {
+		record, err := s.recordRepo.GetServiceRecordByID(*req.ServiceRecordID, deviceID, userID)
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取维修保养记录失败")
+		}
+		if record == nil {
+			return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "维修保养记录不存在")
+		}
+		attachment.ServiceRecordID = &record.ID
+	}
+
+	// 校验发票信息
+	if err := s.applyInvoiceFields(attachment, rule, req); err != nil {
+		return nil, err
+	}
+
+	// 标题默认取文件名
+	attachment.Title = strings.TrimSpace(req.Title)
+	if attachment.Title == "" {
+		attachment.Title = strings.TrimSuffix(attachment.FileName, filepath.Ext(attachment.FileName))
+	}
+	if utf8.RuneCountInString(attachment.Title) > 200 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "附件标题不能超过200个字符")
+	}
+
+	// 校验文件大小与格式
+	if fileHeader.Size <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "上传文件为空")
+	}
+	if fileHeader.Size > rule.MaxSize {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("%s文件大小不能超过 %dMB", rule.Name, rule.MaxSize/1024/1024))
+	}
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	format, err := detectAttachmentFormat(file, fileHeader.Size, ext)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(rule.Formats, format) {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("%s仅支持 %s 格式", rule.Name, strings.ToUpper(strings.Join(rule.Formats, "、"))))
+	}
+	attachment.MimeType = attachmentFormats[format].MimeType
+
+	// 保存文件
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "读取上传文件失败")
+	}
+	storageKey, err := newAttachmentStorageKey(deviceID, ext)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "生成文件名失败")
+	}
+	if err := s.blobStore.Put(storageKey, file, fileHeader.Size, attachment.MimeType); err != nil {
+		logs.Error("保存设备附件失败: device=%d, key=%s, err=%v", deviceID, storageKey, err)
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "保存附件文件失败")
+	}
+	attachment.StorageKey = storageKey
+
+	if err := s.attachmentRepo.CreateAttachment(attachment); err != nil {
+		if delErr := s.blobStore.Delete(storageKey); delErr != nil {
+			logs.Warn("删除存储对象失败: key=%s, err=%v", storageKey, delErr)
+		}
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "保存附件记录失败")
+	}
+	fillAttachmentURLs(attachment)
+
+	return attachment, nil
+}
+
+// OpenAttachment 打开附件文件，调用方负责关闭返回的 ReadCloser
+func (s *AttachmentService) OpenAttachment(attachmentID, deviceID, userID int) (*model.DeviceAttachment, io.ReadCloser, *storage.ObjectInfo, error) {
+	if attachmentID <= 0 || deviceID <= 0 || userID <= 0 {
+		return nil, nil, nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+
+	attachment, err := s.attachmentRepo.GetAttachmentByID(attachmentID, deviceID, userID)
+	if err != nil {
+		return nil, nil, nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取附件信息失败")
+	}
+	if attachment == nil {
+		return nil, nil, nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "附件不存在")
+	}
+
+	reader, info, err := s.blobStore.Get(attachment.StorageKey)
+	if err == storage.ErrNotFound {
+		return nil, nil, nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "附件文件不存在")
+	}
+	if err != nil {
+		logs.Error("读取设备附件失败: attachment=%d, key=%s, err=%v", attachmentID, attachment.StorageKey, err)
+		return nil, nil, nil, utils.NewBusinessError(utils.ERROR_SERVER, "读取附件文件失败")
+	}
+
+	return attachment, reader, info, nil
+}
+
+// DeleteAttachment 删除设备附件
+func (s *AttachmentService) DeleteAttachment(attachmentID, deviceID, userID int) error {
+	if attachmentID <= 0 || deviceID <= 0 || userID <= 0 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+
+	attachment, err := s.attachmentRepo.GetAttachmentByID(attachmentID, deviceID, userID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取附件信息失败")
+	}
+	if attachment == nil {
+		return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "附件不存在")
+	}
+
+	if err := s.attachmentRepo.DeleteAttachment(attachmentID, userID); err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "删除附件失败")
+	}
+
+	// 记录删除成功后再删除文件，失败只记录日志
+	if err := s.blobStore.Delete(attachment.StorageKey); err != nil {
+		logs.Warn("删除存储对象失败: key=%s, err=%v", attachment.StorageKey, err)
+	}
+	return nil
+}
+
+// applyInvoiceFields 按附件类型校验并填充发票号码、金额、日期
+func (s *AttachmentService) applyInvoiceFields(attachment *model.DeviceAttachment, rule *attachmentRule, req *UploadAttachmentRequest) error {
+	invoiceNumber := strings.TrimSpace(req.InvoiceNumber)
+	if invoiceNumber != "" {
+		if !rule.AllowInvoice {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "仅发票类附件可以填写发票号码")
+		}
+		// 纸质/电子发票号码为8位，全电发票为20位
+		if len(invoiceNumber) < 8 || len(invoiceNumber) > 20 || strings.Trim(invoiceNumber, "0123456789") != "" {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "发票号码应为8-20位数字")
+		}
+		attachment.InvoiceNumber = invoiceNumber
+	}
+
+	if req.InvoiceAmount != nil || req.InvoiceDate != "" {
+		if !rule.AllowAmount {
+			return utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("%s不支持填写金额和日期", rule.Name))
+		}
+	}
+	if req.InvoiceAmount != nil {
+		if *req.InvoiceAmount < 0 {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "金额不能为负数")
+		}
+		attachment.InvoiceAmount = req.InvoiceAmount
+	}
+	if req.InvoiceDate != "" {
+		date, err := time.Parse("2006-01-02", req.InvoiceDate)
+		if err != nil {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "日期格式错误，应为YYYY-MM-DD")
+		}
+		if date.After(time.Now()) {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "日期不能晚于今天")
+		}
+		attachment.InvoiceDate = &date
+	}
+	return nil
+}
+
+// detectAttachmentFormat 按文件内容识别格式，并要求扩展名与内容一致
+func detectAttachmentFormat(file multipart.File, size int64, ext string) (string, error) {
+	head := make([]byte, 8)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", utils.NewBusinessError(utils.ERROR_PARAM, "读取上传文件失败")
+	}
+	head = head[:n]
+
+	var format string
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		format = "pdf"
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		format = "jpeg"
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		format = "png"
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		// OFD 为 ZIP 容器，根目录必须包含 OFD.xml
+		if isOFD(file, size) {
+			format = "ofd"
+		}
+	}
+	if format == "" {
+		return "", utils.NewBusinessError(utils.ERROR_PARAM, "无法识别的文件格式")
+	}
+	if !containsString(attachmentFormats[format].Exts, ext) {
+		return "", utils.NewBusinessError(utils.ERROR_PARAM, "文件内容与扩展名不一致")
+	}
+	return format, nil
+}
+
+func isOFD(file multipart.File, size int64) bool {
+	zr, err := zip.NewReader(file, size)
+	if err != nil {
+		return false
+	}
+	for _, f := range zr.File {
+		if strings.EqualFold(f.Name, "OFD.xml") {
+			return true
+		}
+	}
+	return false
+}
+
+// newAttachmentStorageKey 生成附件存储键：attachments/{deviceID}/{时间戳}_{随机串}{扩展名}
+func newAttachmentStorageKey(deviceID int, ext string) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("attachments/%d/%d_%s%s", deviceID, time.Now().UnixNano(), hex.EncodeToString(buf), ext), nil
+}
+
+// fillAttachmentURLs 填充附件下载地址
+func fillAttachmentURLs(attachments ...*model.DeviceAttachment) {
+	for _, attachment := range attachments {
+		attachment.FileURL = fmt.Sprintf("/api/v1/devices/%d/attachments/%d/file", attachment.DeviceID, attachment.ID)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/pkg/utils"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 分类规则的匹配条件
+const (
+	RuleMatchBrand   = "brand"
+	RuleMatchModel   = "model"
+	RuleMatchName    = "name"
+	RuleMatchPattern = "pattern"
+)
+
+// ruleMatchWeights 各条件单独命中时的置信度，多个条件同时命中时按 1-∏(1-w) 合并
+var ruleMatchWeights = map[string]float64{
+	RuleMatchBrand:   0.3,
+	RuleMatchName:    0.5,
+	RuleMatchModel:   0.6,
+	RuleMatchPattern: 0.7,
+}
+
+// minAutoCategorizeConfidence 自动填充分类与模板所需的最低置信度，低于此值只作为建议返回
+const minAutoCategorizeConfidence = 0.6
+
+// maxRuleKeywordsLength 品牌、关键字列表的最大长度
+const maxRuleKeywordsLength = 500
+
+// maxRulePatternLength 规则正则的最大长度
+const maxRulePatternLength = 255
+
+// ruleKeywordSeparator 关键字分隔符，中英文逗号与分号均可
+var ruleKeywordSeparator = regexp.MustCompile(`[,，;；]`)
+
+// compiledCategoryRule 预处理后的规则：关键字转小写，正则不区分大小写
+type compiledCategoryRule struct {
+	rule          *model.CategoryRule
+	brands        []string
+	modelKeywords []string
+	nameKeywords  []string
+	pattern       *regexp.Regexp
+}
+
+// compileCategoryRule 预处理规则，正则无效时返回错误
+func compileCategoryRule(rule *model.CategoryRule) (*compiledCategoryRule, error) {
+	compiled := &compiledCategoryRule{
+		rule:          rule,
+		brands:        splitRuleKeywords(rule.Brands),
+		modelKeywords: splitRuleKeywords(rule.ModelKeywords),
+		nameKeywords:  splitRuleKeywords(rule.NameKeywords),
+	}
+	if pattern := strings.TrimSpace(rule.Pattern); pattern != "" {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled.pattern = re
+	}
+	return compiled, nil
+}
+
+// splitRuleKeywords 拆分关键字列表，去掉空项与重复项
+func splitRuleKeywords(text string) []string {
+	var keywords []string
+	seen := make(map[string]bool)
+	for _, item := range ruleKeywordSeparator.Split(text, -1) {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" && !seen[item] {
+			seen[item] = true
+			keywords = append(keywords, item)
+		}
+	}
+	return keywords
+}
+
+// match 规则中填写的条件需全部命中，返回命中的条件；未命中时返回 nil
+func (r *compiledCategoryRule) match(name, brand, deviceModel string) []string {
+	var matched []string
+	if len(r.brands) > 0 {
+		if !containsString(r.brands, strings.ToLower(strings.TrimSpace(brand))) {
+			return nil
+		}
+		matched = append(matched, RuleMatchBrand)
+	}
+	if len(r.modelKeywords) > 0 {
+		if !containsKeyword(strings.ToLower(deviceModel), r.modelKeywords) {
+			return nil
+		}
+		matched = append(matched, RuleMatchModel)
+	}
+	if len(r.nameKeywords) > 0 {
+		if !containsKeyword(strings.ToLower(name), r.nameKeywords) {
+			return nil
+		}
+		matched = append(matched, RuleMatchName)
+	}
+	if r.pattern != nil {
+		if !r.pattern.MatchString(strings.Join([]string{brand, deviceModel, name}, " ")) {
+			return nil
+		}
+		matched = append(matched, RuleMatchPattern)
+	}
+	return matched
+}
+
+func containsKeyword(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleConfidence 按命中的条件计算置信度，保留两位小数
+func ruleConfidence(matched []string) float64 {
+	miss := 1.0
+	for _, condition := range matched {
+		miss *= 1 - ruleMatchWeights[condition]
+	}
+	return math.Round((1-miss)*100) / 100
+}
+
+// deviceCategorizer 按分类规则为设备给出分类与模板建议，只使用用户可见的分类和模板
+type deviceCategorizer struct {
+	rules  []*compiledCategoryRule
+	lookup *importLookup
+}
+
+// newDeviceCategorizer 加载对用户生效的规则；同一优先级时用户自己的规则在前，正则无效的规则跳过
+func newDeviceCategorizer(rules []*model.CategoryRule, lookup *importLookup) *deviceCategorizer {
+	categorizer := &deviceCategorizer{lookup: lookup}
+	for _, rule := range rules {
+		compiled, err := compileCategoryRule(rule)
+		if err != nil {
+			logs.Warn("分类规则正则无效: rule=%d, err=%v", rule.ID, err)
+			continue
+		}
+		categorizer.rules = append(categorizer.rules, compiled)
+	}
+	sort.SliceStable(categorizer.rules, func(i, j int) bool {
+		a, b := categorizer.rules[i].rule, categorizer.rules[j].rule
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.UserID != nil && b.UserID == nil
+	})
+	return categorizer
+}
+
+// suggest 返回全部命中规则的建议，按优先级、置信度排序；指向相同分类与模板的建议只保留第一条
+func (c *deviceCategorizer) suggest(name, brand, deviceModel string) []*model.CategorySuggestion {
+	type ranked struct {
+		priority   int
+		suggestion *model.CategorySuggestion
+	}
+	var results []*ranked
+	seen := make(map[[2]int]bool)
+	for _, compiled := range c.rules {
+		matched := compiled.match(name, brand, deviceModel)
+		if matched == nil {
+			continue
+		}
+		rule := compiled.rule
+
+		var category *model.Category
+		var template *model.DeviceTemplate
+		if rule.CategoryID != nil {
+			category = c.lookup.categoriesByID[*rule.CategoryID]
+		}
+		if rule.TemplateID != nil {
+			template = c.lookup.templatesByID[*rule.TemplateID]
+		}
+		if category == nil && template == nil {
+			continue
+		}
+		if category == nil && template.CategoryID != nil {
+			category = c.lookup.categoriesByID[*template.CategoryID]
+		}
+		if template == nil {
+			template = c.lookup.resolveTemplate("", category.ID)
+		}
+
+		suggestion := &model.CategorySuggestion{
+			RuleID:     rule.ID,
+			RuleName:   rule.Name,
+			System:     rule.UserID == nil,
+			Confidence: ruleConfidence(matched),
+			Matched:    matched,
+		}
+		if category != nil {
+			suggestion.CategoryID, suggestion.CategoryName = category.ID, category.Name
+		}
+		if template != nil {
+			suggestion.TemplateID, suggestion.TemplateName = template.ID, template.Name
+		}
+		key := [2]int{suggestion.CategoryID, suggestion.TemplateID}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		results = append(results, &ranked{priority: rule.Priority, suggestion: suggestion})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].priority != results[j].priority {
+			return results[i].priority > results[j].priority
+		}
+		return results[i].suggestion.Confidence > results[j].suggestion.Confidence
+	})
+	suggestions := make([]*model.CategorySuggestion, 0, len(results))
+	for _, result := range results {
+		suggestions = append(suggestions, result.suggestion)
+	}
+	return suggestions
+}
+
+// apply 为未指定分类或模板的设备填充：只指定模板时使用模板所属分类，其余按置信度达标的第一条建议填充，
+// 只指定分类且没有匹配的规则时使用分类下最早的模板；返回全部建议，已采用的标记 applied
+func (c *deviceCategorizer) apply(req *CreateDeviceRequest) []*model.CategorySuggestion {
+	if req.CategoryID > 0 && req.TemplateID > 0 {
+		return nil
+	}
+	if req.CategoryID <= 0 && req.TemplateID > 0 {
+		if template := c.lookup.templatesByID[req.TemplateID]; template != nil && template.CategoryID != nil {
+			if _, ok := c.lookup.categoriesByID[*template.CategoryID]; ok {
+				req.CategoryID = *template.CategoryID
+				return nil
+			}
+		}
+	}
+
+	suggestions := c.suggest(req.Name, req.Brand, req.Model)
+	for _, suggestion := range suggestions {
+		if suggestion.Confidence < minAutoCategorizeConfidence {
+			continue
+		}
+		switch {
+		case req.CategoryID <= 0 && req.TemplateID <= 0:
+			if suggestion.CategoryID > 0 && suggestion.TemplateID > 0 {
+				req.CategoryID, req.TemplateID = suggestion.CategoryID, suggestion.TemplateID
+				suggestion.Applied = true
+			}
+		case req.TemplateID <= 0:
+			if suggestion.CategoryID == req.CategoryID && suggestion.TemplateID > 0 {
+				req.TemplateID = suggestion.TemplateID
+				suggestion.Applied = true
+			}
+		default:
+			if suggestion.CategoryID > 0 {
+				req.CategoryID = suggestion.CategoryID
+				suggestion.Applied = true
+			}
+		}
+		if suggestion.Applied {
+			break
+		}
+	}
+
+	if req.CategoryID > 0 && req.TemplateID <= 0 {
+		if template := c.lookup.resolveTemplate("", req.CategoryID); template != nil {
+			req.TemplateID = template.ID
+		}
+	}
+	return suggestions
+}
+
+// GetCategoryRules 列出系统规则与用户自己的规则
+func (s *CategoryService) GetCategoryRules(userID int, req *GetCategoryRulesRequest) ([]*model.CategoryRule, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	if req.Scope != "" && req.Scope != "system" && req.Scope != "mine" {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "scope 只能是 system 或 mine")
+	}
+	rules, err := s.ruleRepo.GetRules(userID, req.Scope)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取分类规则失败")
+	}
+	return rules, nil
+}
+
+// CreateCategoryRule 创建分类规则，管理员传 system=true 时创建系统规则
+func (s *CategoryService) CreateCategoryRule(userID int, req *CategoryRuleRequest) (*model.CategoryRule, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	if req.System && !utils.IsAdmin(userID) {
+		return nil, utils.NewBusinessError(utils.ERROR_FORBIDDEN, "只有管理员可以创建系统规则")
+	}
+
+	rule := &model.CategoryRule{IsActive: true}
+	if !req.System {
+		rule.UserID = &userID
+	}
+	if err := s.applyCategoryRuleRequest(userID, rule, req); err != nil {
+		return nil, err
+	}
+	if err := s.ruleRepo.CreateRule(rule); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "创建分类规则失败")
+	}
+	return rule, nil
+}
+
+// UpdateCategoryRule 修改分类规则，系统规则只有管理员可以修改
+func (s *CategoryService) UpdateCategoryRule(userID, ruleID int, req *CategoryRuleRequest) (*model.CategoryRule, error) {
+	rule, err := s.getEditableRule(userID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyCategoryRuleRequest(userID, rule, req); err != nil {
+		return nil, err
+	}
+	if err := s.ruleRepo.UpdateRule(rule); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "更新分类规则失败")
+	}
+	return rule, nil
+}
+
+// DeleteCategoryRule 删除分类规则
+func (s *CategoryService) DeleteCategoryRule(userID, ruleID int) error {
+	if _, err := s.getEditableRule(userID, ruleID); err != nil {
+		return err
+	}
+	if err := s.ruleRepo.DeleteRule(ruleID); err != nil {
+		return utils.NewBusinessError(utils.ERROR_SERVER, "删除分类规则失败")
+	}
+	return nil
+}
+
+// getEditableRule 获取用户可以修改的规则：自己的规则，管理员还可以修改系统规则
+func (s *CategoryService) getEditableRule(userID, ruleID int) (*model.CategoryRule, error) {
+	if userID <= 0 || ruleID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	rule, err := s.ruleRepo.GetRuleByID(ruleID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取分类规则失败")
+	}
+	if rule == nil || (rule.UserID != nil && *rule.UserID != userID) {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "分类规则不存在")
+	}
+	if rule.UserID == nil && !utils.IsAdmin(userID) {
+		return nil, utils.NewBusinessError(utils.ERROR_FORBIDDEN, "只有管理员可以修改系统规则")
+	}
+	return rule, nil
+}
+
+// applyCategoryRuleRequest 校验请求并写入规则：至少一个匹配条件与一个目标；
+// 系统规则只能指向系统分类与系统模板，用户规则可以指向可见的分类与模板
+func (s *CategoryService) applyCategoryRuleRequest(userID int, rule *model.CategoryRule, req *CategoryRuleRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "规则名称不能为空")
+	}
+	if utf8.RuneCountInString(name) > 100 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "规则名称不能超过100个字符")
+	}
+	for label, value := range map[string]string{"品牌": req.Brands, "型号关键字": req.ModelKeywords, "名称关键字": req.NameKeywords} {
+		if utf8.RuneCountInString(value) > maxRuleKeywordsLength {
+			return utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("%s不能超过%d个字符", label, maxRuleKeywordsLength))
+		}
+	}
+	pattern := strings.TrimSpace(req.Pattern)
+	if utf8.RuneCountInString(pattern) > maxRulePatternLength {
+		return utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("正则不能超过%d个字符", maxRulePatternLength))
+	}
+	if pattern != "" {
+		if _, err := regexp.Compile("(?i)" + pattern); err != nil {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "正则格式错误: "+err.Error())
+		}
+	}
+	if len(splitRuleKeywords(req.Brands)) == 0 && len(splitRuleKeywords(req.ModelKeywords)) == 0 &&
+		len(splitRuleKeywords(req.NameKeywords)) == 0 && pattern == "" {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "请至少填写品牌、型号关键字、名称关键字或正则中的一项")
+	}
+	if req.CategoryID <= 0 && req.TemplateID <= 0 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "请指定规则的分类或模板")
+	}
+	if req.Priority < 0 || req.Priority > 100 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "优先级范围为0-100")
+	}
+
+	system := rule.UserID == nil
+	rule.CategoryID, rule.TemplateID = nil, nil
+	if req.CategoryID > 0 {
+		category, err := s.categoryRepo.GetCategoryByID(req.CategoryID)
+		if err != nil {
+			return utils.NewBusinessError(utils.ERROR_SERVER, "验证分类失败")
+		}
+		if category == nil || (category.Type == "custom" && (system || category.UserID != userID)) {
+			return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "分类不存在")
+		}
+		rule.CategoryID = &category.ID
+	}
+	if req.TemplateID > 0 {
+		template, err := s.templateRepo.GetTemplateByID(req.TemplateID)
+		if err != nil {
+			return utils.NewBusinessError(utils.ERROR_SERVER, "验证模板失败")
+		}
+		if template == nil || !template.IsActive || (system && template.OwnerID != nil) || !canViewTemplate(template, userID) {
+			return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "模板不存在")
+		}
+		rule.TemplateID = &template.ID
+	}
+
+	rule.Name = name
+	rule.Brands = strings.TrimSpace(req.Brands)
+	rule.ModelKeywords = strings.TrimSpace(req.ModelKeywords)
+	rule.NameKeywords = strings.TrimSpace(req.NameKeywords)
+	rule.Pattern = pattern
+	rule.Priority = req.Priority
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	return nil
+}
@@ -4,17 +4,24 @@ import (
 	"Backend_Lili/internal/device/model"
 	"Backend_Lili/internal/device/repository"
 	"Backend_Lili/pkg/utils"
+	"fmt"
 )
 
 type CategoryService struct {
 	categoryRepo *repository.CategoryRepository
 	deviceRepo   *repository.DeviceRepository
+	eventRepo    *repository.DeviceEventRepository
+	ruleRepo     *repository.CategoryRuleRepository
+	templateRepo *repository.TemplateRepository
 }
 
 func NewCategoryService() *CategoryService {
 	return &CategoryService{
 		categoryRepo: repository.NewCategoryRepository(),
 		deviceRepo:   repository.NewDeviceRepository(),
+		eventRepo:    repository.NewDeviceEventRepository(),
+		ruleRepo:     repository.NewCategoryRuleRepository(),
+		templateRepo: repository.NewTemplateRepository(),
 	}
 }
 
@@ -43,6 +50,10 @@ func (s *CategoryService) GetCategoriesList(userID int, req *GetCategoriesListRe
 	if err != nil {
 		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取分类列表失败")
 	}
+	categories, err = s.applyCategoryPreferences(userID, categories, req.IncludeHidden)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取分类设置失败")
+	}
 
 	// 构建层级结构
 	categories = s.buildCategoryTree(categories)
@@ -113,6 +124,13 @@ func (s *CategoryService) CreateCustomCategory(userID int, req *CreateCategoryRe
 		if parentCategory.Type == "custom" && parentCategory.UserID != userID {
 			return nil, utils.NewBusinessError(utils.ERROR_FORBIDDEN, "无权使用此父分类")
 		}
+		forest, err := s.loadCategoryForest(userID)
+		if err != nil {
+			return nil, err
+		}
+		if forest.depth(req.ParentID) >= maxCategoryDepth {
+			return nil, utils.NewBusinessError(utils.ERROR_BUSINESS, fmt.Sprintf("分类层级不能超过%d层", maxCategoryDepth))
+		}
 	}
 
 	// 创建分类
@@ -155,6 +173,9 @@ func (s *CategoryService) UpdateCustomCategory(userID, categoryID int, req *Upda
 	if category.Type != "custom" || category.UserID != userID {
 		return nil, utils.NewBusinessError(utils.ERROR_FORBIDDEN, "无权修改此分类")
 	}
+	if req.Version != nil && *req.Version != category.Version {
+		return nil, categoryConflictError(category)
+	}
 
 	// 更新字段
 	if req.Name != "" {
@@ -171,19 +192,8 @@ func (s *CategoryService) UpdateCustomCategory(userID, categoryID int, req *Upda
 	if req.Description != "" {
 		category.Description = req.Description
 	}
-	if req.ParentID > 0 {
-		// 验证父分类
-		parentCategory, err := s.categoryRepo.GetCategoryByID(req.ParentID)
-		if err != nil {
-			return nil, utils.NewBusinessError(utils.ERROR_SERVER, "验证父分类失败")
-		}
-		if parentCategory == nil {
-			return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "父分类不存在")
-		}
-		// 不能设置自己为父分类
-		if req.ParentID == categoryID {
-			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "不能设置自己为父分类")
-		}
+	parentChanged := req.ParentID > 0 && req.ParentID != category.ParentID
+	if parentChanged {
 		category.ParentID = req.ParentID
 	}
 	if req.Icon != "" {
@@ -196,7 +206,19 @@ func (s *CategoryService) UpdateCustomCategory(userID, categoryID int, req *Upda
 		category.SortOrder = req.SortOrder
 	}
 
-	err = s.categoryRepo.UpdateCategory(category)
+	if parentChanged {
+		// 验证父分类：不能移到自身或子分类下，移动后不能超过最大层级
+		err = s.updateCategoryParent(category)
+	} else {
+		err = s.categoryRepo.UpdateCategory(category)
+		if err == repository.ErrVersionConflict {
+			current, _ := s.categoryRepo.GetCategoryByID(categoryID)
+			return nil, categoryConflictError(current)
+		}
+	}
+	if _, ok := err.(*utils.BusinessError); ok {
+		return nil, err
+	}
 	if err != nil {
 		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "更新分类失败")
 	}
@@ -204,58 +226,23 @@ func (s *CategoryService) UpdateCustomCategory(userID, categoryID int, req *Upda
 	return category, nil
 }
 
-// DeleteCustomCategory 删除自定义分类
-func (s *CategoryService) DeleteCustomCategory(userID, categoryID int) error {
-	if userID <= 0 || categoryID <= 0 {
-		return utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
-	}
-
-	// 获取分类信息
-	category, err := s.categoryRepo.GetCategoryByID(categoryID)
-	if err != nil {
-		return utils.NewBusinessError(utils.ERROR_SERVER, "获取分类失败")
-	}
-	if category == nil {
-		return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "分类不存在")
-	}
-
-	// 验证权限
-	if category.Type != "custom" || category.UserID != userID {
-		return utils.NewBusinessError(utils.ERROR_FORBIDDEN, "无权删除此分类")
-	}
-
-	// 检查分类下是否有设备
-	count, err := s.categoryRepo.GetDeviceCountByCategory(categoryID, userID)
-	if err != nil {
-		return utils.NewBusinessError(utils.ERROR_SERVER, "检查分类使用情况失败")
-	}
-	if count > 0 {
-		return utils.NewBusinessError(utils.ERROR_BUSINESS, "分类下有设备，无法删除")
-	}
-
-	// 检查是否有子分类
-	children, err := s.categoryRepo.GetCategoriesByParentID(categoryID)
-	if err != nil {
-		return utils.NewBusinessError(utils.ERROR_SERVER, "检查子分类失败")
-	}
-	if len(children) > 0 {
-		return utils.NewBusinessError(utils.ERROR_BUSINESS, "分类下有子分类，无法删除")
-	}
-
-	err = s.categoryRepo.DeleteCategory(categoryID, userID)
-	if err != nil {
-		return utils.NewBusinessError(utils.ERROR_SERVER, "删除分类失败")
-	}
-
-	return nil
+// categoryConflictError 版本冲突时返回的业务异常，附带分类的最新状态
+func categoryConflictError(current *model.Category) error {
+	return utils.NewBusinessErrorWithDetails(utils.ERROR_CONFLICT, "分类已被修改，请刷新后重试", map[string]interface{}{
+		"current": current,
+	})
 }
 
-// GetSystemCategories 获取系统默认分类
-func (s *CategoryService) GetSystemCategories() ([]*model.Category, error) {
+// GetSystemCategories 获取系统默认分类，按用户的个人设置排序并去掉隐藏的分类
+func (s *CategoryService) GetSystemCategories(userID int, includeHidden bool) ([]*model.Category, error) {
 	categories, err := s.categoryRepo.GetSystemCategories()
 	if err != nil {
 		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取系统分类失败")
 	}
+	categories, err = s.applyCategoryPreferences(userID, categories, includeHidden)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取分类设置失败")
+	}
 
 	// 构建层级结构
 	categories = s.buildCategoryTree(categories)
@@ -369,6 +356,10 @@ func (s *CategoryService) SearchCategories(userID int, req *SearchCategoriesRequ
 	if err != nil {
 		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "搜索分类失败")
 	}
+	categories, err = s.applyCategoryPreferences(userID, categories, req.IncludeHidden)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取分类设置失败")
+	}
 
 	return categories, nil
 }
@@ -0,0 +1,384 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/device/repository"
+	"Backend_Lili/pkg/utils"
+	"fmt"
+	"sort"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// maxCategoryDepth 分类树的最大层级数，顶级分类为第1层
+const maxCategoryDepth = 5
+
+// 删除分类时子分类与设备的去向
+const (
+	CategoryOrphanReject = "reject" // 存在子分类或设备时拒绝删除
+	CategoryOrphanParent = "parent" // 移到被删除分类的上级分类
+	CategoryOrphanRoot   = "root"   // 子分类移到顶级
+	CategoryOrphanNone   = "none"   // 设备清除分类
+	CategoryOrphanTarget = "target" // 移到指定分类
+)
+
+// categoryForest 用户可见的分类（系统分类与自己的自定义分类），用于校验层级关系
+type categoryForest struct {
+	byID     map[int]*model.Category
+	children map[int][]*model.Category
+}
+
+// loadCategoryForest 读取用户可见的全部分类
+func (s *CategoryService) loadCategoryForest(userID int) (*categoryForest, error) {
+	categories, err := s.categoryRepo.GetCategoriesByType("all", userID, false)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取分类失败")
+	}
+	return newCategoryForest(categories), nil
+}
+
+// newCategoryForest 按分类列表建立父子关系
+func newCategoryForest(categories []*model.Category) *categoryForest {
+	forest := &categoryForest{
+		byID:     make(map[int]*model.Category, len(categories)),
+		children: make(map[int][]*model.Category),
+	}
+	for _, category := range categories {
+		forest.byID[category.ID] = category
+	}
+	for _, category := range categories {
+		if _, exists := forest.byID[category.ParentID]; exists {
+			forest.children[category.ParentID] = append(forest.children[category.ParentID], category)
+		}
+	}
+	return forest
+}
+
+// depth 分类所在层级，顶级分类为1；父分类不可见时视为顶级
+func (f *categoryForest) depth(categoryID int) int {
+	depth := 0
+	for current := categoryID; current != 0 && depth <= len(f.byID); depth++ {
+		category, exists := f.byID[current]
+		if !exists {
+			break
+		}
+		current = category.ParentID
+	}
+	return depth
+}
+
+// height 以分类为根的子树层数，只有自身时为1
+func (f *categoryForest) height(categoryID int) int {
+	return f.subtreeHeight(categoryID, make(map[int]bool))
+}
+
+func (f *categoryForest) subtreeHeight(categoryID int, visited map[int]bool) int {
+	if visited[categoryID] {
+		return 0
+	}
+	visited[categoryID] = true
+	height := 0
+	for _, child := range f.children[categoryID] {
+		if h := f.subtreeHeight(child.ID, visited); h > height {
+			height = h
+		}
+	}
+	return height + 1
+}
+
+// inSubtree 判断分类是否为 rootID 自身或其子孙分类
+func (f *categoryForest) inSubtree(categoryID, rootID int) bool {
+	for current, steps := categoryID, 0; current != 0 && steps <= len(f.byID); steps++ {
+		if current == rootID {
+			return true
+		}
+		category, exists := f.byID[current]
+		if !exists {
+			return false
+		}
+		current = category.ParentID
+	}
+	return false
+}
+
+// checkParent 校验把分类（连同子树）移到 parentID 下：父分类可见、不形成循环且不超过最大层级
+func (f *categoryForest) checkParent(category *model.Category, parentID int) error {
+	if parentID == 0 {
+		return nil
+	}
+	if _, exists := f.byID[parentID]; !exists {
+		return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "父分类不存在")
+	}
+	if f.inSubtree(parentID, category.ID) {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "不能移动到自身或其子分类下")
+	}
+	if f.depth(parentID)+f.height(category.ID) > maxCategoryDepth {
+		return utils.NewBusinessError(utils.ERROR_BUSINESS, fmt.Sprintf("分类层级不能超过%d层", maxCategoryDepth))
+	}
+	return nil
+}
+
+// target 校验删除、合并时的目标分类
+func (f *categoryForest) target(categoryID, targetID int) (*model.Category, error) {
+	if targetID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "请指定目标分类")
+	}
+	if targetID == categoryID {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "目标分类不能是分类自身")
+	}
+	target, exists := f.byID[targetID]
+	if !exists {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "目标分类不存在")
+	}
+	return target, nil
+}
+
+// getOwnCategory 获取用户自己的自定义分类
+func (s *CategoryService) getOwnCategory(userID, categoryID int, forbidden string) (*model.Category, error) {
+	if userID <= 0 || categoryID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	category, err := s.categoryRepo.GetCategoryByID(categoryID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取分类失败")
+	}
+	if category == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "分类不存在")
+	}
+	if category.Type != "custom" || category.UserID != userID {
+		return nil, utils.NewBusinessError(utils.ERROR_FORBIDDEN, forbidden)
+	}
+	return category, nil
+}
+
+// MoveCategory 把自定义分类连同其子分类移到新的父分类下，parent_id 为0时移到顶级
+func (s *CategoryService) MoveCategory(userID, categoryID int, req *MoveCategoryRequest) (*model.Category, error) {
+	category, err := s.getOwnCategory(userID, categoryID, "无权移动此分类")
+	if err != nil {
+		return nil, err
+	}
+	if req.Version != nil && *req.Version != category.Version {
+		return nil, categoryConflictError(category)
+	}
+	if req.ParentID < 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "父分类ID无效")
+	}
+	if req.ParentID == category.ParentID {
+		return category, nil
+	}
+
+	category.ParentID = req.ParentID
+	if err := s.updateCategoryParent(category); err != nil {
+		if _, ok := err.(*utils.BusinessError); ok {
+			return nil, err
+		}
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "移动分类失败")
+	}
+	return category, nil
+}
+
+// updateCategoryParent 保存已修改 parent_id 的分类；层级校验在锁定用户分类的同一事务中进行，
+// 校验失败或版本冲突时返回业务异常
+func (s *CategoryService) updateCategoryParent(category *model.Category) error {
+	err := s.categoryRepo.UpdateCategoryParent(category, func(categories []*model.Category) error {
+		return newCategoryForest(categories).checkParent(category, category.ParentID)
+	})
+	if err == repository.ErrVersionConflict {
+		current, _ := s.categoryRepo.GetCategoryByID(category.ID)
+		return categoryConflictError(current)
+	}
+	return err
+}
+
+// MergeCategory 把自定义分类合并到目标分类：设备、模板、草稿与子分类全部转到目标分类下，然后删除原分类
+func (s *CategoryService) MergeCategory(userID, categoryID int, req *MergeCategoryRequest) (*CategoryTransferResponse, error) {
+	category, err := s.getOwnCategory(userID, categoryID, "无权合并此分类")
+	if err != nil {
+		return nil, err
+	}
+	forest, err := s.loadCategoryForest(userID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := forest.target(categoryID, req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if forest.inSubtree(target.ID, categoryID) {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "不能合并到自身的子分类")
+	}
+	for _, child := range forest.children[categoryID] {
+		if err := forest.checkParent(child, target.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	targetID := target.ID
+	return s.removeCategory(userID, category, targetID, &targetID, map[string]interface{}{"category_merged": categoryID})
+}
+
+// DeleteCustomCategory 删除自定义分类，按请求把子分类与设备转到指定位置；默认存在子分类或设备时拒绝删除
+func (s *CategoryService) DeleteCustomCategory(userID, categoryID int, req *DeleteCategoryRequest) (*CategoryTransferResponse, error) {
+	category, err := s.getOwnCategory(userID, categoryID, "无权删除此分类")
+	if err != nil {
+		return nil, err
+	}
+	forest, err := s.loadCategoryForest(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 设备去向，回收站中的设备一并转移
+	var deviceCategoryID *int
+	switch req.Devices {
+	case "", CategoryOrphanReject:
+		count, err := s.categoryRepo.GetDeviceCountByCategory(categoryID, userID)
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_SERVER, "检查分类使用情况失败")
+		}
+		if count > 0 {
+			return nil, utils.NewBusinessError(utils.ERROR_BUSINESS, "分类下有设备，无法删除")
+		}
+	case CategoryOrphanParent:
+		if category.ParentID > 0 {
+			parentID := category.ParentID
+			deviceCategoryID = &parentID
+		}
+	case CategoryOrphanNone:
+	case CategoryOrphanTarget:
+		target, err := forest.target(categoryID, req.TargetID)
+		if err != nil {
+			return nil, err
+		}
+		deviceCategoryID = &target.ID
+	default:
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "设备去向无效")
+	}
+
+	// 子分类去向
+	children := forest.children[categoryID]
+	childParentID := category.ParentID
+	switch req.Children {
+	case "", CategoryOrphanReject:
+		if len(children) > 0 {
+			return nil, utils.NewBusinessError(utils.ERROR_BUSINESS, "分类下有子分类，无法删除")
+		}
+	case CategoryOrphanParent:
+	case CategoryOrphanRoot:
+		childParentID = 0
+	case CategoryOrphanTarget:
+		target, err := forest.target(categoryID, req.TargetID)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			if err := forest.checkParent(child, target.ID); err != nil {
+				return nil, err
+			}
+		}
+		childParentID = target.ID
+	default:
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "子分类去向无效")
+	}
+
+	return s.removeCategory(userID, category, childParentID, deviceCategoryID, map[string]interface{}{"category_deleted": categoryID})
+}
+
+// removeCategory 转移分类下的数据并删除分类，为转移的设备记录时间线事件
+func (s *CategoryService) removeCategory(userID int, category *model.Category, childParentID int, deviceCategoryID *int, meta map[string]interface{}) (*CategoryTransferResponse, error) {
+	result, err := s.categoryRepo.RemoveCategory(category.ID, userID, childParentID, deviceCategoryID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "删除分类失败")
+	}
+
+	var newCategory interface{}
+	if deviceCategoryID != nil {
+		newCategory = *deviceCategoryID
+	}
+	for _, deviceID := range result.DeviceIDs {
+		changes := map[string]*model.FieldChange{"category_id": {Old: category.ID, New: newCategory}}
+		event := model.NewDeviceEvent(deviceID, userID, userID, model.DeviceEventUpdated, changes, meta)
+		if err := s.eventRepo.CreateEvent(event); err != nil {
+			logs.Warn("记录设备事件失败: device=%d, type=%s, err=%v", deviceID, model.DeviceEventUpdated, err)
+		}
+	}
+
+	return &CategoryTransferResponse{
+		CategoryID:     category.ID,
+		MovedDevices:   len(result.DeviceIDs),
+		MovedTemplates: result.TemplateCount,
+		MovedDrafts:    result.DraftCount,
+		MovedChildren:  result.ChildCount,
+	}, nil
+}
+
+// SetCategoryHidden 隐藏或重新显示系统分类，只影响当前用户；隐藏后其子分类也不再出现在分类列表中
+func (s *CategoryService) SetCategoryHidden(userID, categoryID int, req *SetCategoryHiddenRequest) error {
+	if userID <= 0 || categoryID <= 0 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	category, err := s.categoryRepo.GetCategoryByID(categoryID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_SERVER, "获取分类失败")
+	}
+	if category == nil {
+		return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "分类不存在")
+	}
+	if category.Type != "system" {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "只能隐藏系统分类，自定义分类请直接删除")
+	}
+
+	if err := s.categoryRepo.SetCategoryHidden(userID, categoryID, req.Hidden); err != nil {
+		return utils.NewBusinessError(utils.ERROR_SERVER, "更新分类设置失败")
+	}
+	return nil
+}
+
+// ResetCategoryPreferences 清除用户对系统分类的隐藏与排序设置
+func (s *CategoryService) ResetCategoryPreferences(userID int) error {
+	if userID <= 0 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	if err := s.categoryRepo.ResetCategoryPreferences(userID); err != nil {
+		return utils.NewBusinessError(utils.ERROR_SERVER, "重置分类设置失败")
+	}
+	return nil
+}
+
+// applyCategoryPreferences 按用户的个人设置调整系统分类：使用个人排序，隐藏的分类默认去掉
+func (s *CategoryService) applyCategoryPreferences(userID int, categories []*model.Category, includeHidden bool) ([]*model.Category, error) {
+	prefs, err := s.categoryRepo.GetCategoryPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(prefs) == 0 {
+		return categories, nil
+	}
+
+	resorted := false
+	result := make([]*model.Category, 0, len(categories))
+	for _, category := range categories {
+		pref := prefs[category.ID]
+		if pref == nil || category.Type != "system" {
+			result = append(result, category)
+			continue
+		}
+		if pref.Hidden {
+			if !includeHidden {
+				continue
+			}
+			category.Hidden = true
+		}
+		if pref.SortOrder != nil {
+			category.SortOrder = *pref.SortOrder
+			resorted = true
+		}
+		result = append(result, category)
+	}
+	if resorted {
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].SortOrder < result[j].SortOrder
+		})
+	}
+	return result, nil
+}
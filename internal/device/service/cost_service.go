@@ -0,0 +1,289 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/device/repository"
+	"Backend_Lili/pkg/utils"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// costTypes 设备其他支出类型
+var costTypes = map[string]string{
+	"accessory":    "配件",
+	"subscription": "订阅服务",
+	"fee":          "平台费用",
+}
+
+// billingCycles 订阅周期对应的月数
+var billingCycles = map[string]int{
+	"monthly": 1,
+	"yearly":  12,
+}
+
+type CostService struct {
+	costRepo   *repository.CostRepository
+	deviceRepo *repository.DeviceRepository
+}
+
+func NewCostService() *CostService {
+	return &CostService{
+		costRepo:   repository.NewCostRepository(),
+		deviceRepo: repository.NewDeviceRepository(),
+	}
+}
+
+// GetDeviceCosts 获取设备其他支出
+func (s *CostService) GetDeviceCosts(deviceID, userID int, costType string) ([]*model.DeviceCost, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if costType != "" && costTypes[costType] == "" {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "支出类型无效")
+	}
+	if err := s.checkDevice(deviceID, userID); err != nil {
+		return nil, err
+	}
+
+	costs, err := s.costRepo.GetDeviceCosts(deviceID, userID, costType)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备支出失败")
+	}
+	return costs, nil
+}
+
+// CreateDeviceCost 新增设备支出
+func (s *CostService) CreateDeviceCost(deviceID, userID int, req *DeviceCostRequest) (*model.DeviceCost, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if req.CostType == "" || req.CostDate == "" || req.Amount == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "支出类型、日期和金额不能为空")
+	}
+	if err := s.checkDevice(deviceID, userID); err != nil {
+		return nil, err
+	}
+
+	cost := &model.DeviceCost{DeviceID: deviceID, UserID: userID}
+	if err := applyDeviceCostRequest(cost, req); err != nil {
+		return nil, err
+	}
+	if err := s.costRepo.CreateCost(cost); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "创建设备支出失败")
+	}
+	return cost, nil
+}
+
+// UpdateDeviceCost 更新设备支出，只更新请求中提供的字段
+func (s *CostService) UpdateDeviceCost(costID, deviceID, userID int, req *DeviceCostRequest) (*model.DeviceCost, error) {
+	if costID <= 0 || deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+
+	cost, err := s.costRepo.GetCostByID(costID, deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备支出失败")
+	}
+	if cost == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "支出记录不存在")
+	}
+	if err := applyDeviceCostRequest(cost, req); err != nil {
+		return nil, err
+	}
+	if err := s.costRepo.UpdateCost(cost); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "更新设备支出失败")
+	}
+	return cost, nil
+}
+
+// DeleteDeviceCost 删除设备支出
+func (s *CostService) DeleteDeviceCost(costID, deviceID, userID int) error {
+	if costID <= 0 || deviceID <= 0 || userID <= 0 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+
+	cost, err := s.costRepo.GetCostByID(costID, deviceID, userID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备支出失败")
+	}
+	if cost == nil {
+		return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "支出记录不存在")
+	}
+	if err := s.costRepo.DeleteCost(costID, userID); err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "删除设备支出失败")
+	}
+	return nil
+}
+
+// GetUsageLogs 分页获取设备使用记录
+func (s *CostService) GetUsageLogs(deviceID, userID int, req *GetUsageLogsRequest) (*GetUsageLogsResponse, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+	if err := s.checkDevice(deviceID, userID); err != nil {
+		return nil, err
+	}
+
+	logs, total, err := s.costRepo.GetUsageLogs(deviceID, userID, req.Page, req.Limit)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取使用记录失败")
+	}
+	usageCount, err := s.costRepo.GetUsageCount(deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取使用记录失败")
+	}
+
+	return &GetUsageLogsResponse{
+		Logs:       logs,
+		UsageCount: usageCount,
+		Total:      int(total),
+		Page:       req.Page,
+		Limit:      req.Limit,
+	}, nil
+}
+
+// CreateUsageLog 记录设备使用
+func (s *CostService) CreateUsageLog(deviceID, userID int, req *CreateUsageLogRequest) (*model.DeviceUsageLog, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if err := s.checkDevice(deviceID, userID); err != nil {
+		return nil, err
+	}
+
+	usage := &model.DeviceUsageLog{
+		DeviceID: deviceID,
+		UserID:   userID,
+		UsedOn:   truncateToDate(time.Now()),
+		UseCount: 1,
+		Note:     strings.TrimSpace(req.Note),
+	}
+	if req.UsedOn != "" {
+		usedOn, err := time.Parse("2006-01-02", req.UsedOn)
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "使用日期格式错误")
+		}
+		if usedOn.After(time.Now()) {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "使用日期不能晚于今天")
+		}
+		usage.UsedOn = usedOn
+	}
+	if req.UseCount < 0 || req.UseCount > 1000 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "使用次数须在1-1000之间")
+	}
+	if req.UseCount > 0 {
+		usage.UseCount = req.UseCount
+	}
+	if utf8.RuneCountInString(usage.Note) > 200 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "备注不能超过200个字符")
+	}
+
+	if err := s.costRepo.CreateUsageLog(usage); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "记录设备使用失败")
+	}
+	return usage, nil
+}
+
+// DeleteUsageLog 删除使用记录
+func (s *CostService) DeleteUsageLog(logID, deviceID, userID int) error {
+	if logID <= 0 || deviceID <= 0 || userID <= 0 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+
+	usage, err := s.costRepo.GetUsageLogByID(logID, deviceID, userID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取使用记录失败")
+	}
+	if usage == nil {
+		return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "使用记录不存在")
+	}
+	if err := s.costRepo.DeleteUsageLog(logID, userID); err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "删除使用记录失败")
+	}
+	return nil
+}
+
+func (s *CostService) checkDevice(deviceID, userID int) error {
+	device, err := s.deviceRepo.GetDeviceByID(deviceID, userID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	if device == nil {
+		return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+	}
+	return nil
+}
+
+// applyDeviceCostRequest 校验并写入支出字段
+func applyDeviceCostRequest(cost *model.DeviceCost, req *DeviceCostRequest) error {
+	if req.CostType != "" {
+		if costTypes[req.CostType] == "" {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "支出类型无效，仅支持 accessory、subscription、fee")
+		}
+		cost.CostType = req.CostType
+	}
+	if req.Title != nil {
+		cost.Title = strings.TrimSpace(*req.Title)
+	}
+	if cost.Title == "" {
+		cost.Title = costTypes[cost.CostType]
+	}
+	if utf8.RuneCountInString(cost.Title) > 200 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "支出名称不能超过200个字符")
+	}
+	if req.Amount != nil {
+		if *req.Amount < 0 {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "金额不能为负数")
+		}
+		cost.Amount = *req.Amount
+	}
+	if req.CostDate != "" {
+		costDate, err := time.Parse("2006-01-02", req.CostDate)
+		if err != nil {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "支出日期格式错误")
+		}
+		cost.CostDate = costDate
+	}
+	if req.BillingCycle != nil {
+		cost.BillingCycle = *req.BillingCycle
+	}
+	if req.EndDate != nil {
+		if *req.EndDate == "" {
+			cost.EndDate = nil
+		} else {
+			endDate, err := time.Parse("2006-01-02", *req.EndDate)
+			if err != nil {
+				return utils.NewBusinessError(utils.ERROR_PARAM, "结束日期格式错误")
+			}
+			cost.EndDate = &endDate
+		}
+	}
+	if req.Notes != nil {
+		cost.Notes = strings.TrimSpace(*req.Notes)
+	}
+
+	// 只有订阅需要周期与结束日期
+	if cost.CostType == "subscription" {
+		if billingCycles[cost.BillingCycle] == 0 {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "订阅周期仅支持 monthly、yearly")
+		}
+		if cost.EndDate != nil && cost.EndDate.Before(cost.CostDate) {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "结束日期不能早于开始日期")
+		}
+	} else {
+		cost.BillingCycle = ""
+		cost.EndDate = nil
+	}
+	return nil
+}
+
+func truncateToDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
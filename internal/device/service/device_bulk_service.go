@@ -0,0 +1,343 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/pkg/utils"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+// 批量操作类型
+const (
+	BulkOpSetCategory  = "set_category"
+	BulkOpSetStatus    = "set_status"
+	BulkOpSetCondition = "set_condition"
+	BulkOpAddTags      = "add_tags"
+	BulkOpRemoveTags   = "remove_tags"
+	BulkOpDelete       = "delete"
+	BulkOpRestore      = "restore"
+)
+
+// 批量操作单项结果
+const (
+	BulkResultSuccess = "success"
+	BulkResultSkipped = "skipped"
+	BulkResultFailed  = "failed"
+)
+
+// maxBulkDevices 单次批量操作的设备数量上限
+const maxBulkDevices = 500
+
+// bulkOperation 校验后的批量操作内容
+type bulkOperation struct {
+	name       string
+	params     orm.Params
+	categoryID int
+	status     string
+	condition  string
+	tagIDs     []int
+}
+
+// BulkDevices 批量修改设备：先逐台校验，再在同一事务中写入全部可处理的设备
+func (s *DeviceService) BulkDevices(userID int, req *BulkDevicesRequest) (*BulkDevicesResponse, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+
+	op, err := s.parseBulkOperation(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	deviceIDs, err := s.bulkTargetIDs(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := s.deviceRepo.GetDevicesByIDs(userID, deviceIDs)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	devicesByID := make(map[int]*model.Device, len(devices))
+	for _, device := range devices {
+		devicesByID[device.ID] = device
+	}
+	var existingTags map[int][]int
+	if op.name == BulkOpAddTags || op.name == BulkOpRemoveTags {
+		if existingTags, err = s.deviceRepo.GetDevicesTagIDs(deviceIDs); err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备标签失败")
+		}
+	}
+
+	// 逐台校验，确定需要写入的设备
+	response := &BulkDevicesResponse{Operation: op.name, Total: len(deviceIDs)}
+	addTags := make(map[int][]int)
+	var applied []*model.Device
+	for _, deviceID := range deviceIDs {
+		result := &BulkItemResult{DeviceID: deviceID, Result: BulkResultSuccess}
+		device := devicesByID[deviceID]
+		switch {
+		case device == nil:
+			result.Result, result.Message = BulkResultFailed, "设备不存在"
+		case op.name != BulkOpRestore && !device.DeletedAt.IsZero():
+			result.Result, result.Message = BulkResultFailed, "设备已删除"
+		default:
+			result.Result, result.Message = op.check(device, existingTags[deviceID], addTags)
+		}
+		if result.Result == BulkResultSuccess {
+			applied = append(applied, device)
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	// 要求全部成功时，有失败项则整体不执行
+	failed := 0
+	for _, result := range response.Results {
+		if result.Result == BulkResultFailed {
+			failed++
+		}
+	}
+	response.Committed = !req.AllOrNothing || failed == 0
+	if !response.Committed {
+		for _, result := range response.Results {
+			if result.Result == BulkResultSuccess {
+				result.Result, result.Message = BulkResultSkipped, "其他设备处理失败，未执行"
+			}
+		}
+		applied = nil
+	}
+
+	if len(applied) > 0 {
+		appliedIDs := make([]int, 0, len(applied))
+		for _, device := range applied {
+			appliedIDs = append(appliedIDs, device.ID)
+		}
+		var removeTagIDs []int
+		if op.name == BulkOpRemoveTags {
+			removeTagIDs = op.tagIDs
+		}
+		if err := s.deviceRepo.BulkUpdateDevices(userID, appliedIDs, op.params, addTags, removeTagIDs); err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "批量操作失败，已全部回滚")
+		}
+		for _, device := range applied {
+			s.recordBulkEvent(userID, op, device, addTags[device.ID], existingTags[device.ID])
+		}
+	}
+
+	for _, result := range response.Results {
+		switch result.Result {
+		case BulkResultSuccess:
+			response.SuccessCount++
+		case BulkResultSkipped:
+			response.SkippedCount++
+		case BulkResultFailed:
+			response.FailedCount++
+		}
+	}
+	return response, nil
+}
+
+// parseBulkOperation 校验操作类型及其参数
+func (s *DeviceService) parseBulkOperation(userID int, req *BulkDevicesRequest) (*bulkOperation, error) {
+	op := &bulkOperation{name: strings.TrimSpace(req.Operation), params: orm.Params{}}
+	switch op.name {
+	case BulkOpSetCategory:
+		if req.CategoryID <= 0 {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "请选择设备分类")
+		}
+		category, err := s.categoryRepo.GetCategoryByID(req.CategoryID)
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "查询设备分类失败")
+		}
+		if category == nil || (category.Type == "custom" && category.UserID != userID) {
+			return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备分类不存在")
+		}
+		op.categoryID = category.ID
+		op.params["category_id"] = category.ID
+	case BulkOpSetStatus:
+		switch req.Status {
+		case "active", "broken", "lost":
+		case "sold":
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "出售需要逐台填写出售价格和日期，不支持批量操作")
+		default:
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "无效的设备状态")
+		}
+		op.status = req.Status
+		op.params["status"] = req.Status
+	case BulkOpSetCondition:
+		validConditions := map[string]bool{
+			"new":  true,
+			"good": true,
+			"fair": true,
+			"poor": true,
+		}
+		if !validConditions[req.Condition] {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "无效的设备成色")
+		}
+		op.condition = req.Condition
+		op.params["condition"] = req.Condition
+	case BulkOpAddTags, BulkOpRemoveTags:
+		if len(req.TagIDs) == 0 {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "请选择标签")
+		}
+		if op.name == BulkOpAddTags {
+			tagIDs, err := s.resolveTagIDs(userID, req.TagIDs)
+			if err != nil {
+				return nil, err
+			}
+			op.tagIDs = tagIDs
+		} else {
+			// 移除时不校验标签是否仍可用，已停用的标签同样可以移除
+			seen := make(map[int]bool)
+			for _, tagID := range req.TagIDs {
+				if tagID <= 0 {
+					return nil, utils.NewBusinessError(utils.ERROR_PARAM, "标签ID无效")
+				}
+				if !seen[tagID] {
+					seen[tagID] = true
+					op.tagIDs = append(op.tagIDs, tagID)
+				}
+			}
+		}
+	case BulkOpDelete:
+		op.params["deleted_at"] = time.Now()
+	case BulkOpRestore:
+		op.params["deleted_at"] = nil
+	case "":
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "请指定批量操作类型")
+	default:
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("不支持的批量操作：%s", op.name))
+	}
+	return op, nil
+}
+
+// bulkTargetIDs 按设备ID列表或筛选条件确定要处理的设备，ID去重并保持原有顺序
+func (s *DeviceService) bulkTargetIDs(userID int, req *BulkDevicesRequest) ([]int, error) {
+	if len(req.DeviceIDs) > 0 && req.Filter != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "device_ids 与 filter 只能指定一个")
+	}
+
+	if len(req.DeviceIDs) > 0 {
+		deviceIDs := make([]int, 0, len(req.DeviceIDs))
+		seen := make(map[int]bool)
+		for _, deviceID := range req.DeviceIDs {
+			if deviceID <= 0 {
+				return nil, utils.NewBusinessError(utils.ERROR_PARAM, "设备ID无效")
+			}
+			if !seen[deviceID] {
+				seen[deviceID] = true
+				deviceIDs = append(deviceIDs, deviceID)
+			}
+		}
+		if len(deviceIDs) > maxBulkDevices {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("单次最多操作%d台设备", maxBulkDevices))
+		}
+		return deviceIDs, nil
+	}
+
+	if req.Filter == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "请指定设备ID或筛选条件")
+	}
+	params, err := deviceListParams(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Operation) == BulkOpRestore {
+		params["deleted"] = true
+	}
+	deviceIDs, err := s.deviceRepo.GetDeviceIDsByFilter(userID, params, maxBulkDevices+1)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "查询设备失败")
+	}
+	if len(deviceIDs) > maxBulkDevices {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("筛选结果超过%d台设备，请缩小筛选范围", maxBulkDevices))
+	}
+	return deviceIDs, nil
+}
+
+// check 判断单台设备是否需要处理；新增标签时把需要新增的标签写入 addTags
+func (op *bulkOperation) check(device *model.Device, currentTags []int, addTags map[int][]int) (string, string) {
+	switch op.name {
+	case BulkOpSetCategory:
+		if device.CategoryID != nil && *device.CategoryID == op.categoryID {
+			return BulkResultSkipped, "分类未变化"
+		}
+	case BulkOpSetStatus:
+		if device.Status == op.status {
+			return BulkResultSkipped, "状态未变化"
+		}
+	case BulkOpSetCondition:
+		if device.Condition == op.condition {
+			return BulkResultSkipped, "成色未变化"
+		}
+	case BulkOpAddTags:
+		current := make(map[int]bool, len(currentTags))
+		for _, tagID := range currentTags {
+			current[tagID] = true
+		}
+		var missing []int
+		for _, tagID := range op.tagIDs {
+			if !current[tagID] {
+				missing = append(missing, tagID)
+			}
+		}
+		if len(missing) == 0 {
+			return BulkResultSkipped, "已有这些标签"
+		}
+		addTags[device.ID] = missing
+	case BulkOpRemoveTags:
+		if len(intersectIDs(currentTags, op.tagIDs)) == 0 {
+			return BulkResultSkipped, "没有这些标签"
+		}
+	case BulkOpRestore:
+		if device.DeletedAt.IsZero() {
+			return BulkResultSkipped, "设备未删除"
+		}
+	}
+	return BulkResultSuccess, ""
+}
+
+// recordBulkEvent 为批量操作写入的设备记录时间线事件
+func (s *DeviceService) recordBulkEvent(userID int, op *bulkOperation, device *model.Device, addedTags, currentTags []int) {
+	meta := map[string]interface{}{"bulk": true}
+	before := *device
+	switch op.name {
+	case BulkOpSetCategory:
+		categoryID := op.categoryID
+		device.CategoryID = &categoryID
+		s.recordEvent(device.ID, userID, model.DeviceEventUpdated, model.DiffDevices(&before, device), meta)
+	case BulkOpSetStatus:
+		device.Status = op.status
+		s.recordEvent(device.ID, userID, model.DeviceEventStatusChanged, model.DiffDevices(&before, device), meta)
+	case BulkOpSetCondition:
+		device.Condition = op.condition
+		s.recordEvent(device.ID, userID, model.DeviceEventUpdated, model.DiffDevices(&before, device), meta)
+	case BulkOpAddTags:
+		meta["added_tag_ids"] = addedTags
+		s.recordEvent(device.ID, userID, model.DeviceEventUpdated, nil, meta)
+	case BulkOpRemoveTags:
+		meta["removed_tag_ids"] = intersectIDs(currentTags, op.tagIDs)
+		s.recordEvent(device.ID, userID, model.DeviceEventUpdated, nil, meta)
+	case BulkOpDelete:
+		s.recordEvent(device.ID, userID, model.DeviceEventDeleted, nil, meta)
+	case BulkOpRestore:
+		s.recordEvent(device.ID, userID, model.DeviceEventRestored, nil, meta)
+	}
+}
+
+// intersectIDs 返回同时出现在 a 和 b 中的ID，保持 a 中的顺序
+func intersectIDs(a, b []int) []int {
+	inB := make(map[int]bool, len(b))
+	for _, id := range b {
+		inB[id] = true
+	}
+	var result []int
+	for _, id := range a {
+		if inB[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/device/repository"
+	"Backend_Lili/pkg/utils"
+)
+
+// newCategorizer 加载对用户生效的分类规则，lookup 为空时重新加载用户可见的分类与模板
+func (s *DeviceService) newCategorizer(userID int, lookup *importLookup) (*deviceCategorizer, error) {
+	if lookup == nil {
+		var err error
+		if lookup, err = s.newImportLookup(userID); err != nil {
+			return nil, err
+		}
+	}
+	rules, err := s.ruleRepo.GetActiveRules(userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取分类规则失败")
+	}
+	return newDeviceCategorizer(rules, lookup), nil
+}
+
+// SuggestCategories 按分类规则为设备名称、品牌、型号给出分类与模板建议
+func (s *DeviceService) SuggestCategories(userID int, req *SuggestCategoriesRequest) (*SuggestCategoriesResponse, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	if req.Name == "" && req.Brand == "" && req.Model == "" {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "请至少填写名称、品牌或型号中的一项")
+	}
+
+	categorizer, err := s.newCategorizer(userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SuggestCategoriesResponse{Suggestions: categorizer.suggest(req.Name, req.Brand, req.Model)}, nil
+}
+
+// CategorizeDevices 对未设置分类或模板的设备重新运行分类规则，单次最多处理 maxBulkDevices 台
+func (s *DeviceService) CategorizeDevices(userID int, req *CategorizeDevicesRequest) (*CategorizeDevicesResponse, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+
+	categorizer, err := s.newCategorizer(userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	devices, err := s.deviceRepo.GetUncategorizedDevices(userID, maxBulkDevices)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备列表失败")
+	}
+
+	response := &CategorizeDevicesResponse{
+		DryRun:     req.DryRun,
+		TotalCount: len(devices),
+		Results:    make([]*CategorizeDeviceResult, 0, len(devices)),
+	}
+	for _, device := range devices {
+		result := s.categorizeDevice(userID, device, categorizer, req.DryRun)
+		switch result.Status {
+		case BulkResultSuccess:
+			response.CategorizedCount++
+		case BulkResultSkipped:
+			response.SkippedCount++
+		default:
+			response.FailedCount++
+		}
+		response.Results = append(response.Results, result)
+	}
+	return response, nil
+}
+
+// categorizeDevice 为单台设备应用分类规则，dryRun 时只返回结果
+func (s *DeviceService) categorizeDevice(userID int, device *model.Device, categorizer *deviceCategorizer, dryRun bool) *CategorizeDeviceResult {
+	result := &CategorizeDeviceResult{DeviceID: device.ID, Name: device.Name}
+
+	req := &CreateDeviceRequest{Name: device.Name, Brand: device.Brand, Model: device.Model}
+	if device.CategoryID != nil {
+		req.CategoryID = *device.CategoryID
+	}
+	if device.TemplateID != nil {
+		req.TemplateID = *device.TemplateID
+	}
+	result.Suggestions = categorizer.apply(req)
+
+	categoryChanged := req.CategoryID > 0 && (device.CategoryID == nil || *device.CategoryID != req.CategoryID)
+	templateChanged := req.TemplateID > 0 && (device.TemplateID == nil || *device.TemplateID != req.TemplateID)
+	if !categoryChanged && !templateChanged {
+		result.Status = BulkResultSkipped
+		result.Message = "没有匹配的规则"
+		return result
+	}
+	result.CategoryID, result.TemplateID = req.CategoryID, req.TemplateID
+	result.Status = BulkResultSuccess
+	if dryRun {
+		return result
+	}
+
+	before := *device
+	var template *model.DeviceTemplate
+	if categoryChanged {
+		categoryID := req.CategoryID
+		device.CategoryID = &categoryID
+	}
+	if templateChanged {
+		template = categorizer.lookup.templatesByID[req.TemplateID]
+		templateID := req.TemplateID
+		device.TemplateID = &templateID
+		device.TemplateVersion = template.FieldsVersion
+	}
+	err := s.deviceRepo.UpdateDevice(device)
+	if err == repository.ErrVersionConflict {
+		result.Status, result.Message = BulkResultFailed, "设备已被修改，请重试"
+		return result
+	}
+	if err != nil {
+		result.Status, result.Message = BulkResultFailed, "更新设备失败"
+		return result
+	}
+
+	if template != nil {
+		s.templateSvc.syncSpecIndex(device, template)
+	}
+	var meta map[string]interface{}
+	for _, suggestion := range result.Suggestions {
+		if suggestion.Applied {
+			meta = map[string]interface{}{"category_rule": suggestion.RuleID}
+			break
+		}
+	}
+	if changes := model.DiffDevices(&before, device); len(changes) > 0 {
+		s.recordEvent(device.ID, userID, model.DeviceEventUpdated, changes, meta)
+	}
+	return result
+}
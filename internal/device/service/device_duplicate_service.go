@@ -0,0 +1,616 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/device/repository"
+	"Backend_Lili/pkg/utils"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 重复匹配类型
+const (
+	DuplicateMatchSerial  = "serial"  // 序列号相同
+	DuplicateMatchSimilar = "similar" // 品牌、型号、购买日期与价格相近
+)
+
+const (
+	// defaultDuplicateMinScore 判定为疑似重复的默认相似度
+	defaultDuplicateMinScore = 0.8
+	// minDuplicateScore 扫描时允许的最低相似度下限，过低时几乎所有同品牌设备都会成组
+	minDuplicateScore = 0.5
+	// minModelSimilarity 型号相似度低于该值视为不同设备
+	minModelSimilarity = 0.7
+	// maxMergeSources 单次合并的来源设备数量上限
+	maxMergeSources = 20
+)
+
+// duplicateScore 计算两台设备的重复相似度：
+// 序列号相同直接判定为重复，序列号都填写但不同则视为不同设备；
+// 否则要求品牌相同、型号相似，再按型号(0.4)、品牌(0.2)、购买日期(0.2)、价格(0.2)累加
+func duplicateScore(a, b *model.Device) (float64, string, []string) {
+	serialA := strings.ToUpper(strings.TrimSpace(a.SerialNumber))
+	serialB := strings.ToUpper(strings.TrimSpace(b.SerialNumber))
+	if serialA != "" && serialB != "" {
+		if serialA == serialB {
+			return 1, DuplicateMatchSerial, []string{"序列号相同"}
+		}
+		return 0, "", nil
+	}
+
+	brandA, brandB := normalizeDuplicateKey(a.Brand), normalizeDuplicateKey(b.Brand)
+	if brandA == "" || brandA != brandB {
+		return 0, "", nil
+	}
+	modelSimilarity := stringSimilarity(normalizeDuplicateKey(a.Model), normalizeDuplicateKey(b.Model))
+	if modelSimilarity < minModelSimilarity {
+		return 0, "", nil
+	}
+
+	score := 0.2 + 0.4*modelSimilarity
+	reasons := []string{"品牌相同"}
+	if modelSimilarity == 1 {
+		reasons = append(reasons, "型号相同")
+	} else {
+		reasons = append(reasons, "型号相似")
+	}
+
+	if !a.PurchaseDate.IsZero() && !b.PurchaseDate.IsZero() {
+		days := int(math.Abs(a.PurchaseDate.Sub(b.PurchaseDate).Hours()) / 24)
+		switch {
+		case days == 0:
+			score += 0.2
+			reasons = append(reasons, "购买日期相同")
+		case days <= 3:
+			score += 0.15
+			reasons = append(reasons, fmt.Sprintf("购买日期相差%d天", days))
+		case days <= 30:
+			score += 0.05
+			reasons = append(reasons, fmt.Sprintf("购买日期相差%d天", days))
+		}
+	}
+
+	if maxPrice := math.Max(a.PurchasePrice, b.PurchasePrice); maxPrice > 0 {
+		diff := math.Abs(a.PurchasePrice-b.PurchasePrice) / maxPrice
+		switch {
+		case diff <= 0.01:
+			score += 0.2
+			reasons = append(reasons, "购买价格相同")
+		case diff <= 0.05:
+			score += 0.15
+			reasons = append(reasons, fmt.Sprintf("购买价格相差%.0f%%", diff*100))
+		case diff <= 0.15:
+			score += 0.05
+			reasons = append(reasons, fmt.Sprintf("购买价格相差%.0f%%", diff*100))
+		}
+	}
+
+	return math.Round(score*100) / 100, DuplicateMatchSimilar, reasons
+}
+
+// normalizeDuplicateKey 转小写并去掉空白与标点，"iPhone 15 Pro" 与 "iphone15pro" 视为相同
+func normalizeDuplicateKey(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// stringSimilarity 基于编辑距离的相似度，0-1
+func stringSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+
+	maxLen := len(ra)
+	if len(rb) > maxLen {
+		maxLen = len(rb)
+	}
+	return 1 - float64(prev[len(rb)])/float64(maxLen)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// isKitPair 主设备与其配件不视为重复
+func isKitPair(a, b *model.Device) bool {
+	return (a.ParentID != nil && *a.ParentID == b.ID) || (b.ParentID != nil && *b.ParentID == a.ID)
+}
+
+func newDuplicateMatch(device *model.Device, score float64, matchType string, reasons []string) *DuplicateMatch {
+	match := &DuplicateMatch{
+		DeviceID:      device.ID,
+		Name:          device.Name,
+		Brand:         device.Brand,
+		Model:         device.Model,
+		SerialNumber:  device.SerialNumber,
+		PurchasePrice: device.PurchasePrice,
+		MatchType:     matchType,
+		Score:         score,
+		Reasons:       reasons,
+	}
+	if !device.PurchaseDate.IsZero() {
+		match.PurchaseDate = device.PurchaseDate.Format("2006-01-02")
+	}
+	return match
+}
+
+// findDuplicates 查找与待创建设备疑似重复的已有设备，按相似度降序
+func (s *DeviceService) findDuplicates(userID int, device *model.Device) ([]*DuplicateMatch, error) {
+	candidates, err := s.deviceRepo.GetDuplicateCandidates(userID, strings.TrimSpace(device.SerialNumber), strings.TrimSpace(device.Brand))
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "查询重复设备失败")
+	}
+
+	matches := make([]*DuplicateMatch, 0)
+	for _, candidate := range candidates {
+		if candidate.ID == device.ID || isKitPair(candidate, device) {
+			continue
+		}
+		score, matchType, reasons := duplicateScore(device, candidate)
+		if score >= defaultDuplicateMinScore {
+			matches = append(matches, newDuplicateMatch(candidate, score, matchType, reasons))
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches, nil
+}
+
+// checkDuplicates 创建设备前检查疑似重复，存在时返回带重复设备列表的业务错误
+func (s *DeviceService) checkDuplicates(userID int, device *model.Device) error {
+	duplicates, err := s.findDuplicates(userID, device)
+	if err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return utils.NewBusinessErrorWithDetails(utils.ERROR_BUSINESS, "发现疑似重复的设备，确认不重复请设置 force 后重新提交", map[string]interface{}{
+			"duplicates": duplicates,
+		})
+	}
+	return nil
+}
+
+// ScanDuplicates 扫描用户全部设备，把相互疑似重复的设备合并成组
+func (s *DeviceService) ScanDuplicates(userID int, req *ScanDuplicatesRequest) (*ScanDuplicatesResponse, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	minScore := req.MinScore
+	if minScore == 0 {
+		minScore = defaultDuplicateMinScore
+	}
+	if minScore < minDuplicateScore || minScore > 1 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("相似度下限需在%.1f到1之间", minDuplicateScore))
+	}
+
+	devices, err := s.deviceRepo.GetAllDevices(userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备列表失败")
+	}
+
+	// 只在同品牌或同序列号的设备之间比较
+	buckets := make(map[string][]int)
+	for i, device := range devices {
+		if brand := normalizeDuplicateKey(device.Brand); brand != "" {
+			buckets["b:"+brand] = append(buckets["b:"+brand], i)
+		}
+		if serial := strings.ToUpper(strings.TrimSpace(device.SerialNumber)); serial != "" {
+			buckets["s:"+serial] = append(buckets["s:"+serial], i)
+		}
+	}
+
+	parent := make([]int, len(devices))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	compared := make(map[[2]int]bool)
+	pairs := make(map[[2]int]*DuplicatePair)
+	for _, indexes := range buckets {
+		for x := 0; x < len(indexes); x++ {
+			for y := x + 1; y < len(indexes); y++ {
+				key := [2]int{indexes[x], indexes[y]}
+				if compared[key] {
+					continue
+				}
+				compared[key] = true
+
+				a, b := devices[key[0]], devices[key[1]]
+				if isKitPair(a, b) {
+					continue
+				}
+				score, matchType, reasons := duplicateScore(a, b)
+				if score < minScore {
+					continue
+				}
+				pairs[key] = &DuplicatePair{DeviceID: a.ID, OtherID: b.ID, MatchType: matchType, Score: score, Reasons: reasons}
+				parent[find(key[0])] = find(key[1])
+			}
+		}
+	}
+
+	groupsByRoot := make(map[int]*DuplicateGroup)
+	for key, pair := range pairs {
+		root := find(key[0])
+		group, ok := groupsByRoot[root]
+		if !ok {
+			group = &DuplicateGroup{MatchType: DuplicateMatchSimilar}
+			groupsByRoot[root] = group
+		}
+		group.Pairs = append(group.Pairs, pair)
+		if pair.MatchType == DuplicateMatchSerial {
+			group.MatchType = DuplicateMatchSerial
+		}
+		if pair.Score > group.Score {
+			group.Score = pair.Score
+		}
+	}
+
+	groupDevices := make([]*model.Device, 0)
+	for i, device := range devices {
+		if group, ok := groupsByRoot[find(i)]; ok {
+			group.Devices = append(group.Devices, device)
+			groupDevices = append(groupDevices, device)
+		}
+	}
+	if err := s.fillCoverImages(groupDevices); err != nil {
+		return nil, err
+	}
+
+	groups := make([]*DuplicateGroup, 0, len(groupsByRoot))
+	for _, group := range groupsByRoot {
+		// 设备按ID升序，建议保留最早录入的设备
+		group.SuggestedTargetID = group.Devices[0].ID
+		sort.Slice(group.Pairs, func(i, j int) bool {
+			if group.Pairs[i].DeviceID != group.Pairs[j].DeviceID {
+				return group.Pairs[i].DeviceID < group.Pairs[j].DeviceID
+			}
+			return group.Pairs[i].OtherID < group.Pairs[j].OtherID
+		})
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Score != groups[j].Score {
+			return groups[i].Score > groups[j].Score
+		}
+		return groups[i].SuggestedTargetID < groups[j].SuggestedTargetID
+	})
+
+	return &ScanDuplicatesResponse{
+		MinScore:    minScore,
+		DeviceCount: len(devices),
+		Groups:      groups,
+	}, nil
+}
+
+// fillCoverImages 批量加载设备封面图
+func (s *DeviceService) fillCoverImages(devices []*model.Device) error {
+	if len(devices) == 0 {
+		return nil
+	}
+	deviceIDs := make([]int, 0, len(devices))
+	for _, device := range devices {
+		deviceIDs = append(deviceIDs, device.ID)
+	}
+	covers, err := s.deviceRepo.GetCoverImages(deviceIDs)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备封面失败")
+	}
+	for _, device := range devices {
+		if cover, ok := covers[device.ID]; ok {
+			fillImageURLs(cover)
+			device.CoverImage = cover
+		}
+	}
+	return nil
+}
+
+// MergeDevices 把来源设备并入目标设备：目标设备为空的字段用来源设备补齐，备注追加，
+// 图片（按内容去重）、附件、维修记录、支出、使用记录、价格历史、价格预警、标签与配件转移到目标设备，来源设备随后删除
+func (s *DeviceService) MergeDevices(targetID, userID int, req *MergeDevicesRequest) (*model.Device, error) {
+	if targetID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+
+	sourceIDs := make([]int, 0, len(req.SourceIDs))
+	seen := make(map[int]bool)
+	for _, id := range req.SourceIDs {
+		if id <= 0 || id == targetID {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "来源设备ID无效")
+		}
+		if !seen[id] {
+			seen[id] = true
+			sourceIDs = append(sourceIDs, id)
+		}
+	}
+	if len(sourceIDs) == 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "请选择要合并的设备")
+	}
+	if len(sourceIDs) > maxMergeSources {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("单次最多合并%d台设备", maxMergeSources))
+	}
+
+	target, err := s.deviceRepo.GetDeviceByID(targetID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	if target == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+	}
+	sources := make([]*model.Device, 0, len(sourceIDs))
+	for _, id := range sourceIDs {
+		source, err := s.deviceRepo.GetDeviceByID(id, userID)
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+		}
+		if source == nil {
+			return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, fmt.Sprintf("设备（ID %d）不存在", id))
+		}
+		if target.ParentID != nil && *target.ParentID == source.ID {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "不能把主设备合并到它的配件中")
+		}
+		if target.ParentID != nil && len(source.Accessories) > 0 {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "配件不能接收其他设备的配件，请选择主设备作为保留设备")
+		}
+		sources = append(sources, source)
+	}
+
+	before := *target
+	for _, source := range sources {
+		if err := mergeDeviceFields(target, source); err != nil {
+			return nil, err
+		}
+	}
+	movedImages, droppedImages := planMergedImages(target, sources)
+	droppedImageIDs := make([]int, 0, len(droppedImages))
+	for _, image := range droppedImages {
+		droppedImageIDs = append(droppedImageIDs, image.ID)
+	}
+	if target.Specifications != before.Specifications {
+		if err := s.normalizeMergedSpecs(target, movedImages, droppedImages); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.deviceRepo.MergeDevices(target, sourceIDs, movedImages, droppedImageIDs)
+	if err == repository.ErrVersionConflict {
+		return nil, s.reloadDeviceConflict(targetID, userID)
+	}
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "合并设备失败")
+	}
+	s.releaseImageBlobs(droppedImages...)
+
+	s.templateSvc.syncSpecIndex(target, nil)
+	s.syncWarrantyReminder(target)
+	s.recordEvent(targetID, userID, model.DeviceEventMerged, model.DiffDevices(&before, target), map[string]interface{}{
+		"source_ids":   sourceIDs,
+		"moved_images": len(movedImages),
+	})
+	for _, source := range sources {
+		// 来源设备已删除，清理其自动保修提醒
+		if err := s.reminderSvc.SyncWarrantyReminder(userID, source.ID, source.Name, time.Time{}); err != nil {
+			logs.Warn("清理保修提醒失败: device=%d, err=%v", source.ID, err)
+		}
+		s.recordEvent(source.ID, userID, model.DeviceEventDeleted, nil, map[string]interface{}{"merged_into": targetID})
+	}
+
+	return s.GetDeviceDetail(targetID, userID)
+}
+
+// mergeDeviceFields 用来源设备补齐目标设备为空的字段，规格参数按键补齐，备注追加并注明来源
+func mergeDeviceFields(target, source *model.Device) error {
+	fill := func(dst *string, src string) {
+		if strings.TrimSpace(*dst) == "" {
+			*dst = src
+		}
+	}
+	fill(&target.SerialNumber, source.SerialNumber)
+	fill(&target.Color, source.Color)
+	fill(&target.Storage, source.Storage)
+	fill(&target.Memory, source.Memory)
+	fill(&target.Processor, source.Processor)
+	fill(&target.ScreenSize, source.ScreenSize)
+	if target.WarrantyDate.IsZero() {
+		target.WarrantyDate = source.WarrantyDate
+	}
+
+	if source.Specifications != "" {
+		targetSpecs := map[string]interface{}{}
+		if target.Specifications != "" {
+			if err := json.Unmarshal([]byte(target.Specifications), &targetSpecs); err != nil {
+				return utils.NewBusinessError(utils.ERROR_PARAM, "保留设备的规格参数格式错误")
+			}
+		}
+		sourceSpecs := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(source.Specifications), &sourceSpecs); err == nil {
+			for key, value := range sourceSpecs {
+				if _, ok := targetSpecs[key]; !ok {
+					targetSpecs[key] = value
+				}
+			}
+			if len(targetSpecs) > 0 {
+				data, err := json.Marshal(targetSpecs)
+				if err != nil {
+					return utils.NewBusinessError(utils.ERROR_PARAM, "规格参数格式错误")
+				}
+				target.Specifications = string(data)
+			}
+		}
+	}
+
+	if notes := strings.TrimSpace(source.Notes); notes != "" && !strings.Contains(target.Notes, notes) {
+		header := fmt.Sprintf("【合并自「%s」(ID %d)】", source.Name, source.ID)
+		if strings.TrimSpace(target.Notes) == "" {
+			target.Notes = header + "\n" + notes
+		} else {
+			target.Notes = target.Notes + "\n\n" + header + "\n" + notes
+		}
+	}
+	return nil
+}
+
+// normalizeMergedSpecs 合并后的规格参数按保留设备的模板与字段版本校验；图片字段可以引用随合并转移过来的图片，
+// 引用了因内容重复而删除的图片时改为引用保留设备中内容相同的图片
+func (s *DeviceService) normalizeMergedSpecs(target *model.Device, moved, dropped []*model.DeviceImage) error {
+	specs, err := parseDeviceSpecs(target.Specifications)
+	if err != nil {
+		return err
+	}
+	template, version, err := s.deviceSpecTemplate(target)
+	if err != nil {
+		return err
+	}
+	fields, normalized, err := s.validateDeviceSpecs(template, version, specs)
+	if err != nil {
+		return err
+	}
+
+	// 合并后保留设备的图片：原有图片与转移过来的图片，删除的重复图片对应到内容相同的图片
+	imageIDs := make(map[int]int)
+	hashes := make(map[string]int)
+	for _, images := range [][]*model.DeviceImage{target.Images, moved} {
+		for _, image := range images {
+			imageIDs[image.ID] = image.ID
+			if _, ok := hashes[image.ContentHash]; image.ContentHash != "" && !ok {
+				hashes[image.ContentHash] = image.ID
+			}
+		}
+	}
+	for _, image := range dropped {
+		if id, ok := hashes[image.ContentHash]; ok {
+			imageIDs[image.ID] = id
+		}
+	}
+
+	var errs []*SpecFieldError
+	for _, ref := range specImageRefs(fields, normalized, "") {
+		imageID, ok := imageIDs[ref.imageID]
+		if !ok {
+			errs = append(errs, &SpecFieldError{Field: ref.field, Label: ref.label, Reason: SpecErrorInvalid, Value: ref.imageID,
+				Message: fmt.Sprintf("字段 %s 引用的图片不属于该设备", ref.label)})
+			continue
+		}
+		ref.specs[ref.key] = imageID
+	}
+	if len(errs) > 0 {
+		return specValidationError(errs)
+	}
+
+	if template != nil {
+		target.TemplateVersion = version
+	}
+	target.Specifications, err = marshalDeviceSpecs(normalized)
+	return err
+}
+
+// planMergedImages 规划来源设备图片的去向：与目标设备内容相同的图片删除，其余排到目标设备图片之后；
+// 目标设备已有封面时，来源设备的封面改为普通图片
+func planMergedImages(target *model.Device, sources []*model.Device) (moved, dropped []*model.DeviceImage) {
+	hashes := make(map[string]bool)
+	hasCover := false
+	nextSort := 0
+	for _, image := range target.Images {
+		if image.ContentHash != "" {
+			hashes[image.ContentHash] = true
+		}
+		if image.ImageType == "cover" {
+			hasCover = true
+		}
+		if image.SortOrder >= nextSort {
+			nextSort = image.SortOrder + 1
+		}
+	}
+
+	for _, source := range sources {
+		for _, image := range source.Images {
+			if image.ContentHash != "" && hashes[image.ContentHash] {
+				dropped = append(dropped, image)
+				continue
+			}
+			if image.ContentHash != "" {
+				hashes[image.ContentHash] = true
+			}
+			if image.ImageType == "cover" {
+				if hasCover {
+					image.ImageType = "normal"
+				}
+				hasCover = true
+			}
+			image.SortOrder = nextSort
+			nextSort++
+			moved = append(moved, image)
+		}
+	}
+	return moved, dropped
+}
+
+// resolveTagIDs 去重并校验标签，只允许使用系统标签或本人的自定义标签
+func (s *DeviceService) resolveTagIDs(userID int, tagIDs []int) ([]int, error) {
+	unique := make([]int, 0, len(tagIDs))
+	seen := make(map[int]bool)
+	for _, tagID := range tagIDs {
+		if tagID <= 0 {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "标签ID无效")
+		}
+		if !seen[tagID] {
+			seen[tagID] = true
+			unique = append(unique, tagID)
+		}
+	}
+
+	available, err := s.deviceRepo.GetAvailableTagIDs(userID, unique)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "查询标签失败")
+	}
+	for _, tagID := range unique {
+		if !available[tagID] {
+			return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, fmt.Sprintf("标签（ID %d）不存在", tagID))
+		}
+	}
+	return unique, nil
+}
@@ -0,0 +1,404 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/pkg/sheet"
+	"Backend_Lili/pkg/utils"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// ExportFormatJSONL JSON Lines，每行一个设备对象
+	ExportFormatJSONL = "jsonl"
+	// exportBatchSize 导出时每批读取的设备数量
+	exportBatchSize = 500
+	// maxExportColumns 单次导出的列数上限
+	maxExportColumns = 100
+	// exportSpecPrefix 规格参数列的前缀，如 spec.屏幕刷新率
+	exportSpecPrefix = "spec."
+)
+
+// exportRow 导出一行所需的设备及关联数据
+type exportRow struct {
+	device       *model.Device
+	categoryName string
+	templateName string
+	tags         []string
+	currentPrice *float64
+	specs        map[string]interface{}
+}
+
+// exportColumn 导出列定义
+type exportColumn struct {
+	Key   string
+	Label string
+	Value func(row *exportRow) interface{}
+}
+
+// exportDate 空日期导出为空单元格
+func exportDate(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// exportColumns 可导出的设备字段，按默认展示顺序排列
+var exportColumns = []*exportColumn{
+	{"id", "设备ID", func(r *exportRow) interface{} { return r.device.ID }},
+	{"name", "名称", func(r *exportRow) interface{} { return r.device.Name }},
+	{"brand", "品牌", func(r *exportRow) interface{} { return r.device.Brand }},
+	{"model", "型号", func(r *exportRow) interface{} { return r.device.Model }},
+	{"category", "分类", func(r *exportRow) interface{} { return r.categoryName }},
+	{"template", "模板", func(r *exportRow) interface{} { return r.templateName }},
+	{"serial_number", "序列号", func(r *exportRow) interface{} { return r.device.SerialNumber }},
+	{"color", "颜色", func(r *exportRow) interface{} { return r.device.Color }},
+	{"storage", "存储", func(r *exportRow) interface{} { return r.device.Storage }},
+	{"memory", "内存", func(r *exportRow) interface{} { return r.device.Memory }},
+	{"processor", "处理器", func(r *exportRow) interface{} { return r.device.Processor }},
+	{"screen_size", "屏幕尺寸", func(r *exportRow) interface{} { return r.device.ScreenSize }},
+	{"purchase_price", "购买价格", func(r *exportRow) interface{} { return r.device.PurchasePrice }},
+	{"current_value", "当前估值", func(r *exportRow) interface{} { return r.device.CurrentValue }},
+	{"current_price", "市场价", func(r *exportRow) interface{} {
+		if r.currentPrice == nil {
+			return nil
+		}
+		return *r.currentPrice
+	}},
+	{"purchase_date", "购买日期", func(r *exportRow) interface{} { return exportDate(r.device.PurchaseDate) }},
+	{"warranty_date", "保修截止", func(r *exportRow) interface{} { return exportDate(r.device.WarrantyDate) }},
+	{"condition", "成色", func(r *exportRow) interface{} { return r.device.Condition }},
+	{"status", "状态", func(r *exportRow) interface{} { return r.device.Status }},
+	{"sale_price", "出售价格", func(r *exportRow) interface{} {
+		if r.device.Status != "sold" {
+			return nil
+		}
+		return r.device.SalePrice
+	}},
+	{"sale_date", "出售日期", func(r *exportRow) interface{} { return exportDate(r.device.SaleDate) }},
+	{"parent_id", "所属主设备ID", func(r *exportRow) interface{} {
+		if r.device.ParentID == nil {
+			return nil
+		}
+		return *r.device.ParentID
+	}},
+	{"tags", "标签", func(r *exportRow) interface{} { return r.tags }},
+	{"notes", "备注", func(r *exportRow) interface{} { return r.device.Notes }},
+	{"created_at", "创建时间", func(r *exportRow) interface{} { return exportDate(r.device.CreatedAt) }},
+}
+
+// defaultExportColumns 未指定列时导出的列
+var defaultExportColumns = []string{
+	"id", "name", "brand", "model", "category", "serial_number",
+	"purchase_price", "current_value", "purchase_date", "warranty_date", "condition", "status", "tags",
+}
+
+// specExportColumn 规格参数列，嵌套值导出为JSON文本
+func specExportColumn(key string) *exportColumn {
+	return &exportColumn{
+		Key:   exportSpecPrefix + key,
+		Label: key,
+		Value: func(r *exportRow) interface{} {
+			value, ok := r.specs[key]
+			if !ok {
+				return nil
+			}
+			switch v := value.(type) {
+			case nil, string, float64, bool:
+				return v
+			}
+			data, _ := json.Marshal(value)
+			return string(data)
+		},
+	}
+}
+
+// DeviceExport 一次设备导出，校验通过后由控制器设置响应头并调用 Write 流式写出
+type DeviceExport struct {
+	ContentType string
+	FileName    string
+
+	svc        *DeviceService
+	userID     int
+	format     string
+	params     map[string]interface{}
+	columns    []*exportColumn
+	needTags   bool
+	needPrices bool
+	needSpecs  bool
+	categories map[int]string
+	templates  map[int]string
+}
+
+// NewDeviceExport 校验导出格式与列并预加载分类、模板名称
+func (s *DeviceService) NewDeviceExport(userID int, req *ExportDevicesRequest) (*DeviceExport, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+
+	params, err := deviceListParams(&req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	export := &DeviceExport{
+		svc:    s,
+		userID: userID,
+		format: strings.ToLower(strings.TrimSpace(req.Format)),
+		params: params,
+	}
+	if export.format == "" {
+		export.format = sheet.FormatCSV
+	}
+	date := time.Now().Format("20060102")
+	switch export.format {
+	case sheet.FormatCSV:
+		export.ContentType = "text/csv; charset=utf-8"
+	case sheet.FormatXLSX:
+		export.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case ExportFormatJSONL:
+		export.ContentType = "application/x-ndjson; charset=utf-8"
+	default:
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "导出格式仅支持 csv、xlsx、jsonl")
+	}
+	export.FileName = fmt.Sprintf("devices_%s.%s", date, export.format)
+
+	keys := req.Columns
+	if len(keys) == 0 {
+		keys = defaultExportColumns
+	}
+	if len(keys) > maxExportColumns {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("导出列不能超过%d个", maxExportColumns))
+	}
+	columnsByKey := make(map[string]*exportColumn, len(exportColumns))
+	for _, column := range exportColumns {
+		columnsByKey[column.Key] = column
+	}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if strings.HasPrefix(key, exportSpecPrefix) {
+			specKey := strings.TrimPrefix(key, exportSpecPrefix)
+			if specKey == "" {
+				return nil, utils.NewBusinessError(utils.ERROR_PARAM, "规格参数列缺少键名")
+			}
+			export.columns = append(export.columns, specExportColumn(specKey))
+			export.needSpecs = true
+			continue
+		}
+		column, ok := columnsByKey[key]
+		if !ok {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("不支持的导出列：%s", key))
+		}
+		export.columns = append(export.columns, column)
+		switch key {
+		case "tags":
+			export.needTags = true
+		case "current_price":
+			export.needPrices = true
+		}
+	}
+	if len(export.columns) == 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "请选择导出列")
+	}
+
+	categories, err := s.categoryRepo.GetCategoriesByType("all", userID, false)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备分类失败")
+	}
+	templates, err := s.templateRepo.GetAllTemplates()
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备模板失败")
+	}
+	export.categories = make(map[int]string, len(categories))
+	for _, category := range categories {
+		export.categories[category.ID] = category.Name
+	}
+	export.templates = make(map[int]string, len(templates))
+	for _, template := range templates {
+		export.templates[template.ID] = template.Name
+	}
+	return export, nil
+}
+
+// Write 分批读取设备并逐行写出，内存占用只与批大小有关
+func (e *DeviceExport) Write(w io.Writer) error {
+	var writer rowWriter
+	if e.format == ExportFormatJSONL {
+		writer = newJSONLWriter(w)
+	} else {
+		sheetWriter, err := sheet.NewWriter(w, e.format)
+		if err != nil {
+			return err
+		}
+		writer = &sheetRowWriter{w: sheetWriter}
+	}
+
+	if err := writer.WriteHeader(e.columns); err != nil {
+		return err
+	}
+	err := e.svc.deviceRepo.IterateDevices(e.userID, e.params, exportBatchSize, func(devices []*model.Device) error {
+		rows, err := e.loadRows(devices)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := writer.WriteRow(e.columns, row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// loadRows 为一批设备加载标签、市场价与规格参数
+func (e *DeviceExport) loadRows(devices []*model.Device) ([]*exportRow, error) {
+	deviceIDs := make([]int, 0, len(devices))
+	for _, device := range devices {
+		deviceIDs = append(deviceIDs, device.ID)
+	}
+
+	var tags map[int][]string
+	if e.needTags {
+		var err error
+		if tags, err = e.svc.deviceRepo.GetDeviceTagNames(deviceIDs); err != nil {
+			return nil, err
+		}
+	}
+	var prices map[int]float64
+	if e.needPrices {
+		var err error
+		if prices, err = e.svc.deviceRepo.GetCurrentPrices(deviceIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	rows := make([]*exportRow, 0, len(devices))
+	for _, device := range devices {
+		row := &exportRow{device: device, tags: tags[device.ID]}
+		if device.CategoryID != nil {
+			row.categoryName = e.categories[*device.CategoryID]
+		}
+		if device.TemplateID != nil {
+			row.templateName = e.templates[*device.TemplateID]
+		}
+		if price, ok := prices[device.ID]; ok {
+			row.currentPrice = &price
+		}
+		if e.needSpecs && device.Specifications != "" {
+			json.Unmarshal([]byte(device.Specifications), &row.specs)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// rowWriter 导出格式的统一写出接口
+type rowWriter interface {
+	WriteHeader(columns []*exportColumn) error
+	WriteRow(columns []*exportColumn, row *exportRow) error
+	Close() error
+}
+
+// sheetRowWriter 写出CSV或XLSX，表头使用中文列名，标签以逗号连接
+type sheetRowWriter struct {
+	w sheet.Writer
+}
+
+func (s *sheetRowWriter) WriteHeader(columns []*exportColumn) error {
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column.Label
+	}
+	return s.w.WriteRow(header)
+}
+
+func (s *sheetRowWriter) WriteRow(columns []*exportColumn, row *exportRow) error {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		value := column.Value(row)
+		if list, ok := value.([]string); ok {
+			value = strings.Join(list, ", ")
+		}
+		values[i] = value
+	}
+	return s.w.WriteRow(values)
+}
+
+func (s *sheetRowWriter) Close() error {
+	return s.w.Close()
+}
+
+// jsonlWriter 写出JSON Lines，键为列名，日期格式化为YYYY-MM-DD，标签为数组
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	buffered := bufio.NewWriter(w)
+	enc := json.NewEncoder(buffered)
+	enc.SetEscapeHTML(false)
+	return &jsonlWriter{w: buffered, enc: enc}
+}
+
+func (j *jsonlWriter) WriteHeader(columns []*exportColumn) error {
+	return nil
+}
+
+func (j *jsonlWriter) WriteRow(columns []*exportColumn, row *exportRow) error {
+	object := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		value := column.Value(row)
+		switch v := value.(type) {
+		case time.Time:
+			value = v.Format("2006-01-02")
+			if column.Key == "created_at" {
+				value = v.Format(time.RFC3339)
+			}
+		case []string:
+			if v == nil {
+				value = []string{}
+			}
+		}
+		object[column.Key] = value
+	}
+	return j.enc.Encode(object)
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.w.Flush()
+}
+
+// GetExportColumns 列出可导出的列，包含用户设备中出现过的规格参数
+func (s *DeviceService) GetExportColumns(userID int) (*ExportColumnsResponse, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	specKeys, err := s.deviceRepo.GetSpecificationKeys(userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取规格参数失败")
+	}
+
+	columns := make([]*ExportColumn, 0, len(exportColumns)+len(specKeys))
+	for _, column := range exportColumns {
+		columns = append(columns, &ExportColumn{Key: column.Key, Label: column.Label})
+	}
+	for _, key := range specKeys {
+		columns = append(columns, &ExportColumn{Key: exportSpecPrefix + key, Label: key})
+	}
+	return &ExportColumnsResponse{Columns: columns, DefaultColumns: defaultExportColumns}, nil
+}
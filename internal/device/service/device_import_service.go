@@ -0,0 +1,718 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/pkg/sheet"
+	"Backend_Lili/pkg/utils"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxImportFileSize 导入表格大小上限
+	maxImportFileSize = 5 * 1024 * 1024
+	// maxImportRows 单次导入的数据行上限
+	maxImportRows = 1000
+	// headerScanRows 自动识别表头时扫描的行数
+	headerScanRows = 10
+)
+
+// 导入行状态
+const (
+	ImportRowValid    = "valid"
+	ImportRowInvalid  = "invalid"
+	ImportRowSkipped  = "skipped"
+	ImportRowImported = "imported"
+)
+
+// importFields 可导入字段及可识别的表头别名，比较前统一转小写并去除空白与标点
+var importFields = []struct {
+	Field   string
+	Label   string
+	Aliases []string
+}{
+	{"name", "设备名称", []string{"name", "设备名称", "名称", "设备", "商品名称", "商品"}},
+	{"brand", "品牌", []string{"brand", "品牌", "厂商", "制造商"}},
+	{"model", "型号", []string{"model", "型号", "规格型号", "机型"}},
+	{"category", "分类", []string{"category", "categoryname", "categoryid", "分类", "类别", "设备分类", "分类名称"}},
+	{"template", "模板", []string{"template", "templatename", "templateid", "模板", "设备模板", "模板名称"}},
+	{"purchase_price", "购买价格", []string{"purchaseprice", "price", "购买价格", "价格", "购入价格", "购入价", "买入价", "金额", "实付金额", "实付款"}},
+	{"purchase_date", "购买日期", []string{"purchasedate", "date", "购买日期", "购入日期", "购买时间", "日期", "下单时间"}},
+	{"warranty_date", "保修到期", []string{"warrantydate", "warranty", "保修日期", "保修到期", "保修到期日", "保修截止"}},
+	{"serial_number", "序列号", []string{"serialnumber", "serial", "sn", "序列号", "串号", "imei"}},
+	{"color", "颜色", []string{"color", "颜色"}},
+	{"storage", "存储", []string{"storage", "存储", "存储容量", "容量"}},
+	{"memory", "内存", []string{"memory", "ram", "内存"}},
+	{"processor", "处理器", []string{"processor", "cpu", "处理器"}},
+	{"screen_size", "屏幕尺寸", []string{"screensize", "屏幕尺寸", "屏幕"}},
+	{"condition", "成色", []string{"condition", "成色", "新旧程度", "设备状态"}},
+	{"notes", "备注", []string{"notes", "note", "备注", "说明"}},
+}
+
+// requiredImportFields 表格中必须能定位到的列
+var requiredImportFields = []string{"name", "brand", "model", "purchase_price", "purchase_date"}
+
+// importConditions 成色取值别名
+var importConditions = map[string]string{
+	"new": "new", "全新": "new", "新": "new",
+	"good": "good", "良好": "good", "好": "good", "99新": "good", "95新": "good", "九成新": "good",
+	"fair": "fair", "一般": "fair", "9成新": "fair", "八成新": "fair", "8成新": "fair",
+	"poor": "poor", "较差": "poor", "差": "poor", "损坏": "poor",
+}
+
+// headerNormalizer 表头比较前去除的字符
+var headerNormalizer = strings.NewReplacer(" ", "", "\t", "", "_", "", "-", "", "*", "", "(", "", ")", "", "（", "", "）", "", ":", "", "：", "", "　", "")
+
+// normalizeHeader 统一表头写法
+func normalizeHeader(header string) string {
+	return strings.ToLower(headerNormalizer.Replace(strings.TrimSpace(header)))
+}
+
+// matchImportField 按别名匹配表头对应的字段
+func matchImportField(header string) string {
+	normalized := normalizeHeader(header)
+	if normalized == "" {
+		return ""
+	}
+	for _, f := range importFields {
+		for _, alias := range f.Aliases {
+			if normalized == alias {
+				return f.Field
+			}
+		}
+	}
+	return ""
+}
+
+// importLookup 导入时按名称或ID解析分类与模板
+type importLookup struct {
+	categoriesByID   map[int]*model.Category
+	categoriesByName map[string]*model.Category
+	templatesByID    map[int]*model.DeviceTemplate
+	templatesByName  map[string][]*model.DeviceTemplate
+	templatesByCat   map[int][]*model.DeviceTemplate
+}
+
+// importContext 一次导入的共享状态
+type importContext struct {
+	userID           int
+	ignoreDuplicates bool
+	lookup           *importLookup
+	categorizer      *deviceCategorizer
+	existingSerials  map[string]int // 已有设备：大写序列号 -> 设备ID
+	seenSerials      map[string]int // 本次导入：大写序列号 -> 行号
+	existingDevices  []*model.Device
+	seenDevices      []*importSeenDevice
+}
+
+// importSeenDevice 本次导入中已通过校验的设备，用于行之间的疑似重复判断
+type importSeenDevice struct {
+	row    int
+	device *model.Device
+}
+
+// newImportContext 预加载分类、模板、分类规则、已存在的序列号与设备
+func (s *DeviceService) newImportContext(userID int, ignoreDuplicates bool, serialNumbers []string) (*importContext, error) {
+	lookup, err := s.newImportLookup(userID)
+	if err != nil {
+		return nil, err
+	}
+	categorizer, err := s.newCategorizer(userID, lookup)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.deviceRepo.GetDeviceIDsBySerialNumbers(userID, serialNumbers)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "查询设备序列号失败")
+	}
+	existingDevices, err := s.deviceRepo.GetAllDevices(userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备列表失败")
+	}
+
+	return &importContext{
+		userID:           userID,
+		ignoreDuplicates: ignoreDuplicates,
+		lookup:           lookup,
+		categorizer:      categorizer,
+		existingSerials:  existing,
+		seenSerials:      make(map[string]int),
+		existingDevices:  existingDevices,
+	}, nil
+}
+
+// newImportLookup 加载用户可见的分类与模板，按ID、名称与分类索引
+func (s *DeviceService) newImportLookup(userID int) (*importLookup, error) {
+	categories, err := s.categoryRepo.GetCategoriesByType("all", userID, false)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备分类失败")
+	}
+	templates, err := s.templateRepo.GetAvailableTemplates(userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备模板失败")
+	}
+
+	lookup := &importLookup{
+		categoriesByID:   make(map[int]*model.Category),
+		categoriesByName: make(map[string]*model.Category),
+		templatesByID:    make(map[int]*model.DeviceTemplate),
+		templatesByName:  make(map[string][]*model.DeviceTemplate),
+		templatesByCat:   make(map[int][]*model.DeviceTemplate),
+	}
+	for _, category := range categories {
+		lookup.categoriesByID[category.ID] = category
+		key := strings.ToLower(strings.TrimSpace(category.Name))
+		// 自定义分类与系统分类重名时优先使用自定义分类
+		if current, ok := lookup.categoriesByName[key]; !ok || current.Type != "custom" {
+			lookup.categoriesByName[key] = category
+		}
+	}
+	for _, template := range templates {
+		lookup.templatesByID[template.ID] = template
+		key := strings.ToLower(strings.TrimSpace(template.Name))
+		lookup.templatesByName[key] = append(lookup.templatesByName[key], template)
+		if template.CategoryID != nil {
+			lookup.templatesByCat[*template.CategoryID] = append(lookup.templatesByCat[*template.CategoryID], template)
+		}
+	}
+	return lookup, nil
+}
+
+// resolveCategory 分类列可填名称或ID
+func (l *importLookup) resolveCategory(value string) *model.Category {
+	if id, err := strconv.Atoi(value); err == nil {
+		return l.categoriesByID[id]
+	}
+	return l.categoriesByName[strings.ToLower(value)]
+}
+
+// resolveTemplate 模板列可填名称或ID；重名时优先选同分类的模板，未填写时使用分类下最早的模板
+func (l *importLookup) resolveTemplate(value string, categoryID int) *model.DeviceTemplate {
+	if value == "" {
+		if templates := l.templatesByCat[categoryID]; len(templates) > 0 {
+			return templates[0]
+		}
+		return nil
+	}
+	if id, err := strconv.Atoi(value); err == nil {
+		return l.templatesByID[id]
+	}
+	candidates := l.templatesByName[strings.ToLower(value)]
+	for _, template := range candidates {
+		if template.CategoryID != nil && *template.CategoryID == categoryID {
+			return template
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	return nil
+}
+
+// prepareImportDevice 校验单条导入数据并构建设备；序列号与已有设备或前面的行重复时返回 duplicate
+func (s *DeviceService) prepareImportDevice(req *CreateDeviceRequest, ctx *importContext) (device *model.Device, duplicate *ImportFieldError, err error) {
+	if err := s.validateCreateDeviceRequest(req); err != nil {
+		return nil, nil, err
+	}
+	if _, ok := ctx.lookup.categoriesByID[req.CategoryID]; !ok {
+		return nil, nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备分类不存在")
+	}
+	if _, ok := ctx.lookup.templatesByID[req.TemplateID]; !ok {
+		return nil, nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备模板不存在")
+	}
+
+	parentID, err := s.resolveParent(0, ctx.userID, req.ParentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	purchaseDate, err := time.Parse("2006-01-02", req.PurchaseDate)
+	if err != nil {
+		return nil, nil, utils.NewBusinessError(utils.ERROR_PARAM, "购买日期格式错误")
+	}
+
+	device = &model.Device{
+		UserID:        ctx.userID,
+		TemplateID:    &req.TemplateID,
+		CategoryID:    &req.CategoryID,
+		ParentID:      parentID,
+		Name:          req.Name,
+		Brand:         req.Brand,
+		Model:         req.Model,
+		SerialNumber:  req.SerialNumber,
+		Color:         req.Color,
+		Storage:       req.Storage,
+		Memory:        req.Memory,
+		Processor:     req.Processor,
+		ScreenSize:    req.ScreenSize,
+		PurchasePrice: req.PurchasePrice,
+		CurrentValue:  req.PurchasePrice,
+		PurchaseDate:  purchaseDate,
+		Condition:     req.Condition,
+		Status:        "active",
+		Notes:         req.Notes,
+	}
+	if req.WarrantyDate != "" {
+		warrantyDate, err := time.Parse("2006-01-02", req.WarrantyDate)
+		if err != nil {
+			return nil, nil, utils.NewBusinessError(utils.ERROR_PARAM, "保修日期格式错误")
+		}
+		device.WarrantyDate = warrantyDate
+	}
+
+	template := ctx.lookup.templatesByID[req.TemplateID]
+	device.TemplateVersion = template.FieldsVersion
+	if device.Specifications, err = s.normalizeDeviceSpecs(template, device.TemplateVersion, 0, 0, req.Specifications); err != nil {
+		return nil, nil, err
+	}
+
+	if serial := strings.ToUpper(strings.TrimSpace(req.SerialNumber)); serial != "" {
+		if deviceID, ok := ctx.existingSerials[serial]; ok {
+			return device, &ImportFieldError{Field: "serial_number", Value: req.SerialNumber, Message: fmt.Sprintf("序列号与已有设备（ID %d）重复", deviceID)}, nil
+		}
+		if row, ok := ctx.seenSerials[serial]; ok {
+			return device, &ImportFieldError{Field: "serial_number", Value: req.SerialNumber, Message: fmt.Sprintf("序列号与第%d行重复", row)}, nil
+		}
+	}
+
+	return device, nil, nil
+}
+
+// markSeen 记录已通过校验的行，供后续行判重
+func (ctx *importContext) markSeen(device *model.Device, row int) {
+	if serial := strings.ToUpper(strings.TrimSpace(device.SerialNumber)); serial != "" {
+		ctx.seenSerials[serial] = row
+	}
+	ctx.seenDevices = append(ctx.seenDevices, &importSeenDevice{row: row, device: device})
+}
+
+// findSimilar 按品牌、型号、购买日期与价格查找疑似重复的已有设备或前面的行，返回相似度最高的一条
+func (ctx *importContext) findSimilar(device *model.Device) (*ImportFieldError, int) {
+	var best *ImportFieldError
+	bestScore, duplicateOf := 0.0, 0
+	for _, existing := range ctx.existingDevices {
+		if isKitPair(device, existing) {
+			continue
+		}
+		score, matchType, reasons := duplicateScore(device, existing)
+		if matchType == DuplicateMatchSimilar && score >= defaultDuplicateMinScore && score > bestScore {
+			bestScore, duplicateOf = score, existing.ID
+			best = &ImportFieldError{Field: "model", Value: device.Model, Message: fmt.Sprintf("与已有设备「%s」（ID %d）疑似重复：%s", existing.Name, existing.ID, strings.Join(reasons, "、"))}
+		}
+	}
+	for _, seen := range ctx.seenDevices {
+		score, matchType, reasons := duplicateScore(device, seen.device)
+		if matchType == DuplicateMatchSimilar && score >= defaultDuplicateMinScore && score > bestScore {
+			bestScore, duplicateOf = score, 0
+			best = &ImportFieldError{Field: "model", Value: device.Model, Message: fmt.Sprintf("与第%d行疑似重复：%s", seen.row, strings.Join(reasons, "、"))}
+		}
+	}
+	return best, duplicateOf
+}
+
+// applyImportRow 把单行校验结果写入行结果，返回需要入库的设备
+func (s *DeviceService) applyImportRow(result *ImportRowResult, req *CreateDeviceRequest, ctx *importContext) *model.Device {
+	// 未指定分类或模板时按分类规则填充
+	if req.CategoryID <= 0 || req.TemplateID <= 0 {
+		result.Suggestions = ctx.categorizer.apply(req)
+		if category := ctx.lookup.categoriesByID[req.CategoryID]; category != nil {
+			result.CategoryName = category.Name
+		}
+		if template := ctx.lookup.templatesByID[req.TemplateID]; template != nil {
+			result.TemplateName = template.Name
+		}
+	}
+
+	device, duplicate, err := s.prepareImportDevice(req, ctx)
+	if err != nil {
+		result.Status = ImportRowInvalid
+		if fieldErrs := specFieldErrors(err); len(fieldErrs) > 0 {
+			for _, fieldErr := range fieldErrs {
+				result.Errors = append(result.Errors, &ImportFieldError{Field: "spec." + fieldErr.Field, Value: specValueText(fieldErr.Value), Message: fieldErr.Message})
+			}
+			return nil
+		}
+		result.Errors = append(result.Errors, &ImportFieldError{Message: businessErrorMessage(err)})
+		return nil
+	}
+	if duplicate != nil {
+		if serial := strings.ToUpper(strings.TrimSpace(req.SerialNumber)); serial != "" {
+			result.DuplicateOf = ctx.existingSerials[serial]
+		}
+		if ctx.ignoreDuplicates {
+			result.Status = ImportRowSkipped
+		} else {
+			result.Status = ImportRowInvalid
+		}
+		result.Errors = append(result.Errors, duplicate)
+		return nil
+	}
+
+	// 疑似重复：忽略重复时跳过，否则照常导入并给出提示
+	if similar, duplicateOf := ctx.findSimilar(device); similar != nil {
+		result.DuplicateOf = duplicateOf
+		if ctx.ignoreDuplicates {
+			result.Status = ImportRowSkipped
+			result.Errors = append(result.Errors, similar)
+			return nil
+		}
+		result.Warnings = append(result.Warnings, similar)
+	}
+
+	ctx.markSeen(device, result.Row)
+	result.Status = ImportRowValid
+	return device
+}
+
+// commitImportDevices 在同一事务中写入设备，成功后同步保修提醒并记录创建事件
+func (s *DeviceService) commitImportDevices(userID int, devices []*model.Device, results []*ImportRowResult, source string) error {
+	if len(devices) == 0 {
+		return nil
+	}
+	if _, err := s.deviceRepo.BatchImportDevices(devices); err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "批量导入设备失败")
+	}
+	for i, device := range devices {
+		results[i].Status = ImportRowImported
+		results[i].DeviceID = device.ID
+		s.templateSvc.syncSpecIndex(device, nil)
+		s.syncWarrantyReminder(device)
+		s.recordEvent(device.ID, userID, model.DeviceEventCreated, model.DiffDevices(nil, device), map[string]interface{}{"source": source})
+	}
+	return nil
+}
+
+// ImportDevicesFile 从CSV或XLSX导入设备：识别表头与列映射，逐行校验，非预览模式下在同一事务中写入全部有效行
+func (s *DeviceService) ImportDevicesFile(userID int, file multipart.File, fileHeader *multipart.FileHeader, req *ImportDevicesFileRequest) (*ImportDevicesFileResponse, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	if fileHeader.Size > maxImportFileSize {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "导入文件大小不能超过5MB")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize+1))
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "读取导入文件失败")
+	}
+	if len(data) > maxImportFileSize {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "导入文件大小不能超过5MB")
+	}
+
+	format, err := sheet.DetectFormat(data, fileHeader.Filename)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, err.Error())
+	}
+	rows, err := sheet.Read(data, fileHeader.Filename)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "表格解析失败: "+err.Error())
+	}
+
+	// 定位表头
+	headerIndex := req.HeaderRow - 1
+	if req.HeaderRow <= 0 {
+		headerIndex = detectHeaderRow(rows)
+	}
+	if headerIndex < 0 || headerIndex >= len(rows) {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "未找到表头行，请指定 header_row 或 mapping")
+	}
+	headers := rows[headerIndex]
+
+	columns, unmapped, err := buildImportColumns(headers, req.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, field := range requiredImportFields {
+		if _, ok := columns[field]; !ok {
+			missing = append(missing, importFieldLabel(field))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "缺少必需的列: "+strings.Join(missing, "、"))
+	}
+
+	dataRows := rows[headerIndex+1:]
+	cell := func(row []string, field string) string {
+		if idx, ok := columns[field]; ok && idx < len(row) {
+			return strings.TrimSpace(row[idx])
+		}
+		return ""
+	}
+
+	var serials []string
+	dataCount := 0
+	for _, row := range dataRows {
+		if sheet.IsBlankRow(row) {
+			continue
+		}
+		dataCount++
+		if serial := cell(row, "serial_number"); serial != "" {
+			serials = append(serials, serial)
+		}
+	}
+	if dataCount == 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "表格中没有设备数据")
+	}
+	if dataCount > maxImportRows {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("单次导入不能超过%d行", maxImportRows))
+	}
+
+	ctx, err := s.newImportContext(userID, req.IgnoreDuplicates, serials)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ImportDevicesFileResponse{
+		DryRun:          req.DryRun,
+		Format:          format,
+		HeaderRow:       headerIndex + 1,
+		Columns:         make(map[string]string, len(columns)),
+		UnmappedHeaders: unmapped,
+		Rows:            make([]*ImportRowResult, 0, dataCount),
+	}
+	for field, idx := range columns {
+		response.Columns[field] = headers[idx]
+	}
+
+	var devices []*model.Device
+	var imported []*ImportRowResult
+	for i, row := range dataRows {
+		if sheet.IsBlankRow(row) {
+			continue
+		}
+		result := &ImportRowResult{Row: headerIndex + i + 2}
+		response.Rows = append(response.Rows, result)
+
+		deviceReq := s.parseImportRow(row, columns, cell, req, ctx.lookup, result)
+		result.Device = deviceReq
+		if len(result.Errors) > 0 {
+			result.Status = ImportRowInvalid
+			continue
+		}
+		if device := s.applyImportRow(result, deviceReq, ctx); device != nil {
+			devices = append(devices, device)
+			imported = append(imported, result)
+		}
+	}
+
+	if !req.DryRun {
+		if err := s.commitImportDevices(userID, devices, imported, "file_import"); err != nil {
+			return nil, err
+		}
+	}
+
+	response.TotalCount = len(response.Rows)
+	for _, result := range response.Rows {
+		switch result.Status {
+		case ImportRowValid:
+			response.ValidCount++
+		case ImportRowImported:
+			response.ValidCount++
+			response.ImportedCount++
+		case ImportRowSkipped:
+			response.SkippedCount++
+		default:
+			response.InvalidCount++
+		}
+	}
+	return response, nil
+}
+
+// parseImportRow 把表格行转换为创建请求，单元格解析错误写入 result.Errors
+func (s *DeviceService) parseImportRow(row []string, columns map[string]int, cell func([]string, string) string, req *ImportDevicesFileRequest, lookup *importLookup, result *ImportRowResult) *CreateDeviceRequest {
+	addError := func(field, value, message string) {
+		fieldErr := &ImportFieldError{Field: field, Value: value, Message: message}
+		if idx, ok := columns[field]; ok {
+			fieldErr.Column = sheet.ColumnName(idx)
+		}
+		result.Errors = append(result.Errors, fieldErr)
+	}
+
+	deviceReq := &CreateDeviceRequest{
+		Name:         cell(row, "name"),
+		Brand:        cell(row, "brand"),
+		Model:        cell(row, "model"),
+		SerialNumber: cell(row, "serial_number"),
+		Color:        cell(row, "color"),
+		Storage:      cell(row, "storage"),
+		Memory:       cell(row, "memory"),
+		Processor:    cell(row, "processor"),
+		ScreenSize:   cell(row, "screen_size"),
+		Notes:        cell(row, "notes"),
+	}
+
+	if value := cell(row, "purchase_price"); value != "" {
+		amount, err := sheet.ParseAmount(value)
+		if err != nil {
+			addError("purchase_price", value, err.Error())
+		} else {
+			deviceReq.PurchasePrice = amount
+		}
+	}
+	for _, field := range []string{"purchase_date", "warranty_date"} {
+		value := cell(row, field)
+		if value == "" {
+			continue
+		}
+		date, err := sheet.ParseDate(value)
+		if err != nil {
+			addError(field, value, err.Error())
+			continue
+		}
+		if field == "purchase_date" {
+			deviceReq.PurchaseDate = date.Format("2006-01-02")
+		} else {
+			deviceReq.WarrantyDate = date.Format("2006-01-02")
+		}
+	}
+	if value := cell(row, "condition"); value != "" {
+		condition, ok := importConditions[strings.ToLower(value)]
+		if !ok {
+			addError("condition", value, "无法识别的成色，可填写 全新/良好/一般/较差")
+		} else {
+			deviceReq.Condition = condition
+		}
+	}
+
+	// 分类与模板：行内未填写时使用默认值，模板缺省时取分类下的模板，分类缺省时取模板所属分类
+	categoryValue := cell(row, "category")
+	templateValue := cell(row, "template")
+	var category *model.Category
+	if categoryValue != "" {
+		if category = lookup.resolveCategory(categoryValue); category == nil {
+			addError("category", categoryValue, "分类不存在")
+		}
+	} else if req.DefaultCategoryID > 0 {
+		category = lookup.categoriesByID[req.DefaultCategoryID]
+	}
+	categoryID := 0
+	if category != nil {
+		categoryID = category.ID
+	}
+
+	var template *model.DeviceTemplate
+	if templateValue != "" || req.DefaultTemplateID <= 0 {
+		template = lookup.resolveTemplate(templateValue, categoryID)
+	} else {
+		template = lookup.templatesByID[req.DefaultTemplateID]
+	}
+	if template == nil {
+		if templateValue != "" {
+			addError("template", templateValue, "模板不存在")
+		} else if category != nil {
+			addError("template", "", "该分类下没有可用模板，请填写模板")
+		}
+	}
+	if category == nil && categoryValue == "" && template != nil && template.CategoryID != nil {
+		category = lookup.categoriesByID[*template.CategoryID]
+	}
+
+	if category != nil {
+		deviceReq.CategoryID = category.ID
+		result.CategoryName = category.Name
+	}
+	if template != nil {
+		deviceReq.TemplateID = template.ID
+		result.TemplateName = template.Name
+	}
+	return deviceReq
+}
+
+// detectHeaderRow 在前几行中找匹配字段最多的一行作为表头，至少匹配两列
+func detectHeaderRow(rows [][]string) int {
+	best, bestScore := -1, 1
+	for i := 0; i < len(rows) && i < headerScanRows; i++ {
+		score := 0
+		seen := map[string]bool{}
+		for _, value := range rows[i] {
+			if field := matchImportField(value); field != "" && !seen[field] {
+				seen[field] = true
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// buildImportColumns 合并自动识别与用户映射，返回 字段 -> 列序号 以及未使用的表头
+func buildImportColumns(headers []string, mapping map[string]string) (map[string]int, []string, error) {
+	columns := make(map[string]int)
+	assigned := make(map[int]bool)
+
+	// 用户映射优先，键可以是表头文字或列字母
+	for key, field := range mapping {
+		idx := -1
+		normalizedKey := normalizeHeader(key)
+		for i, header := range headers {
+			if normalizeHeader(header) == normalizedKey && normalizedKey != "" {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			if col := sheet.ColumnIndex(key); col >= 0 && col < len(headers) {
+				idx = col
+			}
+		}
+		if idx < 0 {
+			return nil, nil, utils.NewBusinessError(utils.ERROR_PARAM, "映射的列不存在: "+key)
+		}
+
+		field = strings.TrimSpace(field)
+		assigned[idx] = true
+		if field == "" {
+			continue
+		}
+		if importFieldLabel(field) == "" {
+			return nil, nil, utils.NewBusinessError(utils.ERROR_PARAM, "未知的导入字段: "+field)
+		}
+		if _, ok := columns[field]; ok {
+			return nil, nil, utils.NewBusinessError(utils.ERROR_PARAM, "字段重复映射: "+field)
+		}
+		columns[field] = idx
+	}
+
+	var unmapped []string
+	for i, header := range headers {
+		if assigned[i] {
+			continue
+		}
+		field := matchImportField(header)
+		if field == "" {
+			if header != "" {
+				unmapped = append(unmapped, header)
+			}
+			continue
+		}
+		if _, ok := columns[field]; !ok {
+			columns[field] = i
+		}
+	}
+	return columns, unmapped, nil
+}
+
+// importFieldLabel 字段中文名，未知字段返回空串
+func importFieldLabel(field string) string {
+	for _, f := range importFields {
+		if f.Field == field {
+			return f.Label
+		}
+	}
+	return ""
+}
+
+// businessErrorMessage 取业务异常的提示文字
+func businessErrorMessage(err error) string {
+	if businessErr, ok := err.(*utils.BusinessError); ok {
+		return businessErr.Message
+	}
+	return err.Error()
+}
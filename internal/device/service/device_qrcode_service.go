@@ -0,0 +1,262 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/pkg/label"
+	"Backend_Lili/pkg/qrcode"
+	"Backend_Lili/pkg/utils"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	beego "github.com/beego/beego/v2/server/web"
+)
+
+const (
+	// defaultDeviceQRCodeLink 二维码链接的默认前缀，后接 token
+	defaultDeviceQRCodeLink = "lili://devices/scan/"
+	// deviceQRCodeLevel 标签可能磨损，使用较高的纠错等级
+	deviceQRCodeLevel = qrcode.Quartile
+	// qrCodeTokenBytes token 的随机字节数，编码为小写十六进制
+	qrCodeTokenBytes = 16
+
+	defaultQRCodeImageSize = 300
+	minQRCodeImageSize     = 100
+	maxQRCodeImageSize     = 2000
+)
+
+// qrCodeTokenPattern 扫码传入的 token 格式
+var qrCodeTokenPattern = regexp.MustCompile(`^[0-9a-f]{16,64}$`)
+
+// deviceQRCodeLink 二维码中编码的链接，前缀由配置项 device_qrcode_link 指定
+func deviceQRCodeLink(token string) string {
+	return beego.AppConfig.DefaultString("device_qrcode_link", defaultDeviceQRCodeLink) + token
+}
+
+// newQRCodeToken 生成不可猜测的 token；使用十六进制避免数据库不区分大小写的比较
+func newQRCodeToken() (string, error) {
+	buf := make([]byte, qrCodeTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// GetDeviceQRCode 获取设备二维码的 token 与链接，首次获取时生成
+func (s *DeviceService) GetDeviceQRCode(deviceID, userID int) (*DeviceQRCodeResponse, error) {
+	code, err := s.getDeviceQRCode(deviceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return newDeviceQRCodeResponse(code), nil
+}
+
+// ResetDeviceQRCode 重新生成 token，已打印的旧标签随即失效
+func (s *DeviceService) ResetDeviceQRCode(deviceID, userID int) (*DeviceQRCodeResponse, error) {
+	code, err := s.getDeviceQRCode(deviceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if code.Token, err = newQRCodeToken(); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "生成二维码失败")
+	}
+	if err := s.qrRepo.UpdateQRCodeToken(code); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "保存设备二维码失败")
+	}
+	return newDeviceQRCodeResponse(code), nil
+}
+
+// getDeviceQRCode 校验设备归属后获取设备的二维码
+func (s *DeviceService) getDeviceQRCode(deviceID, userID int) (*model.DeviceQRCode, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	device, err := s.deviceRepo.GetDeviceByID(deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	if device == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+	}
+
+	codes, err := s.ensureQRCodes(userID, []int{deviceID})
+	if err != nil {
+		return nil, err
+	}
+	return codes[deviceID], nil
+}
+
+// GetDeviceQRCodeImage 生成设备二维码图片，支持 png 与 svg
+func (s *DeviceService) GetDeviceQRCodeImage(deviceID, userID int, req *DeviceQRCodeImageRequest) (*DeviceQRCodeImage, error) {
+	format := strings.ToLower(strings.TrimSpace(req.Format))
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "svg" {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "图片格式只能是 png 或 svg")
+	}
+	size := req.Size
+	if size == 0 {
+		size = defaultQRCodeImageSize
+	}
+	if size < minQRCodeImageSize || size > maxQRCodeImageSize {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("图片尺寸范围为%d-%d像素", minQRCodeImageSize, maxQRCodeImageSize))
+	}
+
+	qrCode, err := s.getDeviceQRCode(deviceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	code, err := qrcode.Encode([]byte(deviceQRCodeLink(qrCode.Token)), deviceQRCodeLevel)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "生成二维码失败")
+	}
+
+	// 按模块整数倍缩放，实际边长不超过请求的尺寸
+	scale := size / (code.Size + 2*qrcode.QuietZone)
+	if scale < 1 {
+		scale = 1
+	}
+	image := &DeviceQRCodeImage{FileName: fmt.Sprintf("device_%d_qrcode.%s", deviceID, format)}
+	if format == "svg" {
+		image.ContentType = "image/svg+xml"
+		image.Data = code.SVG(scale, qrcode.QuietZone)
+		return image, nil
+	}
+	image.ContentType = "image/png"
+	if image.Data, err = code.PNG(scale, qrcode.QuietZone); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "生成二维码失败")
+	}
+	return image, nil
+}
+
+// ScanDeviceQRCode 按扫码得到的 token 查找设备，只能查到自己的设备
+func (s *DeviceService) ScanDeviceQRCode(userID int, token string) (*model.Device, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	token = strings.ToLower(strings.TrimSpace(token))
+	if !qrCodeTokenPattern.MatchString(token) {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "二维码无效或设备不存在")
+	}
+
+	code, err := s.qrRepo.GetQRCodeByToken(token)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备二维码失败")
+	}
+	// 其他用户的二维码与不存在的 token 返回相同的错误，不暴露设备是否存在
+	if code == nil || code.UserID != userID {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "二维码无效或设备不存在")
+	}
+	return s.GetDeviceDetail(code.DeviceID, userID)
+}
+
+// GetDeviceLabels 生成 A4 标签纸PDF，每张标签包含名称、型号、购买日期与二维码，按请求的设备顺序排列
+func (s *DeviceService) GetDeviceLabels(userID int, req *DeviceLabelsRequest) (*DeviceLabelsFile, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	sheet := label.A4Sheet
+	if req.Skip < 0 || req.Skip >= sheet.PerPage() {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("skip 范围为0-%d", sheet.PerPage()-1))
+	}
+
+	deviceIDs, err := s.bulkTargetIDs(userID, &BulkDevicesRequest{DeviceIDs: req.DeviceIDs, Filter: req.Filter})
+	if err != nil {
+		return nil, err
+	}
+	if len(deviceIDs) == 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "没有符合条件的设备")
+	}
+	devices, err := s.deviceRepo.GetDevicesByIDs(userID, deviceIDs)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	devicesByID := make(map[int]*model.Device, len(devices))
+	for _, device := range devices {
+		if device.DeletedAt.IsZero() {
+			devicesByID[device.ID] = device
+		}
+	}
+	var missing []string
+	for _, deviceID := range deviceIDs {
+		if devicesByID[deviceID] == nil {
+			missing = append(missing, strconv.Itoa(deviceID))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在: "+strings.Join(missing, ", "))
+	}
+
+	codes, err := s.ensureQRCodes(userID, deviceIDs)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]label.Item, 0, len(deviceIDs))
+	for _, deviceID := range deviceIDs {
+		device := devicesByID[deviceID]
+		code, err := qrcode.Encode([]byte(deviceQRCodeLink(codes[deviceID].Token)), deviceQRCodeLevel)
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_SERVER, "生成二维码失败")
+		}
+		item := label.Item{Title: device.Name, Code: code}
+		if modelName := strings.TrimSpace(device.Brand + " " + device.Model); modelName != "" {
+			item.Lines = append(item.Lines, "型号: "+modelName)
+		}
+		if !device.PurchaseDate.IsZero() {
+			item.Lines = append(item.Lines, "购买日期: "+device.PurchaseDate.Format("2006-01-02"))
+		}
+		items = append(items, item)
+	}
+
+	var buf bytes.Buffer
+	if err := label.WritePDF(&buf, sheet, items, label.Options{Skip: req.Skip, Borders: req.Borders}); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "生成标签失败")
+	}
+	return &DeviceLabelsFile{
+		FileName: fmt.Sprintf("device_labels_%s.pdf", time.Now().Format("20060102")),
+		Data:     buf.Bytes(),
+	}, nil
+}
+
+// ensureQRCodes 获取设备的二维码，没有的生成新的 token
+func (s *DeviceService) ensureQRCodes(userID int, deviceIDs []int) (map[int]*model.DeviceQRCode, error) {
+	codes, err := s.qrRepo.GetQRCodesByDeviceIDs(userID, deviceIDs)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备二维码失败")
+	}
+	for _, deviceID := range deviceIDs {
+		if codes[deviceID] != nil {
+			continue
+		}
+		token, err := newQRCodeToken()
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_SERVER, "生成二维码失败")
+		}
+		code := &model.DeviceQRCode{DeviceID: deviceID, UserID: userID, Token: token}
+		if err := s.qrRepo.CreateQRCode(code); err != nil {
+			// 并发请求可能已为该设备生成了二维码
+			existing, getErr := s.qrRepo.GetQRCodesByDeviceIDs(userID, []int{deviceID})
+			if getErr != nil || existing[deviceID] == nil {
+				return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "保存设备二维码失败")
+			}
+			code = existing[deviceID]
+		}
+		codes[deviceID] = code
+	}
+	return codes, nil
+}
+
+func newDeviceQRCodeResponse(code *model.DeviceQRCode) *DeviceQRCodeResponse {
+	return &DeviceQRCodeResponse{
+		DeviceID:  code.DeviceID,
+		Token:     code.Token,
+		Link:      deviceQRCodeLink(code.Token),
+		UpdatedAt: code.UpdatedAt,
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/pkg/utils"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	beego "github.com/beego/beego/v2/server/web"
+)
+
+const (
+	// defaultRecycleBinRetentionDays 回收站默认保留天数
+	defaultRecycleBinRetentionDays = 30
+	// purgeBatchSize 自动清理时每批读取的设备数量
+	purgeBatchSize = 200
+)
+
+// RecycleBinRetentionDays 回收站保留天数，配置项 recycle_bin_retention_days，0或负数表示不自动清理
+func RecycleBinRetentionDays() int {
+	return beego.AppConfig.DefaultInt("recycle_bin_retention_days", defaultRecycleBinRetentionDays)
+}
+
+// GetRecycleBin 获取回收站中的设备，按删除时间倒序
+func (s *DeviceService) GetRecycleBin(userID int, req *GetRecycleBinRequest) (*GetRecycleBinResponse, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+	if req.Limit > 100 {
+		req.Limit = 100
+	}
+
+	params := map[string]interface{}{
+		"deleted": true,
+		"sort":    "deleted_at",
+		"page":    req.Page,
+		"limit":   req.Limit,
+	}
+	if search := strings.TrimSpace(req.Search); search != "" {
+		params["search"] = search
+	}
+	devices, total, err := s.deviceRepo.GetDevicesList(userID, params)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取回收站失败")
+	}
+	if err := s.fillCoverImages(devices); err != nil {
+		return nil, err
+	}
+
+	retentionDays := RecycleBinRetentionDays()
+	if retentionDays < 0 {
+		retentionDays = 0
+	}
+	items := make([]*RecycleBinDevice, 0, len(devices))
+	for _, device := range devices {
+		item := &RecycleBinDevice{Device: device, DeletedAt: device.DeletedAt}
+		if retentionDays > 0 {
+			purgeAt := device.DeletedAt.AddDate(0, 0, retentionDays)
+			item.PurgeAt = &purgeAt
+		}
+		items = append(items, item)
+	}
+
+	return &GetRecycleBinResponse{
+		Devices:       items,
+		Total:         int(total),
+		Page:          req.Page,
+		Limit:         req.Limit,
+		TotalPages:    int((total + int64(req.Limit) - 1) / int64(req.Limit)),
+		RetentionDays: retentionDays,
+	}, nil
+}
+
+// getDeletedDevice 获取回收站中的设备
+func (s *DeviceService) getDeletedDevice(deviceID, userID int) (*model.Device, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	devices, err := s.deviceRepo.GetDevicesByIDs(userID, []int{deviceID})
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	if len(devices) == 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+	}
+	if devices[0].DeletedAt.IsZero() {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "设备不在回收站中")
+	}
+	return devices[0], nil
+}
+
+// RestoreDevice 从回收站恢复设备，暂停的价格预警随之恢复
+func (s *DeviceService) RestoreDevice(deviceID, userID int) (*model.Device, error) {
+	if _, err := s.getDeletedDevice(deviceID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.deviceRepo.RestoreDevice(deviceID, userID); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "恢复设备失败")
+	}
+	s.recordEvent(deviceID, userID, model.DeviceEventRestored, nil, nil)
+
+	return s.GetDeviceDetail(deviceID, userID)
+}
+
+// PurgeDeletedDevice 彻底删除回收站中的设备
+func (s *DeviceService) PurgeDeletedDevice(deviceID, userID int) error {
+	if _, err := s.getDeletedDevice(deviceID, userID); err != nil {
+		return err
+	}
+	return s.PurgeDevice(deviceID, userID)
+}
+
+// PurgeExpiredDevices 彻底删除在回收站中超过保留天数的设备，单台失败不影响其他设备，返回删除数量
+func (s *DeviceService) PurgeExpiredDevices() (int, error) {
+	retentionDays := RecycleBinRetentionDays()
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+	before := time.Now().AddDate(0, 0, -retentionDays)
+
+	purged, lastID := 0, 0
+	for {
+		devices, err := s.deviceRepo.GetDeletedDevicesBefore(before, lastID, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+		for _, device := range devices {
+			lastID = device.ID
+			if err := s.PurgeDevice(device.ID, device.UserID); err != nil {
+				logs.Warn("清理回收站设备失败: device=%d, err=%v", device.ID, err)
+				continue
+			}
+			purged++
+		}
+		if len(devices) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
@@ -3,27 +3,64 @@ package service
 import (
 	"Backend_Lili/internal/device/model"
 	"Backend_Lili/internal/device/repository"
+	reminderService "Backend_Lili/internal/reminder/service"
+	"Backend_Lili/pkg/imageproc"
+	"Backend_Lili/pkg/storage"
 	"Backend_Lili/pkg/utils"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const (
+	// maxDeviceImageSize 设备图片大小上限
+	maxDeviceImageSize = 5 * 1024 * 1024
+	// maxBatchUploadImages 单次批量上传的图片数量上限
+	maxBatchUploadImages = 20
+	// maxImageCaptionLength 图片说明最大字符数
+	maxImageCaptionLength = 200
 )
 
 type DeviceService struct {
 	deviceRepo   *repository.DeviceRepository
 	categoryRepo *repository.CategoryRepository
-	templateRepo *repository.TemplateRepository
+	templateRepo   *repository.TemplateRepository
+	attachmentRepo *repository.AttachmentRepository
+	recordRepo     *repository.ServiceRecordRepository
+	eventRepo      *repository.DeviceEventRepository
+	costRepo       *repository.CostRepository
+	ruleRepo       *repository.CategoryRuleRepository
+	qrRepo         *repository.DeviceQRCodeRepository
+	reminderSvc    *reminderService.ReminderService
+	templateSvc    *TemplateService
+	blobStore      storage.BlobStore
 }
 
 func NewDeviceService() *DeviceService {
 	return &DeviceService{
 		deviceRepo:   repository.NewDeviceRepository(),
 		categoryRepo: repository.NewCategoryRepository(),
-		templateRepo: repository.NewTemplateRepository(),
+		templateRepo:   repository.NewTemplateRepository(),
+		attachmentRepo: repository.NewAttachmentRepository(),
+		recordRepo:     repository.NewServiceRecordRepository(),
+		eventRepo:      repository.NewDeviceEventRepository(),
+		costRepo:       repository.NewCostRepository(),
+		ruleRepo:       repository.NewCategoryRuleRepository(),
+		qrRepo:         repository.NewDeviceQRCodeRepository(),
+		reminderSvc:    reminderService.NewReminderService(),
+		templateSvc:    NewTemplateService(),
+		blobStore:      storage.Default(),
 	}
 }
 
@@ -34,7 +71,6 @@ func (s *DeviceService) GetDevicesList(userID int, req *GetDevicesListRequest) (
 	}
 
 	// 构建查询参数
-	params := make(map[string]interface{})
 	if req.Page <= 0 {
 		req.Page = 1
 	}
@@ -45,8 +81,47 @@ func (s *DeviceService) GetDevicesList(userID int, req *GetDevicesListRequest) (
 		req.Limit = 100 // 限制最大页大小
 	}
 
+	params, err := deviceListParams(req)
+	if err != nil {
+		return nil, err
+	}
 	params["page"] = req.Page
 	params["limit"] = req.Limit
+
+	devices, total, err := s.deviceRepo.GetDevicesList(userID, params)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备列表失败")
+	}
+
+	// 一次查询加载当前页全部设备的封面图
+	if err := s.fillCoverImages(devices); err != nil {
+		return nil, err
+	}
+
+	// 主设备展示套装汇总价值
+	kits, err := s.deviceRepo.GetKitSummaries(userID, devices)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取套装信息失败")
+	}
+	for _, device := range devices {
+		device.Kit = kits[device.ID]
+	}
+
+	// 计算分页信息
+	totalPages := int((total + int64(req.Limit) - 1) / int64(req.Limit))
+
+	return &GetDevicesListResponse{
+		Devices:    devices,
+		Total:      int(total),
+		Page:       req.Page,
+		Limit:      req.Limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// deviceListParams 把列表筛选与排序条件转为查询参数，不含分页
+func deviceListParams(req *GetDevicesListRequest) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
 	if req.CategoryID > 0 {
 		params["category_id"] = req.CategoryID
 	}
@@ -56,6 +131,19 @@ func (s *DeviceService) GetDevicesList(userID int, req *GetDevicesListRequest) (
 	if req.Search != "" {
 		params["search"] = strings.TrimSpace(req.Search)
 	}
+	if req.ParentID > 0 {
+		params["parent_id"] = req.ParentID
+	}
+	if req.TopLevel {
+		params["top_level"] = true
+	}
+	if strings.TrimSpace(req.Specs) != "" {
+		filters, err := parseSpecFilters(req.Specs)
+		if err != nil {
+			return nil, err
+		}
+		params["spec_filters"] = filters
+	}
 	if req.Sort != "" {
 		// 验证排序字段
 		validSorts := map[string]bool{
@@ -71,22 +159,7 @@ func (s *DeviceService) GetDevicesList(userID int, req *GetDevicesListRequest) (
 			}
 		}
 	}
-
-	devices, total, err := s.deviceRepo.GetDevicesList(userID, params)
-	if err != nil {
-		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备列表失败")
-	}
-
-	// 计算分页信息
-	totalPages := int((total + int64(req.Limit) - 1) / int64(req.Limit))
-
-	return &GetDevicesListResponse{
-		Devices:    devices,
-		Total:      int(total),
-		Page:       req.Page,
-		Limit:      req.Limit,
-		TotalPages: totalPages,
-	}, nil
+	return params, nil
 }
 
 // GetDeviceDetail 获取设备详情
@@ -102,6 +175,24 @@ func (s *DeviceService) GetDeviceDetail(deviceID, userID int) (*model.Device, er
 	if device == nil {
 		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
 	}
+	fillImageURLs(device.Images...)
+	fillAttachmentURLs(device.Attachments...)
+
+	ownership, err := s.GetOwnershipCost(device)
+	if err != nil {
+		return nil, err
+	}
+	device.Ownership = ownership
+
+	if len(device.Accessories) > 0 {
+		device.Kit = &model.KitSummary{
+			TotalPurchasePrice: device.PurchasePrice,
+			TotalCurrentValue:  device.CurrentValue,
+		}
+		for _, accessory := range device.Accessories {
+			device.Kit.AddAccessory(accessory)
+		}
+	}
 
 	// 获取价格历史，用于计算趋势
 	priceHistories, err := s.deviceRepo.GetPriceHistory(deviceID, userID, 10)
@@ -119,6 +210,16 @@ func (s *DeviceService) CreateDevice(userID int, req *CreateDeviceRequest) (*mod
 		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
 	}
 
+	// 未指定分类或模板时按分类规则填充
+	var suggestions []*model.CategorySuggestion
+	if req.CategoryID <= 0 || req.TemplateID <= 0 {
+		categorizer, err := s.newCategorizer(userID, nil)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = categorizer.apply(req)
+	}
+
 	// 验证必填参数
 	if err := s.validateCreateDeviceRequest(req); err != nil {
 		return nil, err
@@ -129,7 +230,7 @@ func (s *DeviceService) CreateDevice(userID int, req *CreateDeviceRequest) (*mod
 	if err != nil {
 		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "查询设备模板失败")
 	}
-	if template == nil {
+	if template == nil || !canViewTemplate(template, userID) {
 		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备模板不存在")
 	}
 
@@ -141,6 +242,12 @@ func (s *DeviceService) CreateDevice(userID int, req *CreateDeviceRequest) (*mod
 		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备分类不存在")
 	}
 
+	// 验证所属套装
+	parentID, err := s.resolveParent(0, userID, req.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
 	// 解析日期
 	purchaseDate, err := time.Parse("2006-01-02", req.PurchaseDate)
 	if err != nil {
@@ -152,6 +259,7 @@ func (s *DeviceService) CreateDevice(userID int, req *CreateDeviceRequest) (*mod
 		UserID:        userID,
 		TemplateID:    &req.TemplateID,
 		CategoryID:    &req.CategoryID,
+		ParentID:      parentID,
 		Name:          req.Name,
 		Brand:         req.Brand,
 		Model:         req.Model,
@@ -178,13 +286,23 @@ func (s *DeviceService) CreateDevice(userID int, req *CreateDeviceRequest) (*mod
 		device.WarrantyDate = warrantyDate
 	}
 
-	// 处理规格参数
-	if req.Specifications != nil {
-		specJSON, err := json.Marshal(req.Specifications)
-		if err != nil {
-			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "规格参数格式错误")
+	// 按模板当前字段版本校验规格参数
+	device.TemplateVersion = template.FieldsVersion
+	if device.Specifications, err = s.normalizeDeviceSpecs(template, device.TemplateVersion, 0, userID, req.Specifications); err != nil {
+		return nil, err
+	}
+
+	// 校验标签
+	tagIDs, err := s.resolveTagIDs(userID, req.TagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查疑似重复设备
+	if !req.Force {
+		if err := s.checkDuplicates(userID, device); err != nil {
+			return nil, err
 		}
-		device.Specifications = string(specJSON)
 	}
 
 	// 创建设备
@@ -192,6 +310,17 @@ func (s *DeviceService) CreateDevice(userID int, req *CreateDeviceRequest) (*mod
 	if err != nil {
 		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "创建设备失败")
 	}
+	s.templateSvc.syncSpecIndex(device, template)
+	s.syncWarrantyReminder(device)
+	s.recordEvent(device.ID, userID, model.DeviceEventCreated, model.DiffDevices(nil, device), nil)
+
+	if len(tagIDs) > 0 {
+		if err := s.deviceRepo.SetDeviceTags(device.ID, tagIDs); err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "保存设备标签失败")
+		}
+		device.TagIDs = tagIDs
+	}
+	device.Suggestions = suggestions
 
 	// 注意：价格历史功能应该在价格模块中实现
 	// 暂时跳过添加价格历史记录，避免循环依赖
@@ -213,6 +342,10 @@ func (s *DeviceService) UpdateDevice(deviceID, userID int, req *UpdateDeviceRequ
 	if device == nil {
 		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
 	}
+	if req.Version != nil && *req.Version != device.Version {
+		return nil, deviceConflictError(device)
+	}
+	before := *device
 
 	// 更新字段
 	if req.Name != "" {
@@ -265,19 +398,56 @@ func (s *DeviceService) UpdateDevice(deviceID, userID int, req *UpdateDeviceRequ
 	if req.Notes != "" {
 		device.Notes = req.Notes
 	}
+	var template *model.DeviceTemplate
 	if req.Specifications != nil {
-		specJSON, err := json.Marshal(req.Specifications)
+		if device.TemplateID != nil {
+			if template, err = s.templateRepo.GetTemplateByID(*device.TemplateID); err != nil {
+				return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "查询设备模板失败")
+			}
+		}
+		// 按设备所用的模板字段版本校验，版本未知时使用模板当前版本
+		if template != nil && device.TemplateVersion == 0 {
+			device.TemplateVersion = template.FieldsVersion
+		}
+		if device.Specifications, err = s.normalizeDeviceSpecs(template, device.TemplateVersion, deviceID, userID, req.Specifications); err != nil {
+			return nil, err
+		}
+	}
+	if req.ParentID != nil {
+		parentID, err := s.resolveParent(deviceID, userID, req.ParentID)
 		if err != nil {
-			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "规格参数格式错误")
+			return nil, err
+		}
+		device.ParentID = parentID
+	}
+	var tagIDs []int
+	if req.TagIDs != nil {
+		if tagIDs, err = s.resolveTagIDs(userID, *req.TagIDs); err != nil {
+			return nil, err
 		}
-		device.Specifications = string(specJSON)
 	}
 
 	// 更新设备
 	err = s.deviceRepo.UpdateDevice(device)
+	if err == repository.ErrVersionConflict {
+		return nil, s.reloadDeviceConflict(deviceID, userID)
+	}
 	if err != nil {
 		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "更新设备失败")
 	}
+	if req.TagIDs != nil {
+		if err := s.deviceRepo.SetDeviceTags(deviceID, tagIDs); err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "保存设备标签失败")
+		}
+		device.TagIDs = tagIDs
+	}
+	if req.Specifications != nil {
+		s.templateSvc.syncSpecIndex(device, template)
+	}
+	s.syncWarrantyReminder(device)
+	if changes := model.DiffDevices(&before, device); len(changes) > 0 {
+		s.recordEvent(deviceID, userID, model.DeviceEventUpdated, changes, nil)
+	}
 
 	return device, nil
 }
@@ -302,14 +472,31 @@ func (s *DeviceService) DeleteDevice(deviceID, userID int) error {
 	if err != nil {
 		return utils.NewBusinessError(utils.ERROR_DATABASE, "删除设备失败")
 	}
+	s.recordEvent(deviceID, userID, model.DeviceEventDeleted, nil, nil)
 
 	return nil
 }
 
-// UpdateDeviceStatus 更新设备状态
-func (s *DeviceService) UpdateDeviceStatus(deviceID, userID int, req *UpdateDeviceStatusRequest) error {
+// deviceConflictError 版本冲突时返回的业务异常，附带设备的最新状态
+func deviceConflictError(current *model.Device) error {
+	return utils.NewBusinessErrorWithDetails(utils.ERROR_CONFLICT, "设备已被修改，请刷新后重试", map[string]interface{}{
+		"current": current,
+	})
+}
+
+// reloadDeviceConflict 写入时发现版本冲突，重新读取设备并返回冲突异常
+func (s *DeviceService) reloadDeviceConflict(deviceID, userID int) error {
+	current, err := s.deviceRepo.GetDeviceByID(deviceID, userID)
+	if err != nil || current == nil {
+		return utils.NewBusinessError(utils.ERROR_CONFLICT, "设备已被修改，请刷新后重试")
+	}
+	return deviceConflictError(current)
+}
+
+// UpdateDeviceStatus 更新设备状态，返回更新后的设备
+func (s *DeviceService) UpdateDeviceStatus(deviceID, userID int, req *UpdateDeviceStatusRequest) (*model.Device, error) {
 	if deviceID <= 0 || userID <= 0 {
-		return utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
 	}
 
 	// 验证状态值
@@ -320,7 +507,7 @@ func (s *DeviceService) UpdateDeviceStatus(deviceID, userID int, req *UpdateDevi
 		"lost":   true,
 	}
 	if !validStatuses[req.Status] {
-		return utils.NewBusinessError(utils.ERROR_PARAM, "无效的设备状态")
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "无效的设备状态")
 	}
 
 	var salePrice *float64
@@ -329,28 +516,87 @@ func (s *DeviceService) UpdateDeviceStatus(deviceID, userID int, req *UpdateDevi
 	// 如果是出售状态，验证出售信息
 	if req.Status == "sold" {
 		if req.SalePrice <= 0 {
-			return utils.NewBusinessError(utils.ERROR_PARAM, "出售价格必须大于0")
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "出售价格必须大于0")
 		}
 		if req.SaleDate == "" {
-			return utils.NewBusinessError(utils.ERROR_PARAM, "出售日期不能为空")
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "出售日期不能为空")
 		}
 
 		parsedSaleDate, err := time.Parse("2006-01-02", req.SaleDate)
 		if err != nil {
-			return utils.NewBusinessError(utils.ERROR_PARAM, "出售日期格式错误")
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "出售日期格式错误")
 		}
 
 		salePrice = &req.SalePrice
 		saleDate = &parsedSaleDate
 	}
 
+	device, err := s.deviceRepo.GetDeviceByID(deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	if device == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+	}
+	if req.Version != nil && *req.Version != device.Version {
+		return nil, deviceConflictError(device)
+	}
+	version := device.Version
+	before := *device
+
+	// 级联时只变更与主设备原状态相同的配件，已单独处理过的配件保持不变
+	var cascaded []*model.Device
+	if req.Cascade {
+		for _, accessory := range device.Accessories {
+			if accessory.Status == device.Status && accessory.Status != req.Status {
+				cascaded = append(cascaded, accessory)
+			}
+		}
+	}
+
 	// 更新设备状态
-	err := s.deviceRepo.UpdateDeviceStatus(deviceID, userID, req.Status, salePrice, saleDate, req.Notes)
+	if len(cascaded) > 0 {
+		accessoryIDs := make([]int, 0, len(cascaded))
+		for _, accessory := range cascaded {
+			accessoryIDs = append(accessoryIDs, accessory.ID)
+		}
+		err = s.deviceRepo.UpdateKitStatus(deviceID, userID, version, req.Status, salePrice, saleDate, req.Notes, accessoryIDs)
+	} else {
+		err = s.deviceRepo.UpdateDeviceStatus(deviceID, userID, version, req.Status, salePrice, saleDate, req.Notes)
+	}
+	if err == repository.ErrVersionConflict {
+		return nil, s.reloadDeviceConflict(deviceID, userID)
+	}
 	if err != nil {
-		return utils.NewBusinessError(utils.ERROR_DATABASE, "更新设备状态失败")
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "更新设备状态失败")
 	}
 
-	return nil
+	for _, accessory := range cascaded {
+		accessoryBefore := *accessory
+		accessory.Status = req.Status
+		accessory.Version++
+		if saleDate != nil {
+			accessory.SaleDate = *saleDate
+		}
+		s.recordEvent(accessory.ID, userID, model.DeviceEventStatusChanged, model.DiffDevices(&accessoryBefore, accessory), map[string]interface{}{"kit_parent_id": deviceID})
+	}
+
+	device.Status = req.Status
+	if salePrice != nil {
+		device.SalePrice = *salePrice
+	}
+	if saleDate != nil {
+		device.SaleDate = *saleDate
+	}
+	if req.Notes != "" {
+		device.Notes = req.Notes
+	}
+	device.Version = version + 1
+	if changes := model.DiffDevices(&before, device); len(changes) > 0 {
+		s.recordEvent(deviceID, userID, model.DeviceEventStatusChanged, changes, nil)
+	}
+
+	return device, nil
 }
 
 // GetDeviceValuation 获取设备价值评估
@@ -394,6 +640,19 @@ func (s *DeviceService) GetDeviceValuation(deviceID, userID int) (*DeviceValuati
 	// 计算日均贬值
 	dailyDepreciation := depreciation / float64(holdingDays)
 
+	// 维修保养费用计入总投入，保修范围内的费用不计
+	serviceSummary, err := s.recordRepo.GetServiceCostSummary(deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取维修保养费用失败")
+	}
+
+	// 持有总成本，总投入包含配件、订阅与平台费用
+	ownership, err := s.GetOwnershipCost(device)
+	if err != nil {
+		return nil, err
+	}
+	totalInvestment := ownership.TotalCost + ownership.ResidualValue
+
 	return &DeviceValuationResponse{
 		DeviceID:          deviceID,
 		PurchasePrice:     device.PurchasePrice,
@@ -402,6 +661,11 @@ func (s *DeviceService) GetDeviceValuation(deviceID, userID int) (*DeviceValuati
 		DepreciationRate:  depreciationRate,
 		HoldingDays:       holdingDays,
 		DailyDepreciation: dailyDepreciation,
+		ServiceCost:       serviceSummary.TotalCost,
+		ServiceCount:      serviceSummary.RecordCount,
+		TotalInvestment:   totalInvestment,
+		NetCost:           ownership.TotalCost,
+		OwnershipCost:     ownership,
 		LastUpdateTime:    time.Now(),
 		PriceHistories:    priceHistories,
 	}, nil
@@ -419,78 +683,56 @@ func (s *DeviceService) BatchImportDevices(userID int, req *BatchImportDevicesRe
 		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "批量导入设备数量不能超过100个")
 	}
 
-	var devices []*model.Device
-	var errors []string
-
-	for i, deviceReq := range req.Devices {
-		// 验证每个设备
-		if err := s.validateCreateDeviceRequest(&deviceReq); err != nil {
-			errors = append(errors, "第"+strconv.Itoa(i+1)+"个设备: "+err.Error())
-			continue
+	serials := make([]string, 0, len(req.Devices))
+	for _, deviceReq := range req.Devices {
+		if serial := strings.TrimSpace(deviceReq.SerialNumber); serial != "" {
+			serials = append(serials, serial)
 		}
+	}
+	ctx, err := s.newImportContext(userID, req.IgnoreDuplicates, serials)
+	if err != nil {
+		return nil, err
+	}
 
-		// 解析日期
-		purchaseDate, err := time.Parse("2006-01-02", deviceReq.PurchaseDate)
-		if err != nil {
-			errors = append(errors, "第"+strconv.Itoa(i+1)+"个设备: 购买日期格式错误")
+	var devices []*model.Device
+	var imported []*ImportRowResult
+	var errors []string
+	rows := make([]*ImportRowResult, 0, len(req.Devices))
+
+	for i := range req.Devices {
+		result := &ImportRowResult{Row: i + 1, Device: &req.Devices[i]}
+		rows = append(rows, result)
+		if device := s.applyImportRow(result, &req.Devices[i], ctx); device != nil {
+			devices = append(devices, device)
+			imported = append(imported, result)
 			continue
 		}
-
-		device := &model.Device{
-			UserID:        userID,
-			TemplateID:    &deviceReq.TemplateID,
-			CategoryID:    &deviceReq.CategoryID,
-			Name:          deviceReq.Name,
-			Brand:         deviceReq.Brand,
-			Model:         deviceReq.Model,
-			SerialNumber:  deviceReq.SerialNumber,
-			Color:         deviceReq.Color,
-			Storage:       deviceReq.Storage,
-			Memory:        deviceReq.Memory,
-			Processor:     deviceReq.Processor,
-			ScreenSize:    deviceReq.ScreenSize,
-			PurchasePrice: deviceReq.PurchasePrice,
-			CurrentValue:  deviceReq.PurchasePrice,
-			PurchaseDate:  purchaseDate,
-			Condition:     deviceReq.Condition,
-			Status:        "active",
-			Notes:         deviceReq.Notes,
-		}
-
-		// 处理保修日期
-		if deviceReq.WarrantyDate != "" {
-			warrantyDate, err := time.Parse("2006-01-02", deviceReq.WarrantyDate)
-			if err != nil {
-				errors = append(errors, "第"+strconv.Itoa(i+1)+"个设备: 保修日期格式错误")
-				continue
-			}
-			device.WarrantyDate = warrantyDate
-		}
-
-		// 处理规格参数
-		if deviceReq.Specifications != nil {
-			specJSON, err := json.Marshal(deviceReq.Specifications)
-			if err != nil {
-				errors = append(errors, "第"+strconv.Itoa(i+1)+"个设备: 规格参数格式错误")
-				continue
+		if result.Status == ImportRowInvalid {
+			for _, fieldErr := range result.Errors {
+				errors = append(errors, "第"+strconv.Itoa(i+1)+"个设备: "+fieldErr.Message)
 			}
-			device.Specifications = string(specJSON)
 		}
+	}
 
-		devices = append(devices, device)
+	// 有效设备在同一事务中写入
+	if err := s.commitImportDevices(userID, devices, imported, "import"); err != nil {
+		return nil, err
 	}
 
-	// 批量导入
-	successCount, err := s.deviceRepo.BatchImportDevices(devices)
-	if err != nil {
-		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "批量导入设备失败")
+	skippedCount := 0
+	for _, result := range rows {
+		if result.Status == ImportRowSkipped {
+			skippedCount++
+		}
 	}
 
 	return &BatchImportDevicesResponse{
 		TotalCount:   len(req.Devices),
-		SuccessCount: successCount,
-		FailCount:    len(req.Devices) - successCount,
+		SuccessCount: len(devices),
+		FailCount:    len(req.Devices) - len(devices) - skippedCount,
+		SkippedCount: skippedCount,
 		Errors:       errors,
+		Rows:         rows,
 	}, nil
 }
 
@@ -504,6 +746,7 @@ func (s *DeviceService) GetDeviceImages(deviceID, userID int) ([]*model.DeviceIm
 	if err != nil {
 		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备图片失败")
 	}
+	fillImageURLs(images...)
 
 	return images, nil
 }
@@ -539,37 +782,321 @@ func (s *DeviceService) UploadDeviceImage(deviceID, userID int, file multipart.F
 		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "不支持的文件类型，仅支持 JPEG、PNG、GIF 格式")
 	}
 
+	if req.ImageType == "" {
+		req.ImageType = "normal"
+	}
+	if req.ImageType != "normal" && req.ImageType != "cover" {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "图片类型只能为 normal 或 cover")
+	}
+	if utf8.RuneCountInString(req.Caption) > maxImageCaptionLength {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "图片说明不能超过200个字符")
+	}
+
 	// 验证文件大小 (限制5MB)
-	if fileHeader.Size > 5*1024*1024 {
+	if fileHeader.Size > maxDeviceImageSize {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "文件大小不能超过 5MB")
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxDeviceImageSize+1))
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "读取上传文件失败")
+	}
+	if len(data) > maxDeviceImageSize {
 		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "文件大小不能超过 5MB")
 	}
 
-	// 这里应该实现文件保存到云存储或本地存储的逻辑
-	// 暂时使用简单的URL生成模拟
-	timestamp := time.Now().Unix()
-	fileName := fmt.Sprintf("device_%d_%d_%s", deviceID, timestamp, fileHeader.Filename)
-	imageURL := fmt.Sprintf("/uploads/devices/%s", fileName)
+	// 校验内容、去除EXIF并生成缩略图
+	processed, err := imageproc.Process(data, ext, imageproc.DefaultVariants)
+	switch err {
+	case nil:
+	case imageproc.ErrFormatMismatch:
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "文件内容与扩展名不一致")
+	case imageproc.ErrUnsupportedFormat:
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "无法识别的图片文件")
+	case imageproc.ErrImageTooLarge:
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "图片分辨率过大")
+	default:
+		logs.Error("处理设备图片失败: device=%d, err=%v", deviceID, err)
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "处理图片失败")
+	}
 
-	// 创建图片记录
-	deviceImage := &model.DeviceImage{
-		DeviceID:  deviceID,
-		ImageURL:  imageURL,
-		ImageType: req.ImageType,
-		SortOrder: req.SortOrder,
+	// 同一设备重复上传相同图片时直接返回已有记录
+	existing, err := s.deviceRepo.GetDeviceImageByHash(deviceID, processed.Hash)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取图片信息失败")
+	}
+	if existing != nil {
+		resp := newUploadDeviceImageResponse(existing)
+		resp.Duplicate = true
+		return resp, nil
 	}
 
-	// 保存到数据库
-	err = s.deviceRepo.AddDeviceImage(deviceImage)
+	deviceImage := &model.DeviceImage{
+		DeviceID:    deviceID,
+		ContentHash: processed.Hash,
+		Caption:     req.Caption,
+		ImageType:   req.ImageType,
+		SortOrder:   req.SortOrder,
+	}
+
+	// 其他设备已存储相同内容时复用文件，否则写入对象存储；保存记录失败时清理本次写入的文件
+	var storedKeys []string
+	var storeErr error
+	err = s.deviceRepo.AddSharedDeviceImage(deviceImage, func() error {
+		storedKeys, storeErr = s.storeProcessedImage(deviceImage, processed)
+		return storeErr
+	})
 	if err != nil {
+		s.removeBlobs(storedKeys...)
+		if storeErr != nil {
+			logs.Error("保存设备图片失败: device=%d, hash=%s, err=%v", deviceID, processed.Hash, storeErr)
+			return nil, utils.NewBusinessError(utils.ERROR_SERVER, "保存图片文件失败")
+		}
 		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "保存图片记录失败")
 	}
 
-	return &UploadDeviceImageResponse{
-		ImageID:   deviceImage.ID,
-		ImageURL:  deviceImage.ImageURL,
-		ImageType: deviceImage.ImageType,
-		SortOrder: deviceImage.SortOrder,
-	}, nil
+	// 访问地址依赖图片ID，插入后再回写
+	deviceImage.ImageURL = deviceImageFileURL(deviceID, deviceImage.ID)
+	if err := s.deviceRepo.UpdateDeviceImageURL(deviceImage.ID, deviceImage.ImageURL); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "保存图片记录失败")
+	}
+
+	// 保证每台设备只有一张封面
+	if deviceImage.ImageType == "cover" {
+		if err := s.deviceRepo.SetDeviceCoverImage(deviceID, deviceImage.ID); err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "设置封面失败")
+		}
+	}
+	s.recordEvent(deviceID, userID, model.DeviceEventImageAdded, map[string]*model.FieldChange{
+		"image_id": {New: deviceImage.ID},
+	}, map[string]interface{}{"image_type": deviceImage.ImageType, "caption": deviceImage.Caption})
+
+	return newUploadDeviceImageResponse(deviceImage), nil
+}
+
+// UploadDeviceImages 批量上传设备图片，逐个文件返回结果，单个文件失败不影响其他文件
+func (s *DeviceService) UploadDeviceImages(deviceID, userID int, fileHeaders []*multipart.FileHeader, req *UploadDeviceImageRequest) (*BatchUploadDeviceImagesResponse, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if len(fileHeaders) == 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "请选择要上传的图片")
+	}
+	if len(fileHeaders) > maxBatchUploadImages {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("单次最多上传%d张图片", maxBatchUploadImages))
+	}
+
+	device, err := s.deviceRepo.GetDeviceByID(deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	if device == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+	}
+
+	// 新图片排在已有图片之后
+	nextOrder := req.SortOrder
+	for _, image := range device.Images {
+		if image.SortOrder >= nextOrder {
+			nextOrder = image.SortOrder + 1
+		}
+	}
+
+	response := &BatchUploadDeviceImagesResponse{
+		Results: make([]*UploadDeviceImageResult, 0, len(fileHeaders)),
+	}
+	for i, fileHeader := range fileHeaders {
+		result := &UploadDeviceImageResult{Filename: fileHeader.Filename}
+		// 只有第一张可以按请求设为封面
+		itemReq := &UploadDeviceImageRequest{
+			ImageType: "normal",
+			SortOrder: nextOrder,
+			Caption:   req.Caption,
+		}
+		if i == 0 {
+			itemReq.ImageType = req.ImageType
+		}
+
+		image, err := s.uploadImageFile(deviceID, userID, fileHeader, itemReq)
+		if err != nil {
+			result.Error = "上传失败"
+			if businessErr, ok := err.(*utils.BusinessError); ok {
+				result.Error = businessErr.Message
+			}
+			response.FailCount++
+		} else {
+			result.Success = true
+			result.Image = image
+			response.SuccessCount++
+			if !image.Duplicate {
+				nextOrder++
+			}
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	return response, nil
+}
+
+// uploadImageFile 打开并上传单个文件
+func (s *DeviceService) uploadImageFile(deviceID, userID int, fileHeader *multipart.FileHeader, req *UploadDeviceImageRequest) (*UploadDeviceImageResponse, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "读取上传文件失败")
+	}
+	defer file.Close()
+	return s.UploadDeviceImage(deviceID, userID, file, fileHeader, req)
+}
+
+// UpdateDeviceImage 修改图片说明
+func (s *DeviceService) UpdateDeviceImage(imageID, deviceID, userID int, req *UpdateDeviceImageRequest) (*model.DeviceImage, error) {
+	if imageID <= 0 || deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if req.Caption == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "没有需要更新的字段")
+	}
+	caption := strings.TrimSpace(*req.Caption)
+	if utf8.RuneCountInString(caption) > maxImageCaptionLength {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "图片说明不能超过200个字符")
+	}
+
+	image, err := s.deviceRepo.GetDeviceImageByID(imageID, deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取图片信息失败")
+	}
+	if image == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "图片不存在")
+	}
+
+	if err := s.deviceRepo.UpdateDeviceImageCaption(imageID, caption); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "更新图片说明失败")
+	}
+	if image.Caption != caption {
+		s.recordEvent(deviceID, userID, model.DeviceEventImageUpdated, map[string]*model.FieldChange{
+			"caption": {Old: image.Caption, New: caption},
+		}, map[string]interface{}{"image_id": imageID})
+	}
+	image.Caption = caption
+	fillImageURLs(image)
+
+	return image, nil
+}
+
+// ReorderDeviceImages 调整设备图片顺序，需提交设备的全部图片ID
+func (s *DeviceService) ReorderDeviceImages(deviceID, userID int, req *ReorderDeviceImagesRequest) ([]*model.DeviceImage, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if len(req.ImageIDs) == 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "图片ID列表不能为空")
+	}
+
+	device, err := s.deviceRepo.GetDeviceByID(deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	if device == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+	}
+
+	err = s.deviceRepo.ReorderDeviceImages(deviceID, req.ImageIDs)
+	if err == repository.ErrImageSetMismatch {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "图片ID列表必须包含设备的全部图片且不能重复")
+	}
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "调整图片顺序失败")
+	}
+
+	oldOrder := make([]int, 0, len(device.Images))
+	for _, image := range device.Images {
+		oldOrder = append(oldOrder, image.ID)
+	}
+	s.recordEvent(deviceID, userID, model.DeviceEventImageUpdated, map[string]*model.FieldChange{
+		"image_order": {Old: oldOrder, New: req.ImageIDs},
+	}, nil)
+
+	return s.GetDeviceImages(deviceID, userID)
+}
+
+// SetDeviceCoverImage 设置设备封面图
+func (s *DeviceService) SetDeviceCoverImage(imageID, deviceID, userID int) error {
+	if imageID <= 0 || deviceID <= 0 || userID <= 0 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+
+	image, err := s.deviceRepo.GetDeviceImageByID(imageID, deviceID, userID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取图片信息失败")
+	}
+	if image == nil {
+		return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "图片不存在")
+	}
+
+	images, err := s.deviceRepo.GetDeviceImages(deviceID, userID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取图片信息失败")
+	}
+	var oldCover interface{}
+	for _, item := range images {
+		if item.ImageType == "cover" {
+			oldCover = item.ID
+		}
+	}
+
+	if err := s.deviceRepo.SetDeviceCoverImage(deviceID, imageID); err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "设置封面失败")
+	}
+	if oldCover != imageID {
+		s.recordEvent(deviceID, userID, model.DeviceEventImageUpdated, map[string]*model.FieldChange{
+			"cover_image_id": {Old: oldCover, New: imageID},
+		}, nil)
+	}
+	return nil
+}
+
+// storeProcessedImage 写入原图及缩略图，返回已写入的存储键（失败时用于清理）；
+// 存储键为 images/{哈希前2位}/{哈希}/{随机串}/，每次写入互不覆盖，释放旧文件时不会删掉新写入的相同内容
+func (s *DeviceService) storeProcessedImage(deviceImage *model.DeviceImage, processed *imageproc.Result) ([]string, error) {
+	var keys []string
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return keys, err
+	}
+	prefix := fmt.Sprintf("images/%s/%s/%s", processed.Hash[:2], processed.Hash, hex.EncodeToString(buf))
+
+	original := processed.Original
+	key := prefix + "/original" + original.Ext
+	if err := s.blobStore.Put(key, bytes.NewReader(original.Data), int64(len(original.Data)), original.ContentType); err != nil {
+		return keys, err
+	}
+	keys = append(keys, key)
+
+	variants := make(map[string]*model.ImageVariant, len(processed.Variants))
+	for _, v := range processed.Variants {
+		vkey := prefix + "/" + v.Name + v.Ext
+		if err := s.blobStore.Put(vkey, bytes.NewReader(v.Data), int64(len(v.Data)), v.ContentType); err != nil {
+			return keys, err
+		}
+		keys = append(keys, vkey)
+		variants[v.Name] = &model.ImageVariant{
+			Key:      vkey,
+			Width:    v.Width,
+			Height:   v.Height,
+			ByteSize: int64(len(v.Data)),
+		}
+	}
+	variantsJSON, err := json.Marshal(variants)
+	if err != nil {
+		return keys, err
+	}
+
+	deviceImage.StorageKey = key
+	deviceImage.ContentType = original.ContentType
+	deviceImage.Width = original.Width
+	deviceImage.Height = original.Height
+	deviceImage.ByteSize = int64(len(original.Data))
+	deviceImage.Variants = string(variantsJSON)
+	return keys, nil
 }
 
 // DeleteDeviceImage 删除设备图片
@@ -578,15 +1105,242 @@ func (s *DeviceService) DeleteDeviceImage(imageID, deviceID, userID int) error {
 		return utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
 	}
 
+	image, err := s.deviceRepo.GetDeviceImageByID(imageID, deviceID, userID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取图片信息失败")
+	}
+	if image == nil {
+		return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "图片不存在")
+	}
+
 	// 删除图片记录
-	err := s.deviceRepo.DeleteDeviceImage(imageID, deviceID, userID)
+	err = s.deviceRepo.DeleteDeviceImage(imageID, deviceID, userID)
 	if err != nil {
 		return utils.NewBusinessError(utils.ERROR_DATABASE, "删除图片失败")
 	}
+	s.recordEvent(deviceID, userID, model.DeviceEventImageRemoved, map[string]*model.FieldChange{
+		"image_id": {Old: imageID},
+	}, map[string]interface{}{"image_type": image.ImageType, "caption": image.Caption})
 
+	// 记录删除成功后再删除文件，文件删除失败只留下孤立对象，不影响数据一致性
+	s.releaseImageBlobs(image)
+
+	return nil
+}
+
+// OpenDeviceImage 打开设备图片文件，size 为空时返回原图，否则返回对应尺寸的缩略图
+// 调用方负责关闭返回的 ReadCloser
+func (s *DeviceService) OpenDeviceImage(imageID, deviceID, userID int, size string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	if imageID <= 0 || deviceID <= 0 || userID <= 0 {
+		return nil, nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+
+	image, err := s.deviceRepo.GetDeviceImageByID(imageID, deviceID, userID)
+	if err != nil {
+		return nil, nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取图片信息失败")
+	}
+	if image == nil || image.StorageKey == "" {
+		return nil, nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "图片不存在")
+	}
+
+	key := image.StorageKey
+	if size != "" && size != "original" {
+		variant, ok := image.VariantMap()[size]
+		if !ok {
+			return nil, nil, utils.NewBusinessError(utils.ERROR_PARAM, "不支持的图片尺寸")
+		}
+		key = variant.Key
+	}
+
+	reader, info, err := s.blobStore.Get(key)
+	if err == storage.ErrNotFound {
+		return nil, nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "图片文件不存在")
+	}
+	if err != nil {
+		logs.Error("读取设备图片失败: image=%d, key=%s, err=%v", imageID, key, err)
+		return nil, nil, utils.NewBusinessError(utils.ERROR_SERVER, "读取图片文件失败")
+	}
+
+	return reader, info, nil
+}
+
+// GetDeviceImageURL 获取设备图片的限时访问地址
+// S3兼容存储返回预签名链接；本地存储不支持签名时返回需携带Token的下载接口地址
+func (s *DeviceService) GetDeviceImageURL(imageID, deviceID, userID int, expiresIn int) (*DeviceImageURLResponse, error) {
+	if imageID <= 0 || deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if expiresIn <= 0 {
+		expiresIn = 900
+	}
+	if expiresIn > 7*24*3600 {
+		expiresIn = 7 * 24 * 3600
+	}
+
+	image, err := s.deviceRepo.GetDeviceImageByID(imageID, deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取图片信息失败")
+	}
+	if image == nil || image.StorageKey == "" {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "图片不存在")
+	}
+
+	expires := time.Duration(expiresIn) * time.Second
+	signedURL, err := s.blobStore.SignedURL(image.StorageKey, expires)
+	if err == storage.ErrSignedURLNotSupported {
+		return &DeviceImageURLResponse{
+			ImageID: imageID,
+			URL:     deviceImageFileURL(deviceID, imageID),
+			Signed:  false,
+		}, nil
+	}
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "生成图片访问地址失败")
+	}
+
+	return &DeviceImageURLResponse{
+		ImageID:   imageID,
+		URL:       signedURL,
+		Signed:    true,
+		ExpiresAt: time.Now().Add(expires),
+	}, nil
+}
+
+// PurgeDevice 彻底删除设备，同时删除已存储的图片和附件文件
+func (s *DeviceService) PurgeDevice(deviceID, userID int) error {
+	if deviceID <= 0 || userID <= 0 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+
+	attachments, err := s.attachmentRepo.GetDeviceAttachments(deviceID, userID, "")
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备附件失败")
+	}
+
+	images, err := s.deviceRepo.HardDeleteDevice(deviceID, userID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "彻底删除设备失败")
+	}
+
+	s.releaseImageBlobs(images...)
+	for _, attachment := range attachments {
+		s.removeBlobs(attachment.StorageKey)
+	}
 	return nil
 }
 
+// releaseImageBlobs 删除图片记录后释放文件，仍被其他记录（包括其他用户的图片）引用的文件保留
+func (s *DeviceService) releaseImageBlobs(images ...*model.DeviceImage) {
+	released := make(map[string]bool)
+	for _, image := range images {
+		if image.ContentHash == "" || image.StorageKey == "" {
+			s.removeBlobs(image.StorageKeys()...)
+			continue
+		}
+		if released[image.StorageKey] {
+			continue
+		}
+		released[image.StorageKey] = true
+		err := s.deviceRepo.ReleaseSharedImage(image.ContentHash, image.StorageKey, func() {
+			s.removeBlobs(image.StorageKeys()...)
+		})
+		if err != nil {
+			logs.Warn("释放图片文件失败: image=%d, key=%s, err=%v", image.ID, image.StorageKey, err)
+		}
+	}
+}
+
+// resolveParent 校验套装主设备，套装只支持一层，配件不能再挂配件；parentID为0表示解除关联
+func (s *DeviceService) resolveParent(deviceID, userID int, parentID *int) (*int, error) {
+	if parentID == nil || *parentID == 0 {
+		return nil, nil
+	}
+	if *parentID < 0 || *parentID == deviceID {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "所属主设备无效")
+	}
+
+	parent, err := s.deviceRepo.GetDeviceByID(*parentID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取主设备失败")
+	}
+	if parent == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "主设备不存在")
+	}
+	if parent.ParentID != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "配件不能作为主设备")
+	}
+
+	if deviceID > 0 {
+		count, err := s.deviceRepo.CountAccessories(deviceID, userID)
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取配件信息失败")
+		}
+		if count > 0 {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "已有配件的设备不能归入其他套装")
+		}
+	}
+
+	id := parent.ID
+	return &id, nil
+}
+
+// syncWarrantyReminder 按保修日期维护自动提醒，失败只记录日志，不影响设备保存
+func (s *DeviceService) syncWarrantyReminder(device *model.Device) {
+	if device.ID <= 0 {
+		return
+	}
+	if err := s.reminderSvc.SyncWarrantyReminder(device.UserID, device.ID, device.Name, device.WarrantyDate); err != nil {
+		logs.Warn("同步保修提醒失败: device=%d, err=%v", device.ID, err)
+	}
+}
+
+// removeBlobs 删除存储对象，失败只记录日志
+func (s *DeviceService) removeBlobs(keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.blobStore.Delete(key); err != nil {
+			logs.Warn("删除存储对象失败: key=%s, err=%v", key, err)
+		}
+	}
+}
+
+// deviceImageFileURL 设备图片下载接口地址
+func deviceImageFileURL(deviceID, imageID int) string {
+	return fmt.Sprintf("/api/v1/devices/%d/images/%d/file", deviceID, imageID)
+}
+
+// fillImageURLs 填充各尺寸的访问地址
+func fillImageURLs(images ...*model.DeviceImage) {
+	for _, image := range images {
+		if image == nil || image.StorageKey == "" {
+			continue
+		}
+		base := deviceImageFileURL(image.DeviceID, image.ID)
+		image.URLs = map[string]string{"original": base}
+		for name := range image.VariantMap() {
+			image.URLs[name] = base + "?size=" + name
+		}
+	}
+}
+
+// newUploadDeviceImageResponse 由图片记录构造上传响应
+func newUploadDeviceImageResponse(image *model.DeviceImage) *UploadDeviceImageResponse {
+	fillImageURLs(image)
+	return &UploadDeviceImageResponse{
+		ImageID:   image.ID,
+		ImageURL:  image.ImageURL,
+		ImageType: image.ImageType,
+		SortOrder: image.SortOrder,
+		Caption:   image.Caption,
+		Width:     image.Width,
+		Height:    image.Height,
+		ByteSize:  image.ByteSize,
+		URLs:      image.URLs,
+	}
+}
+
 // PredictDevicePrice 预测设备价格 - 实现简单的价格预测算法
 func (s *DeviceService) PredictDevicePrice(deviceID, userID int, req *PricePredictionRequest) (*PricePredictionResponse, error) {
 	if deviceID <= 0 || userID <= 0 {
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/device/repository"
+	"Backend_Lili/pkg/utils"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// maxSpecFilters 设备列表一次最多使用的规格筛选条件数
+const maxSpecFilters = 10
+
+// specIndexBatchSize 重建规格参数索引时每批读取的设备数量
+const specIndexBatchSize = 200
+
+// specFilterPattern 规格筛选表达式：字段名 比较符 值
+var specFilterPattern = regexp.MustCompile(`^([^<>=!~]+?)\s*(>=|<=|!=|=|>|<|~)\s*(.*)$`)
+
+// specFilterOps 比较符对应的筛选方式
+var specFilterOps = map[string]string{
+	"=":  repository.SpecOpEq,
+	"!=": repository.SpecOpNe,
+	">":  repository.SpecOpGt,
+	">=": repository.SpecOpGte,
+	"<":  repository.SpecOpLt,
+	"<=": repository.SpecOpLte,
+	"~":  repository.SpecOpContains,
+}
+
+// parseSpecFilters 解析设备列表的规格筛选，多个条件以分号分隔且需同时满足，如 memory>=32GB;storage=512GB|1TB；
+// = 与 != 可用 | 分隔多个值，~ 为包含文本
+func parseSpecFilters(text string) ([]*repository.SpecFilter, error) {
+	var filters []*repository.SpecFilter
+	for _, expr := range strings.Split(text, ";") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		match := specFilterPattern.FindStringSubmatch(expr)
+		if match == nil || strings.TrimSpace(match[3]) == "" {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "规格筛选格式错误: "+expr)
+		}
+		filter := &repository.SpecFilter{Field: strings.TrimSpace(match[1]), Op: specFilterOps[match[2]]}
+
+		raw := []string{strings.TrimSpace(match[3])}
+		if filter.Op == repository.SpecOpEq || filter.Op == repository.SpecOpNe {
+			raw = strings.Split(raw[0], "|")
+		}
+		for _, item := range raw {
+			value, err := parseSpecFilterValue(filter.Op, strings.TrimSpace(item))
+			if err != nil {
+				return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("规格筛选 %s: %s", expr, err.Error()))
+			}
+			filter.Values = append(filter.Values, value)
+		}
+		filters = append(filters, filter)
+	}
+	if len(filters) > maxSpecFilters {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("规格筛选条件不能超过%d个", maxSpecFilters))
+	}
+	return filters, nil
+}
+
+// parseSpecFilterValue 解析筛选值：日期、带单位的数量（换算为基准单位）、带币种的金额与纯数字，同时保留原文本；
+// 范围比较只接受数值与日期
+func parseSpecFilterValue(op, text string) (*repository.SpecFilterValue, error) {
+	value := &repository.SpecFilterValue{Text: text}
+	if op == repository.SpecOpContains {
+		return value, nil
+	}
+	if date, ok := specDate(text); ok {
+		value.Date = &date
+		return value, nil
+	}
+
+	if match := quantityPattern.FindStringSubmatch(strings.ReplaceAll(text, ",", "")); match != nil {
+		number, err := strconv.ParseFloat(match[1], 64)
+		if err == nil {
+			switch unit := lookupSpecUnit(match[2]); {
+			case match[2] == "":
+				value.Number = &number
+			case unit != nil:
+				base := roundSignificant(number * unit.Factor)
+				value.Number, value.Dimension = &base, unit.Dimension
+			case currencyPattern.MatchString(strings.ToUpper(match[2])):
+				value.Number, value.Dimension = &number, strings.ToUpper(match[2])
+			case op != repository.SpecOpEq && op != repository.SpecOpNe:
+				return nil, fmt.Errorf("无法识别的单位 %s", match[2])
+			}
+		}
+	}
+	if value.Number == nil && op != repository.SpecOpEq && op != repository.SpecOpNe {
+		return nil, fmt.Errorf("范围比较需要数值或日期")
+	}
+	return value, nil
+}
+
+// roundSignificant 保留12位有效数字，避免单位换算的浮点误差
+func roundSignificant(number float64) float64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(number, 'g', 12, 64), 64)
+	return rounded
+}
+
+// specIndexValues 按模板字段把规格参数转为索引行，字段组的子字段名为 组名.子字段名；图片字段不建索引
+func specIndexValues(fields []*model.TemplateField, specs map[string]interface{}, prefix string) []*model.DeviceSpecValue {
+	var values []*model.DeviceSpecValue
+	for _, field := range fields {
+		value, exists := specs[field.FieldName]
+		if !exists || isEmptySpecValue(value) || field.FieldType == FieldTypeImage {
+			continue
+		}
+		name := prefix + field.FieldName
+
+		if field.FieldType == FieldTypeGroup {
+			list, _ := specArray(value)
+			for _, raw := range list {
+				if item, ok := raw.(map[string]interface{}); ok {
+					values = append(values, specIndexValues(field.Fields, item, name+".")...)
+				}
+			}
+			continue
+		}
+
+		var unit *specUnit
+		if rules, err := parseFieldRules(field); err == nil {
+			unit = rules.unit
+		}
+		for _, row := range specIndexRows(field, unit, value) {
+			row.FieldName = name
+			row.FieldType = field.FieldType
+			if utf8.RuneCountInString(row.ValueText) > 255 {
+				row.ValueText = string([]rune(row.ValueText)[:255])
+			}
+			values = append(values, row)
+		}
+	}
+	return values
+}
+
+// specIndexRows 单个字段值的索引行，多选字段每个选项一行；无法按字段类型解析的历史值按文本索引
+func specIndexRows(field *model.TemplateField, unit *specUnit, value interface{}) []*model.DeviceSpecValue {
+	switch field.FieldType {
+	case FieldTypeNumber:
+		number, ok := specNumber(value)
+		if unit != nil {
+			number, ok = specQuantity(value, unit)
+		}
+		if ok {
+			return []*model.DeviceSpecValue{specNumberRow(number, unit)}
+		}
+	case FieldTypeDate:
+		if text, ok := value.(string); ok {
+			if date, ok := specDate(text); ok {
+				return []*model.DeviceSpecValue{{ValueText: date.Format("2006-01-02"), ValueDate: &date, UnitFactor: 1}}
+			}
+		}
+	case FieldTypeMultiSelect:
+		if items, ok := specStringList(value); ok {
+			rows := make([]*model.DeviceSpecValue, 0, len(items))
+			for _, item := range items {
+				rows = append(rows, specTextRow(item, nil))
+			}
+			return rows
+		}
+	case FieldTypeBoolean:
+		if flag, ok := specBool(value); ok {
+			number := 0.0
+			if flag {
+				number = 1
+			}
+			return []*model.DeviceSpecValue{{ValueText: strconv.FormatBool(flag), ValueNum: &number, UnitFactor: 1}}
+		}
+	case FieldTypeMoney:
+		currency := field.Currency
+		if currency == "" {
+			currency = defaultCurrency
+		}
+		if amount, currency, ok := specMoney(value, currency); ok {
+			return []*model.DeviceSpecValue{{
+				ValueText:  strconv.FormatFloat(amount, 'f', -1, 64) + " " + currency,
+				ValueNum:   &amount,
+				Dimension:  currency,
+				UnitFactor: 1,
+			}}
+		}
+	}
+
+	if text, ok := specText(value); ok && text != "" {
+		return []*model.DeviceSpecValue{specTextRow(text, unit)}
+	}
+	return nil
+}
+
+// specNumberRow 数值索引行，带单位时换算为量纲的基准单位
+func specNumberRow(number float64, unit *specUnit) *model.DeviceSpecValue {
+	row := &model.DeviceSpecValue{ValueText: strconv.FormatFloat(number, 'f', -1, 64), UnitFactor: 1}
+	if unit == nil {
+		row.ValueNum = &number
+		return row
+	}
+	base := roundSignificant(number * unit.Factor)
+	row.ValueText += unit.Name
+	row.ValueNum, row.Dimension, row.UnitFactor = &base, unit.Dimension, unit.Factor
+	return row
+}
+
+// specTextRow 文本索引行；文本是数字或带已知单位的数量（如 512GB）时同时按数值索引
+func specTextRow(text string, unit *specUnit) *model.DeviceSpecValue {
+	if unit != nil {
+		if number, ok := parseQuantity(text, unit); ok {
+			row := specNumberRow(number, unit)
+			row.ValueText = text
+			return row
+		}
+	} else if match := quantityPattern.FindStringSubmatch(strings.ReplaceAll(text, ",", "")); match != nil {
+		if number, err := strconv.ParseFloat(match[1], 64); err == nil {
+			if match[2] == "" {
+				return &model.DeviceSpecValue{ValueText: text, ValueNum: &number, UnitFactor: 1}
+			}
+			if textUnit := lookupSpecUnit(match[2]); textUnit != nil {
+				row := specNumberRow(number, textUnit)
+				row.ValueText = text
+				return row
+			}
+		}
+	}
+	return &model.DeviceSpecValue{ValueText: text, UnitFactor: 1}
+}
+
+// indexDeviceSpecs 按设备所用字段版本重建规格参数索引；template 为空时按设备的模板ID读取
+func (s *TemplateService) indexDeviceSpecs(device *model.Device, template *model.DeviceTemplate) error {
+	if template == nil && device.TemplateID != nil {
+		var err error
+		if template, err = s.templateRepo.GetTemplateByID(*device.TemplateID); err != nil {
+			return err
+		}
+	}
+
+	var values []*model.DeviceSpecValue
+	if template != nil && device.Specifications != "" {
+		fields, err := s.versionFields(template, device.TemplateVersion)
+		if err != nil {
+			return err
+		}
+		var specs map[string]interface{}
+		if err := json.Unmarshal([]byte(device.Specifications), &specs); err != nil {
+			return err
+		}
+		values = specIndexValues(fields, specs, "")
+		for _, value := range values {
+			value.DeviceID = device.ID
+			value.UserID = device.UserID
+			value.TemplateID = template.ID
+		}
+	}
+	return s.deviceRepo.ReplaceDeviceSpecValues(device.ID, values)
+}
+
+// syncSpecIndex 规格参数写入后重建索引，失败只记录日志，可通过重建索引接口补齐
+func (s *TemplateService) syncSpecIndex(device *model.Device, template *model.DeviceTemplate) {
+	if device.ID <= 0 {
+		return
+	}
+	if err := s.indexDeviceSpecs(device, template); err != nil {
+		logs.Warn("更新规格参数索引失败: device=%d, err=%v", device.ID, err)
+	}
+}
+
+// ReindexDeviceSpecs 重建用户全部设备（含回收站）的规格参数索引，用于索引上线前的历史设备与写入失败的补齐
+func (s *DeviceService) ReindexDeviceSpecs(userID int) (*ReindexDeviceSpecsResponse, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+
+	response := &ReindexDeviceSpecsResponse{}
+	templates := make(map[int]*model.DeviceTemplate)
+	for afterID := 0; ; {
+		devices, err := s.deviceRepo.GetDevicesForSpecIndex(userID, afterID, specIndexBatchSize)
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备失败")
+		}
+		for _, device := range devices {
+			afterID = device.ID
+			var template *model.DeviceTemplate
+			if device.TemplateID != nil {
+				if template = templates[*device.TemplateID]; template == nil {
+					if template, err = s.templateRepo.GetTemplateByID(*device.TemplateID); err != nil {
+						return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备模板失败")
+					}
+					templates[*device.TemplateID] = template
+				}
+			}
+			if err := s.templateSvc.indexDeviceSpecs(device, template); err != nil {
+				logs.Warn("重建规格参数索引失败: device=%d, err=%v", device.ID, err)
+				response.FailedDeviceIDs = append(response.FailedDeviceIDs, device.ID)
+				continue
+			}
+			response.Indexed++
+		}
+		if len(devices) < specIndexBatchSize {
+			return response, nil
+		}
+	}
+}
@@ -0,0 +1,635 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/pkg/utils"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 规格参数字段错误原因
+const (
+	SpecErrorRequired = "required" // 必填字段缺失
+	SpecErrorInvalid  = "invalid"  // 值与字段定义不符
+)
+
+// 模板字段类型
+const (
+	FieldTypeText        = "text"
+	FieldTypeTextarea    = "textarea"
+	FieldTypeNumber      = "number"
+	FieldTypeSelect      = "select"
+	FieldTypeDate        = "date"
+	FieldTypeMultiSelect = "multiselect"
+	FieldTypeBoolean     = "boolean"
+	FieldTypeMoney       = "money"
+	FieldTypeURL         = "url"
+	FieldTypeImage       = "image"
+	FieldTypeGroup       = "group"
+)
+
+// TemplateFieldTypes 支持的字段类型及其值格式，随字段定义一起返回给客户端用于选择输入控件
+var TemplateFieldTypes = []*TemplateFieldTypeInfo{
+	{Type: FieldTypeText, Label: "文本", Value: "字符串，不超过1000字符"},
+	{Type: FieldTypeTextarea, Label: "多行文本", Value: "字符串"},
+	{Type: FieldTypeNumber, Label: "数字", Value: "数字；声明了 unit 规则时可提交带单位的数量，保存为该单位的数值"},
+	{Type: FieldTypeSelect, Label: "单选", Value: "options 中的一个值"},
+	{Type: FieldTypeDate, Label: "日期", Value: "YYYY-MM-DD"},
+	{Type: FieldTypeMultiSelect, Label: "多选", Value: "options 中值组成的数组，也可以提交逗号分隔的文本"},
+	{Type: FieldTypeBoolean, Label: "是/否", Value: "true/false，也接受 是/否、1/0"},
+	{Type: FieldTypeMoney, Label: "金额", Value: `{"amount": 数值, "currency": "CNY"}，也可以提交数字或 "USD 12.5" 等文本；options 为可选货币，currency 为默认货币`},
+	{Type: FieldTypeURL, Label: "网址", Value: "http/https 网址"},
+	{Type: FieldTypeImage, Label: "图片", Value: "设备图片的ID，只能引用该设备已上传的图片"},
+	{Type: FieldTypeGroup, Label: "字段组", Value: "对象数组，每一项按 fields 中的子字段填写，可用 min/max 限制项数"},
+}
+
+// defaultCurrency money 字段未指定货币时使用的货币
+const defaultCurrency = "CNY"
+
+// specDateLayouts 日期字段可接受的输入格式，统一保存为 YYYY-MM-DD
+var specDateLayouts = []string{"2006-01-02", "2006/01/02", "2006.01.02", time.RFC3339}
+
+// moneyPattern 金额文本：可选的货币代码或符号、数值、可选的货币代码或"元"
+var moneyPattern = regexp.MustCompile(`^([A-Za-z]{3})?\s*([¥￥$€£])?\s*([+-]?[\d,]*\.?\d+)\s*([A-Za-z]{3}|元)?$`)
+
+// currencySymbols 金额文本中货币符号对应的货币
+var currencySymbols = map[string]string{"¥": "CNY", "￥": "CNY", "元": "CNY", "$": "USD", "€": "EUR", "£": "GBP"}
+
+// currencyPattern ISO 4217 货币代码
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// parseTemplateFields 解析模板当前的字段定义
+func parseTemplateFields(template *model.DeviceTemplate) ([]*model.TemplateField, error) {
+	return parseFieldsJSON(template.Fields)
+}
+
+// parseFieldsJSON 解析JSON格式的字段定义
+func parseFieldsJSON(fieldsJSON string) ([]*model.TemplateField, error) {
+	var fields []*model.TemplateField
+	if fieldsJSON == "" {
+		return fields, nil
+	}
+	if err := json.Unmarshal([]byte(fieldsJSON), &fields); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "解析模板字段失败")
+	}
+	return fields, nil
+}
+
+// NormalizeSpecifications 按模板字段定义校验规格参数：缺失的字段补全默认值，
+// 值转换为字段类型对应的JSON类型（number/image 为数字，boolean 为布尔值，multiselect 为字符串数组，
+// money 为 {amount, currency}，group 为逐项规范化后的对象数组，其余为字符串）；模板未定义的键原样保留
+func (s *TemplateService) NormalizeSpecifications(fields []*model.TemplateField, specs map[string]interface{}) (map[string]interface{}, []*SpecFieldError) {
+	normalized := make(map[string]interface{}, len(specs)+len(fields))
+	for key, value := range specs {
+		normalized[key] = value
+	}
+	labels := make(map[string]string, len(fields))
+	for _, field := range fields {
+		labels[field.FieldName] = field.FieldLabel
+	}
+
+	var errs []*SpecFieldError
+	for _, field := range fields {
+		rules, err := parseFieldRules(field)
+		if err != nil {
+			errs = append(errs, &SpecFieldError{Field: field.FieldName, Label: field.FieldLabel, Reason: SpecErrorInvalid, Message: fmt.Sprintf("字段 %s 验证规则错误", field.FieldLabel)})
+			continue
+		}
+
+		value, exists := specs[field.FieldName]
+		if !exists || isEmptySpecValue(value) {
+			delete(normalized, field.FieldName)
+			if field.DefaultValue != "" {
+				value = field.DefaultValue
+			} else {
+				if required, message := rules.requiredBy(field, specs, labels); required {
+					errs = append(errs, &SpecFieldError{Field: field.FieldName, Label: field.FieldLabel, Reason: SpecErrorRequired, Message: message})
+				}
+				continue
+			}
+		}
+
+		if field.FieldType == FieldTypeGroup {
+			items, itemErrs := s.normalizeGroupItems(field, rules, value)
+			if len(itemErrs) > 0 {
+				errs = append(errs, itemErrs...)
+				continue
+			}
+			normalized[field.FieldName] = items
+			continue
+		}
+
+		coerced, err := s.coerceFieldValue(field, rules, value)
+		if err != nil {
+			errs = append(errs, &SpecFieldError{Field: field.FieldName, Label: field.FieldLabel, Reason: SpecErrorInvalid, Value: value, Message: err.Error()})
+			continue
+		}
+		normalized[field.FieldName] = coerced
+	}
+
+	return normalized, errs
+}
+
+// coerceFieldValue 把单个值转换为字段类型并校验选项与验证规则；
+// 声明了单位的数字字段换算为该单位的数值保存
+func (s *TemplateService) coerceFieldValue(field *model.TemplateField, rules *fieldRules, value interface{}) (interface{}, error) {
+	var coerced interface{}
+	var ruleValue ruleValue
+
+	switch field.FieldType {
+	case FieldTypeNumber:
+		number, ok := specNumber(value)
+		if rules.unit != nil {
+			number, ok = specQuantity(value, rules.unit)
+		}
+		if !ok {
+			if rules.unit != nil {
+				return nil, fmt.Errorf("字段 %s 必须是数字或带单位的数值（如 256%s）", field.FieldLabel, rules.unit.Name)
+			}
+			return nil, fmt.Errorf("字段 %s 必须是数字", field.FieldLabel)
+		}
+		coerced, ruleValue.number = number, number
+		ruleValue.text = strconv.FormatFloat(number, 'f', -1, 64)
+	case FieldTypeDate:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 必须是有效日期格式(YYYY-MM-DD)", field.FieldLabel)
+		}
+		date, ok := specDate(str)
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 必须是有效日期格式(YYYY-MM-DD)", field.FieldLabel)
+		}
+		ruleValue.text, ruleValue.date = date.Format("2006-01-02"), date
+		coerced = ruleValue.text
+	case FieldTypeSelect:
+		str, ok := specText(value)
+		if !ok || !s.contains(field.Options, str) {
+			return nil, fmt.Errorf("字段 %s 的值不在有效选项中", field.FieldLabel)
+		}
+		coerced, ruleValue.text = str, str
+	case FieldTypeMultiSelect:
+		items, ok := specStringList(value)
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 必须是选项数组", field.FieldLabel)
+		}
+		for _, item := range items {
+			if !s.contains(field.Options, item) {
+				return nil, fmt.Errorf("字段 %s 的值 %s 不在有效选项中", field.FieldLabel, item)
+			}
+		}
+		coerced, ruleValue.count = items, len(items)
+		ruleValue.text = strings.Join(items, ",")
+	case FieldTypeBoolean:
+		flag, ok := specBool(value)
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 必须是“是”或“否”", field.FieldLabel)
+		}
+		coerced, ruleValue.text = flag, strconv.FormatBool(flag)
+	case FieldTypeMoney:
+		currency := field.Currency
+		if currency == "" {
+			currency = defaultCurrency
+		}
+		amount, currency, ok := specMoney(value, currency)
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 必须是金额（如 12.5 或 USD 12.5）", field.FieldLabel)
+		}
+		if len(field.Options) > 0 && !s.contains(field.Options, currency) {
+			return nil, fmt.Errorf("字段 %s 的货币 %s 不在可选范围内", field.FieldLabel, currency)
+		}
+		coerced = map[string]interface{}{"amount": amount, "currency": currency}
+		ruleValue.number, ruleValue.text = amount, strconv.FormatFloat(amount, 'f', -1, 64)
+	case FieldTypeURL:
+		str, ok := specText(value)
+		if !ok || !isWebURL(str) {
+			return nil, fmt.Errorf("字段 %s 必须是有效的网址", field.FieldLabel)
+		}
+		coerced, ruleValue.text = str, str
+	case FieldTypeImage:
+		imageID, ok := specImageID(value)
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 必须是图片ID", field.FieldLabel)
+		}
+		coerced, ruleValue.text = imageID, strconv.Itoa(imageID)
+	default:
+		str, ok := specText(value)
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 必须是文本", field.FieldLabel)
+		}
+		if field.FieldType == FieldTypeText && len(str) > 1000 {
+			return nil, fmt.Errorf("字段 %s 长度不能超过1000字符", field.FieldLabel)
+		}
+		if rules.unit != nil {
+			number, ok := parseQuantity(str, rules.unit)
+			if !ok {
+				return nil, fmt.Errorf("字段 %s 必须是带单位的数值（如 256%s）", field.FieldLabel, rules.unit.Name)
+			}
+			ruleValue.number = number
+		}
+		coerced, ruleValue.text = str, str
+	}
+
+	if err := rules.check(field, ruleValue); err != nil {
+		return nil, err
+	}
+	return coerced, nil
+}
+
+// normalizeGroupItems 逐项按子字段规范化字段组，子字段错误的字段名为 组名[序号].子字段名
+func (s *TemplateService) normalizeGroupItems(field *model.TemplateField, rules *fieldRules, value interface{}) ([]interface{}, []*SpecFieldError) {
+	list, ok := specArray(value)
+	if !ok {
+		return nil, []*SpecFieldError{{Field: field.FieldName, Label: field.FieldLabel, Reason: SpecErrorInvalid, Value: value, Message: fmt.Sprintf("字段 %s 必须是对象数组", field.FieldLabel)}}
+	}
+
+	items := make([]interface{}, 0, len(list))
+	var errs []*SpecFieldError
+	for i, raw := range list {
+		item, ok := raw.(map[string]interface{})
+		if !ok {
+			errs = append(errs, &SpecFieldError{Field: fmt.Sprintf("%s[%d]", field.FieldName, i), Label: field.FieldLabel, Reason: SpecErrorInvalid, Value: raw, Message: fmt.Sprintf("%s第%d项必须是对象", field.FieldLabel, i+1)})
+			continue
+		}
+		normalized, itemErrs := s.NormalizeSpecifications(field.Fields, item)
+		for _, itemErr := range itemErrs {
+			itemErr.Field = fmt.Sprintf("%s[%d].%s", field.FieldName, i, itemErr.Field)
+			itemErr.Message = fmt.Sprintf("%s第%d项: %s", field.FieldLabel, i+1, itemErr.Message)
+		}
+		errs = append(errs, itemErrs...)
+		items = append(items, normalized)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	if err := rules.check(field, ruleValue{count: len(items)}); err != nil {
+		return nil, []*SpecFieldError{{Field: field.FieldName, Label: field.FieldLabel, Reason: SpecErrorInvalid, Value: value, Message: err.Error()}}
+	}
+	return items, nil
+}
+
+// isEmptySpecValue nil、空白字符串与空数组视为未填写
+func isEmptySpecValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	case []string:
+		return len(v) == 0
+	}
+	return false
+}
+
+// specArray 接受JSON数组与JSON数组文本（表格导入时为文本）
+func specArray(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, true
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+		return list, true
+	case string:
+		var list []interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(v)), &list); err != nil {
+			return nil, false
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// specStringList 多选值：数组或以逗号、顿号分隔的文本，去掉空项与重复项
+func specStringList(value interface{}) ([]string, bool) {
+	list, ok := specArray(value)
+	if !ok {
+		text, isText := value.(string)
+		if !isText {
+			return nil, false
+		}
+		list = nil
+		for _, item := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == '，' || r == '、' }) {
+			list = append(list, item)
+		}
+	}
+
+	items := make([]string, 0, len(list))
+	seen := make(map[string]bool, len(list))
+	for _, raw := range list {
+		item, ok := specText(raw)
+		if !ok {
+			return nil, false
+		}
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		items = append(items, item)
+	}
+	return items, true
+}
+
+// specBool 接受布尔值、1/0 与常见的是/否文本
+func specBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, true
+		}
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "1", "yes", "y", "是", "有":
+			return true, true
+		case "false", "0", "no", "n", "否", "无", "没有":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// specMoney 接受 {amount, currency} 对象、数字（使用默认货币）与 "USD 12.5"、"¥12.5"、"12.5元" 等文本
+func specMoney(value interface{}, currency string) (float64, string, bool) {
+	var amount float64
+	var ok bool
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if amount, ok = specNumber(v["amount"]); !ok {
+			return 0, "", false
+		}
+		if code, isText := v["currency"].(string); isText && strings.TrimSpace(code) != "" {
+			currency = code
+		}
+	case string:
+		match := moneyPattern.FindStringSubmatch(strings.TrimSpace(v))
+		if match == nil {
+			return 0, "", false
+		}
+		if amount, ok = specNumber(match[3]); !ok {
+			return 0, "", false
+		}
+		for _, code := range []string{match[1], currencySymbols[match[2]], match[4]} {
+			if symbol, isSymbol := currencySymbols[code]; isSymbol {
+				code = symbol
+			}
+			if code != "" {
+				currency = code
+				break
+			}
+		}
+	default:
+		if amount, ok = specNumber(value); !ok {
+			return 0, "", false
+		}
+	}
+
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !currencyPattern.MatchString(currency) {
+		return 0, "", false
+	}
+	return amount, currency, true
+}
+
+// specImageID 图片字段的值为正整数ID
+func specImageID(value interface{}) (int, bool) {
+	number, ok := specNumber(value)
+	if !ok || number <= 0 || number != math.Trunc(number) || number > math.MaxInt32 {
+		return 0, false
+	}
+	return int(number), true
+}
+
+// specNumber 接受JSON数字与数字字符串（允许千分位逗号）
+func specNumber(value interface{}) (float64, bool) {
+	var number float64
+	switch v := value.(type) {
+	case float64:
+		number = v
+	case int:
+		number = float64(v)
+	case int64:
+		number = float64(v)
+	case json.Number:
+		parsed, err := v.Float64()
+		if err != nil {
+			return 0, false
+		}
+		number = parsed
+	case string:
+		parsed, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(v), ",", ""), 64)
+		if err != nil {
+			return 0, false
+		}
+		number = parsed
+	default:
+		return 0, false
+	}
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, false
+	}
+	return number, true
+}
+
+// specDate 按可接受的格式解析日期
+func specDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range specDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// specText 文本字段接受字符串、数字与布尔值，对象和数组不是文本
+func specText(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int, int64, bool, json.Number:
+		return fmt.Sprint(v), true
+	}
+	return "", false
+}
+
+// specValueText 错误值转为文本，便于在导入结果中展示
+func specValueText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// specValidationError 规格参数不符合模板时返回的业务异常，details.fields 为逐字段错误
+func specValidationError(errs []*SpecFieldError) error {
+	return utils.NewBusinessErrorWithDetails(utils.ERROR_PARAM, "规格参数不符合模板要求", map[string]interface{}{
+		"fields": errs,
+	})
+}
+
+// specFieldErrors 取出规格参数校验异常中的逐字段错误，其他异常返回 nil
+func specFieldErrors(err error) []*SpecFieldError {
+	businessErr, ok := err.(*utils.BusinessError)
+	if !ok {
+		return nil
+	}
+	details, ok := businessErr.Details.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	errs, _ := details["fields"].([]*SpecFieldError)
+	return errs
+}
+
+// normalizeDeviceSpecs 按设备模板的指定字段版本校验并规范化规格参数，返回要保存的JSON文本；
+// deviceID 为 0 表示设备尚未创建，此时不能引用图片
+func (s *DeviceService) normalizeDeviceSpecs(template *model.DeviceTemplate, version, deviceID, userID int, specs map[string]interface{}) (string, error) {
+	fields, normalized, err := s.validateDeviceSpecs(template, version, specs)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkSpecImages(deviceID, userID, specImageRefs(fields, normalized, "")); err != nil {
+		return "", err
+	}
+	return marshalDeviceSpecs(normalized)
+}
+
+// validateDeviceSpecs 按模板的指定字段版本校验规格参数，返回该版本的字段与规范化后的值；没有模板时原样返回
+func (s *DeviceService) validateDeviceSpecs(template *model.DeviceTemplate, version int, specs map[string]interface{}) ([]*model.TemplateField, map[string]interface{}, error) {
+	if template == nil {
+		return nil, specs, nil
+	}
+	fields, err := s.templateSvc.versionFields(template, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	normalized, errs := s.templateSvc.NormalizeSpecifications(fields, specs)
+	if len(errs) > 0 {
+		return nil, nil, specValidationError(errs)
+	}
+	return fields, normalized, nil
+}
+
+// marshalDeviceSpecs 规格参数序列化为要保存的JSON文本，为空时保存空字符串
+func marshalDeviceSpecs(specs map[string]interface{}) (string, error) {
+	if len(specs) == 0 {
+		return "", nil
+	}
+	specJSON, err := json.Marshal(specs)
+	if err != nil {
+		return "", utils.NewBusinessError(utils.ERROR_PARAM, "规格参数格式错误")
+	}
+	return string(specJSON), nil
+}
+
+// parseDeviceSpecs 解析已保存的规格参数JSON文本
+func parseDeviceSpecs(text string) (map[string]interface{}, error) {
+	specs := map[string]interface{}{}
+	if strings.TrimSpace(text) == "" {
+		return specs, nil
+	}
+	if err := json.Unmarshal([]byte(text), &specs); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "规格参数格式错误")
+	}
+	return specs, nil
+}
+
+// deviceSpecTemplate 获取设备所用的模板与校验规格参数的字段版本，版本未知时使用模板当前版本
+func (s *DeviceService) deviceSpecTemplate(device *model.Device) (*model.DeviceTemplate, int, error) {
+	if device.TemplateID == nil {
+		return nil, 0, nil
+	}
+	template, err := s.templateRepo.GetTemplateByID(*device.TemplateID)
+	if err != nil {
+		return nil, 0, utils.NewBusinessError(utils.ERROR_DATABASE, "查询设备模板失败")
+	}
+	if template == nil {
+		return nil, 0, nil
+	}
+	version := device.TemplateVersion
+	if version == 0 {
+		version = template.FieldsVersion
+	}
+	return template, version, nil
+}
+
+// specImageRef 规格参数中的一处图片引用
+type specImageRef struct {
+	field   string
+	label   string
+	imageID int
+	specs   map[string]interface{} // 引用所在的规格参数对象，键为 key
+	key     string
+}
+
+// specImageRefs 收集规范化后规格参数中的图片引用，包括字段组中的子字段
+func specImageRefs(fields []*model.TemplateField, specs map[string]interface{}, prefix string) []*specImageRef {
+	var refs []*specImageRef
+	for _, field := range fields {
+		switch field.FieldType {
+		case FieldTypeImage:
+			if imageID, ok := specs[field.FieldName].(int); ok {
+				refs = append(refs, &specImageRef{field: prefix + field.FieldName, label: field.FieldLabel, imageID: imageID, specs: specs, key: field.FieldName})
+			}
+		case FieldTypeGroup:
+			items, _ := specs[field.FieldName].([]interface{})
+			for i, raw := range items {
+				if item, ok := raw.(map[string]interface{}); ok {
+					refs = append(refs, specImageRefs(field.Fields, item, fmt.Sprintf("%s%s[%d].", prefix, field.FieldName, i))...)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// checkSpecImages 图片字段只能引用该设备已上传的图片
+func (s *DeviceService) checkSpecImages(deviceID, userID int, refs []*specImageRef) error {
+	if len(refs) == 0 {
+		return nil
+	}
+
+	imageIDs := make(map[int]bool)
+	if deviceID > 0 {
+		images, err := s.deviceRepo.GetDeviceImages(deviceID, userID)
+		if err != nil {
+			return utils.NewBusinessError(utils.ERROR_DATABASE, "查询设备图片失败")
+		}
+		for _, image := range images {
+			imageIDs[image.ID] = true
+		}
+	}
+
+	var errs []*SpecFieldError
+	for _, ref := range refs {
+		if imageIDs[ref.imageID] {
+			continue
+		}
+		message := fmt.Sprintf("字段 %s 引用的图片不属于该设备", ref.label)
+		if deviceID == 0 {
+			message = fmt.Sprintf("字段 %s 需要在设备创建并上传图片后再填写", ref.label)
+		}
+		errs = append(errs, &SpecFieldError{Field: ref.field, Label: ref.label, Reason: SpecErrorInvalid, Value: ref.imageID, Message: message})
+	}
+	if len(errs) > 0 {
+		return specValidationError(errs)
+	}
+	return nil
+}
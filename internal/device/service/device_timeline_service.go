@@ -0,0 +1,279 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/device/repository"
+	"Backend_Lili/pkg/utils"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// restorableFields 可从历史恢复的设备字段，状态与出售信息需通过状态接口变更
+var restorableFields = map[string]bool{
+	"category_id":    true,
+	"name":           true,
+	"brand":          true,
+	"model":          true,
+	"serial_number":  true,
+	"color":          true,
+	"storage":        true,
+	"memory":         true,
+	"processor":      true,
+	"screen_size":    true,
+	"purchase_price": true,
+	"current_value":  true,
+	"purchase_date":  true,
+	"warranty_date":  true,
+	"condition":      true,
+	"notes":          true,
+	"specifications": true,
+}
+
+// GetDeviceTimeline 分页获取设备变更时间线
+func (s *DeviceService) GetDeviceTimeline(deviceID, userID int, req *GetDeviceTimelineRequest) (*GetDeviceTimelineResponse, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	if req.Limit > 100 {
+		req.Limit = 100
+	}
+
+	device, err := s.deviceRepo.GetDeviceByID(deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	if device == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+	}
+
+	events, total, err := s.eventRepo.GetDeviceEvents(deviceID, userID, req.EventType, req.Page, req.Limit)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备时间线失败")
+	}
+	for _, event := range events {
+		event.Decode()
+	}
+
+	return &GetDeviceTimelineResponse{
+		Events:     events,
+		Total:      int(total),
+		Page:       req.Page,
+		Limit:      req.Limit,
+		TotalPages: int((total + int64(req.Limit) - 1) / int64(req.Limit)),
+	}, nil
+}
+
+// RestoreDeviceField 将字段恢复为指定事件发生前的值，恢复操作本身也会记录为新事件
+func (s *DeviceService) RestoreDeviceField(deviceID, userID int, req *RestoreDeviceFieldRequest) (*model.Device, error) {
+	if deviceID <= 0 || userID <= 0 || req.EventID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if !restorableFields[req.Field] {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "该字段不支持恢复")
+	}
+
+	device, err := s.deviceRepo.GetDeviceByID(deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	if device == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+	}
+	if req.Version != nil && *req.Version != device.Version {
+		return nil, deviceConflictError(device)
+	}
+
+	event, err := s.eventRepo.GetEventByID(req.EventID, deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取历史记录失败")
+	}
+	if event == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "历史记录不存在")
+	}
+	event.Decode()
+	change, ok := event.Diff[req.Field]
+	if !ok || event.EventType == model.DeviceEventCreated {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "该历史记录中没有此字段的变更")
+	}
+	if req.Field == "specifications" {
+		if err := s.checkSpecsRestorable(event); err != nil {
+			return nil, err
+		}
+	}
+
+	before := *device
+	if err := s.applyDeviceField(device, req.Field, change.Old); err != nil {
+		return nil, err
+	}
+	changes := model.DiffDevices(&before, device)
+	if len(changes) == 0 {
+		return device, nil
+	}
+
+	err = s.deviceRepo.UpdateDevice(device)
+	if err == repository.ErrVersionConflict {
+		return nil, s.reloadDeviceConflict(deviceID, userID)
+	}
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "恢复字段失败")
+	}
+	if req.Field == "warranty_date" || req.Field == "name" {
+		s.syncWarrantyReminder(device)
+	}
+	if req.Field == "specifications" {
+		s.templateSvc.syncSpecIndex(device, nil)
+	}
+	s.recordEvent(deviceID, userID, model.DeviceEventFieldRestored, changes, map[string]interface{}{"event_id": event.ID})
+
+	return device, nil
+}
+
+// checkSpecsRestorable 规格参数按设备模板的字段版本保存，记录之后更换过模板或迁移过字段版本时，
+// 当时的规格参数不再适用，不允许恢复
+func (s *DeviceService) checkSpecsRestorable(event *model.DeviceEvent) error {
+	later, err := s.eventRepo.GetEventsAfter(event.ID, event.DeviceID, event.UserID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取历史记录失败")
+	}
+	for _, item := range append([]*model.DeviceEvent{event}, later...) {
+		item.Decode()
+		if _, ok := item.Diff["template_id"]; ok || item.EventType == model.DeviceEventSpecsMigrated {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "设备模板或模板字段版本在该记录之后已变更，不能恢复当时的规格参数")
+		}
+	}
+	return nil
+}
+
+// applyDeviceField 按字段名写入历史值，值来自JSON解析（字符串、数字或null）
+func (s *DeviceService) applyDeviceField(device *model.Device, field string, value interface{}) error {
+	switch field {
+	case "category_id":
+		if value == nil {
+			device.CategoryID = nil
+			return nil
+		}
+		number, ok := value.(float64)
+		if !ok {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "历史值格式错误")
+		}
+		category, err := s.categoryRepo.GetCategoryByID(int(number))
+		if err != nil {
+			return utils.NewBusinessError(utils.ERROR_DATABASE, "查询设备分类失败")
+		}
+		if category == nil {
+			return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "历史分类已不存在")
+		}
+		device.CategoryID = &category.ID
+	case "purchase_price", "current_value":
+		number, ok := value.(float64)
+		if !ok {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "历史值格式错误")
+		}
+		if field == "purchase_price" {
+			if number <= 0 {
+				return utils.NewBusinessError(utils.ERROR_PARAM, "购买价格必须大于0")
+			}
+			device.PurchasePrice = number
+		} else {
+			device.CurrentValue = number
+		}
+	case "purchase_date", "warranty_date":
+		var date time.Time
+		if text, ok := value.(string); ok && text != "" {
+			parsed, err := time.Parse("2006-01-02", text)
+			if err != nil {
+				return utils.NewBusinessError(utils.ERROR_PARAM, "历史值格式错误")
+			}
+			date = parsed
+		}
+		if field == "purchase_date" {
+			if date.IsZero() {
+				return utils.NewBusinessError(utils.ERROR_PARAM, "购买日期不能为空")
+			}
+			device.PurchaseDate = date
+		} else {
+			device.WarrantyDate = date
+		}
+	case "specifications":
+		// 历史规格参数按设备当前的模板与字段版本重新校验
+		text, ok := value.(string)
+		if value != nil && !ok {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "历史值格式错误")
+		}
+		specs, err := parseDeviceSpecs(text)
+		if err != nil {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "历史值格式错误")
+		}
+		template, version, err := s.deviceSpecTemplate(device)
+		if err != nil {
+			return err
+		}
+		if device.Specifications, err = s.normalizeDeviceSpecs(template, version, device.ID, device.UserID, specs); err != nil {
+			return err
+		}
+		if template != nil {
+			device.TemplateVersion = version
+		}
+	default:
+		text := ""
+		if value != nil {
+			str, ok := value.(string)
+			if !ok {
+				return utils.NewBusinessError(utils.ERROR_PARAM, "历史值格式错误")
+			}
+			text = str
+		}
+		switch field {
+		case "name", "brand", "model":
+			if strings.TrimSpace(text) == "" {
+				return utils.NewBusinessError(utils.ERROR_PARAM, "名称、品牌、型号不能为空")
+			}
+		}
+		setDeviceStringField(device, field, text)
+	}
+	return nil
+}
+
+// setDeviceStringField 写入字符串类型的设备字段
+func setDeviceStringField(device *model.Device, field, value string) {
+	switch field {
+	case "name":
+		device.Name = value
+	case "brand":
+		device.Brand = value
+	case "model":
+		device.Model = value
+	case "serial_number":
+		device.SerialNumber = value
+	case "color":
+		device.Color = value
+	case "storage":
+		device.Storage = value
+	case "memory":
+		device.Memory = value
+	case "processor":
+		device.Processor = value
+	case "screen_size":
+		device.ScreenSize = value
+	case "condition":
+		device.Condition = value
+	case "notes":
+		device.Notes = value
+	}
+}
+
+// recordEvent 追加设备事件，写入失败只记录日志，不影响主流程
+func (s *DeviceService) recordEvent(deviceID, userID int, eventType string, changes map[string]*model.FieldChange, meta map[string]interface{}) {
+	event := model.NewDeviceEvent(deviceID, userID, userID, eventType, changes, meta)
+	if err := s.eventRepo.CreateEvent(event); err != nil {
+		logs.Warn("记录设备事件失败: device=%d, type=%s, err=%v", deviceID, eventType, err)
+	}
+}
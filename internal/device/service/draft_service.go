@@ -0,0 +1,373 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/device/repository"
+	"Backend_Lili/pkg/orderimport"
+	"Backend_Lili/pkg/sheet"
+	"Backend_Lili/pkg/utils"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// draftStatuses 草稿状态
+var draftStatuses = map[string]bool{
+	model.DraftStatusPending:  true,
+	model.DraftStatusAccepted: true,
+	model.DraftStatusRejected: true,
+}
+
+type DraftService struct {
+	draftRepo *repository.DraftRepository
+	deviceSvc *DeviceService
+}
+
+func NewDraftService() *DraftService {
+	return &DraftService{
+		draftRepo: repository.NewDraftRepository(),
+		deviceSvc: NewDeviceService(),
+	}
+}
+
+// ImportOrders 解析京东、淘宝/天猫订单导出文件，为数码商品生成待确认的设备草稿
+func (s *DraftService) ImportOrders(userID int, file multipart.File, fileHeader *multipart.FileHeader, req *ImportOrdersRequest) (*ImportOrdersResponse, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	if req.Platform != "" && req.Platform != orderimport.PlatformJD && req.Platform != orderimport.PlatformTaobao {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "平台仅支持 jd 或 taobao")
+	}
+	if fileHeader.Size > maxImportFileSize {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "导入文件大小不能超过5MB")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize+1))
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "读取导入文件失败")
+	}
+	if len(data) > maxImportFileSize {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "导入文件大小不能超过5MB")
+	}
+	rows, err := sheet.Read(data, fileHeader.Filename)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "表格解析失败: "+err.Error())
+	}
+	platform, items, err := orderimport.Parse(rows, req.Platform)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, err.Error())
+	}
+	if len(items) > maxImportRows {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("单次导入不能超过%d行", maxImportRows))
+	}
+
+	orderNos := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.OrderNo != "" {
+			orderNos = append(orderNos, item.OrderNo)
+		}
+	}
+	imported, err := s.draftRepo.GetImportedOrderKeys(userID, platform, orderNos)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "查询已导入订单失败")
+	}
+	ctx, err := s.deviceSvc.newImportContext(userID, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ImportOrdersResponse{Platform: platform, TotalCount: len(items)}
+	drafts := make([]*model.DeviceDraft, 0, len(items))
+	for _, item := range items {
+		if item.Skipped {
+			response.CancelledCount++
+			continue
+		}
+		key := repository.DraftOrderKey(item.OrderNo, truncateRunes(item.Title, 500))
+		if item.OrderNo != "" && imported[key] {
+			response.DuplicateCount++
+			continue
+		}
+		inference := orderimport.Infer(item.Title)
+		if !inference.IsElectronics && !req.IncludeAll {
+			response.IgnoredCount++
+			continue
+		}
+		imported[key] = true
+		draft := newDeviceDraft(userID, platform, item, inference, ctx.lookup)
+		if draft.CategoryID == nil {
+			applyDraftCategoryRules(draft, ctx.categorizer)
+		}
+		drafts = append(drafts, draft)
+	}
+
+	if err := s.draftRepo.CreateDrafts(drafts); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "保存设备草稿失败")
+	}
+	response.DraftCount = len(drafts)
+	response.Drafts = drafts
+	return response, nil
+}
+
+// newDeviceDraft 由订单商品生成草稿：名称取品牌加型号，识别不到时截取标题；分类按关键字匹配同名分类
+func newDeviceDraft(userID int, platform string, item *orderimport.OrderItem, inference *orderimport.Inference, lookup *importLookup) *model.DeviceDraft {
+	draft := &model.DeviceDraft{
+		UserID:        userID,
+		Source:        platform,
+		OrderNo:       item.OrderNo,
+		Shop:          truncateRunes(item.Shop, 200),
+		Title:         truncateRunes(item.Title, 500),
+		Quantity:      item.Quantity,
+		PricePaid:     item.PricePaid,
+		PurchasePrice: item.UnitPrice,
+		Brand:         inference.Brand,
+		Model:         truncateRunes(inference.Model, 100),
+		Status:        model.DraftStatusPending,
+	}
+	if !item.OrderDate.IsZero() {
+		orderDate := item.OrderDate
+		draft.OrderDate = &orderDate
+	}
+
+	draft.Name = strings.TrimSpace(draft.Brand + " " + draft.Model)
+	if draft.Brand == "" || draft.Model == "" {
+		draft.Name = truncateRunes(item.Title, 50)
+	}
+
+	notes := []string{}
+	if item.Shop != "" {
+		notes = append(notes, "店铺: "+item.Shop)
+	}
+	if item.OrderNo != "" {
+		notes = append(notes, "订单号: "+item.OrderNo)
+	}
+	if item.Quantity > 1 {
+		notes = append(notes, fmt.Sprintf("购买数量: %d，合计实付 %.2f", item.Quantity, item.PricePaid))
+	}
+	draft.Notes = strings.Join(notes, "\n")
+
+	if inference.CategoryHint != "" {
+		if category := lookup.resolveCategory(inference.CategoryHint); category != nil {
+			categoryID := category.ID
+			draft.CategoryID = &categoryID
+			if template := lookup.resolveTemplate("", categoryID); template != nil {
+				templateID := template.ID
+				draft.TemplateID = &templateID
+			}
+		}
+	}
+	return draft
+}
+
+// applyDraftCategoryRules 关键字未识别出分类时，按分类规则填充草稿的分类与模板
+func applyDraftCategoryRules(draft *model.DeviceDraft, categorizer *deviceCategorizer) {
+	req := &CreateDeviceRequest{Name: draft.Name, Brand: draft.Brand, Model: draft.Model}
+	categorizer.apply(req)
+	if req.CategoryID > 0 {
+		categoryID := req.CategoryID
+		draft.CategoryID = &categoryID
+	}
+	if req.TemplateID > 0 {
+		templateID := req.TemplateID
+		draft.TemplateID = &templateID
+	}
+}
+
+// GetDrafts 获取草稿列表，默认只返回待确认的草稿
+func (s *DraftService) GetDrafts(userID int, req *GetDraftsRequest) (*GetDraftsResponse, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	if req.Status == "" {
+		req.Status = model.DraftStatusPending
+	}
+	if req.Status != "all" && !draftStatuses[req.Status] {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "无效的草稿状态")
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+
+	status := req.Status
+	if status == "all" {
+		status = ""
+	}
+	drafts, total, err := s.draftRepo.GetDrafts(userID, status, req.Page, req.Limit)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备草稿失败")
+	}
+
+	return &GetDraftsResponse{
+		Drafts: drafts,
+		Total:  int(total),
+		Page:   req.Page,
+		Limit:  req.Limit,
+	}, nil
+}
+
+// UpdateDraft 编辑待确认的草稿
+func (s *DraftService) UpdateDraft(draftID, userID int, req *UpdateDraftRequest) (*model.DeviceDraft, error) {
+	draft, err := s.getPendingDraft(draftID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyUpdateDraftRequest(draft, req); err != nil {
+		return nil, err
+	}
+	if err := s.draftRepo.UpdateDraft(draft); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "更新设备草稿失败")
+	}
+	return draft, nil
+}
+
+// AcceptDraft 确认草稿并创建设备，可同时提交修改
+func (s *DraftService) AcceptDraft(draftID, userID int, req *UpdateDraftRequest) (*model.Device, error) {
+	draft, err := s.getPendingDraft(draftID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyUpdateDraftRequest(draft, req); err != nil {
+		return nil, err
+	}
+
+	createReq := &CreateDeviceRequest{
+		Name:          draft.Name,
+		Brand:         draft.Brand,
+		Model:         draft.Model,
+		PurchasePrice: draft.PurchasePrice,
+		SerialNumber:  draft.SerialNumber,
+		Notes:         draft.Notes,
+		Force:         req.Force,
+
+		Specifications: req.Specifications,
+	}
+	if draft.CategoryID != nil {
+		createReq.CategoryID = *draft.CategoryID
+	}
+	if draft.TemplateID != nil {
+		createReq.TemplateID = *draft.TemplateID
+	}
+	if draft.OrderDate != nil {
+		createReq.PurchaseDate = draft.OrderDate.Format("2006-01-02")
+	}
+
+	device, err := s.deviceSvc.CreateDevice(userID, createReq)
+	if err != nil {
+		return nil, err
+	}
+
+	draft.Status = model.DraftStatusAccepted
+	draft.DeviceID = &device.ID
+	if err := s.draftRepo.UpdateDraft(draft); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "更新设备草稿失败")
+	}
+	return device, nil
+}
+
+// RejectDraft 忽略草稿，之后重复导入同一订单不会再生成
+func (s *DraftService) RejectDraft(draftID, userID int) error {
+	draft, err := s.getPendingDraft(draftID, userID)
+	if err != nil {
+		return err
+	}
+	draft.Status = model.DraftStatusRejected
+	if err := s.draftRepo.UpdateDraft(draft); err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "更新设备草稿失败")
+	}
+	return nil
+}
+
+// DeleteDraft 删除草稿，删除后重复导入同一订单会重新生成
+func (s *DraftService) DeleteDraft(draftID, userID int) error {
+	if draftID <= 0 || userID <= 0 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	draft, err := s.draftRepo.GetDraftByID(draftID, userID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备草稿失败")
+	}
+	if draft == nil {
+		return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备草稿不存在")
+	}
+	if err := s.draftRepo.DeleteDraft(draftID, userID); err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "删除设备草稿失败")
+	}
+	return nil
+}
+
+// getPendingDraft 获取待确认的草稿
+func (s *DraftService) getPendingDraft(draftID, userID int) (*model.DeviceDraft, error) {
+	if draftID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	draft, err := s.draftRepo.GetDraftByID(draftID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备草稿失败")
+	}
+	if draft == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备草稿不存在")
+	}
+	if draft.Status != model.DraftStatusPending {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "草稿已处理")
+	}
+	return draft, nil
+}
+
+// applyUpdateDraftRequest 把修改写入草稿
+func applyUpdateDraftRequest(draft *model.DeviceDraft, req *UpdateDraftRequest) error {
+	if req == nil {
+		return nil
+	}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" || utf8.RuneCountInString(*req.Name) > 200 {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "设备名称不能为空且不超过200个字符")
+		}
+		draft.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Brand != nil {
+		draft.Brand = strings.TrimSpace(*req.Brand)
+	}
+	if req.Model != nil {
+		draft.Model = strings.TrimSpace(*req.Model)
+	}
+	if req.CategoryID != nil {
+		draft.CategoryID = req.CategoryID
+	}
+	if req.TemplateID != nil {
+		draft.TemplateID = req.TemplateID
+	}
+	if req.PurchasePrice != nil {
+		if *req.PurchasePrice <= 0 {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "购买价格必须大于0")
+		}
+		draft.PurchasePrice = *req.PurchasePrice
+	}
+	if req.PurchaseDate != nil {
+		date, err := time.Parse("2006-01-02", *req.PurchaseDate)
+		if err != nil {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "购买日期格式错误")
+		}
+		draft.OrderDate = &date
+	}
+	if req.SerialNumber != nil {
+		draft.SerialNumber = strings.TrimSpace(*req.SerialNumber)
+	}
+	if req.Notes != nil {
+		draft.Notes = *req.Notes
+	}
+	return nil
+}
+
+// truncateRunes 按字符截断
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/pkg/utils"
+	"math"
+	"sort"
+	"time"
+)
+
+// daysPerMonth 平均每月天数，用于折算月均成本
+const daysPerMonth = 365.25 / 12
+
+// GetOwnershipCost 计算单台设备的持有总成本
+func (s *DeviceService) GetOwnershipCost(device *model.Device) (*model.OwnershipCost, error) {
+	costs, err := s.costRepo.GetDeviceCosts(device.ID, device.UserID, "")
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备支出失败")
+	}
+	serviceSummary, err := s.recordRepo.GetServiceCostSummary(device.ID, device.UserID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取维修保养费用失败")
+	}
+	usageCount, err := s.costRepo.GetUsageCount(device.ID, device.UserID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取使用记录失败")
+	}
+	return calculateOwnershipCost(device, costs, serviceSummary.TotalCost, usageCount, time.Now()), nil
+}
+
+// GetOwnershipCostRanking 按日均持有成本从高到低排列用户设备
+func (s *DeviceService) GetOwnershipCostRanking(userID int, includeSold bool, limit int) ([]*OwnershipCostRankItem, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+
+	devices, _, err := s.deviceRepo.GetDevicesList(userID, map[string]interface{}{})
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备列表失败")
+	}
+	costs, err := s.costRepo.GetUserCosts(userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备支出失败")
+	}
+	repairCosts, err := s.recordRepo.GetServiceCostTotals(userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取维修保养费用失败")
+	}
+	usageCounts, err := s.costRepo.GetUsageCounts(userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取使用记录失败")
+	}
+
+	now := time.Now()
+	items := make([]*OwnershipCostRankItem, 0, len(devices))
+	for _, device := range devices {
+		if !includeSold && device.Status == "sold" {
+			continue
+		}
+		items = append(items, &OwnershipCostRankItem{
+			DeviceID:      device.ID,
+			Name:          device.Name,
+			Brand:         device.Brand,
+			Model:         device.Model,
+			Status:        device.Status,
+			OwnershipCost: calculateOwnershipCost(device, costs[device.ID], repairCosts[device.ID], usageCounts[device.ID], now),
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].OwnershipCost.CostPerDay > items[j].OwnershipCost.CostPerDay
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	for i, item := range items {
+		item.Rank = i + 1
+	}
+	return items, nil
+}
+
+// calculateOwnershipCost 持有总成本 = 购买价格 + 配件 + 自付维修 + 订阅 + 平台费用 - 出售所得或当前估值
+func calculateOwnershipCost(device *model.Device, costs []*model.DeviceCost, repairCost float64, usageCount int, now time.Time) *model.OwnershipCost {
+	// 已出售的设备持有期截止到出售日期
+	holdingEnd := now
+	if device.Status == "sold" && !device.SaleDate.IsZero() {
+		holdingEnd = device.SaleDate
+	}
+
+	result := &model.OwnershipCost{
+		PurchasePrice: device.PurchasePrice,
+		RepairCost:    repairCost,
+		UsageCount:    usageCount,
+	}
+	for _, cost := range costs {
+		switch cost.CostType {
+		case "accessory":
+			result.AccessoryCost += cost.Amount
+		case "fee":
+			result.FeeCost += cost.Amount
+		case "subscription":
+			end := holdingEnd
+			if cost.EndDate != nil && cost.EndDate.Before(end) {
+				end = *cost.EndDate
+			}
+			result.SubscriptionCost += cost.Amount * float64(billingPeriods(cost.CostDate, end, billingCycles[cost.BillingCycle]))
+		}
+	}
+
+	switch device.Status {
+	case "sold":
+		result.ResidualValue = device.SalePrice
+		result.ResidualSource = "sale"
+	case "lost":
+		result.ResidualSource = "none"
+	default:
+		result.ResidualValue = device.CurrentValue
+		result.ResidualSource = "current_value"
+	}
+
+	result.TotalCost = roundMoney(result.PurchasePrice + result.AccessoryCost + result.RepairCost +
+		result.SubscriptionCost + result.FeeCost - result.ResidualValue)
+
+	result.HoldingDays = int(holdingEnd.Sub(device.PurchaseDate).Hours() / 24)
+	if result.HoldingDays < 1 {
+		result.HoldingDays = 1
+	}
+	result.CostPerDay = roundMoney(result.TotalCost / float64(result.HoldingDays))
+	result.CostPerMonth = roundMoney(result.TotalCost / float64(result.HoldingDays) * daysPerMonth)
+	if usageCount > 0 {
+		perUse := roundMoney(result.TotalCost / float64(usageCount))
+		result.CostPerUse = &perUse
+	}
+	return result
+}
+
+// billingPeriods 计算从开始日期到截止日期已计费的周期数，开始当天计第一期
+func billingPeriods(start, end time.Time, months int) int {
+	if months <= 0 || end.Before(start) {
+		return 0
+	}
+	elapsed := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+	if end.Day() < start.Day() {
+		elapsed--
+	}
+	return elapsed/months + 1
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/device/repository"
+	"Backend_Lili/pkg/storage"
+	"Backend_Lili/pkg/utils"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// serviceTypes 维修保养类型
+var serviceTypes = map[string]string{
+	"repair":       "维修",
+	"battery_swap": "更换电池",
+	"cleaning":     "清洁保养",
+	"upgrade":      "升级改装",
+}
+
+type ServiceRecordService struct {
+	recordRepo *repository.ServiceRecordRepository
+	deviceRepo *repository.DeviceRepository
+	blobStore  storage.BlobStore
+}
+
+func NewServiceRecordService() *ServiceRecordService {
+	return &ServiceRecordService{
+		recordRepo: repository.NewServiceRecordRepository(),
+		deviceRepo: repository.NewDeviceRepository(),
+		blobStore:  storage.Default(),
+	}
+}
+
+// GetServiceRecords 获取设备维修保养记录
+func (s *ServiceRecordService) GetServiceRecords(deviceID, userID int, serviceType string) (*GetServiceRecordsResponse, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if serviceType != "" && serviceTypes[serviceType] == "" {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "维修保养类型无效")
+	}
+	if err := s.checkDevice(deviceID, userID); err != nil {
+		return nil, err
+	}
+
+	records, err := s.recordRepo.GetServiceRecords(deviceID, userID, serviceType)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取维修保养记录失败")
+	}
+	if err := s.loadAttachments(records...); err != nil {
+		return nil, err
+	}
+
+	response := &GetServiceRecordsResponse{Records: records}
+	for _, record := range records {
+		if record.WarrantyCovered {
+			response.CoveredCost += record.Cost
+		} else {
+			response.TotalCost += record.Cost
+		}
+	}
+	return response, nil
+}
+
+// GetServiceRecord 获取维修保养记录详情
+func (s *ServiceRecordService) GetServiceRecord(recordID, deviceID, userID int) (*model.DeviceServiceRecord, error) {
+	if recordID <= 0 || deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if err := s.checkDevice(deviceID, userID); err != nil {
+		return nil, err
+	}
+
+	record, err := s.recordRepo.GetServiceRecordByID(recordID, deviceID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取维修保养记录失败")
+	}
+	if record == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "维修保养记录不存在")
+	}
+	if err := s.loadAttachments(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// CreateServiceRecord 创建维修保养记录
+func (s *ServiceRecordService) CreateServiceRecord(deviceID, userID int, req *ServiceRecordRequest) (*model.DeviceServiceRecord, error) {
+	if deviceID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+	if err := s.checkDevice(deviceID, userID); err != nil {
+		return nil, err
+	}
+
+	record := &model.DeviceServiceRecord{
+		DeviceID: deviceID,
+		UserID:   userID,
+	}
+	if req.ServiceDate == "" || req.ServiceType == "" {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "服务日期和类型不能为空")
+	}
+	if err := applyServiceRecordRequest(record, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.recordRepo.CreateServiceRecord(record); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "创建维修保养记录失败")
+	}
+	return record, nil
+}
+
+// UpdateServiceRecord 更新维修保养记录，只更新请求中提供的字段
+func (s *ServiceRecordService) UpdateServiceRecord(recordID, deviceID, userID int, req *ServiceRecordRequest) (*model.DeviceServiceRecord, error) {
+	record, err := s.GetServiceRecord(recordID, deviceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyServiceRecordRequest(record, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.recordRepo.UpdateServiceRecord(record); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "更新维修保养记录失败")
+	}
+	return record, nil
+}
+
+// DeleteServiceRecord 删除维修保养记录，同时删除关联的单据附件
+func (s *ServiceRecordService) DeleteServiceRecord(recordID, deviceID, userID int) error {
+	if _, err := s.GetServiceRecord(recordID, deviceID, userID); err != nil {
+		return err
+	}
+
+	attachments, err := s.recordRepo.DeleteServiceRecord(recordID, userID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "删除维修保养记录失败")
+	}
+	for _, attachment := range attachments {
+		if err := s.blobStore.Delete(attachment.StorageKey); err != nil {
+			logs.Warn("删除存储对象失败: key=%s, err=%v", attachment.StorageKey, err)
+		}
+	}
+	return nil
+}
+
+func (s *ServiceRecordService) checkDevice(deviceID, userID int) error {
+	device, err := s.deviceRepo.GetDeviceByID(deviceID, userID)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+	if device == nil {
+		return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+	}
+	return nil
+}
+
+func (s *ServiceRecordService) loadAttachments(records ...*model.DeviceServiceRecord) error {
+	ids := make([]int, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	attachments, err := s.recordRepo.GetRecordAttachments(ids)
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取维修单据失败")
+	}
+	for _, record := range records {
+		record.Attachments = attachments[record.ID]
+		fillAttachmentURLs(record.Attachments...)
+	}
+	return nil
+}
+
+// applyServiceRecordRequest 校验并写入请求字段
+func applyServiceRecordRequest(record *model.DeviceServiceRecord, req *ServiceRecordRequest) error {
+	if req.ServiceType != "" {
+		if serviceTypes[req.ServiceType] == "" {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "维修保养类型无效，仅支持 repair、battery_swap、cleaning、upgrade")
+		}
+		record.ServiceType = req.ServiceType
+	}
+	if req.ServiceDate != "" {
+		serviceDate, err := time.Parse("2006-01-02", req.ServiceDate)
+		if err != nil {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "服务日期格式错误")
+		}
+		if serviceDate.After(time.Now()) {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "服务日期不能晚于今天")
+		}
+		record.ServiceDate = serviceDate
+	}
+	if req.Vendor != nil {
+		record.Vendor = strings.TrimSpace(*req.Vendor)
+		if utf8.RuneCountInString(record.Vendor) > 200 {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "服务商名称不能超过200个字符")
+		}
+	}
+	if req.Cost != nil {
+		if *req.Cost < 0 {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "费用不能为负数")
+		}
+		record.Cost = *req.Cost
+	}
+	if req.WarrantyCovered != nil {
+		record.WarrantyCovered = *req.WarrantyCovered
+	}
+	if req.Description != nil {
+		record.Description = strings.TrimSpace(*req.Description)
+	}
+	return nil
+}
@@ -0,0 +1,664 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 模板字段验证规则语法：
+//
+//	规则之间用 | 分隔，规则名与参数用 : 分隔，多个参数用 , 分隔（regex 的参数整体作为一个）；
+//	# 之后为该规则的自定义错误消息，可用 {label} 表示字段显示名称；\| 与 \# 表示字面字符。
+//	例如 unit:GB|between:64GB,8TB#{label}需在64GB到8TB之间|step:64
+//
+// min/max/between 按字段类型比较：数字与金额字段比较数值（声明了 unit 时比较换算后的数量），
+// 日期字段比较日期，多选与字段组比较项数，其余字段比较字符数；布尔与图片字段只支持 required_if/required_with。
+const (
+	ruleUnit          = "unit"            // unit:GB 值为带单位的数量，数字字段统一换算为该单位保存
+	ruleMin           = "min"             // min:N
+	ruleMax           = "max"             // max:N
+	ruleBetween       = "between"         // between:N,M
+	ruleMinLength     = "min_length"      // min_length:N 字符数
+	ruleMaxLength     = "max_length"      // max_length:N 字符数
+	ruleInteger       = "integer"         // 必须是整数
+	ruleStep          = "step"            // step:N 必须是 N 的整数倍
+	rulePrecision     = "precision"       // precision:N 最多 N 位小数
+	ruleAfter         = "after"           // after:日期|today[+-N]
+	ruleAfterOrEqual  = "after_or_equal"  // after_or_equal:日期|today[+-N]
+	ruleBefore        = "before"          // before:日期|today[+-N]
+	ruleBeforeOrEqual = "before_or_equal" // before_or_equal:日期|today[+-N]
+	ruleIn            = "in"              // in:a,b,c 枚举
+	ruleNotIn         = "not_in"          // not_in:a,b,c
+	ruleURL           = "url"             // http/https 网址
+	ruleEmail         = "email"           // 邮箱地址
+	ruleRegex         = "regex"           // regex:表达式
+	ruleRequiredIf    = "required_if"     // required_if:字段,值1,值2 其他字段为指定值时必填
+	ruleRequiredWith  = "required_with"   // required_with:字段1,字段2 其他任一字段已填写时必填
+)
+
+// 规则比较的对象
+const (
+	ruleKindText   = "text"   // 字符数
+	ruleKindNumber = "number" // 数值或带单位的数量
+	ruleKindDate   = "date"   // 日期
+	ruleKindList   = "list"   // 项数
+	ruleKindNone   = "none"   // 不比较值
+)
+
+// specUnit 规格参数的计量单位，同一量纲的单位按 Factor 换算
+type specUnit struct {
+	Name      string
+	Dimension string
+	Factor    float64
+}
+
+// specUnits 支持的单位，按小写名称查找；存储容量按1024进制换算
+var specUnits = func() map[string]*specUnit {
+	units := []*specUnit{
+		{"B", "data", 1}, {"KB", "data", 1 << 10}, {"MB", "data", 1 << 20},
+		{"GB", "data", 1 << 30}, {"TB", "data", 1 << 40}, {"PB", "data", 1 << 50},
+		{"mm", "length", 0.001}, {"cm", "length", 0.01}, {"m", "length", 1}, {"km", "length", 1000}, {"in", "length", 0.0254},
+		{"mg", "weight", 0.001}, {"g", "weight", 1}, {"kg", "weight", 1000}, {"t", "weight", 1000000},
+		{"Hz", "frequency", 1}, {"kHz", "frequency", 1e3}, {"MHz", "frequency", 1e6}, {"GHz", "frequency", 1e9},
+		{"mAh", "charge", 1}, {"Ah", "charge", 1000},
+		{"mW", "power", 0.001}, {"W", "power", 1}, {"kW", "power", 1000},
+		{"ms", "time", 0.001}, {"s", "time", 1}, {"min", "time", 60}, {"h", "time", 3600},
+	}
+	byName := make(map[string]*specUnit, len(units))
+	for _, unit := range units {
+		byName[strings.ToLower(unit.Name)] = unit
+	}
+	return byName
+}()
+
+// quantityPattern 数量文本：数字后跟可选的单位
+var quantityPattern = regexp.MustCompile(`^([+-]?(?:\d+\.?\d*|\.\d+))\s*([a-zA-Z]*)$`)
+
+// fieldRulesCache 按字段类型与规则文本缓存解析结果
+var fieldRulesCache sync.Map
+
+// validationRule 单条验证规则
+type validationRule struct {
+	Name    string
+	Args    []string
+	Message string // 自定义错误消息
+
+	numbers []float64    // 数值参数（数量已换算为字段单位）
+	dates   []*dateBound // 日期参数
+	pattern *regexp.Regexp
+}
+
+// dateBound 日期参数，today 相对于校验当天
+type dateBound struct {
+	today bool
+	days  int
+	date  time.Time
+}
+
+// resolve 计算日期参数对应的日期
+func (b *dateBound) resolve() time.Time {
+	if !b.today {
+		return b.date
+	}
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, b.days)
+}
+
+// fieldRules 字段解析后的验证规则
+type fieldRules struct {
+	kind  string
+	unit  *specUnit
+	rules []*validationRule
+}
+
+// ruleValue 参与规则校验的值
+type ruleValue struct {
+	text   string
+	number float64   // kind 为 number 时有效
+	date   time.Time // kind 为 date 时有效
+	count  int       // kind 为 list 时有效
+}
+
+// parseFieldRules 解析字段的验证规则，规则有误时返回错误
+func parseFieldRules(field *model.TemplateField) (*fieldRules, error) {
+	cacheKey := field.FieldType + "\x00" + field.ValidationRules
+	if cached, ok := fieldRulesCache.Load(cacheKey); ok {
+		return cached.(*fieldRules), nil
+	}
+
+	rules, err := splitValidationRules(field.ValidationRules)
+	if err != nil {
+		return nil, err
+	}
+
+	fr := &fieldRules{kind: ruleKindText}
+	switch field.FieldType {
+	case FieldTypeNumber, FieldTypeMoney:
+		fr.kind = ruleKindNumber
+	case FieldTypeDate:
+		fr.kind = ruleKindDate
+	case FieldTypeMultiSelect, FieldTypeGroup:
+		fr.kind = ruleKindList
+	case FieldTypeBoolean, FieldTypeImage:
+		fr.kind = ruleKindNone
+	}
+	// 先确定单位，数量参数按单位换算
+	for _, rule := range rules {
+		if rule.Name != ruleUnit {
+			continue
+		}
+		if fr.unit != nil {
+			return nil, errors.New("unit 规则只能出现一次")
+		}
+		if len(rule.Args) != 1 {
+			return nil, errors.New("unit 规则需要1个参数")
+		}
+		if field.FieldType != FieldTypeNumber && field.FieldType != FieldTypeText && field.FieldType != FieldTypeTextarea {
+			return nil, errors.New("unit 规则只适用于数字或文本字段")
+		}
+		if fr.unit = lookupSpecUnit(rule.Args[0]); fr.unit == nil {
+			return nil, fmt.Errorf("不支持的单位: %s", rule.Args[0])
+		}
+		fr.kind = ruleKindNumber
+	}
+	for _, rule := range rules {
+		if err := fr.compile(field, rule); err != nil {
+			return nil, err
+		}
+	}
+	fr.rules = rules
+
+	fieldRulesCache.Store(cacheKey, fr)
+	return fr, nil
+}
+
+// splitValidationRules 按语法拆分规则文本
+func splitValidationRules(text string) ([]*validationRule, error) {
+	// 先把转义字符替换为占位符，拆分后再还原
+	text = strings.NewReplacer(`\|`, "\x00", `\#`, "\x01").Replace(text)
+	restore := strings.NewReplacer("\x00", "|", "\x01", "#")
+
+	var rules []*validationRule
+	for _, token := range strings.Split(text, "|") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		rule := &validationRule{}
+		if index := strings.Index(token, "#"); index >= 0 {
+			rule.Message = strings.TrimSpace(restore.Replace(token[index+1:]))
+			token = strings.TrimSpace(token[:index])
+		}
+		name, args, hasArgs := strings.Cut(token, ":")
+		rule.Name = strings.ToLower(strings.TrimSpace(name))
+		if hasArgs {
+			if rule.Name == ruleRegex {
+				rule.Args = []string{restore.Replace(args)}
+			} else {
+				for _, arg := range strings.Split(args, ",") {
+					rule.Args = append(rule.Args, strings.TrimSpace(restore.Replace(arg)))
+				}
+			}
+		}
+		for _, arg := range rule.Args {
+			if arg == "" {
+				return nil, fmt.Errorf("规则 %s 的参数不能为空", rule.Name)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// compile 检查规则的参数与适用的字段类型，并预先解析参数
+func (fr *fieldRules) compile(field *model.TemplateField, rule *validationRule) error {
+	argCount := func(min, max int) error {
+		if len(rule.Args) < min || (max >= 0 && len(rule.Args) > max) {
+			if min == max {
+				return fmt.Errorf("规则 %s 需要%d个参数", rule.Name, min)
+			}
+			return fmt.Errorf("规则 %s 至少需要%d个参数", rule.Name, min)
+		}
+		return nil
+	}
+	requireKind := func(kind, label string) error {
+		if fr.kind != kind {
+			return fmt.Errorf("规则 %s 只适用于%s字段", rule.Name, label)
+		}
+		return nil
+	}
+
+	// 多选与字段组只比较项数，布尔与图片字段只能声明条件必填
+	switch {
+	case rule.Name == ruleRequiredIf || rule.Name == ruleRequiredWith:
+	case fr.kind == ruleKindNone,
+		fr.kind == ruleKindList && rule.Name != ruleMin && rule.Name != ruleMax && rule.Name != ruleBetween:
+		return fmt.Errorf("规则 %s 不适用于%s类型字段", rule.Name, field.FieldType)
+	}
+
+	switch rule.Name {
+	case ruleUnit:
+		return nil
+	case ruleMin, ruleMax, ruleBetween:
+		count := 1
+		if rule.Name == ruleBetween {
+			count = 2
+		}
+		if err := argCount(count, count); err != nil {
+			return err
+		}
+		for _, arg := range rule.Args {
+			if err := fr.compileBound(rule, arg); err != nil {
+				return err
+			}
+		}
+		if rule.Name == ruleBetween && fr.kind != ruleKindDate && rule.numbers[0] > rule.numbers[1] {
+			return fmt.Errorf("规则 %s 的下限不能大于上限", rule.Name)
+		}
+	case ruleMinLength, ruleMaxLength:
+		if err := argCount(1, 1); err != nil {
+			return err
+		}
+		return compileLength(rule, rule.Args[0])
+	case ruleInteger:
+		if err := argCount(0, 0); err != nil {
+			return err
+		}
+		return requireKind(ruleKindNumber, "数字")
+	case ruleStep:
+		if err := argCount(1, 1); err != nil {
+			return err
+		}
+		if err := requireKind(ruleKindNumber, "数字"); err != nil {
+			return err
+		}
+		if err := fr.compileBound(rule, rule.Args[0]); err != nil {
+			return err
+		}
+		if rule.numbers[0] <= 0 {
+			return fmt.Errorf("规则 %s 的参数必须大于0", rule.Name)
+		}
+	case rulePrecision:
+		if err := argCount(1, 1); err != nil {
+			return err
+		}
+		if err := requireKind(ruleKindNumber, "数字"); err != nil {
+			return err
+		}
+		return compileLength(rule, rule.Args[0])
+	case ruleAfter, ruleAfterOrEqual, ruleBefore, ruleBeforeOrEqual:
+		if err := argCount(1, 1); err != nil {
+			return err
+		}
+		if err := requireKind(ruleKindDate, "日期"); err != nil {
+			return err
+		}
+		return fr.compileBound(rule, rule.Args[0])
+	case ruleIn, ruleNotIn:
+		if err := argCount(1, -1); err != nil {
+			return err
+		}
+		if fr.kind == ruleKindNumber {
+			for _, arg := range rule.Args {
+				if err := fr.compileBound(rule, arg); err != nil {
+					return err
+				}
+			}
+		}
+	case ruleURL, ruleEmail:
+		if err := argCount(0, 0); err != nil {
+			return err
+		}
+		if fr.kind != ruleKindText || field.FieldType == FieldTypeSelect {
+			return fmt.Errorf("规则 %s 只适用于文本字段", rule.Name)
+		}
+	case ruleRegex:
+		if err := argCount(1, 1); err != nil {
+			return err
+		}
+		pattern, err := regexp.Compile(rule.Args[0])
+		if err != nil {
+			return fmt.Errorf("正则表达式无效: %s", rule.Args[0])
+		}
+		rule.pattern = pattern
+	case ruleRequiredIf:
+		return argCount(2, -1)
+	case ruleRequiredWith:
+		return argCount(1, -1)
+	default:
+		return fmt.Errorf("未知的规则: %s", rule.Name)
+	}
+	return nil
+}
+
+// compileBound 按字段的比较对象解析一个参数
+func (fr *fieldRules) compileBound(rule *validationRule, arg string) error {
+	switch fr.kind {
+	case ruleKindNumber:
+		number, ok := fr.parseNumber(arg)
+		if !ok {
+			if fr.unit != nil {
+				return fmt.Errorf("规则 %s 的参数必须是数值或%s量纲的数量: %s", rule.Name, fr.unit.Name, arg)
+			}
+			return fmt.Errorf("规则 %s 的参数必须是数字: %s", rule.Name, arg)
+		}
+		rule.numbers = append(rule.numbers, number)
+	case ruleKindDate:
+		bound, ok := parseDateBound(arg)
+		if !ok {
+			return fmt.Errorf("规则 %s 的参数必须是日期(YYYY-MM-DD)或today: %s", rule.Name, arg)
+		}
+		rule.dates = append(rule.dates, bound)
+	default:
+		return compileLength(rule, arg)
+	}
+	return nil
+}
+
+// compileLength 解析非负整数参数
+func compileLength(rule *validationRule, arg string) error {
+	length, err := strconv.Atoi(arg)
+	if err != nil || length < 0 {
+		return fmt.Errorf("规则 %s 的参数必须是非负整数: %s", rule.Name, arg)
+	}
+	rule.numbers = append(rule.numbers, float64(length))
+	return nil
+}
+
+// parseNumber 解析数值参数，声明了单位时按数量换算
+func (fr *fieldRules) parseNumber(text string) (float64, bool) {
+	if fr.unit != nil {
+		return parseQuantity(text, fr.unit)
+	}
+	return specNumber(text)
+}
+
+// parseDateBound 解析日期参数：YYYY-MM-DD、today、today+N、today-N（N为天数）
+func parseDateBound(text string) (*dateBound, bool) {
+	lower := strings.ToLower(strings.TrimSpace(text))
+	if strings.HasPrefix(lower, "today") {
+		offset := strings.TrimPrefix(lower, "today")
+		if offset == "" {
+			return &dateBound{today: true}, true
+		}
+		if offset[0] != '+' && offset[0] != '-' {
+			return nil, false
+		}
+		days, err := strconv.Atoi(offset)
+		if err != nil {
+			return nil, false
+		}
+		return &dateBound{today: true, days: days}, true
+	}
+	date, ok := specDate(text)
+	if !ok {
+		return nil, false
+	}
+	return &dateBound{date: date}, true
+}
+
+// lookupSpecUnit 按名称查找单位，不区分大小写
+func lookupSpecUnit(name string) *specUnit {
+	return specUnits[strings.ToLower(strings.TrimSpace(name))]
+}
+
+// parseQuantity 解析带单位的数量（如 1TB、1,024 GB）并换算为 base 单位，不带单位时视为 base 单位
+func parseQuantity(text string, base *specUnit) (float64, bool) {
+	match := quantityPattern.FindStringSubmatch(strings.ReplaceAll(strings.TrimSpace(text), ",", ""))
+	if match == nil {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	if match[2] == "" {
+		return number, true
+	}
+	unit := lookupSpecUnit(match[2])
+	if unit == nil || unit.Dimension != base.Dimension {
+		return 0, false
+	}
+	// 换算后保留12位有效数字，避免浮点误差
+	converted, _ := strconv.ParseFloat(strconv.FormatFloat(number*unit.Factor/base.Factor, 'g', 12, 64), 64)
+	return converted, true
+}
+
+// specQuantity 数字字段声明了单位时，接受JSON数字（视为该单位）与带单位的数量文本
+func specQuantity(value interface{}, base *specUnit) (float64, bool) {
+	if text, ok := value.(string); ok {
+		return parseQuantity(text, base)
+	}
+	return specNumber(value)
+}
+
+// referencedFields 规则中引用的其他字段
+func (fr *fieldRules) referencedFields() []string {
+	var names []string
+	for _, rule := range fr.rules {
+		switch rule.Name {
+		case ruleRequiredIf:
+			names = append(names, rule.Args[0])
+		case ruleRequiredWith:
+			names = append(names, rule.Args...)
+		}
+	}
+	return names
+}
+
+// requiredBy 判断字段在当前规格参数下是否必填，必填时返回未填写的错误消息
+func (fr *fieldRules) requiredBy(field *model.TemplateField, specs map[string]interface{}, labels map[string]string) (bool, string) {
+	if field.Required {
+		return true, field.FieldLabel + "是必填字段"
+	}
+	label := func(name string) string {
+		if labels[name] != "" {
+			return labels[name]
+		}
+		return name
+	}
+
+	for _, rule := range fr.rules {
+		var message string
+		switch rule.Name {
+		case ruleRequiredIf:
+			other, ok := specText(specs[rule.Args[0]])
+			if ok && containsString(rule.Args[1:], other) {
+				message = fmt.Sprintf("%s为%s时%s是必填字段", label(rule.Args[0]), other, field.FieldLabel)
+			}
+		case ruleRequiredWith:
+			for _, name := range rule.Args {
+				if !isEmptySpecValue(specs[name]) {
+					message = fmt.Sprintf("填写%s时%s是必填字段", label(name), field.FieldLabel)
+					break
+				}
+			}
+		}
+		if message != "" {
+			return true, rule.customMessage(field, message)
+		}
+	}
+	return false, ""
+}
+
+// check 按顺序校验全部规则，返回第一条不满足的规则的错误
+func (fr *fieldRules) check(field *model.TemplateField, value ruleValue) error {
+	for _, rule := range fr.rules {
+		if message := fr.checkRule(field, rule, value); message != "" {
+			return errors.New(rule.customMessage(field, message))
+		}
+	}
+	return nil
+}
+
+// checkRule 校验单条规则，满足时返回空字符串，否则返回默认错误消息
+func (fr *fieldRules) checkRule(field *model.TemplateField, rule *validationRule, value ruleValue) string {
+	label := field.FieldLabel
+	length := float64(utf8.RuneCountInString(value.text))
+	if fr.kind == ruleKindList {
+		length = float64(value.count)
+	}
+
+	switch rule.Name {
+	case ruleMin, ruleMax, ruleBetween:
+		lower, upper := rule.Name != ruleMax, rule.Name != ruleMin
+		switch fr.kind {
+		case ruleKindNumber:
+			min, max := rule.bounds(lower, upper)
+			if lower && value.number < min || upper && value.number > max {
+				return boundMessage(label, rule, lower, upper, "不能小于", "不能大于")
+			}
+		case ruleKindDate:
+			dates := rule.dates
+			if lower && value.date.Before(dates[0].resolve()) || upper && value.date.After(dates[len(dates)-1].resolve()) {
+				return dateBoundMessage(label, rule, lower, upper)
+			}
+		default:
+			min, max := rule.bounds(lower, upper)
+			if fr.kind == ruleKindList && (lower && length < min || upper && length > max) {
+				switch {
+				case lower && upper:
+					return fmt.Sprintf("字段 %s 必须有 %d 到 %d 项", label, int(min), int(max))
+				case lower:
+					return fmt.Sprintf("字段 %s 至少需要 %d 项", label, int(min))
+				default:
+					return fmt.Sprintf("字段 %s 最多 %d 项", label, int(max))
+				}
+			}
+			if lower && length < min || upper && length > max {
+				switch {
+				case lower && upper:
+					return fmt.Sprintf("字段 %s 长度必须在 %d 到 %d 字符之间", label, int(min), int(max))
+				case lower:
+					return fmt.Sprintf("字段 %s 长度不能少于 %d 字符", label, int(min))
+				default:
+					return fmt.Sprintf("字段 %s 长度不能超过 %d 字符", label, int(max))
+				}
+			}
+		}
+	case ruleMinLength:
+		if length < rule.numbers[0] {
+			return fmt.Sprintf("字段 %s 长度不能少于 %d 字符", label, int(rule.numbers[0]))
+		}
+	case ruleMaxLength:
+		if length > rule.numbers[0] {
+			return fmt.Sprintf("字段 %s 长度不能超过 %d 字符", label, int(rule.numbers[0]))
+		}
+	case ruleInteger:
+		if value.number != math.Trunc(value.number) {
+			return fmt.Sprintf("字段 %s 必须是整数", label)
+		}
+	case ruleStep:
+		quotient := value.number / rule.numbers[0]
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			return fmt.Sprintf("字段 %s 必须是 %s 的整数倍", label, rule.Args[0])
+		}
+	case rulePrecision:
+		text := strconv.FormatFloat(value.number, 'f', -1, 64)
+		if index := strings.Index(text, "."); index >= 0 && len(text)-index-1 > int(rule.numbers[0]) {
+			return fmt.Sprintf("字段 %s 最多保留 %d 位小数", label, int(rule.numbers[0]))
+		}
+	case ruleAfter, ruleAfterOrEqual, ruleBefore, ruleBeforeOrEqual:
+		bound := rule.dates[0].resolve()
+		boundText := bound.Format("2006-01-02")
+		switch {
+		case rule.Name == ruleAfter && !value.date.After(bound):
+			return fmt.Sprintf("字段 %s 必须晚于 %s", label, boundText)
+		case rule.Name == ruleAfterOrEqual && value.date.Before(bound):
+			return fmt.Sprintf("字段 %s 不能早于 %s", label, boundText)
+		case rule.Name == ruleBefore && !value.date.Before(bound):
+			return fmt.Sprintf("字段 %s 必须早于 %s", label, boundText)
+		case rule.Name == ruleBeforeOrEqual && value.date.After(bound):
+			return fmt.Sprintf("字段 %s 不能晚于 %s", label, boundText)
+		}
+	case ruleIn, ruleNotIn:
+		found := containsString(rule.Args, value.text)
+		if fr.kind == ruleKindNumber {
+			found = false
+			for _, number := range rule.numbers {
+				if number == value.number {
+					found = true
+					break
+				}
+			}
+		}
+		if rule.Name == ruleIn && !found {
+			return fmt.Sprintf("字段 %s 的值必须是 %s 之一", label, strings.Join(rule.Args, "、"))
+		}
+		if rule.Name == ruleNotIn && found {
+			return fmt.Sprintf("字段 %s 的值不能是 %s", label, strings.Join(rule.Args, "、"))
+		}
+	case ruleURL:
+		if !isWebURL(value.text) {
+			return fmt.Sprintf("字段 %s 必须是有效的网址", label)
+		}
+	case ruleEmail:
+		address, err := mail.ParseAddress(value.text)
+		if err != nil || address.Address != value.text {
+			return fmt.Sprintf("字段 %s 必须是有效的邮箱地址", label)
+		}
+	case ruleRegex:
+		if !rule.pattern.MatchString(value.text) {
+			return fmt.Sprintf("字段 %s 格式不正确", label)
+		}
+	}
+	return ""
+}
+
+// bounds 取 min/max/between 的上下限
+func (r *validationRule) bounds(lower, upper bool) (float64, float64) {
+	if lower && upper {
+		return r.numbers[0], r.numbers[1]
+	}
+	return r.numbers[0], r.numbers[0]
+}
+
+// customMessage 有自定义错误消息时替换默认消息
+func (r *validationRule) customMessage(field *model.TemplateField, message string) string {
+	if r.Message == "" {
+		return message
+	}
+	return strings.ReplaceAll(r.Message, "{label}", field.FieldLabel)
+}
+
+// boundMessage 数值范围的默认错误消息，参数按规则中的原文展示（保留单位）
+func boundMessage(label string, rule *validationRule, lower, upper bool, belowText, aboveText string) string {
+	switch {
+	case lower && upper:
+		return fmt.Sprintf("字段 %s 必须在 %s 到 %s 之间", label, rule.Args[0], rule.Args[1])
+	case lower:
+		return fmt.Sprintf("字段 %s %s %s", label, belowText, rule.Args[0])
+	default:
+		return fmt.Sprintf("字段 %s %s %s", label, aboveText, rule.Args[0])
+	}
+}
+
+// dateBoundMessage 日期范围的默认错误消息
+func dateBoundMessage(label string, rule *validationRule, lower, upper bool) string {
+	first := rule.dates[0].resolve().Format("2006-01-02")
+	last := rule.dates[len(rule.dates)-1].resolve().Format("2006-01-02")
+	switch {
+	case lower && upper:
+		return fmt.Sprintf("字段 %s 必须在 %s 到 %s 之间", label, first, last)
+	case lower:
+		return fmt.Sprintf("字段 %s 不能早于 %s", label, first)
+	default:
+		return fmt.Sprintf("字段 %s 不能晚于 %s", label, last)
+	}
+}
+
+// isWebURL 检查是否为 http/https 网址
+func isWebURL(text string) bool {
+	parsed, err := url.ParseRequestURI(text)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
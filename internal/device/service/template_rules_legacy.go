@@ -0,0 +1,119 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RuleMigrationReport 旧语法验证规则的改写结果
+type RuleMigrationReport struct {
+	Templates int      // 改写的模板数
+	Versions  int      // 改写的模板字段版本数
+	Problems  []string // 被删除的旧规则，以及改写后仍无法解析的规则
+}
+
+// MigrateLegacyRules 把按旧语法保存的验证规则改写为当前语法：旧语法只识别 min/max/regex，
+// min/max 对所有字段类型都表示长度，改写为 min_length/max_length；regex 中的 # 转义为 \#；
+// 旧语法不识别或参数无法改写的规则删除并记入报告，改写后仍无法解析的规则保留并记入报告
+func (s *TemplateService) MigrateLegacyRules() (*RuleMigrationReport, error) {
+	templates, versions, err := s.templateRepo.GetLegacyRuleTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RuleMigrationReport{}
+	for _, template := range templates {
+		fields, problems, err := upgradeLegacyFields(template.Fields)
+		if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("模板 %d（%s）字段定义无法解析: %v", template.ID, template.Name, err))
+			continue
+		}
+		for _, problem := range problems {
+			report.Problems = append(report.Problems, fmt.Sprintf("模板 %d（%s）%s", template.ID, template.Name, problem))
+		}
+		if err := s.templateRepo.UpgradeTemplateRules(template.ID, fields); err != nil {
+			return report, err
+		}
+		report.Templates++
+	}
+	for _, version := range versions {
+		fields, problems, err := upgradeLegacyFields(version.Fields)
+		if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("模板 %d 字段版本 %d 字段定义无法解析: %v", version.TemplateID, version.Version, err))
+			continue
+		}
+		for _, problem := range problems {
+			report.Problems = append(report.Problems, fmt.Sprintf("模板 %d 字段版本 %d %s", version.TemplateID, version.Version, problem))
+		}
+		if err := s.templateRepo.UpgradeTemplateVersionRules(version.ID, fields); err != nil {
+			return report, err
+		}
+		report.Versions++
+	}
+	return report, nil
+}
+
+// upgradeLegacyFields 改写字段定义JSON中全部字段（含字段组的子字段）的验证规则
+func upgradeLegacyFields(fieldsJSON string) (string, []string, error) {
+	var fields []*model.TemplateField
+	if fieldsJSON != "" {
+		if err := json.Unmarshal([]byte(fieldsJSON), &fields); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var problems []string
+	var upgrade func(fields []*model.TemplateField, prefix string)
+	upgrade = func(fields []*model.TemplateField, prefix string) {
+		for _, field := range fields {
+			name := prefix + field.FieldName
+			rules, dropped := upgradeLegacyRules(field.ValidationRules)
+			field.ValidationRules = rules
+			for _, rule := range dropped {
+				problems = append(problems, fmt.Sprintf("字段 %s 的规则 %q 在旧语法中不生效或无法改写，已删除", name, rule))
+			}
+			if _, err := parseFieldRules(field); err != nil {
+				problems = append(problems, fmt.Sprintf("字段 %s 的规则 %q 无法解析: %v", name, rules, err))
+			}
+			upgrade(field.Fields, name+".")
+		}
+	}
+	upgrade(fields, "")
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(data), problems, nil
+}
+
+// upgradeLegacyRules 按旧语法拆分规则文本并改写为当前语法，返回改写后的文本与删除的规则
+func upgradeLegacyRules(text string) (string, []string) {
+	var rules, dropped []string
+	for _, token := range strings.Split(text, "|") {
+		token = strings.TrimSpace(token)
+		switch {
+		case token == "":
+		case strings.HasPrefix(token, "min:"), strings.HasPrefix(token, "max:"):
+			// 旧语法按 Atoi 解析参数，解析失败时忽略该规则
+			length, err := strconv.Atoi(token[len("min:"):])
+			if err != nil || length < 0 {
+				dropped = append(dropped, token)
+				continue
+			}
+			name := ruleMinLength
+			if strings.HasPrefix(token, "max:") {
+				name = ruleMaxLength
+			}
+			rules = append(rules, name+":"+strconv.Itoa(length))
+		case strings.HasPrefix(token, "regex:"):
+			rules = append(rules, ruleRegex+":"+strings.ReplaceAll(strings.TrimPrefix(token, "regex:"), "#", `\#`))
+		default:
+			dropped = append(dropped, token)
+		}
+	}
+	return strings.Join(rules, "|"), dropped
+}
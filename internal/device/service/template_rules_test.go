@@ -0,0 +1,213 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// daysFromToday 相对今天 N 天的日期文本，与 today±N 参数的计算方式一致
+func daysFromToday(days int) string {
+	return time.Now().AddDate(0, 0, days).Format("2006-01-02")
+}
+
+func TestParseFieldRulesErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		fieldType string
+		rules     string
+		wantErr   string // 为空表示规则有效
+	}{
+		{name: "有效的数量规则", fieldType: FieldTypeNumber, rules: "unit:GB|between:64GB,8TB|step:64"},
+		{name: "有效的日期规则", fieldType: FieldTypeDate, rules: "after:today-30|before_or_equal:today+7"},
+		{name: "转义的分隔符", fieldType: FieldTypeText, rules: `regex:^(a\|b)$#只能填a或b\#`},
+		{name: "参数为空", fieldType: FieldTypeText, rules: "min:", wantErr: "参数不能为空"},
+		{name: "未知规则", fieldType: FieldTypeText, rules: "uppercase", wantErr: "未知的规则"},
+		{name: "参数个数", fieldType: FieldTypeNumber, rules: "between:1", wantErr: "需要2个参数"},
+		{name: "数字字段的非数字参数", fieldType: FieldTypeNumber, rules: "min:abc", wantErr: "必须是数字"},
+		{name: "文本字段的负数长度", fieldType: FieldTypeText, rules: "max:-1", wantErr: "非负整数"},
+		{name: "下限大于上限", fieldType: FieldTypeNumber, rules: "between:5,1", wantErr: "下限不能大于上限"},
+		{name: "无效日期", fieldType: FieldTypeDate, rules: "min:2024-13-01", wantErr: "必须是日期"},
+		{name: "无效的today偏移", fieldType: FieldTypeDate, rules: "after:today7", wantErr: "必须是日期"},
+		{name: "日期规则用于文本字段", fieldType: FieldTypeText, rules: "before:today", wantErr: "只适用于日期字段"},
+		{name: "整数规则用于文本字段", fieldType: FieldTypeText, rules: "integer", wantErr: "只适用于数字字段"},
+		{name: "步长为0", fieldType: FieldTypeNumber, rules: "step:0", wantErr: "必须大于0"},
+		{name: "多选字段只比较项数", fieldType: FieldTypeMultiSelect, rules: "regex:^a", wantErr: "不适用于multiselect"},
+		{name: "布尔字段只支持条件必填", fieldType: FieldTypeBoolean, rules: "min:1", wantErr: "不适用于boolean"},
+		{name: "不支持的单位", fieldType: FieldTypeNumber, rules: "unit:parsec", wantErr: "不支持的单位"},
+		{name: "单位用于日期字段", fieldType: FieldTypeDate, rules: "unit:GB", wantErr: "只适用于数字或文本字段"},
+		{name: "重复的单位", fieldType: FieldTypeText, rules: "unit:GB|unit:MB", wantErr: "只能出现一次"},
+		{name: "参数与单位量纲不同", fieldType: FieldTypeNumber, rules: "unit:GB|max:5kg", wantErr: "GB量纲"},
+		{name: "无效正则", fieldType: FieldTypeText, rules: "regex:(", wantErr: "正则表达式无效"},
+		{name: "required_if 缺少值", fieldType: FieldTypeText, rules: "required_if:type", wantErr: "至少需要2个参数"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFieldRules(&model.TemplateField{FieldName: "value", FieldLabel: "值", FieldType: tt.fieldType, ValidationRules: tt.rules})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("规则 %q 返回错误: %v", tt.rules, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("规则 %q 返回错误 %v，期望包含 %q", tt.rules, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFieldRulesCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		fieldType string
+		options   []string
+		rules     string
+		value     interface{}
+		wantErr   string      // 为空表示校验通过
+		want      interface{} // 不为空时检查规范化后的值
+	}{
+		// 数字与金额比较数值
+		{name: "数字不小于下限", fieldType: FieldTypeNumber, rules: "min:10", value: 10},
+		{name: "数字小于下限", fieldType: FieldTypeNumber, rules: "min:10", value: 5, wantErr: "字段 规格 不能小于 10"},
+		{name: "数字文本大于上限", fieldType: FieldTypeNumber, rules: "max:10", value: "11", wantErr: "字段 规格 不能大于 10"},
+		{name: "数字超出范围", fieldType: FieldTypeNumber, rules: "between:1,5", value: 6, wantErr: "字段 规格 必须在 1 到 5 之间"},
+		{name: "数字不按字符数比较", fieldType: FieldTypeNumber, rules: "min:5", value: 123},
+		{name: "金额小于下限", fieldType: FieldTypeMoney, rules: "min:100", value: 50, wantErr: "不能小于 100"},
+		// 文本比较字符数
+		{name: "文本过短", fieldType: FieldTypeText, rules: "min:3", value: "ab", wantErr: "字段 规格 长度不能少于 3 字符"},
+		{name: "文本按字符数而非字节数", fieldType: FieldTypeText, rules: "max:2", value: "中文"},
+		{name: "文本过长", fieldType: FieldTypeTextarea, rules: "between:1,2", value: "中文字", wantErr: "长度必须在 1 到 2 字符之间"},
+		{name: "max_length", fieldType: FieldTypeText, rules: "max_length:3", value: "abcd", wantErr: "长度不能超过 3 字符"},
+		// 多选比较项数
+		{name: "多选项数不足", fieldType: FieldTypeMultiSelect, options: []string{"a", "b", "c"}, rules: "min:2", value: []interface{}{"a"}, wantErr: "至少需要 2 项"},
+		{name: "多选项数超出", fieldType: FieldTypeMultiSelect, options: []string{"a", "b", "c"}, rules: "between:1,2", value: []interface{}{"a", "b", "c"}, wantErr: "必须有 1 到 2 项"},
+		// 日期比较日期
+		{name: "日期早于下限", fieldType: FieldTypeDate, rules: "min:2024-01-01", value: "2023-12-31", wantErr: "不能早于 2024-01-01"},
+		{name: "日期在范围内", fieldType: FieldTypeDate, rules: "between:2024-01-01,2024-12-31", value: "2024-06-01"},
+		{name: "日期晚于上限", fieldType: FieldTypeDate, rules: "max:2024-12-31", value: "2025-01-01", wantErr: "不能晚于 2024-12-31"},
+		{name: "不晚于今天", fieldType: FieldTypeDate, rules: "before_or_equal:today", value: daysFromToday(0)},
+		{name: "晚于今天", fieldType: FieldTypeDate, rules: "before_or_equal:today", value: daysFromToday(1), wantErr: "不能晚于 " + daysFromToday(0)},
+		{name: "after 不含边界", fieldType: FieldTypeDate, rules: "after:today-7", value: daysFromToday(-7), wantErr: "必须晚于 " + daysFromToday(-7)},
+		{name: "after 边界之后", fieldType: FieldTypeDate, rules: "after:today-7", value: daysFromToday(-6)},
+		{name: "before today+N", fieldType: FieldTypeDate, rules: "before:today+30", value: daysFromToday(30), wantErr: "必须早于 " + daysFromToday(30)},
+		{name: "min today-N", fieldType: FieldTypeDate, rules: "min:today-1", value: daysFromToday(-2), wantErr: "不能早于 " + daysFromToday(-1)},
+		// 带单位的数量
+		{name: "换算为字段单位", fieldType: FieldTypeNumber, rules: "unit:GB", value: "1TB", want: float64(1024)},
+		{name: "不带单位视为字段单位", fieldType: FieldTypeNumber, rules: "unit:GB|min:64GB", value: 512, want: float64(512)},
+		{name: "换算后比较下限", fieldType: FieldTypeNumber, rules: "unit:GB|min:64GB", value: "32768 MB", wantErr: "不能小于 64GB"},
+		{name: "范围参数带不同单位", fieldType: FieldTypeNumber, rules: "unit:GB|between:64GB,1TB", value: "2048MB", wantErr: "必须在 64GB 到 1TB 之间"},
+		{name: "换算后检查步长", fieldType: FieldTypeNumber, rules: "unit:GB|step:64", value: "96GB", wantErr: "必须是 64 的整数倍"},
+		{name: "量纲不同", fieldType: FieldTypeNumber, rules: "unit:GB", value: "5 kg", wantErr: "必须是数字或带单位的数值"},
+		{name: "文本字段保存原文", fieldType: FieldTypeText, rules: "unit:mAh|min:1Ah", value: "4500mAh", want: "4500mAh"},
+		// 自定义错误消息
+		{name: "自定义消息替换字段名", fieldType: FieldTypeNumber, rules: "min:10#{label}至少为10", value: 5, wantErr: "规格至少为10"},
+		{name: "只替换对应规则的消息", fieldType: FieldTypeNumber, rules: "min:10#{label}至少为10|max:20", value: 30, wantErr: "字段 规格 不能大于 20"},
+		{name: "消息与正则中的转义#", fieldType: FieldTypeText, rules: `regex:^\#\d+$#{label}需以\#开头`, value: "12", wantErr: "规格需以#开头"},
+		{name: "正则匹配字面#", fieldType: FieldTypeText, rules: `regex:^\#\d+$`, value: "#12"},
+	}
+	svc := &TemplateService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := &model.TemplateField{FieldName: "spec", FieldLabel: "规格", FieldType: tt.fieldType, Options: tt.options, ValidationRules: tt.rules}
+			normalized, errs := svc.NormalizeSpecifications([]*model.TemplateField{field}, map[string]interface{}{"spec": tt.value})
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Fatalf("值 %v 校验失败: %s", tt.value, errs[0].Message)
+				}
+				if tt.want != nil && normalized["spec"] != tt.want {
+					t.Errorf("规范化后的值为 %v，期望 %v", normalized["spec"], tt.want)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Message, tt.wantErr) {
+				t.Fatalf("值 %v 的校验错误为 %v，期望包含 %q", tt.value, errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConditionalRequired(t *testing.T) {
+	fields := []*model.TemplateField{
+		{FieldName: "type", FieldLabel: "类型", FieldType: FieldTypeSelect, Options: []string{"laptop", "phone"}},
+		{FieldName: "battery", FieldLabel: "电池", FieldType: FieldTypeText, ValidationRules: "required_if:type,laptop,tablet"},
+		{FieldName: "warranty", FieldLabel: "保修商", FieldType: FieldTypeText},
+		{FieldName: "warranty_no", FieldLabel: "保修单号", FieldType: FieldTypeText, ValidationRules: "required_with:warranty#请同时填写{label}"},
+	}
+	tests := []struct {
+		name    string
+		specs   map[string]interface{}
+		wantErr map[string]string
+	}{
+		{name: "条件不满足", specs: map[string]interface{}{"type": "phone"}},
+		{name: "required_if 条件满足", specs: map[string]interface{}{"type": "laptop"}, wantErr: map[string]string{"battery": "类型为laptop时电池是必填字段"}},
+		{name: "required_if 已填写", specs: map[string]interface{}{"type": "laptop", "battery": "60Wh"}},
+		{name: "required_with 自定义消息", specs: map[string]interface{}{"warranty": "官方"}, wantErr: map[string]string{"warranty_no": "请同时填写保修单号"}},
+		{name: "required_with 空值不触发", specs: map[string]interface{}{"warranty": " "}},
+	}
+	svc := &TemplateService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := svc.NormalizeSpecifications(fields, tt.specs)
+			if len(errs) != len(tt.wantErr) {
+				t.Fatalf("返回 %d 个错误，期望 %d 个: %v", len(errs), len(tt.wantErr), errs)
+			}
+			for _, err := range errs {
+				if err.Reason != SpecErrorRequired || err.Message != tt.wantErr[err.Field] {
+					t.Errorf("字段 %s 的错误为 %s/%s，期望 %s/%s", err.Field, err.Reason, err.Message, SpecErrorRequired, tt.wantErr[err.Field])
+				}
+			}
+		})
+	}
+}
+
+func TestUpgradeLegacyRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		rules       string
+		want        string
+		wantDropped []string
+	}{
+		{name: "空规则", rules: "", want: ""},
+		{name: "min/max 改为长度", rules: "min:2|max:20", want: "min_length:2|max_length:20"},
+		{name: "正则中的#转义", rules: "regex:^#[0-9a-f]{6}$", want: `regex:^\#[0-9a-f]{6}$`},
+		{name: "旧语法忽略的规则删除", rules: "required| min:abc |max:-1|between:1,2", want: "", wantDropped: []string{"required", "min:abc", "max:-1", "between:1,2"}},
+		{name: "前后空白", rules: " max:5 | regex:^a ", want: "max_length:5|regex:^a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, dropped := upgradeLegacyRules(tt.rules)
+			if got != tt.want {
+				t.Errorf("改写为 %q，期望 %q", got, tt.want)
+			}
+			if strings.Join(dropped, "|") != strings.Join(tt.wantDropped, "|") {
+				t.Errorf("删除的规则为 %v，期望 %v", dropped, tt.wantDropped)
+			}
+		})
+	}
+}
+
+// TestUpgradeLegacyFields 改写后旧规则的含义不变：数字与日期字段的 min/max 仍按长度比较
+func TestUpgradeLegacyFields(t *testing.T) {
+	legacy := `[{"field_name":"count","field_label":"数量","field_type":"number","validation_rules":"max:3"},` +
+		`{"field_name":"color","field_label":"颜色","field_type":"text","validation_rules":"regex:^#[0-9a-f]{6}$"},` +
+		`{"field_name":"code","field_label":"编码","field_type":"text","validation_rules":"regex:("}]`
+
+	fieldsJSON, problems, err := upgradeLegacyFields(legacy)
+	if err != nil {
+		t.Fatalf("改写返回错误: %v", err)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0], "字段 code") || !strings.Contains(problems[0], "无法解析") {
+		t.Errorf("报告为 %v，期望只报告字段 code 无法解析", problems)
+	}
+
+	fields, err := parseFieldsJSON(fieldsJSON)
+	if err != nil {
+		t.Fatalf("改写后的字段定义无法解析: %v", err)
+	}
+	_, errs := (&TemplateService{}).NormalizeSpecifications(fields[:2], map[string]interface{}{"count": 1000, "color": "#00ff00"})
+	if len(errs) != 1 || errs[0].Field != "count" || !strings.Contains(errs[0].Message, "长度不能超过 3 字符") {
+		t.Errorf("校验错误为 %v，期望数量按长度超出", errs)
+	}
+}
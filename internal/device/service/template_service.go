@@ -7,16 +7,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
-	"regexp"
-	"strconv"
-	"strings"
-	"time"
+	"reflect"
 )
 
 type TemplateService struct {
 	templateRepo *repository.TemplateRepository
 	categoryRepo *repository.CategoryRepository
 	deviceRepo   *repository.DeviceRepository
+	eventRepo    *repository.DeviceEventRepository
 }
 
 func NewTemplateService() *TemplateService {
@@ -24,11 +22,19 @@ func NewTemplateService() *TemplateService {
 		templateRepo: repository.NewTemplateRepository(),
 		categoryRepo: repository.NewCategoryRepository(),
 		deviceRepo:   repository.NewDeviceRepository(),
+		eventRepo:    repository.NewDeviceEventRepository(),
 	}
 }
 
 // GetTemplatesList 获取设备模板列表
-func (s *TemplateService) GetTemplatesList(req *GetTemplatesListRequest) (*GetTemplatesListResponse, error) {
+func (s *TemplateService) GetTemplatesList(userID int, req *GetTemplatesListRequest) (*GetTemplatesListResponse, error) {
+	if req.Scope != "" && req.Scope != "mine" && req.Scope != "library" {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "无效的范围: "+req.Scope)
+	}
+	if req.Sort != "" && req.Sort != "popular" && req.Sort != "rating" && req.Sort != "newest" {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "无效的排序: "+req.Sort)
+	}
+
 	// 设置默认值
 	if req.Page <= 0 {
 		req.Page = 1
@@ -40,7 +46,7 @@ func (s *TemplateService) GetTemplatesList(req *GetTemplatesListRequest) (*GetTe
 		req.Limit = 100
 	}
 
-	templates, total, err := s.templateRepo.GetTemplatesWithPagination(req.CategoryID, req.Active, req.Page, req.Limit)
+	templates, total, err := s.templateRepo.GetTemplatesWithPagination(userID, req.CategoryID, req.Active, req.Scope, req.Sort, req.Page, req.Limit)
 	if err != nil {
 		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取模板列表失败")
 	}
@@ -57,34 +63,15 @@ func (s *TemplateService) GetTemplatesList(req *GetTemplatesListRequest) (*GetTe
 }
 
 // GetTemplateDetail 获取设备模板详情
-func (s *TemplateService) GetTemplateDetail(templateID int) (*model.DeviceTemplate, error) {
-	if templateID <= 0 {
-		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "模板ID无效")
-	}
-
-	template, err := s.templateRepo.GetTemplateByID(templateID)
-	if err != nil {
-		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取模板详情失败")
-	}
-	if template == nil {
-		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "模板不存在")
-	}
-
-	return template, nil
+func (s *TemplateService) GetTemplateDetail(userID, templateID int) (*model.DeviceTemplate, error) {
+	return s.getViewableTemplate(userID, templateID)
 }
 
 // GetTemplateFields 获取模板字段定义
-func (s *TemplateService) GetTemplateFields(templateID int) ([]*model.TemplateField, error) {
-	if templateID <= 0 {
-		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "模板ID无效")
-	}
-
-	template, err := s.templateRepo.GetTemplateByID(templateID)
+func (s *TemplateService) GetTemplateFields(userID, templateID int) ([]*model.TemplateField, error) {
+	template, err := s.getViewableTemplate(userID, templateID)
 	if err != nil {
-		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取模板失败")
-	}
-	if template == nil {
-		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "模板不存在")
+		return nil, err
 	}
 
 	var fields []*model.TemplateField
@@ -98,13 +85,8 @@ func (s *TemplateService) GetTemplateFields(templateID int) ([]*model.TemplateFi
 	return fields, nil
 }
 
-// CreateTemplate 创建设备模板（管理员）
+// CreateTemplate 创建设备模板，模板属于创建者，默认仅自己可见
 func (s *TemplateService) CreateTemplate(userID int, req *CreateTemplateRequest) (*model.DeviceTemplate, error) {
-	// TODO: 验证管理员权限
-	// if !s.isAdmin(userID) {
-	//     return nil, utils.NewBusinessError(utils.ERROR_FORBIDDEN, "需要管理员权限")
-	// }
-
 	// 验证必填参数
 	if req.Name == "" {
 		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "模板名称不能为空")
@@ -146,9 +128,17 @@ func (s *TemplateService) CreateTemplate(userID int, req *CreateTemplateRequest)
 		Fields:      string(fieldsJSON),
 		IsActive:    req.Active,
 		UseCount:    0,
+		OwnerID:     &userID,
+	}
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = model.TemplateVisibilityPrivate
+	}
+	if err := applyVisibility(template, visibility, userID); err != nil {
+		return nil, err
 	}
 
-	err = s.templateRepo.CreateTemplate(template)
+	err = s.templateRepo.CreateTemplate(template, userID)
 	if err != nil {
 		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "创建模板失败")
 	}
@@ -156,24 +146,15 @@ func (s *TemplateService) CreateTemplate(userID int, req *CreateTemplateRequest)
 	return template, nil
 }
 
-// UpdateTemplate 更新设备模板（管理员）
+// UpdateTemplate 更新设备模板（创建者或管理员，系统模板仅管理员）
 func (s *TemplateService) UpdateTemplate(userID, templateID int, req *UpdateTemplateRequest) (*model.DeviceTemplate, error) {
-	// TODO: 验证管理员权限
-	// if !s.isAdmin(userID) {
-	//     return nil, utils.NewBusinessError(utils.ERROR_FORBIDDEN, "需要管理员权限")
-	// }
-
-	if templateID <= 0 {
-		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "模板ID无效")
-	}
-
 	// 获取现有模板
-	template, err := s.templateRepo.GetTemplateByID(templateID)
+	template, err := s.getEditableTemplate(userID, templateID)
 	if err != nil {
-		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取模板失败")
+		return nil, err
 	}
-	if template == nil {
-		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "模板不存在")
+	if req.Version != nil && *req.Version != template.Version {
+		return nil, templateConflictError(template)
 	}
 
 	// 更新字段
@@ -197,12 +178,19 @@ func (s *TemplateService) UpdateTemplate(userID, templateID int, req *UpdateTemp
 	if req.Icon != "" {
 		template.Icon = req.Icon
 	}
+	fieldsChanged := false
 	if len(req.Fields) > 0 {
 		// 验证字段定义
 		err = s.validateTemplateFields(req.Fields)
 		if err != nil {
 			return nil, err
 		}
+		currentFields, err := parseTemplateFields(template)
+		if err != nil {
+			return nil, err
+		}
+		// 字段定义有变化时生成新版本，已有设备保留原版本，需通过迁移升级
+		fieldsChanged = !reflect.DeepEqual(currentFields, req.Fields)
 
 		// 序列化字段定义
 		fieldsJSON, err := json.Marshal(req.Fields)
@@ -213,7 +201,16 @@ func (s *TemplateService) UpdateTemplate(userID, templateID int, req *UpdateTemp
 	}
 	template.IsActive = req.Active
 
-	err = s.templateRepo.UpdateTemplate(template)
+	// 公开模板的内容被创建者修改后需要重新审核
+	if template.OwnerID != nil && template.Visibility == model.TemplateVisibilityPublic && !utils.IsAdmin(userID) {
+		template.ReviewStatus = model.TemplateReviewPending
+	}
+
+	err = s.templateRepo.UpdateTemplate(template, fieldsChanged, userID)
+	if err == repository.ErrVersionConflict {
+		current, _ := s.templateRepo.GetTemplateByID(templateID)
+		return nil, templateConflictError(current)
+	}
 	if err != nil {
 		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "更新模板失败")
 	}
@@ -221,15 +218,17 @@ func (s *TemplateService) UpdateTemplate(userID, templateID int, req *UpdateTemp
 	return template, nil
 }
 
-// DeleteTemplate 删除设备模板（管理员）
-func (s *TemplateService) DeleteTemplate(userID, templateID int) error {
-	// TODO: 验证管理员权限
-	// if !s.isAdmin(userID) {
-	//     return utils.NewBusinessError(utils.ERROR_FORBIDDEN, "需要管理员权限")
-	// }
+// templateConflictError 版本冲突时返回的业务异常，附带模板的最新状态
+func templateConflictError(current *model.DeviceTemplate) error {
+	return utils.NewBusinessErrorWithDetails(utils.ERROR_CONFLICT, "模板已被修改，请刷新后重试", map[string]interface{}{
+		"current": current,
+	})
+}
 
-	if templateID <= 0 {
-		return utils.NewBusinessError(utils.ERROR_PARAM, "模板ID无效")
+// DeleteTemplate 删除设备模板（创建者或管理员，系统模板仅管理员）
+func (s *TemplateService) DeleteTemplate(userID, templateID int) error {
+	if _, err := s.getEditableTemplate(userID, templateID); err != nil {
+		return err
 	}
 
 	// 检查模板是否被使用
@@ -261,13 +260,9 @@ func (s *TemplateService) GetPopularTemplates(limit int) ([]*model.DeviceTemplat
 }
 
 // ValidateDeviceData 根据模板验证设备数据
-func (s *TemplateService) ValidateDeviceData(templateID int, req *ValidateDeviceDataRequest) (*ValidateDeviceDataResponse, error) {
-	if templateID <= 0 {
-		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "模板ID无效")
-	}
-
+func (s *TemplateService) ValidateDeviceData(userID, templateID int, req *ValidateDeviceDataRequest) (*ValidateDeviceDataResponse, error) {
 	// 获取模板字段定义
-	fields, err := s.GetTemplateFields(templateID)
+	fields, err := s.GetTemplateFields(userID, templateID)
 	if err != nil {
 		return nil, err
 	}
@@ -279,31 +274,20 @@ func (s *TemplateService) ValidateDeviceData(templateID int, req *ValidateDevice
 		Invalid: make(map[string]interface{}),
 	}
 
-	// 验证每个字段
-	for _, field := range fields {
-		value, exists := req.DeviceData[field.FieldName]
-
-		// 检查必填字段
-		if field.Required && (!exists || value == nil || value == "") {
-			response.Valid = false
-			response.Missing = append(response.Missing, field.FieldName)
-			response.Errors[field.FieldName] = field.FieldLabel + "是必填字段"
-			continue
-		}
-
-		// 如果字段不存在或为空，跳过验证
-		if !exists || value == nil {
-			continue
-		}
-
-		// 根据字段类型验证
-		err := s.validateFieldValue(field, value)
-		if err != nil {
-			response.Valid = false
-			response.Invalid[field.FieldName] = value
-			response.Errors[field.FieldName] = err.Error()
+	// 按写入设备时相同的规则校验
+	normalized, errs := s.NormalizeSpecifications(fields, req.DeviceData)
+	for _, fieldErr := range errs {
+		response.Valid = false
+		response.Errors[fieldErr.Field] = fieldErr.Message
+		if fieldErr.Reason == SpecErrorRequired {
+			response.Missing = append(response.Missing, fieldErr.Field)
+		} else {
+			response.Invalid[fieldErr.Field] = fieldErr.Value
 		}
 	}
+	if response.Valid {
+		response.Specifications = normalized
+	}
 
 	return response, nil
 }
@@ -323,18 +307,11 @@ func (s *TemplateService) GetRecommendedTemplates(userID int) ([]*model.DeviceTe
 }
 
 // GetTemplateStatistics 获取模板统计信息
-func (s *TemplateService) GetTemplateStatistics(templateID int) (*TemplateStatisticsResponse, error) {
-	if templateID <= 0 {
-		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "模板ID无效")
-	}
-
+func (s *TemplateService) GetTemplateStatistics(userID, templateID int) (*TemplateStatisticsResponse, error) {
 	// 获取模板基本信息
-	template, err := s.templateRepo.GetTemplateByID(templateID)
+	template, err := s.getViewableTemplate(userID, templateID)
 	if err != nil {
-		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取模板失败")
-	}
-	if template == nil {
-		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "模板不存在")
+		return nil, err
 	}
 
 	// 获取统计信息
@@ -373,88 +350,70 @@ func (s *TemplateService) validateTemplateFields(fields []*model.TemplateField)
 		fieldNames[field.FieldName] = true
 
 		// 检查字段类型
-		validTypes := []string{"text", "number", "select", "date", "textarea"}
-		if !s.contains(validTypes, field.FieldType) {
+		validType := false
+		for _, fieldType := range TemplateFieldTypes {
+			validType = validType || fieldType.Type == field.FieldType
+		}
+		if !validType {
 			return utils.NewBusinessError(utils.ERROR_PARAM, "无效的字段类型: "+field.FieldType)
 		}
 
-		// 检查select类型的选项
-		if field.FieldType == "select" && len(field.Options) == 0 {
-			return utils.NewBusinessError(utils.ERROR_PARAM, "select类型字段必须提供选项列表")
+		// 检查select/multiselect类型的选项
+		if (field.FieldType == FieldTypeSelect || field.FieldType == FieldTypeMultiSelect) && len(field.Options) == 0 {
+			return utils.NewBusinessError(utils.ERROR_PARAM, field.FieldType+"类型字段必须提供选项列表")
 		}
-	}
 
-	return nil
-}
-
-// validateFieldValue 验证字段值
-func (s *TemplateService) validateFieldValue(field *model.TemplateField, value interface{}) error {
-	strValue := fmt.Sprintf("%v", value)
+		// 检查money类型的货币
+		if field.FieldType == FieldTypeMoney {
+			for _, currency := range append([]string{field.Currency}, field.Options...) {
+				if currency != "" && !currencyPattern.MatchString(currency) {
+					return utils.NewBusinessError(utils.ERROR_PARAM, "无效的货币代码: "+currency)
+				}
+			}
+			if field.Currency != "" && len(field.Options) > 0 && !s.contains(field.Options, field.Currency) {
+				return utils.NewBusinessError(utils.ERROR_PARAM, "默认货币不在可选货币中: "+field.FieldName)
+			}
+		}
 
-	switch field.FieldType {
-	case "number":
-		_, err := strconv.ParseFloat(strValue, 64)
-		if err != nil {
-			return fmt.Errorf("字段 %s 必须是数字", field.FieldLabel)
+		// image/group 类型没有默认值
+		if (field.FieldType == FieldTypeImage || field.FieldType == FieldTypeGroup) && field.DefaultValue != "" {
+			return utils.NewBusinessError(utils.ERROR_PARAM, field.FieldType+"类型字段不支持默认值: "+field.FieldName)
 		}
-	case "date":
-		_, err := time.Parse("2006-01-02", strValue)
-		if err != nil {
-			return fmt.Errorf("字段 %s 必须是有效日期格式(YYYY-MM-DD)", field.FieldLabel)
+
+		// 检查group类型的子字段，字段组不能嵌套
+		if field.FieldType != FieldTypeGroup {
+			if len(field.Fields) > 0 {
+				return utils.NewBusinessError(utils.ERROR_PARAM, "只有group类型字段可以包含子字段: "+field.FieldName)
+			}
+			continue
 		}
-	case "select":
-		if !s.contains(field.Options, strValue) {
-			return fmt.Errorf("字段 %s 的值不在有效选项中", field.FieldLabel)
+		if len(field.Fields) == 0 {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "group类型字段必须提供子字段: "+field.FieldName)
 		}
-	case "text", "textarea":
-		// 可以添加长度验证等
-		if len(strValue) > 1000 && field.FieldType == "text" {
-			return fmt.Errorf("字段 %s 长度不能超过1000字符", field.FieldLabel)
+		for _, subField := range field.Fields {
+			if subField.FieldType == FieldTypeGroup {
+				return utils.NewBusinessError(utils.ERROR_PARAM, "字段组不能嵌套: "+field.FieldName)
+			}
 		}
-	}
-
-	// 验证规则
-	if field.ValidationRules != "" {
-		err := s.validateByRules(field.ValidationRules, strValue, field.FieldLabel)
-		if err != nil {
+		if err := s.validateTemplateFields(field.Fields); err != nil {
 			return err
 		}
 	}
 
-	return nil
-}
-
-// validateByRules 根据验证规则验证值
-func (s *TemplateService) validateByRules(rules, value, fieldLabel string) error {
-	// 简单的验证规则解析
-	ruleList := strings.Split(rules, "|")
-
-	for _, rule := range ruleList {
-		rule = strings.TrimSpace(rule)
-		if rule == "" {
-			continue
+	// 检查验证规则，以及默认值是否符合规则
+	for _, field := range fields {
+		rules, err := parseFieldRules(field)
+		if err != nil {
+			return utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("字段 %s 的验证规则无效: %v", field.FieldLabel, err))
 		}
-
-		if strings.HasPrefix(rule, "min:") {
-			minStr := strings.TrimPrefix(rule, "min:")
-			min, err := strconv.Atoi(minStr)
-			if err == nil && len(value) < min {
-				return fmt.Errorf("字段 %s 长度不能少于 %d 字符", fieldLabel, min)
-			}
-		} else if strings.HasPrefix(rule, "max:") {
-			maxStr := strings.TrimPrefix(rule, "max:")
-			max, err := strconv.Atoi(maxStr)
-			if err == nil && len(value) > max {
-				return fmt.Errorf("字段 %s 长度不能超过 %d 字符", fieldLabel, max)
+		for _, name := range rules.referencedFields() {
+			if name == field.FieldName || !fieldNames[name] {
+				return utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("字段 %s 的验证规则引用了无效字段: %s", field.FieldLabel, name))
 			}
-		} else if strings.HasPrefix(rule, "regex:") {
-			pattern := strings.TrimPrefix(rule, "regex:")
-			matched, err := regexp.MatchString(pattern, value)
-			if err != nil {
-				return fmt.Errorf("字段 %s 验证规则错误", fieldLabel)
-			}
-			if !matched {
-				return fmt.Errorf("字段 %s 格式不正确", fieldLabel)
+		}
+		if field.DefaultValue != "" {
+			if _, err := s.coerceFieldValue(field, rules, field.DefaultValue); err != nil {
+				return utils.NewBusinessError(utils.ERROR_PARAM, fmt.Sprintf("字段 %s 的默认值无效: %v", field.FieldLabel, err))
 			}
 		}
 	}
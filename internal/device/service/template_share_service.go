@@ -0,0 +1,358 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/device/repository"
+	"Backend_Lili/pkg/utils"
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 模板导出文件的格式标识与版本
+const (
+	TemplateExportFormat        = "lili-device-template"
+	TemplateExportFormatVersion = 1
+)
+
+// 模板审核操作
+const (
+	TemplateReviewApprove = "approve"
+	TemplateReviewReject  = "reject"
+)
+
+// canViewTemplate 系统模板、自己的模板、不公开列出的模板与审核通过的公开模板可以查看和使用，管理员可以查看全部
+func canViewTemplate(template *model.DeviceTemplate, userID int) bool {
+	if template.OwnerID == nil || *template.OwnerID == userID || utils.IsAdmin(userID) {
+		return true
+	}
+	switch template.Visibility {
+	case model.TemplateVisibilityUnlisted:
+		return true
+	case model.TemplateVisibilityPublic:
+		return template.ReviewStatus == model.TemplateReviewApproved
+	}
+	return false
+}
+
+// canEditTemplate 创建者可以修改自己的模板，系统模板只有管理员可以修改
+func canEditTemplate(template *model.DeviceTemplate, userID int) bool {
+	return (template.OwnerID != nil && *template.OwnerID == userID) || utils.IsAdmin(userID)
+}
+
+// getViewableTemplate 获取用户可以查看的模板，无权查看时与不存在一样返回404
+func (s *TemplateService) getViewableTemplate(userID, templateID int) (*model.DeviceTemplate, error) {
+	if templateID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "模板ID无效")
+	}
+	template, err := s.templateRepo.GetTemplateByID(templateID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取模板失败")
+	}
+	if template == nil || !canViewTemplate(template, userID) {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "模板不存在")
+	}
+	return template, nil
+}
+
+// getEditableTemplate 获取用户可以修改的模板
+func (s *TemplateService) getEditableTemplate(userID, templateID int) (*model.DeviceTemplate, error) {
+	template, err := s.getViewableTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEditTemplate(template, userID) {
+		return nil, utils.NewBusinessError(utils.ERROR_FORBIDDEN, "只能修改自己创建的模板")
+	}
+	return template, nil
+}
+
+// applyVisibility 设置可见范围：公开模板需要审核，管理员公开的模板直接通过
+func applyVisibility(template *model.DeviceTemplate, visibility string, userID int) error {
+	switch visibility {
+	case model.TemplateVisibilityPrivate, model.TemplateVisibilityUnlisted:
+		template.ReviewStatus = model.TemplateReviewNone
+		template.ReviewNote = ""
+		template.ReviewedAt = nil
+	case model.TemplateVisibilityPublic:
+		if utils.IsAdmin(userID) {
+			now := time.Now()
+			template.ReviewStatus = model.TemplateReviewApproved
+			template.ReviewedAt = &now
+		} else if template.Visibility != model.TemplateVisibilityPublic || template.ReviewStatus != model.TemplateReviewApproved {
+			template.ReviewStatus = model.TemplateReviewPending
+		}
+	default:
+		return utils.NewBusinessError(utils.ERROR_PARAM, "无效的可见范围: "+visibility)
+	}
+	template.Visibility = visibility
+	return nil
+}
+
+// saveTemplate 保存模板的非字段修改，版本冲突时返回最新状态
+func (s *TemplateService) saveTemplate(template *model.DeviceTemplate, userID int) error {
+	err := s.templateRepo.UpdateTemplate(template, false, userID)
+	if err == repository.ErrVersionConflict {
+		current, _ := s.templateRepo.GetTemplateByID(template.ID)
+		return templateConflictError(current)
+	}
+	if err != nil {
+		return utils.NewBusinessError(utils.ERROR_SERVER, "更新模板失败")
+	}
+	return nil
+}
+
+// SetTemplateVisibility 修改模板的可见范围，设为公开时提交审核
+func (s *TemplateService) SetTemplateVisibility(userID, templateID int, req *SetTemplateVisibilityRequest) (*model.DeviceTemplate, error) {
+	template, err := s.getEditableTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template.OwnerID == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "系统模板始终公开")
+	}
+	if req.Version != nil && *req.Version != template.Version {
+		return nil, templateConflictError(template)
+	}
+	if err := applyVisibility(template, req.Visibility, userID); err != nil {
+		return nil, err
+	}
+	if err := s.saveTemplate(template, userID); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// ForkTemplate 把可以查看的模板复制到自己名下，副本默认仅自己可见
+func (s *TemplateService) ForkTemplate(userID, templateID int, req *ForkTemplateRequest) (*model.DeviceTemplate, error) {
+	source, err := s.getViewableTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = source.Name
+	}
+	fork := &model.DeviceTemplate{
+		Name:         name,
+		Description:  source.Description,
+		Icon:         source.Icon,
+		Fields:       source.Fields,
+		CategoryID:   source.CategoryID,
+		IsActive:     true,
+		OwnerID:      &userID,
+		Visibility:   model.TemplateVisibilityPrivate,
+		ReviewStatus: model.TemplateReviewNone,
+		ForkedFromID: &source.ID,
+	}
+	if err := s.templateRepo.ForkTemplate(fork, userID); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "复制模板失败")
+	}
+	return fork, nil
+}
+
+// GetReviewTemplates 获取待审核（或指定审核状态）的公开模板（管理员）
+func (s *TemplateService) GetReviewTemplates(userID int, req *GetReviewTemplatesRequest) (*GetTemplatesListResponse, error) {
+	if !utils.IsAdmin(userID) {
+		return nil, utils.NewBusinessError(utils.ERROR_FORBIDDEN, "需要管理员权限")
+	}
+	status := req.Status
+	if status == "" {
+		status = model.TemplateReviewPending
+	}
+	if status != model.TemplateReviewPending && status != model.TemplateReviewApproved && status != model.TemplateReviewRejected {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "无效的审核状态: "+status)
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+
+	templates, total, err := s.templateRepo.GetTemplatesByReviewStatus(status, req.Page, req.Limit)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取待审核模板失败")
+	}
+	return &GetTemplatesListResponse{
+		Templates:  templates,
+		Total:      total,
+		Page:       req.Page,
+		Limit:      req.Limit,
+		TotalPages: int(math.Ceil(float64(total) / float64(req.Limit))),
+	}, nil
+}
+
+// ReviewTemplate 审核公开模板（管理员）：通过后进入模板库，驳回时需填写原因；已通过的模板也可以驳回下架
+func (s *TemplateService) ReviewTemplate(userID, templateID int, req *ReviewTemplateRequest) (*model.DeviceTemplate, error) {
+	if !utils.IsAdmin(userID) {
+		return nil, utils.NewBusinessError(utils.ERROR_FORBIDDEN, "需要管理员权限")
+	}
+	template, err := s.getViewableTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template.OwnerID == nil || template.Visibility != model.TemplateVisibilityPublic {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "只能审核用户提交公开的模板")
+	}
+
+	note := strings.TrimSpace(req.Note)
+	switch req.Action {
+	case TemplateReviewApprove:
+		template.ReviewStatus = model.TemplateReviewApproved
+	case TemplateReviewReject:
+		if note == "" {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "驳回时请填写原因")
+		}
+		template.ReviewStatus = model.TemplateReviewRejected
+	default:
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "无效的审核操作: "+req.Action)
+	}
+	if utf8.RuneCountInString(note) > 500 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "审核意见不能超过500字符")
+	}
+	now := time.Now()
+	template.ReviewNote = note
+	template.ReviewedAt = &now
+
+	if err := s.saveTemplate(template, userID); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// RateTemplate 为模板评分，重复评分时覆盖之前的评分；不能给自己的模板评分
+func (s *TemplateService) RateTemplate(userID, templateID int, req *RateTemplateRequest) (*model.TemplateRating, error) {
+	template, err := s.getViewableTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template.OwnerID != nil && *template.OwnerID == userID {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "不能给自己的模板评分")
+	}
+	if req.Score < 1 || req.Score > 5 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "评分必须在1到5之间")
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if utf8.RuneCountInString(comment) > 500 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "评价不能超过500字符")
+	}
+
+	rating := &model.TemplateRating{
+		TemplateID: templateID,
+		UserID:     userID,
+		Score:      req.Score,
+		Comment:    comment,
+	}
+	if err := s.templateRepo.SaveTemplateRating(rating); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "保存评分失败")
+	}
+	return rating, nil
+}
+
+// GetTemplateRatings 分页获取模板的评分
+func (s *TemplateService) GetTemplateRatings(userID, templateID int, req *GetTemplateRatingsRequest) (*GetTemplateRatingsResponse, error) {
+	template, err := s.getViewableTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+
+	ratings, total, err := s.templateRepo.GetTemplateRatings(templateID, req.Page, req.Limit)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取模板评分失败")
+	}
+	return &GetTemplateRatingsResponse{
+		TemplateID:  templateID,
+		RatingCount: template.RatingCount,
+		RatingAvg:   template.RatingAvg,
+		Ratings:     ratings,
+		Total:       total,
+		Page:        req.Page,
+		Limit:       req.Limit,
+	}, nil
+}
+
+// ExportTemplate 把模板导出为JSON文件内容
+func (s *TemplateService) ExportTemplate(userID, templateID int) (*TemplateExportFile, error) {
+	template, err := s.getViewableTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseTemplateFields(template)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &TemplateExportFile{
+		Format:           TemplateExportFormat,
+		FormatVersion:    TemplateExportFormatVersion,
+		Name:             template.Name,
+		Description:      template.Description,
+		Icon:             template.Icon,
+		Fields:           fields,
+		SourceTemplateID: template.ID,
+		ExportedAt:       time.Now(),
+	}
+	if template.CategoryID != nil {
+		category, err := s.categoryRepo.GetCategoryByID(*template.CategoryID)
+		if err == nil && category != nil {
+			file.Category = category.Name
+		}
+	}
+	return file, nil
+}
+
+// ImportTemplate 从导出的JSON文件创建模板，导入的模板属于当前用户且仅自己可见；
+// 未指定分类时按文件中的分类名称匹配
+func (s *TemplateService) ImportTemplate(userID int, data []byte, req *ImportTemplateRequest) (*model.DeviceTemplate, error) {
+	file := &TemplateExportFile{}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "模板文件不是有效的JSON")
+	}
+	if file.Format != TemplateExportFormat {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "不是设备模板文件")
+	}
+	if file.FormatVersion > TemplateExportFormatVersion {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "模板文件版本过高，请升级后再导入")
+	}
+
+	categoryID := req.CategoryID
+	if categoryID <= 0 && file.Category != "" {
+		categories, err := s.categoryRepo.GetCategoriesByType("all", userID, false)
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备分类失败")
+		}
+		for _, category := range categories {
+			if category.Name == file.Category {
+				categoryID = category.ID
+				break
+			}
+		}
+	}
+	if categoryID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "未找到模板文件中的分类，请指定分类")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = file.Name
+	}
+	return s.CreateTemplate(userID, &CreateTemplateRequest{
+		Name:        name,
+		CategoryID:  categoryID,
+		Description: file.Description,
+		Icon:        file.Icon,
+		Fields:      file.Fields,
+		Active:      true,
+		Visibility:  model.TemplateVisibilityPrivate,
+	})
+}
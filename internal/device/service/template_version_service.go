@@ -0,0 +1,458 @@
+package service
+
+import (
+	"Backend_Lili/internal/device/model"
+	"Backend_Lili/pkg/utils"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 模板迁移中单台设备的结果
+const (
+	MigrationResultMigrated = "migrated"
+	MigrationResultFailed   = "failed"
+)
+
+// templateMigrationBatchSize 迁移时每批读取的设备数量
+const templateMigrationBatchSize = 200
+
+// findVersionFields 获取模板指定字段版本的定义，found 为 false 表示该版本不存在
+func (s *TemplateService) findVersionFields(template *model.DeviceTemplate, version int) (fields []*model.TemplateField, found bool, err error) {
+	if version == template.FieldsVersion {
+		fields, err = parseTemplateFields(template)
+		return fields, err == nil, err
+	}
+	templateVersion, err := s.templateRepo.GetTemplateVersion(template.ID, version)
+	if err != nil {
+		return nil, false, utils.NewBusinessError(utils.ERROR_SERVER, "获取模板版本失败")
+	}
+	if templateVersion == nil {
+		return nil, false, nil
+	}
+	fields, err = parseFieldsJSON(templateVersion.Fields)
+	return fields, err == nil, err
+}
+
+// versionFields 获取设备所用字段版本的定义；版本未知或历史版本缺失时使用模板当前的字段定义
+func (s *TemplateService) versionFields(template *model.DeviceTemplate, version int) ([]*model.TemplateField, error) {
+	if version > 0 {
+		fields, found, err := s.findVersionFields(template, version)
+		if err != nil || found {
+			return fields, err
+		}
+	}
+	return parseTemplateFields(template)
+}
+
+// GetTemplateVersions 获取模板的全部字段版本及各版本的设备数量
+func (s *TemplateService) GetTemplateVersions(userID, templateID int) (*GetTemplateVersionsResponse, error) {
+	template, err := s.getViewableTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := s.templateRepo.GetTemplateVersions(templateID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "获取模板版本失败")
+	}
+	counts, err := s.templateRepo.CountDevicesByTemplateVersion(templateID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_SERVER, "统计模板设备失败")
+	}
+
+	response := &GetTemplateVersionsResponse{
+		TemplateID:     templateID,
+		CurrentVersion: template.FieldsVersion,
+		Versions:       make([]*TemplateVersionInfo, 0, len(versions)+1),
+		UnknownCount:   counts[0],
+	}
+	hasCurrent := false
+	for _, version := range versions {
+		fields, err := parseFieldsJSON(version.Fields)
+		if err != nil {
+			return nil, err
+		}
+		hasCurrent = hasCurrent || version.Version == template.FieldsVersion
+		response.Versions = append(response.Versions, &TemplateVersionInfo{
+			Version:     version.Version,
+			Fields:      fields,
+			Current:     version.Version == template.FieldsVersion,
+			DeviceCount: counts[version.Version],
+			CreatedBy:   version.CreatedBy,
+			CreatedAt:   version.CreatedAt,
+		})
+	}
+	// 版本记录缺失（例如未执行迁移脚本）时，以模板当前字段定义作为当前版本
+	if !hasCurrent {
+		fields, err := parseTemplateFields(template)
+		if err != nil {
+			return nil, err
+		}
+		current := &TemplateVersionInfo{
+			Version:     template.FieldsVersion,
+			Fields:      fields,
+			Current:     true,
+			DeviceCount: counts[template.FieldsVersion],
+			CreatedAt:   template.UpdatedAt,
+		}
+		response.Versions = append([]*TemplateVersionInfo{current}, response.Versions...)
+	}
+
+	return response, nil
+}
+
+// GetTemplateVersion 获取模板的指定字段版本
+func (s *TemplateService) GetTemplateVersion(userID, templateID, version int) (*TemplateVersionInfo, error) {
+	response, err := s.GetTemplateVersions(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range response.Versions {
+		if info.Version == version {
+			return info, nil
+		}
+	}
+	return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "模板版本不存在")
+}
+
+// DiffTemplateVersions 对比模板的两个字段版本
+func (s *TemplateService) DiffTemplateVersions(userID, templateID int, req *DiffTemplateVersionsRequest) (*TemplateVersionDiff, error) {
+	template, err := s.getViewableTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	to := req.To
+	if to <= 0 {
+		to = template.FieldsVersion
+	}
+	from := req.From
+	if from <= 0 {
+		from = to - 1
+	}
+	if from <= 0 || from == to {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "请指定两个不同的模板版本")
+	}
+
+	fromFields, err := s.mustVersionFields(template, from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := s.mustVersionFields(template, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffTemplateFields(fromFields, toFields)
+	diff.TemplateID = templateID
+	diff.From = from
+	diff.To = to
+	return diff, nil
+}
+
+// mustVersionFields 获取指定字段版本的定义，版本不存在时返回业务异常
+func (s *TemplateService) mustVersionFields(template *model.DeviceTemplate, version int) ([]*model.TemplateField, error) {
+	fields, found, err := s.findVersionFields(template, version)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, fmt.Sprintf("模板版本 %d 不存在", version))
+	}
+	return fields, nil
+}
+
+// diffTemplateFields 按字段名对比两组字段定义；删除的字段与新增字段显示名称和类型都相同时推测为改名
+func diffTemplateFields(fromFields, toFields []*model.TemplateField) *TemplateVersionDiff {
+	diff := &TemplateVersionDiff{
+		Added:            make([]*model.TemplateField, 0),
+		Removed:          make([]*model.TemplateField, 0),
+		Changed:          make([]*TemplateFieldDiff, 0),
+		SuggestedRenames: make(map[string]string),
+	}
+
+	fromByName := make(map[string]*model.TemplateField, len(fromFields))
+	for _, field := range fromFields {
+		fromByName[field.FieldName] = field
+	}
+	toByName := make(map[string]*model.TemplateField, len(toFields))
+	for _, field := range toFields {
+		toByName[field.FieldName] = field
+		old, ok := fromByName[field.FieldName]
+		if !ok {
+			diff.Added = append(diff.Added, field)
+			continue
+		}
+		if changes := diffTemplateField(old, field); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, &TemplateFieldDiff{FieldName: field.FieldName, Changes: changes})
+		}
+	}
+	for _, field := range fromFields {
+		if _, ok := toByName[field.FieldName]; !ok {
+			diff.Removed = append(diff.Removed, field)
+		}
+	}
+
+	// 只有唯一匹配时才给出改名建议
+	for _, removed := range diff.Removed {
+		var candidate *model.TemplateField
+		matches := 0
+		for _, added := range diff.Added {
+			if added.FieldLabel == removed.FieldLabel && added.FieldType == removed.FieldType {
+				candidate = added
+				matches++
+			}
+		}
+		if matches == 1 {
+			diff.SuggestedRenames[removed.FieldName] = candidate.FieldName
+		}
+	}
+
+	return diff
+}
+
+// diffTemplateField 对比同名字段的定义属性
+func diffTemplateField(old, new *model.TemplateField) map[string]*model.FieldChange {
+	changes := make(map[string]*model.FieldChange)
+	oldAttrs, newAttrs := templateFieldAttrs(old), templateFieldAttrs(new)
+	for attr, value := range newAttrs {
+		if !reflect.DeepEqual(oldAttrs[attr], value) {
+			changes[attr] = &model.FieldChange{Old: oldAttrs[attr], New: value}
+		}
+	}
+	return changes
+}
+
+// templateFieldAttrs 字段定义中参与对比的属性
+func templateFieldAttrs(field *model.TemplateField) map[string]interface{} {
+	options := field.Options
+	if options == nil {
+		options = []string{}
+	}
+	subFields := field.Fields
+	if subFields == nil {
+		subFields = []*model.TemplateField{}
+	}
+	return map[string]interface{}{
+		"field_label":      field.FieldLabel,
+		"field_type":       field.FieldType,
+		"required":         field.Required,
+		"default_value":    field.DefaultValue,
+		"validation_rules": field.ValidationRules,
+		"options":          options,
+		"currency":         field.Currency,
+		"fields":           subFields,
+		"placeholder":      field.Placeholder,
+		"help_text":        field.HelpText,
+	}
+}
+
+// MigrateTemplateDevices 把使用模板旧字段版本的设备迁移到目标版本：按改名映射搬移值、删除目标版本已移除的字段、
+// 补全新字段的默认值，并按目标版本校验；dry_run 默认开启，只返回每台设备的迁移结果而不写入。
+// 模板的创建者只能迁移自己的设备，管理员迁移全部用户的设备
+func (s *TemplateService) MigrateTemplateDevices(userID, templateID int, req *MigrateTemplateDevicesRequest) (*MigrateTemplateDevicesResponse, error) {
+	template, err := s.getEditableTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	ownerID := userID
+	if utils.IsAdmin(userID) {
+		ownerID = 0
+	}
+
+	to := req.ToVersion
+	if to <= 0 {
+		to = template.FieldsVersion
+	}
+	if req.FromVersion == to {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "源版本与目标版本相同")
+	}
+	toFields, err := s.mustVersionFields(template, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMigrationRenames(req.Renames, toFields); err != nil {
+		return nil, err
+	}
+
+	response := &MigrateTemplateDevicesResponse{
+		TemplateID: templateID,
+		ToVersion:  to,
+		DryRun:     req.DryRun == nil || *req.DryRun,
+		Results:    make([]*TemplateMigrationItem, 0),
+	}
+
+	// 各源版本的字段定义，版本未知或缺失时为 nil，此时不删除任何字段
+	sourceFields := make(map[int][]*model.TemplateField)
+	for afterID := 0; ; {
+		devices, err := s.deviceRepo.GetTemplateDevices(templateID, ownerID, req.FromVersion, to, afterID, templateMigrationBatchSize)
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取模板设备失败")
+		}
+		for _, device := range devices {
+			fromFields, loaded := sourceFields[device.TemplateVersion]
+			if !loaded && device.TemplateVersion > 0 {
+				if fromFields, _, err = s.findVersionFields(template, device.TemplateVersion); err != nil {
+					return nil, err
+				}
+			}
+			sourceFields[device.TemplateVersion] = fromFields
+
+			item, specs := s.planDeviceMigration(device, fromFields, toFields, req.Renames)
+			if item.Result == MigrationResultMigrated && !response.DryRun {
+				s.applyDeviceMigration(userID, template, device, to, specs, item)
+			}
+
+			response.Total++
+			if item.Result == MigrationResultMigrated {
+				response.MigratedCount++
+			} else {
+				response.FailedCount++
+			}
+			response.Results = append(response.Results, item)
+		}
+		if len(devices) < templateMigrationBatchSize {
+			break
+		}
+		afterID = devices[len(devices)-1].ID
+	}
+
+	return response, nil
+}
+
+// validateMigrationRenames 改名的目标字段必须在目标版本中，来源字段不能仍在目标版本中
+func validateMigrationRenames(renames map[string]string, toFields []*model.TemplateField) error {
+	toNames := make(map[string]bool, len(toFields))
+	for _, field := range toFields {
+		toNames[field.FieldName] = true
+	}
+	for oldName, newName := range renames {
+		if oldName == "" || oldName == newName {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "改名映射无效: "+oldName)
+		}
+		if !toNames[newName] {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "改名目标字段不在目标版本中: "+newName)
+		}
+		if toNames[oldName] {
+			return utils.NewBusinessError(utils.ERROR_PARAM, "字段仍在目标版本中，不能改名: "+oldName)
+		}
+	}
+	return nil
+}
+
+// planDeviceMigration 计算单台设备迁移后的规格参数，返回结果与要保存的JSON文本
+func (s *TemplateService) planDeviceMigration(device *model.Device, fromFields, toFields []*model.TemplateField, renames map[string]string) (*TemplateMigrationItem, string) {
+	item := &TemplateMigrationItem{DeviceID: device.ID, FromVersion: device.TemplateVersion}
+
+	specs := make(map[string]interface{})
+	if device.Specifications != "" {
+		if err := json.Unmarshal([]byte(device.Specifications), &specs); err != nil {
+			item.Result = MigrationResultFailed
+			item.Message = "规格参数不是有效的JSON对象"
+			return item, ""
+		}
+	}
+
+	// 改名：目标字段已有值时保留目标字段的值
+	oldNames := make([]string, 0, len(renames))
+	for oldName := range renames {
+		oldNames = append(oldNames, oldName)
+	}
+	sort.Strings(oldNames)
+	for _, oldName := range oldNames {
+		value, ok := specs[oldName]
+		if !ok {
+			continue
+		}
+		newName := renames[oldName]
+		if existing, has := specs[newName]; !has || isEmptySpecValue(existing) {
+			specs[newName] = value
+		}
+		delete(specs, oldName)
+		if item.Renamed == nil {
+			item.Renamed = make(map[string]string)
+		}
+		item.Renamed[oldName] = newName
+	}
+
+	// 删除源版本中有、目标版本中已移除的字段；模板之外的自定义键保留
+	toNames := make(map[string]bool, len(toFields))
+	for _, field := range toFields {
+		toNames[field.FieldName] = true
+	}
+	for _, field := range fromFields {
+		if toNames[field.FieldName] || renames[field.FieldName] != "" {
+			continue
+		}
+		if _, ok := specs[field.FieldName]; ok {
+			delete(specs, field.FieldName)
+			item.Dropped = append(item.Dropped, field.FieldName)
+		}
+	}
+
+	filled := make(map[string]bool, len(toFields))
+	for _, field := range toFields {
+		filled[field.FieldName] = !isEmptySpecValue(specs[field.FieldName])
+	}
+	normalized, errs := s.NormalizeSpecifications(toFields, specs)
+	if len(errs) > 0 {
+		item.Result = MigrationResultFailed
+		item.Errors = errs
+		item.Message = "规格参数不符合目标版本"
+		return item, ""
+	}
+	for _, field := range toFields {
+		if _, ok := normalized[field.FieldName]; ok && !filled[field.FieldName] {
+			item.Defaulted = append(item.Defaulted, field.FieldName)
+		}
+	}
+
+	item.Result = MigrationResultMigrated
+	if len(normalized) == 0 {
+		return item, ""
+	}
+	specJSON, err := json.Marshal(normalized)
+	if err != nil {
+		item.Result = MigrationResultFailed
+		item.Message = "规格参数序列化失败"
+		return item, ""
+	}
+	return item, string(specJSON)
+}
+
+// applyDeviceMigration 写入单台设备的迁移结果并记录时间线，设备在预览后被修改时标记为失败
+func (s *TemplateService) applyDeviceMigration(actorID int, template *model.DeviceTemplate, device *model.Device, toVersion int, specs string, item *TemplateMigrationItem) {
+	ok, err := s.deviceRepo.MigrateDeviceSpecs(device, toVersion, specs)
+	if err != nil {
+		item.Result = MigrationResultFailed
+		item.Message = "写入设备失败"
+		return
+	}
+	if !ok {
+		item.Result = MigrationResultFailed
+		item.Message = "设备已被修改，请重新执行迁移"
+		return
+	}
+	migrated := *device
+	migrated.Specifications, migrated.TemplateVersion = specs, toVersion
+	s.syncSpecIndex(&migrated, template)
+
+	var changes map[string]*model.FieldChange
+	if specs != device.Specifications {
+		changes = map[string]*model.FieldChange{
+			"specifications": {Old: device.Specifications, New: specs},
+		}
+	}
+	event := model.NewDeviceEvent(device.ID, device.UserID, actorID, model.DeviceEventSpecsMigrated, changes, map[string]interface{}{
+		"template_id":  template.ID,
+		"from_version": device.TemplateVersion,
+		"to_version":   toVersion,
+	})
+	if err := s.eventRepo.CreateEvent(event); err != nil {
+		logs.Warn("记录设备事件失败: device=%d, type=%s, err=%v", device.ID, model.DeviceEventSpecsMigrated, err)
+	}
+}
@@ -14,6 +14,9 @@ type GetDevicesListRequest struct {
 	Sort       string `json:"sort" form:"sort"`               // 排序字段
 	Order      string `json:"order" form:"order"`             // 排序方式
 	Search     string `json:"search" form:"search"`           // 搜索关键词
+	ParentID   int    `json:"parent_id" form:"parent_id"`     // 只列出该主设备的配件
+	TopLevel   bool   `json:"top_level" form:"top_level"`     // 只列出独立设备与主设备，不含配件
+	Specs      string `json:"specs" form:"specs"`             // 规格筛选，如 memory>=32GB;storage=512GB|1TB
 }
 
 // 设备列表响应
@@ -45,6 +48,9 @@ type CreateDeviceRequest struct {
 	Notes          string                 `json:"notes"`
 	Images         []string               `json:"images"`         // 图片URL数组
 	Specifications map[string]interface{} `json:"specifications"` // 其他规格参数
+	ParentID       *int                   `json:"parent_id"`      // 所属套装的主设备
+	TagIDs         []int                  `json:"tag_ids"`        // 设备标签
+	Force          bool                   `json:"force"`          // 发现疑似重复设备时仍然创建
 }
 
 // 更新设备请求
@@ -64,6 +70,9 @@ type UpdateDeviceRequest struct {
 	Condition      string                 `json:"condition"`     // new/good/fair/poor
 	Notes          string                 `json:"notes"`
 	Specifications map[string]interface{} `json:"specifications"` // 其他规格参数
+	ParentID       *int                   `json:"parent_id"`      // 所属套装的主设备，传0解除关联
+	TagIDs         *[]int                 `json:"tag_ids"`        // 设备标签，传空数组清空
+	Version        *int                   `json:"version"`        // 读取时的版本号，未传 If-Match 请求头时必填
 }
 
 // 更新设备状态请求
@@ -72,6 +81,8 @@ type UpdateDeviceStatusRequest struct {
 	SalePrice float64 `json:"sale_price"`              // 出售价格，当status为sold时必填
 	SaleDate  string  `json:"sale_date"`               // 出售日期，当status为sold时必填
 	Notes     string  `json:"notes"`                   // 状态变更备注
+	Cascade   bool    `json:"cascade"`                 // 同时变更与主设备原状态相同的配件
+	Version   *int    `json:"version"`                 // 读取时的版本号，未传 If-Match 请求头时必填
 }
 
 // 设备价值评估响应
@@ -83,6 +94,11 @@ type DeviceValuationResponse struct {
 	DepreciationRate  float64               `json:"depreciation_rate"`  // 贬值率(%)
 	HoldingDays       int                   `json:"holding_days"`       // 持有天数
 	DailyDepreciation float64               `json:"daily_depreciation"` // 日均贬值
+	ServiceCost       float64               `json:"service_cost"`       // 维修保养自付费用
+	ServiceCount      int                   `json:"service_count"`      // 维修保养次数
+	TotalInvestment   float64               `json:"total_investment"`   // 总投入（购买价格+配件+维修保养+订阅+平台费用）
+	NetCost           float64               `json:"net_cost"`           // 净成本（总投入-出售所得或当前估值）
+	OwnershipCost     *model.OwnershipCost  `json:"ownership_cost"`     // 持有总成本明细
 	LastUpdateTime    time.Time             `json:"last_update_time"`
 	PriceHistories    []interface{} `json:"price_histories"` // 价格历史，具体类型在价格模块中定义
 }
@@ -95,24 +111,207 @@ type BatchImportDevicesRequest struct {
 
 // 批量导入设备响应
 type BatchImportDevicesResponse struct {
-	TotalCount   int      `json:"total_count"`
-	SuccessCount int      `json:"success_count"`
-	FailCount    int      `json:"fail_count"`
-	Errors       []string `json:"errors"`
+	TotalCount   int                `json:"total_count"`
+	SuccessCount int                `json:"success_count"`
+	FailCount    int                `json:"fail_count"`
+	SkippedCount int                `json:"skipped_count"` // ignore_duplicates 为 true 时跳过的重复设备数
+	Errors       []string           `json:"errors"`
+	Rows         []*ImportRowResult `json:"rows"` // 逐条结果
+}
+
+// 表格导入设备请求，来自 multipart 表单字段
+type ImportDevicesFileRequest struct {
+	DryRun            bool              // 只校验不入库
+	HeaderRow         int               // 表头所在行（从1开始），0 表示自动识别
+	Mapping           map[string]string // 列映射：表头文字或列字母 -> 字段名，字段名为空表示忽略该列
+	IgnoreDuplicates  bool              // 序列号重复的行跳过而不报错
+	DefaultCategoryID int               // 行内没有分类时使用的分类
+	DefaultTemplateID int               // 行内没有模板时使用的模板
+}
+
+// 导入字段错误
+type ImportFieldError struct {
+	Field   string `json:"field,omitempty"`
+	Column  string `json:"column,omitempty"` // 列字母，JSON导入为空
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+// 导入单行结果
+type ImportRowResult struct {
+	Row          int                         `json:"row"`    // 表格行号（从1开始），JSON导入为数组序号（从1开始）
+	Status       string                      `json:"status"` // valid/invalid/skipped/imported
+	DeviceID     int                         `json:"device_id,omitempty"`
+	DuplicateOf  int                         `json:"duplicate_of,omitempty"` // 重复或疑似重复的已有设备ID
+	CategoryName string                      `json:"category_name,omitempty"`
+	TemplateName string                      `json:"template_name,omitempty"`
+	Errors       []*ImportFieldError         `json:"errors,omitempty"`
+	Warnings     []*ImportFieldError         `json:"warnings,omitempty"`    // 不影响导入的提示，如疑似重复
+	Suggestions  []*model.CategorySuggestion `json:"suggestions,omitempty"` // 未指定分类或模板时分类规则的建议
+	Device       *CreateDeviceRequest        `json:"device,omitempty"`      // 解析后的设备数据
+}
+
+// 表格导入设备响应
+type ImportDevicesFileResponse struct {
+	DryRun          bool               `json:"dry_run"`
+	Format          string             `json:"format"`     // csv/xlsx
+	HeaderRow       int                `json:"header_row"` // 实际使用的表头行
+	Columns         map[string]string  `json:"columns"`    // 字段名 -> 表头文字
+	UnmappedHeaders []string           `json:"unmapped_headers"`
+	TotalCount      int                `json:"total_count"`
+	ValidCount      int                `json:"valid_count"`
+	InvalidCount    int                `json:"invalid_count"`
+	SkippedCount    int                `json:"skipped_count"`
+	ImportedCount   int                `json:"imported_count"`
+	Rows            []*ImportRowResult `json:"rows"`
 }
 
 // 上传设备图片请求
 type UploadDeviceImageRequest struct {
 	ImageType string `json:"image_type"` // normal/cover
 	SortOrder int    `json:"sort_order"`
+	Caption   string `json:"caption"`
+}
+
+// 批量上传设备图片响应
+type BatchUploadDeviceImagesResponse struct {
+	SuccessCount int                        `json:"success_count"`
+	FailCount    int                        `json:"fail_count"`
+	Results      []*UploadDeviceImageResult `json:"results"`
+}
+
+// 单个文件的上传结果
+type UploadDeviceImageResult struct {
+	Filename string                     `json:"filename"`
+	Success  bool                       `json:"success"`
+	Image    *UploadDeviceImageResponse `json:"image,omitempty"`
+	Error    string                     `json:"error,omitempty"`
+}
+
+// 更新设备图片请求
+type UpdateDeviceImageRequest struct {
+	Caption *string `json:"caption"`
+}
+
+// 上传设备附件请求
+type UploadAttachmentRequest struct {
+	AttachmentType  string   `json:"attachment_type"` // receipt/invoice/warranty/manual/service
+	Title           string   `json:"title"`
+	InvoiceNumber   string   `json:"invoice_number"`
+	InvoiceAmount   *float64 `json:"invoice_amount"`
+	InvoiceDate     string   `json:"invoice_date"`      // YYYY-MM-DD格式
+	ServiceRecordID *int     `json:"service_record_id"` // 关联的维修保养记录
+}
+
+// 设备时间线请求
+type GetDeviceTimelineRequest struct {
+	Page      int    `json:"page" form:"page"`
+	Limit     int    `json:"limit" form:"limit"`
+	EventType string `json:"event_type" form:"event_type"` // 按事件类型筛选
+}
+
+// 设备时间线响应
+type GetDeviceTimelineResponse struct {
+	Events     []*model.DeviceEvent `json:"events"`
+	Total      int                  `json:"total"`
+	Page       int                  `json:"page"`
+	Limit      int                  `json:"limit"`
+	TotalPages int                  `json:"total_pages"`
+}
+
+// 从历史恢复字段请求，字段恢复为该事件发生前的值
+type RestoreDeviceFieldRequest struct {
+	EventID int    `json:"event_id"`
+	Field   string `json:"field"`
+	Version *int   `json:"version"` // 读取时的版本号，未传 If-Match 请求头时必填
+}
+
+// 设备支出请求（创建与更新共用，更新时只修改提供的字段）
+type DeviceCostRequest struct {
+	CostType     string   `json:"cost_type"` // accessory/subscription/fee
+	Title        *string  `json:"title"`
+	Amount       *float64 `json:"amount"`        // 单次金额，订阅为每期金额
+	CostDate     string   `json:"cost_date"`     // YYYY-MM-DD格式，订阅为开始日期
+	BillingCycle *string  `json:"billing_cycle"` // 订阅周期 monthly/yearly
+	EndDate      *string  `json:"end_date"`      // 订阅结束日期，传空字符串表示持续中
+	Notes        *string  `json:"notes"`
+}
+
+// 使用记录列表请求
+type GetUsageLogsRequest struct {
+	Page  int `json:"page" form:"page"`
+	Limit int `json:"limit" form:"limit"`
+}
+
+// 使用记录列表响应
+type GetUsageLogsResponse struct {
+	Logs       []*model.DeviceUsageLog `json:"logs"`
+	UsageCount int                     `json:"usage_count"` // 累计使用次数
+	Total      int                     `json:"total"`
+	Page       int                     `json:"page"`
+	Limit      int                     `json:"limit"`
+}
+
+// 记录设备使用请求
+type CreateUsageLogRequest struct {
+	UsedOn   string `json:"used_on"`   // YYYY-MM-DD格式，默认今天
+	UseCount int    `json:"use_count"` // 默认1
+	Note     string `json:"note"`
+}
+
+// 持有成本排行项
+type OwnershipCostRankItem struct {
+	Rank          int                  `json:"rank"`
+	DeviceID      int                  `json:"device_id"`
+	Name          string               `json:"name"`
+	Brand         string               `json:"brand"`
+	Model         string               `json:"model"`
+	Status        string               `json:"status"`
+	OwnershipCost *model.OwnershipCost `json:"ownership_cost"`
+}
+
+// 维修保养记录请求（创建与更新共用，更新时只修改提供的字段）
+type ServiceRecordRequest struct {
+	ServiceDate     string   `json:"service_date"` // YYYY-MM-DD格式
+	ServiceType     string   `json:"service_type"` // repair/battery_swap/cleaning/upgrade
+	Vendor          *string  `json:"vendor"`
+	Cost            *float64 `json:"cost"`
+	WarrantyCovered *bool    `json:"warranty_covered"`
+	Description     *string  `json:"description"`
+}
+
+// 维修保养记录列表响应
+type GetServiceRecordsResponse struct {
+	Records     []*model.DeviceServiceRecord `json:"records"`
+	TotalCost   float64                      `json:"total_cost"`   // 自付费用合计
+	CoveredCost float64                      `json:"covered_cost"` // 保修范围内费用合计
+}
+
+// 调整图片顺序请求
+type ReorderDeviceImagesRequest struct {
+	ImageIDs []int `json:"image_ids"` // 按新顺序排列的全部图片ID
 }
 
 // 上传设备图片响应
 type UploadDeviceImageResponse struct {
-	ImageID   int    `json:"image_id"`
-	ImageURL  string `json:"image_url"`
-	ImageType string `json:"image_type"`
-	SortOrder int    `json:"sort_order"`
+	ImageID   int               `json:"image_id"`
+	ImageURL  string            `json:"image_url"`
+	ImageType string            `json:"image_type"`
+	SortOrder int               `json:"sort_order"`
+	Caption   string            `json:"caption"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	ByteSize  int64             `json:"byte_size"`
+	URLs      map[string]string `json:"urls,omitempty"` // 各尺寸访问地址
+	Duplicate bool              `json:"duplicate"`      // 是否为已上传过的相同图片
+}
+
+// 设备图片访问地址响应
+type DeviceImageURLResponse struct {
+	ImageID   int       `json:"image_id"`
+	URL       string    `json:"url"`
+	Signed    bool      `json:"signed"`               // 是否为预签名链接（无需Token即可访问）
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // 预签名链接过期时间
 }
 
 // 价格预测请求
@@ -151,10 +350,12 @@ type TrendAnalysis struct {
 
 // 获取模板列表请求
 type GetTemplatesListRequest struct {
-	CategoryID int  `json:"category_id" form:"category_id"` // 分类ID
-	Active     bool `json:"active" form:"active"`           // 是否只显示启用的模板
-	Page       int  `json:"page" form:"page"`               // 页码
-	Limit      int  `json:"limit" form:"limit"`             // 每页数量
+	CategoryID int    `json:"category_id" form:"category_id"` // 分类ID
+	Active     bool   `json:"active" form:"active"`           // 是否只显示启用的模板
+	Scope      string `json:"scope" form:"scope"`             // mine:自己的模板 library:模板库 默认为可以浏览的全部模板
+	Sort       string `json:"sort" form:"sort"`               // popular(默认)/rating/newest
+	Page       int    `json:"page" form:"page"`               // 页码
+	Limit      int    `json:"limit" form:"limit"`             // 每页数量
 }
 
 // 获取模板列表响应
@@ -174,6 +375,7 @@ type CreateTemplateRequest struct {
 	Icon        string                 `json:"icon"`
 	Fields      []*model.TemplateField `json:"fields" valid:"Required"`
 	Active      bool                   `json:"active"`
+	Visibility  string                 `json:"visibility"` // private(默认)/unlisted/public，公开需管理员审核
 }
 
 // 更新模板请求
@@ -184,6 +386,7 @@ type UpdateTemplateRequest struct {
 	Icon        string                 `json:"icon"`
 	Fields      []*model.TemplateField `json:"fields"`
 	Active      bool                   `json:"active"`
+	Version     *int                   `json:"version"` // 读取时的版本号，未传 If-Match 请求头时必填
 }
 
 // 验证设备数据请求
@@ -193,10 +396,93 @@ type ValidateDeviceDataRequest struct {
 
 // 验证设备数据响应
 type ValidateDeviceDataResponse struct {
-	Valid   bool                   `json:"valid"`
-	Errors  map[string]string      `json:"errors,omitempty"`
-	Missing []string               `json:"missing,omitempty"` // 缺失的必填字段
-	Invalid map[string]interface{} `json:"invalid,omitempty"` // 无效的字段值
+	Valid          bool                   `json:"valid"`
+	Errors         map[string]string      `json:"errors,omitempty"`
+	Missing        []string               `json:"missing,omitempty"`        // 缺失的必填字段
+	Invalid        map[string]interface{} `json:"invalid,omitempty"`        // 无效的字段值
+	Specifications map[string]interface{} `json:"specifications,omitempty"` // 校验通过时补全默认值并转换类型后的规格参数
+}
+
+// 规格参数字段错误
+type SpecFieldError struct {
+	Field   string      `json:"field"`
+	Label   string      `json:"label"`
+	Reason  string      `json:"reason"` // required/invalid
+	Value   interface{} `json:"value,omitempty"`
+	Message string      `json:"message"`
+}
+
+// 模板字段类型说明
+type TemplateFieldTypeInfo struct {
+	Type  string `json:"type"`
+	Label string `json:"label"`
+	Value string `json:"value"` // 值的格式
+}
+
+// 修改模板可见范围请求
+type SetTemplateVisibilityRequest struct {
+	Visibility string `json:"visibility"` // private/unlisted/public
+	Version    *int   `json:"version"`    // 读取时的版本号，未传 If-Match 请求头时必填
+}
+
+// 复制模板请求
+type ForkTemplateRequest struct {
+	Name string `json:"name"` // 副本名称，默认与来源模板相同
+}
+
+// 待审核模板列表请求
+type GetReviewTemplatesRequest struct {
+	Status string `json:"status" form:"status"` // pending(默认)/approved/rejected
+	Page   int    `json:"page" form:"page"`
+	Limit  int    `json:"limit" form:"limit"`
+}
+
+// 审核模板请求
+type ReviewTemplateRequest struct {
+	Action string `json:"action"` // approve/reject
+	Note   string `json:"note"`   // 审核意见，驳回时必填
+}
+
+// 模板评分请求
+type RateTemplateRequest struct {
+	Score   int    `json:"score"` // 1-5
+	Comment string `json:"comment"`
+}
+
+// 模板评分列表请求
+type GetTemplateRatingsRequest struct {
+	Page  int `json:"page" form:"page"`
+	Limit int `json:"limit" form:"limit"`
+}
+
+// 模板评分列表响应
+type GetTemplateRatingsResponse struct {
+	TemplateID  int                     `json:"template_id"`
+	RatingCount int                     `json:"rating_count"`
+	RatingAvg   float64                 `json:"rating_avg"`
+	Ratings     []*model.TemplateRating `json:"ratings"`
+	Total       int                     `json:"total"`
+	Page        int                     `json:"page"`
+	Limit       int                     `json:"limit"`
+}
+
+// 模板导出文件
+type TemplateExportFile struct {
+	Format           string                 `json:"format"`         // 固定为 lili-device-template
+	FormatVersion    int                    `json:"format_version"` // 文件格式版本
+	Name             string                 `json:"name"`
+	Description      string                 `json:"description"`
+	Icon             string                 `json:"icon"`
+	Category         string                 `json:"category,omitempty"` // 分类名称，导入时用于匹配分类
+	Fields           []*model.TemplateField `json:"fields"`
+	SourceTemplateID int                    `json:"source_template_id"`
+	ExportedAt       time.Time              `json:"exported_at"`
+}
+
+// 导入模板请求
+type ImportTemplateRequest struct {
+	CategoryID int    `json:"category_id" form:"category_id"` // 不传时按文件中的分类名称匹配
+	Name       string `json:"name" form:"name"`               // 不传时使用文件中的名称
 }
 
 // 模板统计响应
@@ -222,7 +508,8 @@ type PopularFieldInfo struct {
 type GetCategoriesListRequest struct {
 	Type         string `json:"type" form:"type"`                   // system/custom/all
 	ParentID     int    `json:"parent_id" form:"parent_id"`         // 父分类ID
-	IncludeCount bool   `json:"include_count" form:"include_count"` // 是否包含设备数量
+	IncludeCount  bool   `json:"include_count" form:"include_count"`   // 是否包含设备数量
+	IncludeHidden bool   `json:"include_hidden" form:"include_hidden"` // 是否包含已隐藏的系统分类
 }
 
 // 获取分类列表响应
@@ -248,6 +535,39 @@ type UpdateCategoryRequest struct {
 	Icon        string `json:"icon"`
 	Color       string `json:"color"`
 	SortOrder   int    `json:"sort_order"`
+	Version     *int   `json:"version"` // 读取时的版本号，未传 If-Match 请求头时必填
+}
+
+// 移动分类请求
+type MoveCategoryRequest struct {
+	ParentID int  `json:"parent_id"` // 新的父分类ID，0表示移到顶级
+	Version  *int `json:"version"`   // 读取时的版本号，未传 If-Match 请求头时必填
+}
+
+// 合并分类请求
+type MergeCategoryRequest struct {
+	TargetID int `json:"target_id" valid:"Required"` // 合并到的分类，可以是系统分类或自己的其他自定义分类
+}
+
+// 删除分类请求，决定子分类与设备的去向
+type DeleteCategoryRequest struct {
+	Children string `json:"children" form:"children"`   // reject（默认，有子分类时拒绝删除）/parent（移到上级分类）/root（移到顶级）/target（移到 target_id 下）
+	Devices  string `json:"devices" form:"devices"`     // reject（默认，有设备时拒绝删除）/parent（移到上级分类）/none（清除分类）/target（移到 target_id）
+	TargetID int    `json:"target_id" form:"target_id"` // children 或 devices 为 target 时使用
+}
+
+// 删除或合并分类时转移的数据
+type CategoryTransferResponse struct {
+	CategoryID     int   `json:"category_id"`
+	MovedDevices   int   `json:"moved_devices"` // 含回收站中的设备
+	MovedTemplates int64 `json:"moved_templates"`
+	MovedDrafts    int64 `json:"moved_drafts"`
+	MovedChildren  int64 `json:"moved_children"`
+}
+
+// 隐藏系统分类请求
+type SetCategoryHiddenRequest struct {
+	Hidden bool `json:"hidden"`
 }
 
 // 分类排序请求
@@ -305,6 +625,347 @@ type CategoryValueDistribution struct {
 
 // 搜索分类请求
 type SearchCategoriesRequest struct {
-	Keyword string `json:"keyword" form:"keyword" valid:"Required"`
-	Type    string `json:"type" form:"type"` // system/custom/all
+	Keyword       string `json:"keyword" form:"keyword" valid:"Required"`
+	Type          string `json:"type" form:"type"`                     // system/custom/all
+	IncludeHidden bool   `json:"include_hidden" form:"include_hidden"` // 是否包含已隐藏的系统分类
+}
+
+// 分类规则列表请求
+type GetCategoryRulesRequest struct {
+	Scope string `json:"scope" form:"scope"` // system/mine，为空时两者都列
+}
+
+// 创建、修改分类规则请求，关键字多个以逗号分隔
+type CategoryRuleRequest struct {
+	Name          string `json:"name" valid:"Required"`
+	Brands        string `json:"brands"`         // 品牌，不区分大小写完全匹配任一
+	ModelKeywords string `json:"model_keywords"` // 型号包含任一关键字
+	NameKeywords  string `json:"name_keywords"`  // 名称包含任一关键字
+	Pattern       string `json:"pattern"`        // 正则，匹配「品牌 型号 名称」，不区分大小写
+	CategoryID    int    `json:"category_id"`
+	TemplateID    int    `json:"template_id"`
+	Priority      int    `json:"priority"`  // 0-100，越大越优先
+	IsActive      *bool  `json:"is_active"` // 为空时保持不变，新建默认启用
+	System        bool   `json:"system"`    // 管理员创建系统规则，仅创建时有效
+}
+
+// 分类建议请求
+type SuggestCategoriesRequest struct {
+	Name  string `json:"name"`
+	Brand string `json:"brand"`
+	Model string `json:"model"`
+}
+
+// 分类建议响应
+type SuggestCategoriesResponse struct {
+	Suggestions []*model.CategorySuggestion `json:"suggestions"`
+}
+
+// 按规则重新分类设备请求
+type CategorizeDevicesRequest struct {
+	DryRun bool `json:"dry_run"` // 只返回结果，不修改设备
+}
+
+// 按规则重新分类设备响应
+type CategorizeDevicesResponse struct {
+	DryRun           bool                      `json:"dry_run"`
+	TotalCount       int                       `json:"total_count"` // 未设置分类或模板的设备数量
+	CategorizedCount int                       `json:"categorized_count"`
+	SkippedCount     int                       `json:"skipped_count"`
+	FailedCount      int                       `json:"failed_count"`
+	Results          []*CategorizeDeviceResult `json:"results"`
+}
+
+// 单台设备的重新分类结果
+type CategorizeDeviceResult struct {
+	DeviceID    int                         `json:"device_id"`
+	Name        string                      `json:"name"`
+	Status      string                      `json:"status"` // success/skipped/failed
+	Message     string                      `json:"message,omitempty"`
+	CategoryID  int                         `json:"category_id,omitempty"`
+	TemplateID  int                         `json:"template_id,omitempty"`
+	Suggestions []*model.CategorySuggestion `json:"suggestions,omitempty"`
+}
+
+// 导入电商订单请求，来自 multipart 表单字段
+type ImportOrdersRequest struct {
+	Platform   string // jd/taobao，为空时按表头识别
+	IncludeAll bool   // 为 true 时非数码商品也生成草稿
+}
+
+// 导入电商订单响应
+type ImportOrdersResponse struct {
+	Platform       string               `json:"platform"`
+	TotalCount     int                  `json:"total_count"`     // 订单商品行数
+	DraftCount     int                  `json:"draft_count"`     // 新生成的草稿数
+	DuplicateCount int                  `json:"duplicate_count"` // 之前已导入过的商品
+	CancelledCount int                  `json:"cancelled_count"` // 已取消、关闭或退款的订单
+	IgnoredCount   int                  `json:"ignored_count"`   // 非数码商品
+	Drafts         []*model.DeviceDraft `json:"drafts"`
+}
+
+// 草稿列表请求
+type GetDraftsRequest struct {
+	Status string `json:"status" form:"status"` // pending/accepted/rejected，默认 pending
+	Page   int    `json:"page" form:"page"`
+	Limit  int    `json:"limit" form:"limit"`
+}
+
+// 草稿列表响应
+type GetDraftsResponse struct {
+	Drafts []*model.DeviceDraft `json:"drafts"`
+	Total  int                  `json:"total"`
+	Page   int                  `json:"page"`
+	Limit  int                  `json:"limit"`
+}
+
+// 编辑草稿请求，只更新提供的字段；确认草稿时也可一并提交修改
+type UpdateDraftRequest struct {
+	Name          *string  `json:"name"`
+	Brand         *string  `json:"brand"`
+	Model         *string  `json:"model"`
+	CategoryID    *int     `json:"category_id"`
+	TemplateID    *int     `json:"template_id"`
+	PurchasePrice *float64 `json:"purchase_price"`
+	PurchaseDate  *string  `json:"purchase_date"` // YYYY-MM-DD格式
+	SerialNumber  *string  `json:"serial_number"`
+	Notes         *string  `json:"notes"`
+	Force         bool     `json:"force"` // 仅确认时使用：发现疑似重复设备时仍然创建
+
+	Specifications map[string]interface{} `json:"specifications"` // 仅确认时使用：按模板填写的规格参数
+}
+
+// 疑似重复的设备
+type DuplicateMatch struct {
+	DeviceID      int      `json:"device_id"`
+	Name          string   `json:"name"`
+	Brand         string   `json:"brand"`
+	Model         string   `json:"model"`
+	SerialNumber  string   `json:"serial_number"`
+	PurchaseDate  string   `json:"purchase_date"`
+	PurchasePrice float64  `json:"purchase_price"`
+	MatchType     string   `json:"match_type"` // serial/similar
+	Score         float64  `json:"score"`      // 相似度 0-1，序列号相同为1
+	Reasons       []string `json:"reasons"`
+}
+
+// 重复设备扫描请求
+type ScanDuplicatesRequest struct {
+	MinScore float64 `json:"min_score" form:"min_score"` // 相似度下限，默认0.8
+}
+
+// 重复设备组中两台设备的匹配情况
+type DuplicatePair struct {
+	DeviceID  int      `json:"device_id"`
+	OtherID   int      `json:"other_id"`
+	MatchType string   `json:"match_type"`
+	Score     float64  `json:"score"`
+	Reasons   []string `json:"reasons"`
+}
+
+// 一组疑似重复的设备
+type DuplicateGroup struct {
+	MatchType         string           `json:"match_type"` // 组内存在序列号相同的设备时为 serial
+	Score             float64          `json:"score"`      // 组内最高相似度
+	SuggestedTargetID int              `json:"suggested_target_id"`
+	Devices           []*model.Device  `json:"devices"`
+	Pairs             []*DuplicatePair `json:"pairs"`
+}
+
+// 重复设备扫描响应
+type ScanDuplicatesResponse struct {
+	MinScore    float64           `json:"min_score"`
+	DeviceCount int               `json:"device_count"` // 参与扫描的设备数
+	Groups      []*DuplicateGroup `json:"groups"`
+}
+
+// 合并设备请求
+type MergeDevicesRequest struct {
+	SourceIDs []int `json:"source_ids" valid:"Required"` // 并入目标设备后删除的设备
+}
+
+// 导出设备请求，筛选与排序条件与设备列表相同，分页参数不生效
+type ExportDevicesRequest struct {
+	Filter  GetDevicesListRequest
+	Format  string   // csv/xlsx/jsonl，默认csv
+	Columns []string // 导出列，为空时使用默认列；规格参数列写作 spec.键名
+}
+
+// 可导出的列
+type ExportColumn struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+// 可导出列响应
+type ExportColumnsResponse struct {
+	Columns        []*ExportColumn `json:"columns"`
+	DefaultColumns []string        `json:"default_columns"`
+}
+
+// 批量操作设备请求，device_ids 与 filter 二选一
+type BulkDevicesRequest struct {
+	DeviceIDs    []int                  `json:"device_ids"`
+	Filter       *GetDevicesListRequest `json:"filter"`    // 按列表筛选条件选择设备，restore 时在已删除的设备中筛选
+	Operation    string                 `json:"operation"` // set_category/set_status/set_condition/add_tags/remove_tags/delete/restore
+	CategoryID   int                    `json:"category_id"`
+	Status       string                 `json:"status"`
+	Condition    string                 `json:"condition"`
+	TagIDs       []int                  `json:"tag_ids"`
+	AllOrNothing bool                   `json:"all_or_nothing"` // 任一设备无法处理时整体不执行
+}
+
+// 批量操作单个设备的结果
+type BulkItemResult struct {
+	DeviceID int    `json:"device_id"`
+	Result   string `json:"result"` // success/skipped/failed
+	Message  string `json:"message,omitempty"`
+}
+
+// 批量操作设备响应
+type BulkDevicesResponse struct {
+	Operation    string            `json:"operation"`
+	Total        int               `json:"total"`
+	SuccessCount int               `json:"success_count"`
+	SkippedCount int               `json:"skipped_count"`
+	FailedCount  int               `json:"failed_count"`
+	Committed    bool              `json:"committed"` // all_or_nothing 下有失败项时为 false，表示整体未执行
+	Results      []*BulkItemResult `json:"results"`
+}
+
+// 重建规格参数索引响应
+type ReindexDeviceSpecsResponse struct {
+	Indexed         int   `json:"indexed"`
+	FailedDeviceIDs []int `json:"failed_device_ids,omitempty"`
+}
+
+// 回收站列表请求
+type GetRecycleBinRequest struct {
+	Page   int    `json:"page" form:"page"`
+	Limit  int    `json:"limit" form:"limit"`
+	Search string `json:"search" form:"search"`
+}
+
+// 回收站中的设备
+type RecycleBinDevice struct {
+	*model.Device
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at"` // 到期后自动彻底删除的时间，未开启自动清理时为空
+}
+
+// 回收站列表响应
+type GetRecycleBinResponse struct {
+	Devices       []*RecycleBinDevice `json:"devices"`
+	Total         int                 `json:"total"`
+	Page          int                 `json:"page"`
+	Limit         int                 `json:"limit"`
+	TotalPages    int                 `json:"total_pages"`
+	RetentionDays int                 `json:"retention_days"` // 保留天数，0表示不自动清理
+}
+
+// 模板字段版本
+type TemplateVersionInfo struct {
+	Version     int                    `json:"version"`
+	Fields      []*model.TemplateField `json:"fields"`
+	Current     bool                   `json:"current"`      // 是否为模板当前使用的版本
+	DeviceCount int64                  `json:"device_count"` // 仍使用该版本的设备数量（含回收站）
+	CreatedBy   int                    `json:"created_by"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+// 模板字段版本列表响应
+type GetTemplateVersionsResponse struct {
+	TemplateID     int                    `json:"template_id"`
+	CurrentVersion int                    `json:"current_version"`
+	Versions       []*TemplateVersionInfo `json:"versions"`
+	UnknownCount   int64                  `json:"unknown_count"` // 字段版本未知的设备数量（版本功能上线前创建且未迁移）
+}
+
+// 模板版本对比请求
+type DiffTemplateVersionsRequest struct {
+	From int `json:"from" form:"from"` // 不传时为当前版本的上一版
+	To   int `json:"to" form:"to"`     // 不传时为当前版本
+}
+
+// 单个字段的定义变化
+type TemplateFieldDiff struct {
+	FieldName string                        `json:"field_name"`
+	Changes   map[string]*model.FieldChange `json:"changes"` // 键为 field_type/required/default_value 等字段定义属性
+}
+
+// 模板版本对比响应
+type TemplateVersionDiff struct {
+	TemplateID       int                    `json:"template_id"`
+	From             int                    `json:"from"`
+	To               int                    `json:"to"`
+	Added            []*model.TemplateField `json:"added"`
+	Removed          []*model.TemplateField `json:"removed"`
+	Changed          []*TemplateFieldDiff   `json:"changed"`
+	SuggestedRenames map[string]string      `json:"suggested_renames"` // 根据显示名称与类型推测的改名（旧字段名 → 新字段名），供迁移时确认
+}
+
+// 模板设备迁移请求
+type MigrateTemplateDevicesRequest struct {
+	FromVersion int               `json:"from_version"` // 只迁移该版本的设备，不传时迁移所有其他版本的设备
+	ToVersion   int               `json:"to_version"`   // 目标版本，不传时为当前版本
+	Renames     map[string]string `json:"renames"`      // 改名映射（旧字段名 → 新字段名）
+	DryRun      *bool             `json:"dry_run"`      // 默认 true，只预览不写入；确认结果后传 false 执行
+}
+
+// 单台设备的迁移结果
+type TemplateMigrationItem struct {
+	DeviceID    int               `json:"device_id"`
+	FromVersion int               `json:"from_version"`
+	Result      string            `json:"result"` // migrated/failed
+	Renamed     map[string]string `json:"renamed,omitempty"`
+	Dropped     []string          `json:"dropped,omitempty"`
+	Defaulted   []string          `json:"defaulted,omitempty"`
+	Errors      []*SpecFieldError `json:"errors,omitempty"`
+	Message     string            `json:"message,omitempty"`
+}
+
+// 模板设备迁移响应
+type MigrateTemplateDevicesResponse struct {
+	TemplateID    int                      `json:"template_id"`
+	ToVersion     int                      `json:"to_version"`
+	DryRun        bool                     `json:"dry_run"`
+	Total         int                      `json:"total"`
+	MigratedCount int                      `json:"migrated_count"` // 预览时为可迁移的数量
+	FailedCount   int                      `json:"failed_count"`
+	Results       []*TemplateMigrationItem `json:"results"`
+}
+
+// 设备二维码信息
+type DeviceQRCodeResponse struct {
+	DeviceID  int       `json:"device_id"`
+	Token     string    `json:"token"`
+	Link      string    `json:"link"`       // 二维码中编码的链接
+	UpdatedAt time.Time `json:"updated_at"` // token 生成时间
+}
+
+// 设备二维码图片请求
+type DeviceQRCodeImageRequest struct {
+	Format string `form:"format"` // png/svg，默认 png
+	Size   int    `form:"size"`   // 图片边长（像素），默认 300
+}
+
+// 设备二维码图片
+type DeviceQRCodeImage struct {
+	ContentType string
+	FileName    string
+	Data        []byte
+}
+
+// 打印设备标签请求
+type DeviceLabelsRequest struct {
+	DeviceIDs []int                  `json:"device_ids"`
+	Filter    *GetDevicesListRequest `json:"filter"`  // 按列表筛选条件选择设备
+	Skip      int                    `json:"skip"`    // 跳过第一页前面已用掉的标签位
+	Borders   bool                   `json:"borders"` // 绘制裁切线，用于普通纸打印
+}
+
+// 设备标签PDF
+type DeviceLabelsFile struct {
+	FileName string
+	Data     []byte
 }
@@ -202,7 +202,7 @@ func (c *PriceController) UpdatePrice() {
 // @router /prices/device/:deviceId/alerts [post]
 func (c *PriceController) CreatePriceAlert() {
 	// 从JWT中获取用户ID
-	userID, ok := c.Ctx.Input.GetData("userID").(int)
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
 		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
 		return
@@ -241,7 +241,7 @@ func (c *PriceController) CreatePriceAlert() {
 // @router /prices/alerts [get]
 func (c *PriceController) GetPriceAlerts() {
 	// 从JWT中获取用户ID
-	userID, ok := c.Ctx.Input.GetData("userID").(int)
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
 		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
 		return
@@ -268,11 +268,44 @@ func (c *PriceController) GetPriceAlerts() {
 	utils.WriteSuccess(c.Ctx, response)
 }
 
+// GetPriceAlert 获取单个价格预警
+// @router /prices/alerts/:alertId [get]
+func (c *PriceController) GetPriceAlert() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取预警ID
+	alertIDStr := c.Ctx.Input.Param(":alertId")
+	alertID, err := strconv.Atoi(alertIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "预警ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	alert, err := c.priceService.GetPriceAlert(alertID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.SetETag(c.Ctx, alert.Version)
+	utils.WriteSuccess(c.Ctx, alert)
+}
+
 // UpdatePriceAlert 更新价格预警
 // @router /prices/alerts/:alertId [put]
 func (c *PriceController) UpdatePriceAlert() {
 	// 从JWT中获取用户ID
-	userID, ok := c.Ctx.Input.GetData("userID").(int)
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
 		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
 		return
@@ -293,25 +326,34 @@ func (c *PriceController) UpdatePriceAlert() {
 		return
 	}
 
+	// 读取客户端持有的版本号
+	version, fromHeader, err := utils.ResolveVersion(c.Ctx, req.Version)
+	if err != nil {
+		utils.HandleBusinessError(c.Ctx, err)
+		return
+	}
+	req.Version = version
+
 	// 调用服务层
-	err = c.priceService.UpdatePriceAlert(alertID, userID, req)
+	alert, err := c.priceService.UpdatePriceAlert(alertID, userID, req)
 	if err != nil {
 		if businessErr, ok := err.(*utils.BusinessError); ok {
-			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+			utils.WriteVersionedError(c.Ctx, businessErr, fromHeader)
 		} else {
 			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
 		}
 		return
 	}
 
-	utils.WriteSuccess(c.Ctx, map[string]interface{}{"message": "更新成功"})
+	utils.SetETag(c.Ctx, alert.Version)
+	utils.WriteSuccess(c.Ctx, alert)
 }
 
 // DeletePriceAlert 删除价格预警
 // @router /prices/alerts/:alertId [delete]
 func (c *PriceController) DeletePriceAlert() {
 	// 从JWT中获取用户ID
-	userID, ok := c.Ctx.Input.GetData("userID").(int)
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
 		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
 		return
@@ -343,7 +385,7 @@ func (c *PriceController) DeletePriceAlert() {
 // @router /prices/device/:deviceId/comparison [get]
 func (c *PriceController) GetMarketComparison() {
 	// 从JWT中获取用户ID
-	userID, ok := c.Ctx.Input.GetData("userID").(int)
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
 	if !ok {
 		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
 		return
@@ -58,7 +58,8 @@ type PriceAlert struct {
 	NotificationMethods string    `orm:"column(notification_methods);size(200);null" json:"notification_methods"` // JSON格式存储通知方式
 	LastTriggeredAt     time.Time `orm:"column(last_triggered_at);type(datetime);null" json:"last_triggered_at"`
 	TriggerCount        int       `orm:"column(trigger_count);default(0)" json:"trigger_count"` // 触发次数
-	Status              string    `orm:"column(status);size(20);default(active)" json:"status"` // active/triggered/disabled/expired
+	Version             int       `orm:"column(version);default(1)" json:"version"`             // 乐观锁版本号，每次修改加1
+	Status              string    `orm:"column(status);size(20);default(active)" json:"status"` // active/triggered/disabled/expired/suspended（设备在回收站中暂停）
 	CreatedAt           time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
 	UpdatedAt           time.Time `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
 }
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	deviceRepository "Backend_Lili/internal/device/repository"
 	"Backend_Lili/internal/price/model"
 	"time"
 
@@ -126,15 +127,23 @@ func (r *PriceRepository) GetPriceAlerts(userID int, params map[string]interface
 // CreatePriceAlert 创建价格预警
 func (r *PriceRepository) CreatePriceAlert(alert *model.PriceAlert) error {
 	o := orm.NewOrm()
+	alert.Version = 1
 	_, err := o.Insert(alert)
 	return err
 }
 
-// UpdatePriceAlert 更新价格预警
+// UpdatePriceAlert 更新价格预警，alert.Version 为读取时的版本号，已被修改时返回 deviceRepository.ErrVersionConflict
 func (r *PriceRepository) UpdatePriceAlert(alert *model.PriceAlert) error {
 	o := orm.NewOrm()
-	_, err := o.Update(alert)
-	return err
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	if err := deviceRepository.UpdateWithVersion(tx, "price_alerts", alert.ID, &alert.Version, alert); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 // DeletePriceAlert 删除价格预警
@@ -307,11 +316,12 @@ func (r *PriceRepository) CheckPriceAlerts(deviceID int, currentPrice float64) (
 
 	var alerts []*model.PriceAlert
 
-	// 获取该设备的所有启用的预警
+	// 获取该设备的所有启用的预警，回收站中的设备不触发预警
 	_, err := o.QueryTable("price_alerts").
 		Filter("device_id", deviceID).
 		Filter("enabled", true).
 		Filter("status", "active").
+		FilterRaw("device_id", "IN (SELECT id FROM devices WHERE deleted_at IS NULL)").
 		All(&alerts)
 
 	if err != nil {
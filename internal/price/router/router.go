@@ -28,6 +28,7 @@ func InitPriceRoutes() {
 		// 价格预警相关路由
 		web.NSRouter("/device/:deviceId/alerts", priceController, "post:CreatePriceAlert"), // 设置价格预警
 		web.NSRouter("/alerts", priceController, "get:GetPriceAlerts"),                     // 获取价格预警列表
+		web.NSRouter("/alerts/:alertId", priceController, "get:GetPriceAlert"),             // 获取单个价格预警
 		web.NSRouter("/alerts/:alertId", priceController, "put:UpdatePriceAlert"),          // 更新价格预警
 		web.NSRouter("/alerts/:alertId", priceController, "delete:DeletePriceAlert"),       // 删除价格预警
 
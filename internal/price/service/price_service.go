@@ -1,6 +1,8 @@
 package service
 
 import (
+	deviceModel "Backend_Lili/internal/device/model"
+	deviceRepository "Backend_Lili/internal/device/repository"
 	"Backend_Lili/internal/price/model"
 	"Backend_Lili/internal/price/repository"
 	"Backend_Lili/pkg/utils"
@@ -9,15 +11,21 @@ import (
 	"math"
 	"sort"
 	"time"
+
+	"github.com/beego/beego/v2/core/logs"
 )
 
 type PriceService struct {
-	priceRepo *repository.PriceRepository
+	priceRepo  *repository.PriceRepository
+	deviceRepo *deviceRepository.DeviceRepository
+	eventRepo  *deviceRepository.DeviceEventRepository
 }
 
 func NewPriceService() *PriceService {
 	return &PriceService{
-		priceRepo: repository.NewPriceRepository(),
+		priceRepo:  repository.NewPriceRepository(),
+		deviceRepo: deviceRepository.NewDeviceRepository(),
+		eventRepo:  deviceRepository.NewDeviceEventRepository(),
 	}
 }
 
@@ -262,6 +270,9 @@ func (s *PriceService) UpdatePrice(deviceID, userID int) (*UpdatePriceResponse,
 	}
 	s.priceRepo.CreatePriceHistory(history)
 
+	// 同步设备当前估值
+	s.syncDeviceValue(deviceID, userID, newPrice)
+
 	// 检查价格预警
 	s.checkAndTriggerAlerts(deviceID, newPrice)
 
@@ -374,19 +385,39 @@ func (s *PriceService) GetPriceAlerts(userID int, req *GetPriceAlertsRequest) (*
 	}, nil
 }
 
-// UpdatePriceAlert 更新价格预警
-func (s *PriceService) UpdatePriceAlert(alertID, userID int, req *UpdatePriceAlertRequest) error {
+// GetPriceAlert 获取单个价格预警
+func (s *PriceService) GetPriceAlert(alertID, userID int) (*model.PriceAlert, error) {
 	if alertID <= 0 || userID <= 0 {
-		return utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+
+	alert, err := s.priceRepo.GetPriceAlertByID(alertID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取价格预警失败")
+	}
+	if alert == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "价格预警不存在")
+	}
+
+	return alert, nil
+}
+
+// UpdatePriceAlert 更新价格预警，返回更新后的预警
+func (s *PriceService) UpdatePriceAlert(alertID, userID int, req *UpdatePriceAlertRequest) (*model.PriceAlert, error) {
+	if alertID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
 	}
 
 	// 获取现有预警
 	alert, err := s.priceRepo.GetPriceAlertByID(alertID, userID)
 	if err != nil {
-		return utils.NewBusinessError(utils.ERROR_DATABASE, "获取价格预警失败")
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取价格预警失败")
 	}
 	if alert == nil {
-		return utils.NewBusinessError(utils.ERROR_NOT_FOUND, "价格预警不存在")
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "价格预警不存在")
+	}
+	if req.Version != nil && *req.Version != alert.Version {
+		return nil, alertConflictError(alert)
 	}
 
 	// 更新字段
@@ -409,11 +440,22 @@ func (s *PriceService) UpdatePriceAlert(alertID, userID int, req *UpdatePriceAle
 	}
 
 	err = s.priceRepo.UpdatePriceAlert(alert)
+	if err == deviceRepository.ErrVersionConflict {
+		current, _ := s.priceRepo.GetPriceAlertByID(alertID, userID)
+		return nil, alertConflictError(current)
+	}
 	if err != nil {
-		return utils.NewBusinessError(utils.ERROR_DATABASE, "更新价格预警失败")
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "更新价格预警失败")
 	}
 
-	return nil
+	return alert, nil
+}
+
+// alertConflictError 版本冲突时返回的业务异常，附带预警的最新状态
+func alertConflictError(current *model.PriceAlert) error {
+	return utils.NewBusinessErrorWithDetails(utils.ERROR_CONFLICT, "价格预警已被修改，请刷新后重试", map[string]interface{}{
+		"current": current,
+	})
 }
 
 // DeletePriceAlert 删除价格预警
@@ -771,6 +813,25 @@ func (s *PriceService) fetchPriceFromSources(deviceID int) (float64, error) {
 	return 1000.0 + float64(deviceID%100), nil
 }
 
+// syncDeviceValue 将最新价格写入设备当前估值，并在设备时间线中记录估值变化，失败只记录日志
+func (s *PriceService) syncDeviceValue(deviceID, userID int, newPrice float64) {
+	device, err := s.deviceRepo.GetDeviceByID(deviceID, userID)
+	if err != nil || device == nil || device.CurrentValue == newPrice {
+		return
+	}
+	if err := s.deviceRepo.UpdateDeviceCurrentValue(deviceID, newPrice); err != nil {
+		logs.Warn("同步设备估值失败: device=%d, err=%v", deviceID, err)
+		return
+	}
+
+	event := deviceModel.NewDeviceEvent(deviceID, userID, userID, deviceModel.DeviceEventValueChanged, map[string]*deviceModel.FieldChange{
+		"current_value": {Old: device.CurrentValue, New: newPrice},
+	}, map[string]interface{}{"source": "api"})
+	if err := s.eventRepo.CreateEvent(event); err != nil {
+		logs.Warn("记录设备事件失败: device=%d, type=%s, err=%v", deviceID, event.EventType, err)
+	}
+}
+
 // determineTrendStatus 确定趋势状态
 func (s *PriceService) determineTrendStatus(changeRate float64) string {
 	if changeRate > 1 {
@@ -800,6 +861,7 @@ func (s *PriceService) triggerAlert(alert *model.PriceAlert, currentPrice float6
 	alert.Status = "triggered"
 	alert.LastTriggeredAt = time.Now()
 	alert.TriggerCount++
+	// 预警在检查后被用户修改时版本冲突，以用户的修改为准，本次不再记录触发
 	s.priceRepo.UpdatePriceAlert(alert)
 
 	// 发送通知（简化处理）
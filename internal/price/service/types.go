@@ -133,6 +133,7 @@ type UpdatePriceAlertRequest struct {
 	ThresholdType       string   `json:"threshold_type"`
 	Enabled             *bool    `json:"enabled"` // 使用指针以区分false和未设置
 	NotificationMethods []string `json:"notification_methods"`
+	Version             *int     `json:"version"` // 读取时的版本号，未传 If-Match 请求头时必填
 }
 
 // 价格预警列表请求
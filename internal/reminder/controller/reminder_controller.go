@@ -0,0 +1,249 @@
+package controller
+
+import (
+	base "Backend_Lili/internal/auth/controller"
+	"Backend_Lili/internal/reminder/service"
+	"Backend_Lili/pkg/utils"
+	"strconv"
+)
+
+type ReminderController struct {
+	base.BaseController
+	reminderService *service.ReminderService
+}
+
+func NewReminderController() *ReminderController {
+	return &ReminderController{}
+}
+
+func (c *ReminderController) Prepare() {
+	// 调用父类的Prepare方法
+	c.BaseController.Prepare()
+
+	// 初始化提醒服务
+	c.reminderService = service.NewReminderService()
+}
+
+// GetReminders 获取提醒列表
+// @router /reminders [get]
+func (c *ReminderController) GetReminders() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析查询参数
+	req := &service.GetRemindersRequest{}
+	if err := c.ParseForm(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	response, err := c.reminderService.GetReminders(userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, response)
+}
+
+// GetUpcomingReminders 获取即将到期的提醒
+// @router /reminders/upcoming [get]
+func (c *ReminderController) GetUpcomingReminders() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 未传入时由服务层使用默认天数
+	days, _ := c.GetInt("days", 0)
+
+	// 调用服务层
+	response, err := c.reminderService.GetUpcomingReminders(userID, days)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, response)
+}
+
+// GetReminder 获取提醒详情
+// @router /reminders/:reminderId [get]
+func (c *ReminderController) GetReminder() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取提醒ID
+	reminderIDStr := c.Ctx.Input.Param(":reminderId")
+	reminderID, err := strconv.Atoi(reminderIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "提醒ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	reminder, err := c.reminderService.GetReminder(reminderID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, reminder)
+}
+
+// CreateReminder 创建提醒
+// @router /reminders [post]
+func (c *ReminderController) CreateReminder() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.CreateReminderRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	reminder, err := c.reminderService.CreateReminder(userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, reminder)
+}
+
+// UpdateReminder 更新提醒
+// @router /reminders/:reminderId [put]
+func (c *ReminderController) UpdateReminder() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取提醒ID
+	reminderIDStr := c.Ctx.Input.Param(":reminderId")
+	reminderID, err := strconv.Atoi(reminderIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "提醒ID格式错误")
+		return
+	}
+
+	// 解析请求参数
+	req := &service.UpdateReminderRequest{}
+	if err := c.BindJSON(req); err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "参数解析失败")
+		return
+	}
+
+	// 调用服务层
+	reminder, err := c.reminderService.UpdateReminder(reminderID, userID, req)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, reminder)
+}
+
+// DeleteReminder 删除提醒
+// @router /reminders/:reminderId [delete]
+func (c *ReminderController) DeleteReminder() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取提醒ID
+	reminderIDStr := c.Ctx.Input.Param(":reminderId")
+	reminderID, err := strconv.Atoi(reminderIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "提醒ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	err = c.reminderService.DeleteReminder(reminderID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, nil)
+}
+
+// CompleteReminder 完成提醒（重复提醒自动顺延）
+// @router /reminders/:reminderId/complete [post]
+func (c *ReminderController) CompleteReminder() {
+	// 从JWT中获取用户ID
+	userID, ok := c.Ctx.Input.GetData("user_id").(int)
+	if !ok {
+		utils.WriteError(c.Ctx, utils.ERROR_AUTH, "用户认证失败")
+		return
+	}
+
+	// 获取提醒ID
+	reminderIDStr := c.Ctx.Input.Param(":reminderId")
+	reminderID, err := strconv.Atoi(reminderIDStr)
+	if err != nil {
+		utils.WriteError(c.Ctx, utils.ERROR_PARAM, "提醒ID格式错误")
+		return
+	}
+
+	// 调用服务层
+	reminder, err := c.reminderService.CompleteReminder(reminderID, userID)
+	if err != nil {
+		if businessErr, ok := err.(*utils.BusinessError); ok {
+			utils.WriteError(c.Ctx, businessErr.Code, businessErr.Message)
+		} else {
+			utils.WriteError(c.Ctx, utils.ERROR_SERVER, "服务器内部错误")
+		}
+		return
+	}
+
+	utils.WriteSuccess(c.Ctx, reminder)
+}
@@ -0,0 +1,13 @@
+package model
+
+import (
+	"github.com/beego/beego/v2/client/orm"
+)
+
+// Init 初始化提醒模块数据模型
+func Init() {
+	// 注册模型
+	orm.RegisterModel(
+		new(Reminder),
+	)
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// Reminder 提醒表（保修到期、电池保养、滤芯更换、自定义）
+type Reminder struct {
+	ID              int       `orm:"column(id);auto;pk" json:"id"`
+	UserID          int       `orm:"column(user_id)" json:"user_id"`
+	DeviceID        *int      `orm:"column(device_id);null" json:"device_id"`             // 自定义提醒可不关联设备
+	ReminderType    string    `orm:"column(reminder_type);size(20)" json:"reminder_type"` // warranty/battery/filter/custom
+	Title           string    `orm:"column(title);size(200)" json:"title"`
+	Description     string    `orm:"column(description);size(500);null" json:"description"`
+	DueDate         time.Time `orm:"column(due_date);type(date)" json:"due_date"`
+	RecurrenceRule  string    `orm:"column(recurrence_rule);size(100);null" json:"recurrence_rule"` // 如 FREQ=MONTHLY;INTERVAL=6，为空表示不重复
+	AnchorDay       int       `orm:"column(anchor_day);default(0)" json:"-"`                        // 按月/年重复的日，取设置截止日期时的日，避免月末截断后一直停在28/30日；0 表示取 due_date 的日
+	LeadDays        int       `orm:"column(lead_days);default(7)" json:"lead_days"`                 // 提前提醒天数
+	Status          string    `orm:"column(status);size(20);default(pending)" json:"status"`        // pending/done/dismissed
+	Source          string    `orm:"column(source);size(20);default(manual)" json:"source"`         // manual/auto（由保修日期自动生成）
+	LastCompletedAt time.Time `orm:"column(last_completed_at);type(datetime);null" json:"last_completed_at"`
+	CreatedAt       time.Time `orm:"column(created_at);auto_now_add;type(datetime)" json:"created_at"`
+	UpdatedAt       time.Time `orm:"column(updated_at);auto_now;type(datetime)" json:"updated_at"`
+}
+
+func (r *Reminder) TableName() string {
+	return "reminders"
+}
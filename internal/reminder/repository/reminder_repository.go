@@ -0,0 +1,203 @@
+package repository
+
+import (
+	deviceModel "Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/reminder/model"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+// DefaultUpcomingDays 即将到期列表的默认天数，首页统计按同一窗口计数
+const DefaultUpcomingDays = 30
+
+type ReminderRepository struct{}
+
+func NewReminderRepository() *ReminderRepository {
+	return &ReminderRepository{}
+}
+
+// GetReminders 获取提醒列表
+func (r *ReminderRepository) GetReminders(userID int, params map[string]interface{}) ([]*model.Reminder, int64, error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable("reminders").Filter("user_id", userID)
+
+	// 应用筛选条件
+	if status, ok := params["status"].(string); ok && status != "" {
+		qs = qs.Filter("status", status)
+	}
+	if reminderType, ok := params["reminder_type"].(string); ok && reminderType != "" {
+		qs = qs.Filter("reminder_type", reminderType)
+	}
+	if deviceID, ok := params["device_id"].(int); ok && deviceID > 0 {
+		qs = qs.Filter("device_id", deviceID)
+	}
+
+	total, err := qs.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 分页
+	if page, ok := params["page"].(int); ok && page > 0 {
+		limit := 20
+		if limitParam, ok := params["limit"].(int); ok && limitParam > 0 {
+			limit = limitParam
+		}
+		qs = qs.Limit(limit, (page-1)*limit)
+	}
+
+	var reminders []*model.Reminder
+	_, err = qs.OrderBy("due_date", "id").All(&reminders)
+	return reminders, total, err
+}
+
+// GetReminderByID 获取提醒详情
+func (r *ReminderRepository) GetReminderByID(reminderID, userID int) (*model.Reminder, error) {
+	o := orm.NewOrm()
+	reminder := &model.Reminder{}
+	err := o.QueryTable("reminders").
+		Filter("id", reminderID).
+		Filter("user_id", userID).
+		One(reminder)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reminder, nil
+}
+
+// CreateReminder 创建提醒
+func (r *ReminderRepository) CreateReminder(reminder *model.Reminder) error {
+	o := orm.NewOrm()
+	reminder.CreatedAt = time.Now()
+	reminder.UpdatedAt = time.Now()
+	_, err := o.Insert(reminder)
+	return err
+}
+
+// UpdateReminder 更新提醒
+func (r *ReminderRepository) UpdateReminder(reminder *model.Reminder) error {
+	o := orm.NewOrm()
+	reminder.UpdatedAt = time.Now()
+	_, err := o.Update(reminder)
+	return err
+}
+
+// DeleteReminder 删除提醒
+func (r *ReminderRepository) DeleteReminder(reminderID, userID int) error {
+	o := orm.NewOrm()
+	_, err := o.QueryTable("reminders").
+		Filter("id", reminderID).
+		Filter("user_id", userID).
+		Delete()
+	return err
+}
+
+// UpcomingRemindersFrom 即将到期提醒的 FROM 与 WHERE 子句，提醒列表与首页统计共用：
+// 待处理、截止日期不晚于今天之后 days 天（含已逾期），已删除设备的提醒不计入
+func UpcomingRemindersFrom(userID, days int) (string, []interface{}) {
+	now := time.Now()
+	until := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, days)
+	return `FROM reminders r
+		LEFT JOIN devices d ON r.device_id = d.id
+		WHERE r.user_id = ? AND r.status = 'pending' AND r.due_date <= ?
+		AND (r.device_id IS NULL OR d.deleted_at IS NULL)`, []interface{}{userID, until.Format("2006-01-02")}
+}
+
+// GetUpcomingReminders 获取未来 days 天内到期及已逾期的待处理提醒
+func (r *ReminderRepository) GetUpcomingReminders(userID, days int) ([]*model.Reminder, error) {
+	o := orm.NewOrm()
+	var reminders []*model.Reminder
+	from, args := UpcomingRemindersFrom(userID, days)
+	_, err := o.Raw("SELECT r.* "+from+" ORDER BY r.due_date, r.id", args...).QueryRows(&reminders)
+	return reminders, err
+}
+
+// GetAutoWarrantyReminder 获取设备由保修日期自动生成的提醒
+func (r *ReminderRepository) GetAutoWarrantyReminder(deviceID int) (*model.Reminder, error) {
+	o := orm.NewOrm()
+	reminder := &model.Reminder{}
+	err := o.QueryTable("reminders").
+		Filter("device_id", deviceID).
+		Filter("reminder_type", "warranty").
+		Filter("source", "auto").
+		One(reminder)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reminder, nil
+}
+
+// DeletePendingAutoWarrantyReminders 删除用户全部未处理的自动保修提醒
+func (r *ReminderRepository) DeletePendingAutoWarrantyReminders(userID int) (int64, error) {
+	o := orm.NewOrm()
+	return o.QueryTable("reminders").
+		Filter("user_id", userID).
+		Filter("reminder_type", "warranty").
+		Filter("source", "auto").
+		Filter("status", "pending").
+		Delete()
+}
+
+// WarrantyReminderEnabled 用户是否开启了保修到期提醒，未保存偏好设置时与列默认值一致视为关闭
+func (r *ReminderRepository) WarrantyReminderEnabled(userID int) (bool, error) {
+	o := orm.NewOrm()
+	var row struct {
+		Enabled bool
+	}
+	err := o.Raw("SELECT warranty_reminder_enabled AS enabled FROM user_preferences WHERE user_id = ?", userID).QueryRow(&row)
+	if err == orm.ErrNoRows {
+		return false, nil
+	}
+	return row.Enabled, err
+}
+
+// GetWarrantyDevices 获取用户保修日期不早于 since 的未删除设备，只读取名称与保修日期
+func (r *ReminderRepository) GetWarrantyDevices(userID int, since time.Time) ([]*deviceModel.Device, error) {
+	o := orm.NewOrm()
+	var devices []*deviceModel.Device
+	_, err := o.QueryTable("devices").
+		Filter("user_id", userID).
+		Filter("deleted_at__isnull", true).
+		Filter("warranty_date__gte", since.Format("2006-01-02")).
+		All(&devices, "id", "user_id", "name", "warranty_date")
+	return devices, err
+}
+
+// GetDeviceNames 批量获取设备名称
+func (r *ReminderRepository) GetDeviceNames(userID int, deviceIDs []int) (map[int]string, error) {
+	names := make(map[int]string)
+	if len(deviceIDs) == 0 {
+		return names, nil
+	}
+
+	o := orm.NewOrm()
+	var devices []*deviceModel.Device
+	_, err := o.QueryTable("devices").
+		Filter("user_id", userID).
+		Filter("id__in", deviceIDs).
+		All(&devices, "id", "name")
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		names[device.ID] = device.Name
+	}
+	return names, nil
+}
+
+// DeviceExists 校验设备归属
+func (r *ReminderRepository) DeviceExists(deviceID, userID int) bool {
+	o := orm.NewOrm()
+	return o.QueryTable("devices").
+		Filter("id", deviceID).
+		Filter("user_id", userID).
+		Filter("deleted_at__isnull", true).
+		Exist()
+}
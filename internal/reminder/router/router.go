@@ -0,0 +1,28 @@
+package router
+
+import (
+	"Backend_Lili/internal/auth/middleware"
+	"Backend_Lili/internal/reminder/controller"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// InitReminderRoutes 初始化提醒模块路由
+func InitReminderRoutes() {
+	// 创建提醒控制器实例
+	reminderController := controller.NewReminderController()
+
+	ns := web.NewNamespace("/api/v1/reminders",
+		web.NSBefore(middleware.JWTAuth),
+
+		// 即将到期提醒 - 需要在具体ID路由之前
+		web.NSRouter("/upcoming", reminderController, "get:GetUpcomingReminders"),
+
+		// 基本CRUD操作
+		web.NSRouter("/", reminderController, "get:GetReminders;post:CreateReminder"),
+		web.NSRouter("/:reminderId", reminderController, "get:GetReminder;put:UpdateReminder;delete:DeleteReminder"),
+		web.NSRouter("/:reminderId/complete", reminderController, "post:CompleteReminder"),
+	)
+
+	web.AddNamespace(ns)
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurrence 重复规则，取 iCalendar RRULE 的 FREQ 与 INTERVAL 子集
+type recurrence struct {
+	Freq     string // DAILY/WEEKLY/MONTHLY/YEARLY
+	Interval int
+}
+
+// parseRecurrenceRule 解析重复规则，空字符串表示不重复
+func parseRecurrenceRule(rule string) (*recurrence, error) {
+	rule = strings.TrimSpace(strings.TrimPrefix(strings.ToUpper(rule), "RRULE:"))
+	if rule == "" {
+		return nil, nil
+	}
+
+	rec := &recurrence{Interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.New("重复规则格式错误")
+		}
+		switch kv[0] {
+		case "FREQ":
+			switch kv[1] {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rec.Freq = kv[1]
+			default:
+				return nil, errors.New("重复频率仅支持 DAILY、WEEKLY、MONTHLY、YEARLY")
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(kv[1])
+			if err != nil || n < 1 || n > 999 {
+				return nil, errors.New("重复间隔应为1-999的整数")
+			}
+			rec.Interval = n
+		default:
+			return nil, errors.New("不支持的重复规则字段: " + kv[0])
+		}
+	}
+	if rec.Freq == "" {
+		return nil, errors.New("重复规则缺少 FREQ")
+	}
+	return rec, nil
+}
+
+// String 规范化后的规则文本
+func (r *recurrence) String() string {
+	if r.Interval == 1 {
+		return "FREQ=" + r.Freq
+	}
+	return "FREQ=" + r.Freq + ";INTERVAL=" + strconv.Itoa(r.Interval)
+}
+
+// Next 计算下一次日期；按月/年重复时取 anchorDay（首次截止日期的日，0 表示取 date 的日），
+// 只对结果按当月天数截断，1月31日之后依次为2月28/29日、3月31日
+func (r *recurrence) Next(date time.Time, anchorDay int) time.Time {
+	switch r.Freq {
+	case "DAILY":
+		return date.AddDate(0, 0, r.Interval)
+	case "WEEKLY":
+		return date.AddDate(0, 0, 7*r.Interval)
+	case "MONTHLY":
+		return addMonths(date, r.Interval, anchorDay)
+	default:
+		return addMonths(date, 12*r.Interval, anchorDay)
+	}
+}
+
+func addMonths(date time.Time, months, anchorDay int) time.Time {
+	y, m, d := date.Date()
+	if anchorDay > 0 {
+		d = anchorDay
+	}
+	first := time.Date(y, m+time.Month(months), 1, 0, 0, 0, 0, date.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if d > lastDay {
+		d = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), d, 0, 0, 0, 0, date.Location())
+}
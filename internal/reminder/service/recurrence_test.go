@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		want  []string
+	}{
+		{name: "月末按首次日期计算", rule: "FREQ=MONTHLY", start: "2023-01-31", want: []string{"2023-02-28", "2023-03-31", "2023-04-30", "2023-05-31"}},
+		{name: "闰年二月", rule: "FREQ=MONTHLY", start: "2024-01-31", want: []string{"2024-02-29", "2024-03-31"}},
+		{name: "间隔月份", rule: "FREQ=MONTHLY;INTERVAL=3", start: "2023-11-30", want: []string{"2024-02-29", "2024-05-30", "2024-08-30"}},
+		{name: "每年闰日", rule: "FREQ=YEARLY", start: "2024-02-29", want: []string{"2025-02-28", "2026-02-28", "2027-02-28", "2028-02-29"}},
+		{name: "每周", rule: "FREQ=WEEKLY;INTERVAL=2", start: "2023-12-25", want: []string{"2024-01-08", "2024-01-22"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := parseRecurrenceRule(tt.rule)
+			if err != nil {
+				t.Fatalf("解析规则返回错误: %v", err)
+			}
+			date, _ := time.Parse("2006-01-02", tt.start)
+			anchorDay := date.Day()
+			for i, want := range tt.want {
+				date = rec.Next(date, anchorDay)
+				if got := date.Format("2006-01-02"); got != want {
+					t.Fatalf("第%d次为 %s，期望 %s", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestRecurrenceNextWithoutAnchor 未记录 anchor_day 的已有提醒按 due_date 的日计算
+func TestRecurrenceNextWithoutAnchor(t *testing.T) {
+	rec, _ := parseRecurrenceRule("FREQ=MONTHLY")
+	date, _ := time.Parse("2006-01-02", "2023-03-15")
+	if got := rec.Next(date, 0).Format("2006-01-02"); got != "2023-04-15" {
+		t.Errorf("下一次为 %s，期望 2023-04-15", got)
+	}
+}
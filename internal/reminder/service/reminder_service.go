@@ -0,0 +1,426 @@
+package service
+
+import (
+	deviceModel "Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/reminder/model"
+	"Backend_Lili/internal/reminder/repository"
+	"Backend_Lili/pkg/utils"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// reminderTypes 提醒类型及默认提前天数
+var reminderTypes = map[string]struct {
+	Name     string
+	LeadDays int
+}{
+	"warranty": {Name: "保修到期", LeadDays: 30},
+	"battery":  {Name: "电池保养", LeadDays: 7},
+	"filter":   {Name: "滤芯更换", LeadDays: 7},
+	"custom":   {Name: "自定义提醒", LeadDays: 1},
+}
+
+const (
+	// maxLeadDays 提前提醒天数上限
+	maxLeadDays = 365
+	// warrantyReminderSuffix 自动生成的保修提醒标题后缀
+	warrantyReminderSuffix = " 保修到期"
+)
+
+// reminderStore 提醒服务使用的数据访问，由 repository.ReminderRepository 实现
+type reminderStore interface {
+	GetReminders(userID int, params map[string]interface{}) ([]*model.Reminder, int64, error)
+	GetReminderByID(reminderID, userID int) (*model.Reminder, error)
+	CreateReminder(reminder *model.Reminder) error
+	UpdateReminder(reminder *model.Reminder) error
+	DeleteReminder(reminderID, userID int) error
+	GetUpcomingReminders(userID, days int) ([]*model.Reminder, error)
+	GetAutoWarrantyReminder(deviceID int) (*model.Reminder, error)
+	DeletePendingAutoWarrantyReminders(userID int) (int64, error)
+	WarrantyReminderEnabled(userID int) (bool, error)
+	GetWarrantyDevices(userID int, since time.Time) ([]*deviceModel.Device, error)
+	GetDeviceNames(userID int, deviceIDs []int) (map[int]string, error)
+	DeviceExists(deviceID, userID int) bool
+}
+
+type ReminderService struct {
+	reminderRepo reminderStore
+}
+
+func NewReminderService() *ReminderService {
+	return &ReminderService{
+		reminderRepo: repository.NewReminderRepository(),
+	}
+}
+
+// GetReminders 获取提醒列表
+func (s *ReminderService) GetReminders(userID int, req *GetRemindersRequest) (*GetRemindersResponse, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	if req.Limit > 100 {
+		req.Limit = 100
+	}
+
+	params := map[string]interface{}{
+		"page":  req.Page,
+		"limit": req.Limit,
+	}
+	if req.Status != "" {
+		params["status"] = req.Status
+	}
+	if req.ReminderType != "" {
+		params["reminder_type"] = req.ReminderType
+	}
+	if req.DeviceID > 0 {
+		params["device_id"] = req.DeviceID
+	}
+
+	reminders, total, err := s.reminderRepo.GetReminders(userID, params)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取提醒列表失败")
+	}
+
+	return &GetRemindersResponse{
+		Reminders:  reminders,
+		Total:      int(total),
+		Page:       req.Page,
+		Limit:      req.Limit,
+		TotalPages: int((total + int64(req.Limit) - 1) / int64(req.Limit)),
+	}, nil
+}
+
+// GetReminder 获取提醒详情
+func (s *ReminderService) GetReminder(reminderID, userID int) (*model.Reminder, error) {
+	if reminderID <= 0 || userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "参数无效")
+	}
+
+	reminder, err := s.reminderRepo.GetReminderByID(reminderID, userID)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取提醒失败")
+	}
+	if reminder == nil {
+		return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "提醒不存在")
+	}
+	return reminder, nil
+}
+
+// CreateReminder 创建提醒
+func (s *ReminderService) CreateReminder(userID int, req *CreateReminderRequest) (*model.Reminder, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+
+	typeInfo, ok := reminderTypes[req.ReminderType]
+	if !ok {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "提醒类型无效，仅支持 warranty、battery、filter、custom")
+	}
+
+	reminder := &model.Reminder{
+		UserID:       userID,
+		ReminderType: req.ReminderType,
+		Title:        strings.TrimSpace(req.Title),
+		Description:  strings.TrimSpace(req.Description),
+		LeadDays:     typeInfo.LeadDays,
+		Status:       "pending",
+		Source:       "manual",
+	}
+
+	if req.DeviceID != nil && *req.DeviceID > 0 {
+		if !s.reminderRepo.DeviceExists(*req.DeviceID, userID) {
+			return nil, utils.NewBusinessError(utils.ERROR_NOT_FOUND, "设备不存在")
+		}
+		reminder.DeviceID = req.DeviceID
+	} else if req.ReminderType != "custom" {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "该类型提醒需要关联设备")
+	}
+
+	if reminder.Title == "" {
+		reminder.Title = typeInfo.Name
+	}
+	if err := validateReminderText(reminder.Title, reminder.Description); err != nil {
+		return nil, err
+	}
+
+	if req.DueDate == "" {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "截止日期不能为空")
+	}
+	dueDate, err := time.Parse("2006-01-02", req.DueDate)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "截止日期格式错误")
+	}
+	reminder.DueDate = dueDate
+	reminder.AnchorDay = dueDate.Day()
+
+	if req.LeadDays != nil {
+		if *req.LeadDays < 0 || *req.LeadDays > maxLeadDays {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "提前提醒天数应在0-365之间")
+		}
+		reminder.LeadDays = *req.LeadDays
+	}
+
+	rec, err := parseRecurrenceRule(req.RecurrenceRule)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, err.Error())
+	}
+	if rec != nil {
+		reminder.RecurrenceRule = rec.String()
+	}
+
+	if err := s.reminderRepo.CreateReminder(reminder); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "创建提醒失败")
+	}
+	return reminder, nil
+}
+
+// UpdateReminder 更新提醒
+func (s *ReminderService) UpdateReminder(reminderID, userID int, req *UpdateReminderRequest) (*model.Reminder, error) {
+	reminder, err := s.GetReminder(reminderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		reminder.Title = strings.TrimSpace(*req.Title)
+		if reminder.Title == "" {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "提醒标题不能为空")
+		}
+	}
+	if req.Description != nil {
+		reminder.Description = strings.TrimSpace(*req.Description)
+	}
+	if err := validateReminderText(reminder.Title, reminder.Description); err != nil {
+		return nil, err
+	}
+	if req.DueDate != "" {
+		dueDate, err := time.Parse("2006-01-02", req.DueDate)
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "截止日期格式错误")
+		}
+		reminder.DueDate = dueDate
+		reminder.AnchorDay = dueDate.Day()
+	}
+	if req.RecurrenceRule != nil {
+		rec, err := parseRecurrenceRule(*req.RecurrenceRule)
+		if err != nil {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, err.Error())
+		}
+		reminder.RecurrenceRule = ""
+		if rec != nil {
+			reminder.RecurrenceRule = rec.String()
+		}
+	}
+	if req.LeadDays != nil {
+		if *req.LeadDays < 0 || *req.LeadDays > maxLeadDays {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "提前提醒天数应在0-365之间")
+		}
+		reminder.LeadDays = *req.LeadDays
+	}
+	if req.Status != "" {
+		if req.Status != "pending" && req.Status != "done" && req.Status != "dismissed" {
+			return nil, utils.NewBusinessError(utils.ERROR_PARAM, "提醒状态无效")
+		}
+		reminder.Status = req.Status
+	}
+
+	if err := s.reminderRepo.UpdateReminder(reminder); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "更新提醒失败")
+	}
+	return reminder, nil
+}
+
+// DeleteReminder 删除提醒
+func (s *ReminderService) DeleteReminder(reminderID, userID int) error {
+	if _, err := s.GetReminder(reminderID, userID); err != nil {
+		return err
+	}
+	if err := s.reminderRepo.DeleteReminder(reminderID, userID); err != nil {
+		return utils.NewBusinessError(utils.ERROR_DATABASE, "删除提醒失败")
+	}
+	return nil
+}
+
+// CompleteReminder 完成提醒；重复提醒顺延到今天之后的下一次日期并保持待处理
+func (s *ReminderService) CompleteReminder(reminderID, userID int) (*model.Reminder, error) {
+	reminder, err := s.GetReminder(reminderID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if reminder.Status != "pending" {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "提醒已处理")
+	}
+
+	now := time.Now()
+	reminder.LastCompletedAt = now
+
+	rec, _ := parseRecurrenceRule(reminder.RecurrenceRule)
+	if rec != nil {
+		today := truncateDay(now)
+		next := rec.Next(reminder.DueDate, reminder.AnchorDay)
+		for !next.After(today) {
+			next = rec.Next(next, reminder.AnchorDay)
+		}
+		reminder.DueDate = next
+	} else {
+		reminder.Status = "done"
+	}
+
+	if err := s.reminderRepo.UpdateReminder(reminder); err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "更新提醒失败")
+	}
+	return reminder, nil
+}
+
+// GetUpcomingReminders 获取未来 days 天内到期及已逾期的待处理提醒
+func (s *ReminderService) GetUpcomingReminders(userID, days int) (*GetUpcomingRemindersResponse, error) {
+	if userID <= 0 {
+		return nil, utils.NewBusinessError(utils.ERROR_PARAM, "用户ID无效")
+	}
+	if days <= 0 {
+		days = repository.DefaultUpcomingDays
+	}
+	if days > 365 {
+		days = 365
+	}
+
+	today := truncateDay(time.Now())
+	reminders, err := s.reminderRepo.GetUpcomingReminders(userID, days)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取即将到期提醒失败")
+	}
+
+	deviceIDs := make([]int, 0)
+	for _, reminder := range reminders {
+		if reminder.DeviceID != nil {
+			deviceIDs = append(deviceIDs, *reminder.DeviceID)
+		}
+	}
+	deviceNames, err := s.reminderRepo.GetDeviceNames(userID, deviceIDs)
+	if err != nil {
+		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取设备信息失败")
+	}
+
+	items := make([]*UpcomingReminder, 0, len(reminders))
+	for _, reminder := range reminders {
+		daysLeft := int(truncateDay(reminder.DueDate).Sub(today).Hours() / 24)
+		item := &UpcomingReminder{
+			Reminder:   reminder,
+			DaysLeft:   daysLeft,
+			Overdue:    daysLeft < 0,
+			InLeadTime: daysLeft <= reminder.LeadDays,
+		}
+		if reminder.DeviceID != nil {
+			item.DeviceName = deviceNames[*reminder.DeviceID]
+		}
+		items = append(items, item)
+	}
+
+	return &GetUpcomingRemindersResponse{
+		Reminders: items,
+		Days:      days,
+		Total:     len(items),
+	}, nil
+}
+
+// SyncWarrantyReminder 按设备保修日期维护自动生成的保修到期提醒
+// 保修日期为空或用户关闭了保修提醒（warranty_reminder_enabled）时删除未处理的自动提醒；已过保的设备不再新建提醒
+func (s *ReminderService) SyncWarrantyReminder(userID, deviceID int, deviceName string, warrantyDate time.Time) error {
+	enabled, err := s.reminderRepo.WarrantyReminderEnabled(userID)
+	if err != nil {
+		return err
+	}
+	return s.syncWarrantyReminder(userID, deviceID, deviceName, warrantyDate, enabled)
+}
+
+// ApplyWarrantyReminderPreference 用户切换保修提醒偏好后调用：关闭时删除全部未处理的自动保修提醒，
+// 开启时为仍在保修期内的设备补建提醒
+func (s *ReminderService) ApplyWarrantyReminderPreference(userID int, enabled bool) error {
+	if !enabled {
+		_, err := s.reminderRepo.DeletePendingAutoWarrantyReminders(userID)
+		return err
+	}
+	devices, err := s.reminderRepo.GetWarrantyDevices(userID, truncateDay(time.Now()))
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		if err := s.syncWarrantyReminder(userID, device.ID, device.Name, device.WarrantyDate, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ReminderService) syncWarrantyReminder(userID, deviceID int, deviceName string, warrantyDate time.Time, enabled bool) error {
+	existing, err := s.reminderRepo.GetAutoWarrantyReminder(deviceID)
+	if err != nil {
+		return err
+	}
+
+	if warrantyDate.IsZero() || !enabled {
+		if existing != nil && existing.Status == "pending" {
+			return s.reminderRepo.DeleteReminder(existing.ID, userID)
+		}
+		return nil
+	}
+
+	title := deviceName + warrantyReminderSuffix
+	if utf8.RuneCountInString(title) > 200 {
+		title = string([]rune(title)[:200])
+	}
+	dueDate := truncateDay(warrantyDate)
+
+	if existing != nil {
+		changed := existing.Title != title
+		existing.Title = title
+		if !truncateDay(existing.DueDate).Equal(dueDate) {
+			// 保修日期变更后重新进入待处理状态
+			existing.DueDate = dueDate
+			existing.Status = "pending"
+			changed = true
+		}
+		if !changed {
+			return nil
+		}
+		return s.reminderRepo.UpdateReminder(existing)
+	}
+
+	if dueDate.Before(truncateDay(time.Now())) {
+		return nil
+	}
+	id := deviceID
+	return s.reminderRepo.CreateReminder(&model.Reminder{
+		UserID:       userID,
+		DeviceID:     &id,
+		ReminderType: "warranty",
+		Title:        title,
+		DueDate:      dueDate,
+		LeadDays:     reminderTypes["warranty"].LeadDays,
+		Status:       "pending",
+		Source:       "auto",
+	})
+}
+
+func validateReminderText(title, description string) error {
+	if utf8.RuneCountInString(title) > 200 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "提醒标题不能超过200个字符")
+	}
+	if utf8.RuneCountInString(description) > 500 {
+		return utils.NewBusinessError(utils.ERROR_PARAM, "提醒说明不能超过500个字符")
+	}
+	return nil
+}
+
+// truncateDay 截断到当天零点（本地时区）
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
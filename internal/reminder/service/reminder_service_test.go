@@ -0,0 +1,154 @@
+package service
+
+import (
+	deviceModel "Backend_Lili/internal/device/model"
+	"Backend_Lili/internal/reminder/model"
+	"testing"
+	"time"
+)
+
+// memoryStore 内存中的提醒数据，只实现保修提醒同步用到的方法
+type memoryStore struct {
+	reminderStore
+	enabled   bool
+	reminders map[int]*model.Reminder
+	devices   []*deviceModel.Device
+	nextID    int
+}
+
+func newMemoryStore(enabled bool) *memoryStore {
+	return &memoryStore{enabled: enabled, reminders: map[int]*model.Reminder{}}
+}
+
+func (m *memoryStore) WarrantyReminderEnabled(userID int) (bool, error) {
+	return m.enabled, nil
+}
+
+func (m *memoryStore) GetAutoWarrantyReminder(deviceID int) (*model.Reminder, error) {
+	for _, reminder := range m.reminders {
+		if reminder.DeviceID != nil && *reminder.DeviceID == deviceID && reminder.ReminderType == "warranty" && reminder.Source == "auto" {
+			copied := *reminder
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) CreateReminder(reminder *model.Reminder) error {
+	m.nextID++
+	reminder.ID = m.nextID
+	copied := *reminder
+	m.reminders[reminder.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) UpdateReminder(reminder *model.Reminder) error {
+	copied := *reminder
+	m.reminders[reminder.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) DeleteReminder(reminderID, userID int) error {
+	delete(m.reminders, reminderID)
+	return nil
+}
+
+func (m *memoryStore) DeletePendingAutoWarrantyReminders(userID int) (int64, error) {
+	var deleted int64
+	for id, reminder := range m.reminders {
+		if reminder.UserID == userID && reminder.ReminderType == "warranty" && reminder.Source == "auto" && reminder.Status == "pending" {
+			delete(m.reminders, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *memoryStore) GetWarrantyDevices(userID int, since time.Time) ([]*deviceModel.Device, error) {
+	var devices []*deviceModel.Device
+	for _, device := range m.devices {
+		if device.UserID == userID && !device.WarrantyDate.Before(since) {
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+func TestSyncWarrantyReminder(t *testing.T) {
+	warranty := truncateDay(time.Now()).AddDate(1, 0, 0)
+	tests := []struct {
+		name      string
+		enabled   bool
+		existing  *model.Reminder
+		wantCount int
+	}{
+		{name: "开启时新建提醒", enabled: true, wantCount: 1},
+		{name: "关闭时不新建提醒", enabled: false, wantCount: 0},
+		{name: "关闭时删除未处理的自动提醒", enabled: false, existing: &model.Reminder{Status: "pending"}, wantCount: 0},
+		{name: "关闭时保留已完成的提醒", enabled: false, existing: &model.Reminder{Status: "done"}, wantCount: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore(tt.enabled)
+			if tt.existing != nil {
+				deviceID := 10
+				tt.existing.UserID, tt.existing.DeviceID = 1, &deviceID
+				tt.existing.ReminderType, tt.existing.Source, tt.existing.DueDate = "warranty", "auto", warranty
+				store.CreateReminder(tt.existing)
+			}
+			svc := &ReminderService{reminderRepo: store}
+
+			if err := svc.SyncWarrantyReminder(1, 10, "MacBook Pro", warranty); err != nil {
+				t.Fatalf("同步保修提醒返回错误: %v", err)
+			}
+			if len(store.reminders) != tt.wantCount {
+				t.Fatalf("提醒数量为 %d，期望 %d", len(store.reminders), tt.wantCount)
+			}
+			if tt.enabled {
+				reminder, _ := store.GetAutoWarrantyReminder(10)
+				if reminder.Title != "MacBook Pro"+warrantyReminderSuffix || !reminder.DueDate.Equal(warranty) || reminder.LeadDays != 30 {
+					t.Errorf("自动提醒为 %s/%s/%d", reminder.Title, reminder.DueDate.Format("2006-01-02"), reminder.LeadDays)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyWarrantyReminderPreference(t *testing.T) {
+	today := truncateDay(time.Now())
+	store := newMemoryStore(false)
+	store.devices = []*deviceModel.Device{
+		{ID: 1, UserID: 1, Name: "在保设备", WarrantyDate: today.AddDate(0, 6, 0)},
+		{ID: 2, UserID: 1, Name: "已过保设备", WarrantyDate: today.AddDate(0, -1, 0)},
+		{ID: 3, UserID: 2, Name: "其他用户的设备", WarrantyDate: today.AddDate(0, 6, 0)},
+	}
+	svc := &ReminderService{reminderRepo: store}
+
+	// 开启后为保修期内的设备补建提醒
+	store.enabled = true
+	if err := svc.ApplyWarrantyReminderPreference(1, true); err != nil {
+		t.Fatalf("开启保修提醒返回错误: %v", err)
+	}
+	if len(store.reminders) != 1 {
+		t.Fatalf("开启后提醒数量为 %d，期望 1", len(store.reminders))
+	}
+	if reminder, _ := store.GetAutoWarrantyReminder(1); reminder == nil {
+		t.Fatal("在保设备没有自动提醒")
+	}
+
+	// 关闭后删除未处理的自动提醒，手动提醒不受影响
+	deviceID := 1
+	store.CreateReminder(&model.Reminder{UserID: 1, DeviceID: &deviceID, ReminderType: "warranty", Source: "manual", Status: "pending"})
+	store.enabled = false
+	if err := svc.ApplyWarrantyReminderPreference(1, false); err != nil {
+		t.Fatalf("关闭保修提醒返回错误: %v", err)
+	}
+	if len(store.reminders) != 1 {
+		t.Fatalf("关闭后提醒数量为 %d，期望只剩手动提醒", len(store.reminders))
+	}
+	for _, reminder := range store.reminders {
+		if reminder.Source != "manual" {
+			t.Errorf("关闭后仍有自动提醒: %+v", reminder)
+		}
+	}
+}
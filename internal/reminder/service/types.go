@@ -0,0 +1,60 @@
+package service
+
+import (
+	"Backend_Lili/internal/reminder/model"
+)
+
+// 提醒列表请求
+type GetRemindersRequest struct {
+	Page         int    `json:"page" form:"page"`
+	Limit        int    `json:"limit" form:"limit"`
+	Status       string `json:"status" form:"status"`               // pending/done/dismissed
+	ReminderType string `json:"reminder_type" form:"reminder_type"` // warranty/battery/filter/custom
+	DeviceID     int    `json:"device_id" form:"device_id"`
+}
+
+// 提醒列表响应
+type GetRemindersResponse struct {
+	Reminders  []*model.Reminder `json:"reminders"`
+	Total      int               `json:"total"`
+	Page       int               `json:"page"`
+	Limit      int               `json:"limit"`
+	TotalPages int               `json:"total_pages"`
+}
+
+// 创建提醒请求
+type CreateReminderRequest struct {
+	DeviceID       *int   `json:"device_id"`
+	ReminderType   string `json:"reminder_type"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	DueDate        string `json:"due_date"`        // YYYY-MM-DD格式
+	RecurrenceRule string `json:"recurrence_rule"` // 如 FREQ=MONTHLY;INTERVAL=6
+	LeadDays       *int   `json:"lead_days"`
+}
+
+// 更新提醒请求
+type UpdateReminderRequest struct {
+	Title          *string `json:"title"`
+	Description    *string `json:"description"`
+	DueDate        string  `json:"due_date"`
+	RecurrenceRule *string `json:"recurrence_rule"`
+	LeadDays       *int    `json:"lead_days"`
+	Status         string  `json:"status"` // pending/done/dismissed
+}
+
+// 即将到期的提醒
+type UpcomingReminder struct {
+	*model.Reminder
+	DeviceName string `json:"device_name,omitempty"`
+	DaysLeft   int    `json:"days_left"`    // 距截止日期天数，负数表示已逾期
+	Overdue    bool   `json:"overdue"`      // 是否已逾期
+	InLeadTime bool   `json:"in_lead_time"` // 是否已进入提前提醒期
+}
+
+// 即将到期提醒响应
+type GetUpcomingRemindersResponse struct {
+	Reminders []*UpcomingReminder `json:"reminders"`
+	Days      int                 `json:"days"`
+	Total     int                 `json:"total"`
+}
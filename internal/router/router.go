@@ -5,6 +5,7 @@ import (
 	"Backend_Lili/internal/auth/middleware"
 	deviceCtrl "Backend_Lili/internal/device/controller"
 	priceRouter "Backend_Lili/internal/price/router"
+	reminderRouter "Backend_Lili/internal/reminder/router"
     statsRouter "Backend_Lili/internal/statistics/router"
     tagsRouter "Backend_Lili/internal/tags/router"
 	userCtrl "Backend_Lili/internal/user/controller"
@@ -28,6 +29,10 @@ func Init() {
 	deviceController := deviceCtrl.NewDeviceController()
 	categoryController := deviceCtrl.NewCategoryController()
 	templateController := deviceCtrl.NewTemplateController()
+	attachmentController := deviceCtrl.NewAttachmentController()
+	serviceRecordController := deviceCtrl.NewServiceRecordController()
+	costController := deviceCtrl.NewCostController()
+	draftController := deviceCtrl.NewDraftController()
 
 	// 创建API命名空间
 	ns := beego.NewNamespace("/api/v1",
@@ -74,16 +79,72 @@ func Init() {
 
 			// 设备价值评估
 			beego.NSRouter("/:deviceId/valuation", deviceController, "get:GetDeviceValuation"),
+			beego.NSRouter("/:deviceId/timeline", deviceController, "get:GetDeviceTimeline"),
+			beego.NSRouter("/:deviceId/timeline/restore", deviceController, "post:RestoreDeviceField"),
+
+			// 重复设备检测与合并
+			beego.NSRouter("/duplicates", deviceController, "get:ScanDuplicates"),
+			beego.NSRouter("/:deviceId/merge", deviceController, "post:MergeDevices"),
 
 			// 设备价格预测
 			beego.NSRouter("/:deviceId/prediction", deviceController, "get:PredictDevicePrice"),
 
 			// 批量导入设备
 			beego.NSRouter("/import", deviceController, "post:BatchImportDevices"),
+			beego.NSRouter("/import/file", deviceController, "post:ImportDevicesFile"),
+
+			// 规格参数索引
+			beego.NSRouter("/specs/reindex", deviceController, "post:ReindexDeviceSpecs"),
+
+			// 回收站
+			beego.NSRouter("/recycle-bin", deviceController, "get:GetRecycleBin"),
+			beego.NSRouter("/recycle-bin/:deviceId", deviceController, "delete:PurgeDeletedDevice"),
+			beego.NSRouter("/recycle-bin/:deviceId/restore", deviceController, "post:RestoreDeletedDevice"),
+
+			// 批量操作设备
+			beego.NSRouter("/bulk", deviceController, "post:BulkDevices"),
+
+			// 按分类规则自动分类
+			beego.NSRouter("/categorize", deviceController, "post:CategorizeDevices"),
+			beego.NSRouter("/categorize/suggest", deviceController, "post:SuggestCategories"),
+
+			// 导出设备
+			beego.NSRouter("/export", deviceController, "get:ExportDevices"),
+			beego.NSRouter("/export/columns", deviceController, "get:GetExportColumns"),
+
+			// 二维码标签与扫码查找
+			beego.NSRouter("/labels", deviceController, "post:GetDeviceLabels"),
+			beego.NSRouter("/scan/:token", deviceController, "get:ScanDeviceQRCode"),
+			beego.NSRouter("/:deviceId/qrcode", deviceController, "get:GetDeviceQRCode"),
+			beego.NSRouter("/:deviceId/qrcode/image", deviceController, "get:GetDeviceQRCodeImage"),
+			beego.NSRouter("/:deviceId/qrcode/reset", deviceController, "post:ResetDeviceQRCode"),
+
+			// 电商订单导入与设备草稿
+			beego.NSRouter("/drafts", draftController, "get:GetDrafts"),
+			beego.NSRouter("/drafts/import", draftController, "post:ImportOrders"),
+			beego.NSRouter("/drafts/:draftId", draftController, "put:UpdateDraft;delete:DeleteDraft"),
+			beego.NSRouter("/drafts/:draftId/accept", draftController, "post:AcceptDraft"),
+			beego.NSRouter("/drafts/:draftId/reject", draftController, "post:RejectDraft"),
 
 			// 设备图片管理
 			beego.NSRouter("/:deviceId/images", deviceController, "get:GetDeviceImages;post:UploadDeviceImage"),
-			beego.NSRouter("/:deviceId/images/:imageId", deviceController, "delete:DeleteDeviceImage"),
+			beego.NSRouter("/:deviceId/images/batch", deviceController, "post:UploadDeviceImages"),
+			beego.NSRouter("/:deviceId/images/order", deviceController, "put:ReorderDeviceImages"),
+			beego.NSRouter("/:deviceId/images/:imageId", deviceController, "patch:UpdateDeviceImage;delete:DeleteDeviceImage"),
+			beego.NSRouter("/:deviceId/images/:imageId/cover", deviceController, "put:SetDeviceCoverImage"),
+			beego.NSRouter("/:deviceId/images/:imageId/file", deviceController, "get:DownloadDeviceImage"),
+			beego.NSRouter("/:deviceId/images/:imageId/url", deviceController, "get:GetDeviceImageURL"),
+
+			// 设备附件管理（小票、发票、保修卡、说明书）
+			beego.NSRouter("/:deviceId/attachments", attachmentController, "get:GetDeviceAttachments;post:UploadAttachment"),
+			beego.NSRouter("/:deviceId/attachments/:attachmentId", attachmentController, "delete:DeleteAttachment"),
+			beego.NSRouter("/:deviceId/attachments/:attachmentId/file", attachmentController, "get:DownloadAttachment"),
+			beego.NSRouter("/:deviceId/service-records", serviceRecordController, "get:GetServiceRecords;post:CreateServiceRecord"),
+			beego.NSRouter("/:deviceId/service-records/:recordId", serviceRecordController, "get:GetServiceRecord;put:UpdateServiceRecord;delete:DeleteServiceRecord"),
+			beego.NSRouter("/:deviceId/costs", costController, "get:GetDeviceCosts;post:CreateDeviceCost"),
+			beego.NSRouter("/:deviceId/costs/:costId", costController, "put:UpdateDeviceCost;delete:DeleteDeviceCost"),
+			beego.NSRouter("/:deviceId/usage", costController, "get:GetUsageLogs;post:CreateUsageLog"),
+			beego.NSRouter("/:deviceId/usage/:usageId", costController, "delete:DeleteUsageLog"),
 		),
 
 		// 设备模板相关路由 - 需要JWT认证
@@ -94,6 +155,9 @@ func Init() {
 			beego.NSRouter("/popular", templateController, "get:GetPopularTemplates"),
 			// 获取推荐模板
 			beego.NSRouter("/recommendations", templateController, "get:GetRecommendedTemplates"),
+			// 模板导入与审核列表
+			beego.NSRouter("/import", templateController, "post:ImportTemplate"),
+			beego.NSRouter("/reviews", templateController, "get:GetReviewTemplates"),
 
 			// 基本CRUD操作
 			beego.NSRouter("/", templateController, "get:GetTemplatesList;post:CreateTemplate"),
@@ -107,6 +171,19 @@ func Init() {
 
 			// 模板统计
 			beego.NSRouter("/:template_id/statistics", templateController, "get:GetTemplateStatistics"),
+
+			// 字段版本与设备迁移
+			beego.NSRouter("/:template_id/versions", templateController, "get:GetTemplateVersions"),
+			beego.NSRouter("/:template_id/versions/:version", templateController, "get:GetTemplateVersion"),
+			beego.NSRouter("/:template_id/diff", templateController, "get:DiffTemplateVersions"),
+			beego.NSRouter("/:template_id/migrate", templateController, "post:MigrateTemplateDevices"),
+
+			// 共享、复制、评分、导出与审核
+			beego.NSRouter("/:template_id/visibility", templateController, "put:SetTemplateVisibility"),
+			beego.NSRouter("/:template_id/fork", templateController, "post:ForkTemplate"),
+			beego.NSRouter("/:template_id/ratings", templateController, "get:GetTemplateRatings;post:RateTemplate"),
+			beego.NSRouter("/:template_id/export", templateController, "get:ExportTemplate"),
+			beego.NSRouter("/:template_id/review", templateController, "post:ReviewTemplate"),
 		),
 
 		// 设备分类相关路由 - 需要JWT认证
@@ -119,10 +196,16 @@ func Init() {
 			beego.NSRouter("/sort", categoryController, "put:SortCategories"),
 			beego.NSRouter("/statistics", categoryController, "get:GetCategoryStatistics"),
 			beego.NSRouter("/search", categoryController, "get:SearchCategories"),
+			beego.NSRouter("/preferences", categoryController, "delete:ResetCategoryPreferences"),
+			beego.NSRouter("/rules", categoryController, "get:GetCategoryRules;post:CreateCategoryRule"),
+			beego.NSRouter("/rules/:rule_id", categoryController, "put:UpdateCategoryRule;delete:DeleteCategoryRule"),
 
 			// 基本CRUD操作
 			beego.NSRouter("/", categoryController, "get:GetCategoriesList;post:CreateCustomCategory"),
 			beego.NSRouter("/:category_id", categoryController, "get:GetCategoryDetail;put:UpdateCustomCategory;delete:DeleteCustomCategory"),
+			beego.NSRouter("/:category_id/move", categoryController, "post:MoveCategory"),
+			beego.NSRouter("/:category_id/merge", categoryController, "post:MergeCategory"),
+			beego.NSRouter("/:category_id/hidden", categoryController, "put:SetCategoryHidden"),
 		),
 	)
 
@@ -135,6 +218,8 @@ func Init() {
     statsRouter.InitStatisticsRoutes()
     // 初始化标签模块路由
     tagsRouter.InitTagsRoutes()
+	// 初始化提醒模块路由
+	reminderRouter.InitReminderRoutes()
 
 	// 健康检查路由
 	beego.Router("/health", authController, "get:Health")
@@ -29,7 +29,9 @@ func (c *StatisticsController) Prepare() {
 func (c *StatisticsController) GetDashboard() {
     claims, err := c.GetCurrentUser()
     if err != nil { c.WriteError(utils.ERROR_AUTH, "认证失败"); return }
-    resp, err := c.svc.GetDashboard(claims.UserID)
+    var req service.DashboardRequest
+    if err := c.ParseForm(&req); err != nil { c.WriteError(utils.ERROR_PARAM, "参数解析失败"); return }
+    resp, err := c.svc.GetDashboard(claims.UserID, &req)
     if err != nil { utils.HandleBusinessError(c.Ctx, err); return }
     c.WriteJSON(resp)
 }
@@ -133,6 +135,17 @@ func (c *StatisticsController) GetInvestmentReturn() {
     c.WriteJSON(resp)
 }
 
+// GET /statistics/ownership-cost
+func (c *StatisticsController) GetOwnershipCostRanking() {
+    claims, err := c.GetCurrentUser()
+    if err != nil { c.WriteError(utils.ERROR_AUTH, "认证失败"); return }
+    var req service.OwnershipCostRankingRequest
+    if err := c.ParseForm(&req); err != nil { c.WriteError(utils.ERROR_PARAM, "参数解析失败"); return }
+    resp, err := c.svc.GetOwnershipCostRanking(claims.UserID, &req)
+    if err != nil { utils.HandleBusinessError(c.Ctx, err); return }
+    c.WriteJSON(resp)
+}
+
 // POST /statistics/custom
 func (c *StatisticsController) PostCustomStatistics() {
     claims, err := c.GetCurrentUser()
@@ -4,6 +4,7 @@ import (
     "fmt"
     "time"
 
+    reminderRepository "Backend_Lili/internal/reminder/repository"
     "github.com/beego/beego/v2/client/orm"
 )
 
@@ -11,9 +12,24 @@ type StatisticsRepository struct{}
 
 func NewStatisticsRepository() *StatisticsRepository { return &StatisticsRepository{} }
 
-func (r *StatisticsRepository) GetDeviceCount(userID int) (int, error) {
+// 计数口径：item 每件设备单独计数，kit 只统计主设备（配件随套装计为一件）
+const (
+    CountModeItem = "item"
+    CountModeKit  = "kit"
+)
+
+// countModeCond 按计数口径追加的设备过滤条件，alias 为 devices 表别名
+func countModeCond(countMode, alias string) string {
+    if countMode != CountModeKit { return "" }
+    if alias != "" { alias += "." }
+    return " AND " + alias + "parent_id IS NULL"
+}
+
+func (r *StatisticsRepository) GetDeviceCount(userID int, countMode string) (int, error) {
     o := orm.NewOrm()
-    cnt, err := o.QueryTable("devices").Filter("user_id", userID).Filter("deleted_at__isnull", true).Count()
+    qs := o.QueryTable("devices").Filter("user_id", userID).Filter("deleted_at__isnull", true)
+    if countMode == CountModeKit { qs = qs.Filter("parent_id__isnull", true) }
+    cnt, err := qs.Count()
     return int(cnt), err
 }
 
@@ -25,13 +41,13 @@ func (r *StatisticsRepository) GetDevicesTotalValue(userID int) (float64, error)
     return row.Total, err
 }
 
-func (r *StatisticsRepository) GetDeviceCountByCategory(userID int) (map[string]int, error) {
+func (r *StatisticsRepository) GetDeviceCountByCategory(userID int, countMode string) (map[string]int, error) {
     o := orm.NewOrm()
     type Row struct{ Name string; Count int }
     var rows []Row
     _, err := o.Raw(`SELECT COALESCE(c.name,'未分类') as name, COUNT(d.id) as count
         FROM categories c
-        LEFT JOIN devices d ON c.id = d.category_id AND d.user_id = ? AND d.deleted_at IS NULL
+        LEFT JOIN devices d ON c.id = d.category_id AND d.user_id = ? AND d.deleted_at IS NULL`+countModeCond(countMode, "d")+`
         GROUP BY c.id, c.name`, userID).QueryRows(&rows)
     if err != nil { return nil, err }
     m := map[string]int{}
@@ -39,9 +55,14 @@ func (r *StatisticsRepository) GetDeviceCountByCategory(userID int) (map[string]
     return m, nil
 }
 
+// GetUpcomingRemindersCount 统计即将到期的待处理提醒数量，与即将到期列表的默认窗口一致
 func (r *StatisticsRepository) GetUpcomingRemindersCount(userID int) (int, error) {
-    // 预留：如有提醒表，可在此统计；当前返回0
-    return 0, nil
+    type Row struct{ Total int }
+    var row Row
+    o := orm.NewOrm()
+    from, args := reminderRepository.UpcomingRemindersFrom(userID, reminderRepository.DefaultUpcomingDays)
+    err := o.Raw("SELECT COUNT(*) AS total "+from, args...).QueryRow(&row)
+    return row.Total, err
 }
 
 func (r *StatisticsRepository) GetActivePriceAlertsCount(userID int) (int, error) {
@@ -50,13 +71,30 @@ func (r *StatisticsRepository) GetActivePriceAlertsCount(userID int) (int, error
     return int(cnt), err
 }
 
-func (r *StatisticsRepository) GetDevicesStatistics(userID int, period, groupBy string) (map[string]int, []map[string]interface{}, error) {
+func (r *StatisticsRepository) GetDevicesStatistics(userID int, period, groupBy, countMode string) (map[string]int, []map[string]interface{}, error) {
     // 简化：返回当前分布与空趋势
-    cats, err := r.GetDeviceCountByCategory(userID)
+    cats, err := r.GetDeviceCountByCategory(userID, countMode)
     if err != nil { return nil, nil, err }
     return cats, []map[string]interface{}{}, nil
 }
 
+// GetDeviceCountBySpec 按规格参数字段的值统计设备数；带单位的数值与金额按换算后的数量分组（如 1TB 与 1024GB 为同一组），
+// 标签取组内的原文本，多选字段每个选项各计一次
+func (r *StatisticsRepository) GetDeviceCountBySpec(userID int, field, countMode string) (map[string]int, error) {
+    o := orm.NewOrm()
+    type Row struct{ Label string; Count int }
+    var rows []Row
+    _, err := o.Raw(`SELECT MIN(v.value_text) AS label, COUNT(DISTINCT v.device_id) AS count
+        FROM device_spec_values v
+        JOIN devices d ON d.id = v.device_id AND d.deleted_at IS NULL`+countModeCond(countMode, "d")+`
+        WHERE v.user_id = ? AND v.field_name = ?
+        GROUP BY CASE WHEN v.dimension <> '' AND v.value_num IS NOT NULL THEN CONCAT(v.dimension, ':', v.value_num) ELSE v.value_text END`, userID, field).QueryRows(&rows)
+    if err != nil { return nil, err }
+    m := map[string]int{}
+    for _, r := range rows { m[r.Label] += r.Count }
+    return m, nil
+}
+
 func (r *StatisticsRepository) GetTotalValueTrend(userID int, period string) ([]map[string]interface{}, error) {
     // 简化：近6期的静态趋势
     trend := make([]map[string]interface{}, 0)
@@ -93,7 +131,8 @@ func (r *StatisticsRepository) GetPriceTrends(userID int, period string, deviceI
     return []map[string]interface{}{}, nil
 }
 
-func (r *StatisticsRepository) GetBrandsStatistics(userID, categoryID int) (map[string]int, map[string]float64, error) {
+// GetBrandsStatistics 品牌分布；kit 口径下数量只计主设备，价值仍按每件设备统计
+func (r *StatisticsRepository) GetBrandsStatistics(userID, categoryID int, countMode string) (map[string]int, map[string]float64, error) {
     o := orm.NewOrm()
     cond := "WHERE d.user_id = ? AND d.deleted_at IS NULL"
     args := []interface{}{userID}
@@ -103,7 +142,7 @@ func (r *StatisticsRepository) GetBrandsStatistics(userID, categoryID int) (map[
     type V struct{ Brand string; Total float64 }
     var cc []C
     var vv []V
-    _, err := o.Raw(fmt.Sprintf("SELECT COALESCE(d.brand,'未知') as brand, COUNT(1) as cnt FROM devices d %s GROUP BY d.brand", cond), args...).QueryRows(&cc)
+    _, err := o.Raw(fmt.Sprintf("SELECT COALESCE(d.brand,'未知') as brand, COUNT(1) as cnt FROM devices d %s%s GROUP BY d.brand", cond, countModeCond(countMode, "d")), args...).QueryRows(&cc)
     if err != nil { return nil, nil, err }
     _, err = o.Raw(fmt.Sprintf("SELECT COALESCE(d.brand,'未知') as brand, COALESCE(SUM(d.purchase_price),0) as total FROM devices d %s GROUP BY d.brand", cond), args...).QueryRows(&vv)
     if err != nil { return nil, nil, err }
@@ -115,9 +154,9 @@ func (r *StatisticsRepository) GetBrandsStatistics(userID, categoryID int) (map[
     return counts, values, nil
 }
 
-func (r *StatisticsRepository) GetDeviceAgeStatistics(userID, categoryID int) (map[string]int, int, error) {
+func (r *StatisticsRepository) GetDeviceAgeStatistics(userID, categoryID int, countMode string) (map[string]int, int, error) {
     o := orm.NewOrm()
-    cond := "WHERE user_id = ? AND deleted_at IS NULL AND purchase_date IS NOT NULL"
+    cond := "WHERE user_id = ? AND deleted_at IS NULL AND purchase_date IS NOT NULL" + countModeCond(countMode, "")
     args := []interface{}{userID}
     if categoryID > 0 { cond += " AND category_id = ?"; args = append(args, categoryID) }
     type Row struct{ Days int }
@@ -142,13 +181,52 @@ func (r *StatisticsRepository) GetDepreciationStatistics(userID, categoryID int,
     return map[string]float64{}, []map[string]interface{}{}, nil
 }
 
+// spendingGroupFormats 支出趋势分组对应的日期格式
+var spendingGroupFormats = map[string]string{
+    "day":   "%Y-%m-%d",
+    "week":  "%x-W%v",
+    "month": "%Y-%m",
+    "year":  "%Y",
+}
+
+// spendingPeriodStart 根据统计周期计算起始日期，all 表示不限
+func spendingPeriodStart(period string) time.Time {
+    now := time.Now()
+    switch period {
+    case "month":
+        return now.AddDate(0, -1, 0)
+    case "quarter":
+        return now.AddDate(0, -3, 0)
+    case "year":
+        return now.AddDate(-1, 0, 0)
+    }
+    return time.Date(1970, 1, 1, 0, 0, 0, 0, time.Local)
+}
+
+// GetSpendingTrend 按购买日期统计设备购入支出、按服务日期统计自付维修保养支出（保修范围内的不计）
 func (r *StatisticsRepository) GetSpendingTrend(userID int, period, groupBy string) ([]map[string]interface{}, error) {
-    // 简化：返回近12期月份的静态趋势
-    trend := make([]map[string]interface{}, 0)
-    for i := 11; i >= 0; i-- {
+    format, ok := spendingGroupFormats[groupBy]
+    if !ok { return nil, fmt.Errorf("unsupported group_by: %s", groupBy) }
+    start := spendingPeriodStart(period).Format("2006-01-02")
+    o := orm.NewOrm()
+    type Row struct{ Period string; PurchaseAmount float64; MaintenanceAmount float64 }
+    var rows []Row
+    _, err := o.Raw(`SELECT t.period, SUM(t.purchase_amount) AS purchase_amount, SUM(t.maintenance_amount) AS maintenance_amount FROM (
+            SELECT DATE_FORMAT(d.purchase_date, ?) AS period, d.purchase_price AS purchase_amount, 0 AS maintenance_amount
+            FROM devices d WHERE d.user_id = ? AND d.deleted_at IS NULL AND d.purchase_date >= ?
+            UNION ALL
+            SELECT DATE_FORMAT(s.service_date, ?) AS period, 0 AS purchase_amount, s.cost AS maintenance_amount
+            FROM device_service_records s JOIN devices d ON d.id = s.device_id
+            WHERE s.user_id = ? AND d.deleted_at IS NULL AND s.warranty_covered = 0 AND s.service_date >= ?
+        ) t GROUP BY t.period ORDER BY t.period`, format, userID, start, format, userID, start).QueryRows(&rows)
+    if err != nil { return nil, err }
+    trend := make([]map[string]interface{}, 0, len(rows))
+    for _, row := range rows {
         trend = append(trend, map[string]interface{}{
-            "period": time.Now().AddDate(0, -i, 0).Format("2006-01"),
-            "amount": float64(500 + i*20),
+            "period":             row.Period,
+            "amount":             row.PurchaseAmount + row.MaintenanceAmount,
+            "purchase_amount":    row.PurchaseAmount,
+            "maintenance_amount": row.MaintenanceAmount,
         })
     }
     return trend, nil
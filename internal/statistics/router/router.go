@@ -25,6 +25,7 @@ func InitStatisticsRoutes() {
             web.NSRouter("/spending", statsController, "get:GetSpendingStatistics"),
             web.NSRouter("/heatmap", statsController, "get:GetHeatmap"),
             web.NSRouter("/investment-return", statsController, "get:GetInvestmentReturn"),
+            web.NSRouter("/ownership-cost", statsController, "get:GetOwnershipCostRanking"),
             web.NSRouter("/custom", statsController, "post:PostCustomStatistics"),
             web.NSRouter("/export", statsController, "post:PostExportReport"),
             web.NSRouter("/insights", statsController, "get:GetInsights"),
@@ -3,21 +3,37 @@ package service
 import (
     "strconv"
     "strings"
-    "time"
 
+    deviceService "Backend_Lili/internal/device/service"
     "Backend_Lili/internal/statistics/repository"
     "Backend_Lili/pkg/utils"
 )
 
-type StatisticsService struct { repo *repository.StatisticsRepository }
+type StatisticsService struct {
+    repo      *repository.StatisticsRepository
+    deviceSvc *deviceService.DeviceService
+}
 
-func NewStatisticsService() *StatisticsService { return &StatisticsService{repo: repository.NewStatisticsRepository()} }
+func NewStatisticsService() *StatisticsService {
+    return &StatisticsService{repo: repository.NewStatisticsRepository(), deviceSvc: deviceService.NewDeviceService()}
+}
 
-func (s *StatisticsService) GetDashboard(userID int) (*DashboardResponse, error) {
+// normalizeCountMode 校验计数口径，默认按单件计数
+func normalizeCountMode(mode string) (string, error) {
+    switch mode {
+    case "": return repository.CountModeItem, nil
+    case repository.CountModeItem, repository.CountModeKit: return mode, nil
+    }
+    return "", utils.NewBusinessError(utils.ERROR_PARAM, "count_mode 仅支持 item 或 kit")
+}
+
+func (s *StatisticsService) GetDashboard(userID int, req *DashboardRequest) (*DashboardResponse, error) {
     if userID <= 0 { return nil, utils.NewBusinessError(utils.ERROR_AUTH, "认证失败") }
-    deviceCount, _ := s.repo.GetDeviceCount(userID)
+    countMode, err := normalizeCountMode(req.CountMode)
+    if err != nil { return nil, err }
+    deviceCount, _ := s.repo.GetDeviceCount(userID, countMode)
     totalValue, _ := s.repo.GetDevicesTotalValue(userID)
-    categories, _ := s.repo.GetDeviceCountByCategory(userID)
+    categories, _ := s.repo.GetDeviceCountByCategory(userID, countMode)
     reminders, _ := s.repo.GetUpcomingRemindersCount(userID)
     alerts, _ := s.repo.GetActivePriceAlertsCount(userID)
     return &DashboardResponse{
@@ -33,7 +49,16 @@ func (s *StatisticsService) GetDevicesStatistics(userID int, req *DevicesStatist
     if userID <= 0 { return nil, utils.NewBusinessError(utils.ERROR_AUTH, "认证失败") }
     if req.Period == "" { req.Period = "month" }
     if req.GroupBy == "" { req.GroupBy = "category" }
-    series, trend, err := s.repo.GetDevicesStatistics(userID, req.Period, req.GroupBy)
+    countMode, err := normalizeCountMode(req.CountMode)
+    if err != nil { return nil, err }
+    if req.GroupBy == "spec" {
+        field := strings.TrimSpace(req.Field)
+        if field == "" { return nil, utils.NewBusinessError(utils.ERROR_PARAM, "按规格分组时 field 必填") }
+        series, err := s.repo.GetDeviceCountBySpec(userID, field, countMode)
+        if err != nil { return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取统计失败") }
+        return &DevicesStatisticsResponse{ Period: req.Period, GroupBy: req.GroupBy, Field: field, Series: series, Trend: []map[string]interface{}{} }, nil
+    }
+    series, trend, err := s.repo.GetDevicesStatistics(userID, req.Period, req.GroupBy, countMode)
     if err != nil { return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取统计失败") }
     return &DevicesStatisticsResponse{ Period: req.Period, GroupBy: req.GroupBy, Series: series, Trend: trend }, nil
 }
@@ -63,14 +88,18 @@ func (s *StatisticsService) GetPriceTrends(userID int, req *PriceTrendsRequest)
 
 func (s *StatisticsService) GetBrandsStatistics(userID int, req *BrandsStatisticsRequest) (*BrandsStatisticsResponse, error) {
     if userID <= 0 { return nil, utils.NewBusinessError(utils.ERROR_AUTH, "认证失败") }
-    counts, values, err := s.repo.GetBrandsStatistics(userID, req.CategoryID)
+    countMode, err := normalizeCountMode(req.CountMode)
+    if err != nil { return nil, err }
+    counts, values, err := s.repo.GetBrandsStatistics(userID, req.CategoryID, countMode)
     if err != nil { return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取品牌统计失败") }
     return &BrandsStatisticsResponse{ BrandCounts: counts, BrandValues: values }, nil
 }
 
 func (s *StatisticsService) GetDeviceAgeStatistics(userID int, req *DeviceAgeStatisticsRequest) (*DeviceAgeStatisticsResponse, error) {
     if userID <= 0 { return nil, utils.NewBusinessError(utils.ERROR_AUTH, "认证失败") }
-    buckets, avgDays, err := s.repo.GetDeviceAgeStatistics(userID, req.CategoryID)
+    countMode, err := normalizeCountMode(req.CountMode)
+    if err != nil { return nil, err }
+    buckets, avgDays, err := s.repo.GetDeviceAgeStatistics(userID, req.CategoryID, countMode)
     if err != nil { return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取使用年限统计失败") }
     return &DeviceAgeStatisticsResponse{ Buckets: buckets, AvgDays: avgDays }, nil
 }
@@ -87,9 +116,17 @@ func (s *StatisticsService) GetSpendingStatistics(userID int, req *SpendingStati
     if userID <= 0 { return nil, utils.NewBusinessError(utils.ERROR_AUTH, "认证失败") }
     if req.Period == "" { req.Period = "year" }
     if req.GroupBy == "" { req.GroupBy = "month" }
+    switch req.Period { case "month", "quarter", "year", "all": default: return nil, utils.NewBusinessError(utils.ERROR_PARAM, "period 仅支持 month/quarter/year/all") }
+    switch req.GroupBy { case "day", "week", "month", "year": default: return nil, utils.NewBusinessError(utils.ERROR_PARAM, "group_by 仅支持 day/week/month/year") }
     trend, err := s.repo.GetSpendingTrend(userID, req.Period, req.GroupBy)
     if err != nil { return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "获取支出统计失败") }
-    return &SpendingStatisticsResponse{ Period: req.Period, GroupBy: req.GroupBy, Trend: trend }, nil
+    resp := &SpendingStatisticsResponse{ Period: req.Period, GroupBy: req.GroupBy, Trend: trend }
+    for _, item := range trend {
+        resp.PurchaseTotal += item["purchase_amount"].(float64)
+        resp.MaintenanceTotal += item["maintenance_amount"].(float64)
+    }
+    resp.Total = resp.PurchaseTotal + resp.MaintenanceTotal
+    return resp, nil
 }
 
 func (s *StatisticsService) GetHeatmap(userID int, req *HeatmapRequest) (*HeatmapResponse, error) {
@@ -100,6 +137,16 @@ func (s *StatisticsService) GetHeatmap(userID int, req *HeatmapRequest) (*Heatma
     return &HeatmapResponse{ Type: req.Type, Data: data }, nil
 }
 
+// GetOwnershipCostRanking 持有成本排行（日均成本最高的设备）
+func (s *StatisticsService) GetOwnershipCostRanking(userID int, req *OwnershipCostRankingRequest) (*OwnershipCostRankingResponse, error) {
+    if userID <= 0 { return nil, utils.NewBusinessError(utils.ERROR_AUTH, "认证失败") }
+    includeSold := true
+    if req.IncludeSold != nil { includeSold = *req.IncludeSold }
+    items, err := s.deviceSvc.GetOwnershipCostRanking(userID, includeSold, req.Limit)
+    if err != nil { return nil, err }
+    return &OwnershipCostRankingResponse{ Items: items }, nil
+}
+
 func (s *StatisticsService) GetInvestmentReturn(userID int, req *InvestmentReturnRequest) (*InvestmentReturnResponse, error) {
     if userID <= 0 { return nil, utils.NewBusinessError(utils.ERROR_AUTH, "认证失败") }
     includeSold := true
@@ -119,9 +166,15 @@ func (s *StatisticsService) PostCustomStatistics(userID int, req *CustomStatisti
 func (s *StatisticsService) PostExportReport(userID int, req *ExportReportRequest) (*ExportReportResponse, error) {
     if userID <= 0 { return nil, utils.NewBusinessError(utils.ERROR_AUTH, "认证失败") }
     if req.ReportType == "" || req.Format == "" { return nil, utils.NewBusinessError(utils.ERROR_PARAM, "report_type/format 必填") }
-    // 简化：生成一个临时下载链接
-    name := "stats_" + strconv.FormatInt(time.Now().Unix(), 10) + "." + strings.ToLower(req.Format)
-    return &ExportReportResponse{ DownloadURL: "/downloads/" + name }, nil
+    // 目前仅支持设备清单，下载地址指向设备导出接口，由其流式生成文件
+    if req.ReportType != "devices" { return nil, utils.NewBusinessError(utils.ERROR_PARAM, "report_type 仅支持 devices") }
+    format := strings.ToLower(req.Format)
+    switch format {
+    case "csv", "xlsx", deviceService.ExportFormatJSONL:
+    default:
+        return nil, utils.NewBusinessError(utils.ERROR_PARAM, "format 仅支持 csv、xlsx、jsonl")
+    }
+    return &ExportReportResponse{ DownloadURL: "/api/v1/devices/export?format=" + format }, nil
 }
 
 func (s *StatisticsService) GetInsights(userID int) (*InsightsResponse, error) {
@@ -1,6 +1,11 @@
 package service
 
+import deviceService "Backend_Lili/internal/device/service"
+
 // 通用周期：month/quarter/year，或7d/30d/90d/180d/1y
+// 计数口径 count_mode：item 每件设备单独计数（默认），kit 套装整体计为一件
+
+type DashboardRequest struct { CountMode string `form:"count_mode"` }
 
 type DashboardResponse struct {
     DeviceCount       int                    `json:"device_count"`
@@ -11,12 +16,15 @@ type DashboardResponse struct {
 }
 
 type DevicesStatisticsRequest struct {
-    Period  string `form:"period"`
-    GroupBy string `form:"group_by"`
+    Period    string `form:"period"`
+    GroupBy   string `form:"group_by"` // category（默认）或 spec
+    Field     string `form:"field"`    // group_by=spec 时的规格字段名，字段组子字段写作 组名.子字段名
+    CountMode string `form:"count_mode"`
 }
 type DevicesStatisticsResponse struct {
     Period   string                   `json:"period"`
     GroupBy  string                   `json:"group_by"`
+    Field    string                   `json:"field,omitempty"`
     Series   map[string]int           `json:"series"`
     Trend    []map[string]interface{} `json:"trend"`
 }
@@ -37,13 +45,19 @@ type PriceTrendsResponse struct {
     Items  []map[string]interface{}   `json:"items"`
 }
 
-type BrandsStatisticsRequest struct { CategoryID int `form:"category_id"` }
+type BrandsStatisticsRequest struct {
+    CategoryID int    `form:"category_id"`
+    CountMode  string `form:"count_mode"`
+}
 type BrandsStatisticsResponse struct {
     BrandCounts map[string]int     `json:"brand_counts"`
     BrandValues map[string]float64 `json:"brand_values"`
 }
 
-type DeviceAgeStatisticsRequest struct { CategoryID int `form:"category_id"` }
+type DeviceAgeStatisticsRequest struct {
+    CategoryID int    `form:"category_id"`
+    CountMode  string `form:"count_mode"`
+}
 type DeviceAgeStatisticsResponse struct {
     Buckets map[string]int `json:"buckets"`
     AvgDays int            `json:"avg_days"`
@@ -66,6 +80,9 @@ type SpendingStatisticsResponse struct {
     Period string                     `json:"period"`
     GroupBy string                    `json:"group_by"`
     Trend  []map[string]interface{}   `json:"trend"`
+    Total            float64          `json:"total"`
+    PurchaseTotal    float64          `json:"purchase_total"`    // 设备购入支出
+    MaintenanceTotal float64          `json:"maintenance_total"` // 自付维修保养支出
 }
 
 type HeatmapRequest struct { Type string `form:"type"` }
@@ -74,6 +91,14 @@ type HeatmapResponse struct {
     Data []map[string]interface{} `json:"data"`
 }
 
+type OwnershipCostRankingRequest struct {
+    Limit       int   `form:"limit"`
+    IncludeSold *bool `form:"include_sold"`
+}
+type OwnershipCostRankingResponse struct {
+    Items []*deviceService.OwnershipCostRankItem `json:"items"` // 按日均持有成本从高到低
+}
+
 type InvestmentReturnRequest struct { IncludeSold *bool `form:"include_sold"` }
 type InvestmentReturnResponse struct {
     SoldReturn      float64 `json:"sold_return"`
@@ -1,18 +1,23 @@
 package service
 
 import (
+	reminderService "Backend_Lili/internal/reminder/service"
 	"Backend_Lili/internal/user/model"
 	"Backend_Lili/internal/user/repository"
 	"Backend_Lili/pkg/utils"
+
+	"github.com/beego/beego/v2/core/logs"
 )
 
 type UserService struct {
-	userRepo *repository.UserRepository
+	userRepo    *repository.UserRepository
+	reminderSvc *reminderService.ReminderService
 }
 
 func NewUserService() *UserService {
 	return &UserService{
-		userRepo: repository.NewUserRepository(),
+		userRepo:    repository.NewUserRepository(),
+		reminderSvc: reminderService.NewReminderService(),
 	}
 }
 
@@ -120,6 +125,7 @@ func (s *UserService) UpdateUserPreferences(userID int, req *UpdateUserPreferenc
 	}
 
 	// 更新偏好设置
+	warrantyReminderChanged := req.WarrantyReminderEnabled != nil && *req.WarrantyReminderEnabled != prefs.WarrantyReminderEnabled
 	if req.NotificationEnabled != nil {
 		prefs.NotificationEnabled = *req.NotificationEnabled
 	}
@@ -144,6 +150,13 @@ func (s *UserService) UpdateUserPreferences(userID int, req *UpdateUserPreferenc
 		return nil, utils.NewBusinessError(utils.ERROR_DATABASE, "更新用户偏好设置失败")
 	}
 
+	// 关闭保修提醒时删除未处理的自动保修提醒，开启时为保修期内的设备补建
+	if warrantyReminderChanged {
+		if err := s.reminderSvc.ApplyWarrantyReminderPreference(userID, prefs.WarrantyReminderEnabled); err != nil {
+			logs.Warn("同步保修提醒失败: user=%d, err=%v", userID, err)
+		}
+	}
+
 	return prefs, nil
 }
 
@@ -0,0 +1,79 @@
+package imageproc
+
+import "encoding/binary"
+
+// JPEGOrientation 读取JPEG中EXIF的方向标记(0x0112)，缺失或异常时返回1（正常方向）
+func JPEGOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF: // 填充字节
+			i++
+			continue
+		case marker == 0xD9 || marker == 0xDA: // EOI / SOS 之后不再有元数据段
+			return 1
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7): // 无长度的独立标记
+			i += 2
+			continue
+		}
+
+		segLen := int(binary.BigEndian.Uint16(data[i+2:]))
+		if segLen < 2 || i+2+segLen > len(data) {
+			return 1
+		}
+		seg := data[i+4 : i+2+segLen]
+		if marker == 0xE1 && len(seg) >= 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(seg[6:])
+		}
+		i += 2 + segLen
+	}
+	return 1
+}
+
+// tiffOrientation 在TIFF结构的IFD0中查找方向标记
+func tiffOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 1
+	}
+
+	var bo binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+	if bo.Uint16(t[2:]) != 42 {
+		return 1
+	}
+
+	offset := int(bo.Uint32(t[4:]))
+	if offset < 8 || offset+2 > len(t) {
+		return 1
+	}
+	count := int(bo.Uint16(t[offset:]))
+	for k := 0; k < count; k++ {
+		entry := offset + 2 + k*12
+		if entry+12 > len(t) {
+			return 1
+		}
+		if bo.Uint16(t[entry:]) == 0x0112 {
+			v := int(bo.Uint16(t[entry+8:]))
+			if v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,178 @@
+package imageproc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedFormat 不支持的图片格式
+	ErrUnsupportedFormat = errors.New("不支持的图片格式")
+	// ErrFormatMismatch 文件内容与扩展名不一致
+	ErrFormatMismatch = errors.New("文件内容与扩展名不一致")
+	// ErrImageTooLarge 图片像素过多
+	ErrImageTooLarge = errors.New("图片尺寸过大")
+)
+
+// MaxPixels 解码前限制像素总数，防止解压炸弹
+const MaxPixels = 50 * 1000 * 1000
+
+// VariantSpec 衍生尺寸定义：按最长边等比缩放，不放大
+type VariantSpec struct {
+	Name    string
+	MaxEdge int
+}
+
+// DefaultVariants 默认生成的缩略图尺寸
+var DefaultVariants = []VariantSpec{
+	{Name: "thumb", MaxEdge: 200},
+	{Name: "small", MaxEdge: 480},
+	{Name: "medium", MaxEdge: 1080},
+}
+
+// Encoded 编码后的图片数据
+type Encoded struct {
+	Name        string
+	Data        []byte
+	Width       int
+	Height      int
+	ContentType string
+	Ext         string
+}
+
+// Result 图片处理结果
+type Result struct {
+	Hash     string // 原始文件内容的SHA-256
+	Format   string // jpeg/png/gif
+	Original *Encoded
+	Variants []*Encoded
+}
+
+// formatByExt 扩展名对应的解码格式
+var formatByExt = map[string]string{
+	".jpg":  "jpeg",
+	".jpeg": "jpeg",
+	".png":  "png",
+	".gif":  "gif",
+}
+
+// Hash 计算内容哈希
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Process 校验并处理上传的图片：
+//  1. 按实际内容识别格式，拒绝与扩展名不符的文件
+//  2. 按EXIF方向自动旋转，重新编码以去除EXIF/文本等元数据
+//  3. 生成各尺寸缩略图（JPEG）
+func Process(data []byte, ext string, variants []VariantSpec) (*Result, error) {
+	expected, ok := formatByExt[strings.ToLower(ext)]
+	if !ok {
+		return nil, ErrUnsupportedFormat
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if format != expected {
+		return nil, ErrFormatMismatch
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	result := &Result{Hash: Hash(data), Format: format}
+
+	var base image.Image
+	switch format {
+	case "jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupportedFormat
+		}
+		base = Orient(img, JPEGOrientation(data))
+		buf := &bytes.Buffer{}
+		if err := jpeg.Encode(buf, base, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, err
+		}
+		result.Original = newEncoded("original", buf.Bytes(), base, "image/jpeg", ".jpg")
+	case "png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupportedFormat
+		}
+		base = img
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, base); err != nil {
+			return nil, err
+		}
+		result.Original = newEncoded("original", buf.Bytes(), base, "image/png", ".png")
+	case "gif":
+		// 动图保留全部帧，仅丢弃注释等扩展块
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(anim.Image) == 0 {
+			return nil, ErrUnsupportedFormat
+		}
+		buf := &bytes.Buffer{}
+		if err := gif.EncodeAll(buf, anim); err != nil {
+			return nil, err
+		}
+		base = anim.Image[0]
+		result.Original = &Encoded{
+			Name:        "original",
+			Data:        buf.Bytes(),
+			Width:       anim.Config.Width,
+			Height:      anim.Config.Height,
+			ContentType: "image/gif",
+			Ext:         ".gif",
+		}
+		if result.Original.Width == 0 || result.Original.Height == 0 {
+			result.Original.Width, result.Original.Height = base.Bounds().Dx(), base.Bounds().Dy()
+		}
+	}
+
+	// 缩略图统一输出为JPEG，透明区域填充白色
+	flat := flatten(base)
+	for _, spec := range variants {
+		thumb := Fit(flat, spec.MaxEdge)
+		buf := &bytes.Buffer{}
+		if err := jpeg.Encode(buf, thumb, &jpeg.Options{Quality: 82}); err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, newEncoded(spec.Name, buf.Bytes(), thumb, "image/jpeg", ".jpg"))
+	}
+
+	return result, nil
+}
+
+func newEncoded(name string, data []byte, img image.Image, contentType, ext string) *Encoded {
+	b := img.Bounds()
+	return &Encoded{
+		Name:        name,
+		Data:        data,
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+		ContentType: contentType,
+		Ext:         ext,
+	}
+}
+
+// flatten 将图片绘制到白色背景的RGBA画布上
+func flatten(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,253 @@
+package label
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"Backend_Lili/pkg/qrcode"
+)
+
+// ErrNoLabels 没有需要打印的标签
+var ErrNoLabels = errors.New("没有需要打印的标签")
+
+// Item 一张标签：左侧二维码，右侧文字，第一行为标题
+type Item struct {
+	Title string
+	Lines []string
+	Code  *qrcode.Code
+}
+
+// Sheet 标签纸规格，尺寸单位为毫米
+type Sheet struct {
+	PageWidth   float64
+	PageHeight  float64
+	Columns     int
+	Rows        int
+	LabelWidth  float64
+	LabelHeight float64
+	MarginLeft  float64
+	MarginTop   float64
+	GapX        float64
+	GapY        float64
+	Padding     float64
+}
+
+// A4Sheet A4 不干胶标签纸，3列8行，每张 70x37mm
+var A4Sheet = Sheet{
+	PageWidth:   210,
+	PageHeight:  297,
+	Columns:     3,
+	Rows:        8,
+	LabelWidth:  70,
+	LabelHeight: 37,
+	MarginLeft:  0,
+	MarginTop:   0.5,
+	Padding:     3,
+}
+
+// Options 打印选项
+type Options struct {
+	Skip    int  // 跳过第一页前面已用掉的标签位
+	Borders bool // 绘制浅灰色裁切线，用于普通纸打印
+}
+
+// PerPage 每页的标签数
+func (s Sheet) PerPage() int {
+	return s.Columns * s.Rows
+}
+
+const (
+	pointsPerMM   = 72 / 25.4
+	titleFontSize = 9
+	lineFontSize  = 7
+	maxTitleLines = 2
+	qrQuietZone   = 2 // 标签内边距也计入静区，这里只留2个模块
+)
+
+// WritePDF 生成标签PDF：文字使用 PDF 阅读器内置的 STSong-Light 中文字体，不嵌入字体文件
+func WritePDF(w io.Writer, sheet Sheet, items []Item, opts Options) error {
+	if len(items) == 0 {
+		return ErrNoLabels
+	}
+	perPage := sheet.PerPage()
+	if opts.Skip < 0 || opts.Skip >= perPage {
+		opts.Skip = 0
+	}
+
+	var pages [][]byte
+	slots := opts.Skip + len(items)
+	for start := 0; start < slots; start += perPage {
+		var content bytes.Buffer
+		for slot := start; slot < start+perPage && slot < slots; slot++ {
+			if slot < opts.Skip {
+				continue
+			}
+			sheet.drawLabel(&content, slot-start, &items[slot-opts.Skip], opts.Borders)
+		}
+		pages = append(pages, content.Bytes())
+	}
+	return writeDocument(w, sheet, pages)
+}
+
+// drawLabel 在第 index 个标签位绘制标签
+func (s Sheet) drawLabel(buf *bytes.Buffer, index int, item *Item, borders bool) {
+	col, row := index%s.Columns, index/s.Columns
+	left := (s.MarginLeft + float64(col)*(s.LabelWidth+s.GapX)) * pointsPerMM
+	top := (s.PageHeight - s.MarginTop - float64(row)*(s.LabelHeight+s.GapY)) * pointsPerMM
+	width, height := s.LabelWidth*pointsPerMM, s.LabelHeight*pointsPerMM
+	padding := s.Padding * pointsPerMM
+
+	if borders {
+		fmt.Fprintf(buf, "q 0.8 G 0.3 w %.2f %.2f %.2f %.2f re S Q\n", left, top-height, width, height)
+	}
+
+	textLeft := left + padding
+	if item.Code != nil {
+		side := height - 2*padding
+		module := side / float64(item.Code.Size+2*qrQuietZone)
+		qrLeft, qrTop := left+padding, top-padding
+		buf.WriteString("0 g\n")
+		item.Code.Runs(func(x, y, n int) {
+			fmt.Fprintf(buf, "%.2f %.2f %.2f %.2f re\n",
+				qrLeft+float64(x+qrQuietZone)*module, qrTop-float64(y+qrQuietZone+1)*module, float64(n)*module, module)
+		})
+		buf.WriteString("f\n")
+		textLeft += side + padding/2
+	}
+
+	textWidth := left + width - padding - textLeft
+	baseline := top - padding - titleFontSize
+	for i, line := range wrapText(item.Title, titleFontSize, textWidth, maxTitleLines) {
+		if i > 0 {
+			baseline -= titleFontSize * 1.2
+		}
+		writeText(buf, textLeft, baseline, titleFontSize, line)
+	}
+	baseline -= titleFontSize*0.4 + lineFontSize*1.2
+	for _, line := range item.Lines {
+		if line == "" {
+			continue
+		}
+		if baseline < top-height+padding {
+			break
+		}
+		for _, part := range wrapText(line, lineFontSize, textWidth, 1) {
+			writeText(buf, textLeft, baseline, lineFontSize, part)
+		}
+		baseline -= lineFontSize * 1.3
+	}
+}
+
+// writeText 输出一行文字，按 UCS-2 编码，超出基本平面的字符替换为问号
+func writeText(buf *bytes.Buffer, x, y float64, size int, text string) {
+	fmt.Fprintf(buf, "BT /F1 %d Tf %.2f %.2f Td <", size, x, y)
+	for _, r := range text {
+		if r > 0xFFFF {
+			r = '?'
+		}
+		fmt.Fprintf(buf, "%04X", r)
+	}
+	buf.WriteString("> Tj ET\n")
+}
+
+// textWidth 估算文字宽度：ASCII 为半角，其余为全角
+func textWidth(text string, size int) float64 {
+	width := 0.0
+	for _, r := range text {
+		if r < 0x80 {
+			width += 0.5
+		} else {
+			width += 1
+		}
+	}
+	return width * float64(size)
+}
+
+// wrapText 按宽度折行，最多 maxLines 行，超出部分以省略号结尾
+func wrapText(text string, size int, width float64, maxLines int) []string {
+	text = strings.Join(strings.Fields(text), " ")
+	var lines []string
+	for text != "" && len(lines) < maxLines {
+		end := len(text)
+		for textWidth(text[:end], size) > width {
+			_, n := utf8.DecodeLastRuneInString(text[:end])
+			end -= n
+		}
+		if end == 0 {
+			break
+		}
+		if end < len(text) && len(lines) == maxLines-1 {
+			// 最后一行放不下，去掉一个字符换成省略号
+			for end > 0 && textWidth(text[:end]+"…", size) > width {
+				_, n := utf8.DecodeLastRuneInString(text[:end])
+				end -= n
+			}
+			lines = append(lines, strings.TrimSpace(text[:end])+"…")
+			break
+		}
+		lines = append(lines, strings.TrimSpace(text[:end]))
+		text = strings.TrimSpace(text[end:])
+	}
+	return lines
+}
+
+// writeDocument 写出PDF对象与交叉引用表；页面内容使用 Flate 压缩
+func writeDocument(w io.Writer, sheet Sheet, pages [][]byte) error {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	// 1 目录，2 页面树，3-5 字体，之后每页占两个对象：页面与内容
+	const firstPage = 6
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>")
+	object("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> " +
+		"/FontDescriptor 5 0 R /DW 1000 /W [1 95 500 7716 7810 500] >>")
+	object("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+
+	mediaBox := fmt.Sprintf("[0 0 %.2f %.2f]", sheet.PageWidth*pointsPerMM, sheet.PageHeight*pointsPerMM)
+	for i, content := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox %s /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			mediaBox, firstPage+2*i+1))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(content); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", len(offsets), compressed.Len())
+		buf.Write(compressed.Bytes())
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,256 @@
+package orderimport
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Inference 从商品标题推断出的设备信息
+type Inference struct {
+	IsElectronics bool   // 是否为数码电子产品
+	Brand         string // 品牌，未识别时为空
+	Model         string // 型号，未识别时为空
+	CategoryHint  string // 建议的分类名称，对应系统分类 手机/电脑/相机
+}
+
+// knownBrands 品牌及其在标题中的常见写法，按顺序匹配，较长的写法放在前面
+var knownBrands = []struct {
+	Name    string
+	Aliases []string
+}{
+	{"Apple", []string{"apple", "苹果"}},
+	{"华为", []string{"huawei", "华为"}},
+	{"荣耀", []string{"honor", "荣耀"}},
+	{"小米", []string{"xiaomi", "小米", "redmi", "红米"}},
+	{"OPPO", []string{"oppo"}},
+	{"vivo", []string{"vivo", "iqoo"}},
+	{"一加", []string{"oneplus", "一加"}},
+	{"三星", []string{"samsung", "三星"}},
+	{"魅族", []string{"meizu", "魅族"}},
+	{"联想", []string{"lenovo", "联想", "thinkpad", "拯救者"}},
+	{"戴尔", []string{"dell", "戴尔", "alienware", "外星人"}},
+	{"惠普", []string{"惠普"}},
+	{"华硕", []string{"asus", "华硕", "玩家国度"}},
+	{"宏碁", []string{"acer", "宏碁"}},
+	{"微软", []string{"microsoft", "微软"}},
+	{"索尼", []string{"sony", "索尼"}},
+	{"佳能", []string{"canon", "佳能"}},
+	{"尼康", []string{"nikon", "尼康"}},
+	{"富士", []string{"fujifilm", "富士"}},
+	{"松下", []string{"panasonic", "松下"}},
+	{"大疆", []string{"dji", "大疆"}},
+	{"影石", []string{"insta360", "影石"}},
+	{"GoPro", []string{"gopro"}},
+	{"罗技", []string{"logitech", "罗技"}},
+	{"雷蛇", []string{"razer", "雷蛇"}},
+	{"Bose", []string{"bose"}},
+	{"漫步者", []string{"edifier", "漫步者"}},
+	{"任天堂", []string{"nintendo", "任天堂"}},
+	{"Kindle", []string{"kindle"}},
+	{"飞利浦", []string{"philips", "飞利浦"}},
+}
+
+// electronicsKeywords 标题中出现即视为数码电子产品的关键字
+var electronicsKeywords = []string{
+	"手机", "笔记本", "电脑", "平板", "ipad", "macbook", "iphone", "主机", "显示器",
+	"相机", "微单", "单反", "镜头", "无人机", "运动相机", "云台",
+	"耳机", "音箱", "音响", "手表", "手环", "watch", "airpods",
+	"键盘", "鼠标", "路由器", "硬盘", "ssd", "固态", "显卡", "游戏机", "switch", "ps5", "xbox",
+	"电子书", "阅读器", "投影", "充电宝", "移动电源",
+}
+
+// categoryKeywords 分类建议关键字，按顺序匹配，镜头等相机配件归入相机
+var categoryKeywords = []struct {
+	Category string
+	Keywords []string
+}{
+	{"相机", []string{"相机", "微单", "单反", "镜头", "卡片机"}},
+	{"电脑", []string{"笔记本", "电脑", "macbook", "thinkpad", "台式机", "主机", "一体机"}},
+	{"手机", []string{"手机", "iphone"}},
+}
+
+// bracketPattern 标题中的营销括号段，如【12期免息】、[现货速发]
+var bracketPattern = regexp.MustCompile(`【[^】]*】|\[[^\]]*\]|（[^）]*）|\([^)]*\)`)
+
+// specPattern 型号之后常见的规格写法，遇到即停止：256GB、8+256G、16G+512G、14英寸
+var specPattern = regexp.MustCompile(`(?i)^(\d+(\.\d+)?(gb|g|tb|t|mm|cm|kg|ml|寸|英寸|w|mah)|\d+(gb|g|tb|t)?\+\d+(gb|g|tb|t)?)$`)
+
+// modelSuffixes 可以接在型号后面的修饰词
+var modelSuffixes = map[string]bool{
+	"pro": true, "max": true, "ultra": true, "plus": true, "mini": true, "air": true,
+	"se": true, "lite": true, "neo": true, "carbon": true, "fold": true, "flip": true,
+	"i": true, "ii": true, "iii": true, "iv": true, "v": true,
+}
+
+// Infer 从商品标题推断品牌、型号与分类
+func Infer(title string) *Inference {
+	result := &Inference{}
+	lower := strings.ToLower(title)
+
+	// 品牌可能写在括号里，如 索尼（SONY），先在原标题上匹配
+	brandAliases := []string{}
+	for _, brand := range knownBrands {
+		for _, alias := range brand.Aliases {
+			if strings.Contains(lower, alias) {
+				if result.Brand == "" {
+					result.Brand = brand.Name
+					brandAliases = brand.Aliases
+				}
+				break
+			}
+		}
+		if result.Brand != "" {
+			break
+		}
+	}
+
+	for _, keyword := range electronicsKeywords {
+		if strings.Contains(lower, keyword) {
+			result.IsElectronics = true
+			break
+		}
+	}
+	for _, item := range categoryKeywords {
+		for _, keyword := range item.Keywords {
+			if strings.Contains(lower, keyword) {
+				result.CategoryHint = item.Category
+				break
+			}
+		}
+		if result.CategoryHint != "" {
+			break
+		}
+	}
+
+	result.Model = inferModel(title, brandAliases)
+	if !result.IsElectronics && result.Brand != "" && result.Model != "" {
+		result.IsElectronics = true
+	}
+	return result
+}
+
+// inferModel 去掉营销括号与品牌写法后，取第一段由字母数字组成的连续词作为型号
+func inferModel(title string, brandAliases []string) string {
+	cleaned := bracketPattern.ReplaceAllString(title, " ")
+	// 品牌与型号常写在一起，如 华为HUAWEI Mate 60、Apple/苹果 iPhone 15；"thinkpad" 等子品牌保留为型号的一部分
+	for _, alias := range brandAliases {
+		if alias == "thinkpad" || alias == "redmi" || alias == "iqoo" || alias == "alienware" {
+			continue
+		}
+		cleaned = replaceFold(cleaned, alias, " ")
+	}
+	cleaned = strings.NewReplacer("/", " ", "|", " ", "，", " ", ",", " ").Replace(cleaned)
+
+	tokens := splitTokens(cleaned)
+	var model []string
+	for _, token := range tokens {
+		isASCII := isASCIIWord(token)
+		if len(model) == 0 {
+			// 型号从第一个含字母或数字的ASCII词开始
+			if isASCII && !specPattern.MatchString(token) {
+				model = append(model, token)
+			}
+			continue
+		}
+		if !isASCII || specPattern.MatchString(token) || len(model) >= 4 {
+			break
+		}
+		if hasDigit(token) || modelSuffixes[strings.ToLower(token)] || isCapitalized(token) {
+			model = append(model, token)
+			continue
+		}
+		break
+	}
+
+	// 单独的纯数字或单个字母不足以作为型号
+	if len(model) == 1 && (len(model[0]) < 2 || !hasLetterAndDigit(model[0]) && !isCapitalized(model[0])) {
+		return ""
+	}
+	return strings.Join(model, " ")
+}
+
+// splitTokens 按空白以及中英文交界切分
+func splitTokens(s string) []string {
+	var tokens []string
+	var current []rune
+	currentASCII := false
+	flush := func() {
+		if len(current) > 0 {
+			tokens = append(tokens, string(current))
+			current = current[:0]
+		}
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			flush()
+			continue
+		}
+		ascii := r < unicode.MaxASCII
+		if len(current) > 0 && ascii != currentASCII {
+			// 14英寸 这类数字加中文单位保留为一个词，便于按规格识别
+			if !(currentASCII && !ascii && isDigits(string(current))) {
+				flush()
+			}
+		}
+		if len(current) == 0 {
+			currentASCII = ascii
+		}
+		current = append(current, r)
+	}
+	flush()
+	return tokens
+}
+
+// replaceFold 不区分大小写替换
+func replaceFold(s, old, replacement string) string {
+	lower := strings.ToLower(s)
+	if len(lower) != len(s) {
+		return strings.ReplaceAll(s, old, replacement)
+	}
+	var sb strings.Builder
+	for {
+		idx := strings.Index(lower, old)
+		if idx < 0 {
+			sb.WriteString(s)
+			return sb.String()
+		}
+		sb.WriteString(s[:idx])
+		sb.WriteString(replacement)
+		s = s[idx+len(old):]
+		lower = lower[idx+len(old):]
+	}
+}
+
+func isASCIIWord(s string) bool {
+	for _, r := range s {
+		if r >= unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' || r == '+') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) && r != '.' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func hasDigit(s string) bool {
+	return strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
+
+func hasLetterAndDigit(s string) bool {
+	return hasDigit(s) && strings.IndexFunc(s, unicode.IsLetter) >= 0
+}
+
+func isCapitalized(s string) bool {
+	for _, r := range s {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package orderimport
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"Backend_Lili/pkg/sheet"
+)
+
+// 支持的平台
+const (
+	PlatformJD     = "jd"
+	PlatformTaobao = "taobao" // 淘宝与天猫导出格式一致
+)
+
+var (
+	// ErrUnknownPlatform 无法识别订单导出文件所属平台
+	ErrUnknownPlatform = errors.New("无法识别订单导出文件的平台，请指定 platform")
+	// ErrMissingColumns 缺少必需的列
+	ErrMissingColumns = errors.New("订单文件缺少商品名称、实付金额或下单时间列")
+)
+
+// OrderItem 订单中的一件商品
+type OrderItem struct {
+	Row         int       // 表格行号（从1开始）
+	OrderNo     string    // 订单号
+	OrderDate   time.Time // 下单时间，只保留日期
+	Title       string    // 商品标题
+	Shop        string    // 店铺名称
+	Quantity    int       // 购买数量
+	PricePaid   float64   // 该行实付金额
+	UnitPrice   float64   // 按数量折算的单价
+	OrderStatus string    // 订单状态
+	Skipped     bool      // 已取消、已关闭或已退款的订单
+}
+
+// platformColumns 各平台导出文件的列名，按优先级排列
+var platformColumns = map[string]map[string][]string{
+	PlatformJD: {
+		"order_no":   {"订单号", "订单编号"},
+		"order_date": {"下单时间", "下单日期", "订单时间", "创建时间"},
+		"title":      {"商品名称", "商品标题", "商品"},
+		"quantity":   {"商品数量", "购买数量", "数量"},
+		"price_paid": {"实付金额", "应付金额", "实付款", "订单金额", "商品金额", "金额"},
+		"shop":       {"店铺名称", "商家名称", "店铺", "商家"},
+		"status":     {"订单状态", "状态"},
+	},
+	PlatformTaobao: {
+		"order_no":   {"订单编号", "订单号"},
+		"order_date": {"订单创建时间", "创建时间", "下单时间", "成交时间", "订单付款时间", "付款时间"},
+		"title":      {"宝贝标题", "商品标题", "宝贝名称", "商品名称", "标题"},
+		"quantity":   {"宝贝数量", "购买数量", "商品数量", "数量"},
+		"price_paid": {"实付款", "实付款(元)", "买家实际支付金额", "实际支付金额", "实付金额", "总金额"},
+		"shop":       {"店铺名称", "卖家店铺", "卖家昵称", "店铺", "卖家"},
+		"status":     {"订单状态", "交易状态", "状态"},
+	},
+}
+
+// platformMarkers 只出现在某一平台导出文件中的表头关键字
+var platformMarkers = map[string][]string{
+	PlatformJD:     {"京东", "商品编号", "商家名称", "京豆"},
+	PlatformTaobao: {"宝贝", "淘宝", "天猫", "卖家", "旺旺"},
+}
+
+// skippedStatusKeywords 不计入购买记录的订单状态
+var skippedStatusKeywords = []string{"取消", "关闭", "退款成功", "已退款", "等待付款", "未付款"}
+
+// headerScanRows 查找表头时扫描的行数，导出文件顶部可能有说明行
+const headerScanRows = 10
+
+// Parse 解析订单导出表格，platform 为空时根据表头识别
+func Parse(rows [][]string, platform string) (string, []*OrderItem, error) {
+	headerIndex, detected := findHeader(rows)
+	if headerIndex < 0 {
+		return "", nil, ErrMissingColumns
+	}
+	if platform == "" {
+		platform = detected
+	}
+	spec, ok := platformColumns[platform]
+	if !ok {
+		return "", nil, ErrUnknownPlatform
+	}
+
+	headers := rows[headerIndex]
+	columns := make(map[string]int)
+	for field, aliases := range spec {
+		if idx := findColumn(headers, aliases); idx >= 0 {
+			columns[field] = idx
+		}
+	}
+	for _, field := range []string{"title", "price_paid", "order_date"} {
+		if _, ok := columns[field]; !ok {
+			return "", nil, ErrMissingColumns
+		}
+	}
+
+	cell := func(row []string, field string) string {
+		if idx, ok := columns[field]; ok && idx < len(row) {
+			// 导出文件常用 ="123" 或前置制表符防止订单号被科学计数
+			value := strings.TrimSpace(row[idx])
+			value = strings.TrimPrefix(value, "=")
+			return strings.Trim(value, "\"\t ")
+		}
+		return ""
+	}
+
+	items := make([]*OrderItem, 0, len(rows)-headerIndex-1)
+	for i := headerIndex + 1; i < len(rows); i++ {
+		row := rows[i]
+		title := cell(row, "title")
+		if sheet.IsBlankRow(row) || title == "" {
+			continue
+		}
+
+		item := &OrderItem{
+			Row:         i + 1,
+			OrderNo:     cell(row, "order_no"),
+			Title:       title,
+			Shop:        cell(row, "shop"),
+			Quantity:    1,
+			OrderStatus: cell(row, "status"),
+		}
+		if quantity, err := strconv.Atoi(cell(row, "quantity")); err == nil && quantity > 0 {
+			item.Quantity = quantity
+		}
+		if date, err := sheet.ParseDate(cell(row, "order_date")); err == nil {
+			item.OrderDate = date
+		}
+		if amount, err := sheet.ParseAmount(cell(row, "price_paid")); err == nil {
+			item.PricePaid = amount
+			item.UnitPrice = math.Round(amount/float64(item.Quantity)*100) / 100
+		}
+		for _, keyword := range skippedStatusKeywords {
+			if strings.Contains(item.OrderStatus, keyword) {
+				item.Skipped = true
+				break
+			}
+		}
+		items = append(items, item)
+	}
+	return platform, items, nil
+}
+
+// findHeader 找到同时包含商品名称与金额列的表头行，并按表头关键字判断平台
+func findHeader(rows [][]string) (int, string) {
+	for i := 0; i < len(rows) && i < headerScanRows; i++ {
+		headers := rows[i]
+		matched := ""
+		for _, platform := range []string{PlatformTaobao, PlatformJD} {
+			spec := platformColumns[platform]
+			if findColumn(headers, spec["title"]) >= 0 && findColumn(headers, spec["price_paid"]) >= 0 {
+				matched = platform
+				break
+			}
+		}
+		if matched == "" {
+			continue
+		}
+
+		scores := map[string]int{}
+		for platform, markers := range platformMarkers {
+			for _, header := range headers {
+				for _, marker := range markers {
+					if strings.Contains(header, marker) {
+						scores[platform]++
+					}
+				}
+			}
+		}
+		switch {
+		case scores[PlatformTaobao] > scores[PlatformJD]:
+			return i, PlatformTaobao
+		case scores[PlatformJD] > scores[PlatformTaobao]:
+			return i, PlatformJD
+		}
+		return i, ""
+	}
+	return -1, ""
+}
+
+// findColumn 按别名顺序查找列，忽略空白与全角括号差异
+func findColumn(headers []string, aliases []string) int {
+	normalize := strings.NewReplacer(" ", "", "（", "(", "）", ")")
+	for _, alias := range aliases {
+		for i, header := range headers {
+			if normalize.Replace(strings.TrimSpace(header)) == normalize.Replace(alias) {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,144 @@
+package orderimport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Backend_Lili/pkg/sheet"
+)
+
+// draftCase 样例订单中一件商品期望得到的草稿内容；name 与草稿一样取品牌加型号
+type draftCase struct {
+	orderNo   string
+	date      string
+	name      string
+	category  string
+	quantity  int
+	pricePaid float64
+	unitPrice float64
+	skipped   bool
+	ignored   bool // 非数码产品，草稿导入时默认忽略
+}
+
+func readFixture(t *testing.T, name string) [][]string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("读取样例文件失败: %v", err)
+	}
+	rows, err := sheet.Read(data, name)
+	if err != nil {
+		t.Fatalf("解析样例文件失败: %v", err)
+	}
+	return rows
+}
+
+func TestParseFixtures(t *testing.T) {
+	tests := []struct {
+		file     string
+		platform string
+		want     []draftCase
+	}{
+		{
+			file:     "jd_orders.csv",
+			platform: PlatformJD,
+			want: []draftCase{
+				{orderNo: "300000000001", date: "2023-11-11", name: "Apple iPhone 15 Pro", category: "手机", quantity: 1, pricePaid: 8499, unitPrice: 8499},
+				{orderNo: "300000000002", date: "2023-06-18", name: "联想 ThinkPad X1 Carbon 2023", category: "电脑", quantity: 1, pricePaid: 11299, unitPrice: 11299},
+				{orderNo: "300000000003", date: "2023-06-18", name: "罗技 MX Master 3S", quantity: 2, pricePaid: 1198, unitPrice: 599},
+				{orderNo: "300000000004", date: "2023-03-08", quantity: 1, pricePaid: 49.9, unitPrice: 49.9, ignored: true},
+				{orderNo: "300000000005", date: "2023-02-01", name: "华为 Mate 60 Pro", category: "手机", quantity: 1, pricePaid: 6999, unitPrice: 6999, skipped: true},
+			},
+		},
+		{
+			file:     "taobao_orders.csv",
+			platform: PlatformTaobao,
+			want: []draftCase{
+				{orderNo: "2000000000000001", date: "2024-01-05", name: "索尼 Alpha 7 IV", category: "相机", quantity: 1, pricePaid: 14999, unitPrice: 14999},
+				{orderNo: "2000000000000002", date: "2024-01-05", name: "大疆 Mini 4 Pro", quantity: 1, pricePaid: 6788, unitPrice: 6788},
+				{orderNo: "2000000000000003", date: "2024-02-14", quantity: 1, pricePaid: 89, unitPrice: 89, ignored: true},
+				{orderNo: "2000000000000004", date: "2024-03-01", name: "小米 Redmi K70", category: "手机", quantity: 1, pricePaid: 2599, unitPrice: 2599, skipped: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			platform, items, err := Parse(readFixture(t, tt.file), "")
+			if err != nil {
+				t.Fatalf("Parse 返回错误: %v", err)
+			}
+			if platform != tt.platform {
+				t.Errorf("平台识别为 %q，期望 %q", platform, tt.platform)
+			}
+			if len(items) != len(tt.want) {
+				t.Fatalf("解析出 %d 件商品，期望 %d 件", len(items), len(tt.want))
+			}
+			for i, want := range tt.want {
+				item := items[i]
+				if item.Row != i+2 {
+					t.Errorf("第%d件商品行号为 %d，期望 %d", i+1, item.Row, i+2)
+				}
+				if item.OrderNo != want.orderNo {
+					t.Errorf("第%d件商品订单号为 %q，期望 %q", i+1, item.OrderNo, want.orderNo)
+				}
+				if got := item.OrderDate.Format("2006-01-02"); got != want.date {
+					t.Errorf("第%d件商品下单日期为 %s，期望 %s", i+1, got, want.date)
+				}
+				if item.Quantity != want.quantity || item.PricePaid != want.pricePaid || item.UnitPrice != want.unitPrice {
+					t.Errorf("第%d件商品数量与金额为 %d/%.2f/%.2f，期望 %d/%.2f/%.2f", i+1,
+						item.Quantity, item.PricePaid, item.UnitPrice, want.quantity, want.pricePaid, want.unitPrice)
+				}
+				if item.Skipped != want.skipped {
+					t.Errorf("第%d件商品 Skipped 为 %v，期望 %v（订单状态 %s）", i+1, item.Skipped, want.skipped, item.OrderStatus)
+				}
+
+				inference := Infer(item.Title)
+				if inference.IsElectronics == want.ignored {
+					t.Errorf("第%d件商品 IsElectronics 为 %v，期望 %v", i+1, inference.IsElectronics, !want.ignored)
+				}
+				if name := strings.TrimSpace(inference.Brand + " " + inference.Model); name != want.name {
+					t.Errorf("第%d件商品名称为 %q，期望 %q", i+1, name, want.name)
+				}
+				if inference.CategoryHint != want.category {
+					t.Errorf("第%d件商品分类建议为 %q，期望 %q", i+1, inference.CategoryHint, want.category)
+				}
+			}
+		})
+	}
+}
+
+func TestParseHeaderAndPlatform(t *testing.T) {
+	jd := readFixture(t, "jd_orders.csv")
+
+	tests := []struct {
+		name     string
+		rows     [][]string
+		platform string
+		want     string
+		wantErr  error
+		wantRows int
+	}{
+		{name: "指定平台", rows: jd, platform: PlatformJD, want: PlatformJD, wantRows: 5},
+		{name: "表头前有说明行", rows: append([][]string{{"订单导出"}, {"导出时间: 2024-01-01"}}, jd...), want: PlatformJD, wantRows: 5},
+		{name: "未知平台", rows: jd, platform: "pdd", wantErr: ErrUnknownPlatform},
+		{name: "缺少金额列", rows: [][]string{{"订单号", "商品名称", "下单时间"}, {"1", "手机", "2024-01-01"}}, wantErr: ErrMissingColumns},
+		{name: "无法识别平台", rows: [][]string{{"商品名称", "实付金额", "下单时间"}, {"手机", "1", "2024-01-01"}}, wantErr: ErrUnknownPlatform},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			platform, items, err := Parse(tt.rows, tt.platform)
+			if err != tt.wantErr {
+				t.Fatalf("Parse 返回错误 %v，期望 %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if platform != tt.want || len(items) != tt.wantRows {
+				t.Errorf("Parse 返回 %s/%d 件，期望 %s/%d 件", platform, len(items), tt.want, tt.wantRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,518 @@
+package qrcode
+
+import (
+	"errors"
+)
+
+// Level 纠错等级
+type Level int
+
+const (
+	Low      Level = iota // 约可恢复7%
+	Medium                // 约可恢复15%
+	Quartile              // 约可恢复25%
+	High                  // 约可恢复30%
+)
+
+const (
+	minVersion = 1
+	maxVersion = 40
+)
+
+var (
+	// ErrDataTooLong 数据超出最大版本的容量
+	ErrDataTooLong = errors.New("二维码数据过长")
+	// ErrInvalidLevel 纠错等级无效
+	ErrInvalidLevel = errors.New("二维码纠错等级无效")
+)
+
+// Code 编码后的二维码矩阵，不含静区
+type Code struct {
+	Version int
+	Level   Level
+	Size    int
+	modules []bool // 按行存储，true 为深色
+}
+
+// Black 模块 (x, y) 是否为深色，越界时返回 false
+func (c *Code) Black(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y*c.Size+x]
+}
+
+// Encode 以字节模式编码数据，自动选择能容纳数据的最小版本与惩罚分最低的掩码
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < Low || level > High {
+		return nil, ErrInvalidLevel
+	}
+	for version := minVersion; version <= maxVersion; version++ {
+		if codewords, ok := encodeSegment(data, version, level); ok {
+			return newCode(version, level, codewords, -1), nil
+		}
+	}
+	return nil, ErrDataTooLong
+}
+
+// encodeSegment 生成数据码字：模式指示、字符计数、数据、终止符与填充，超出容量时返回 false
+func encodeSegment(data []byte, version int, level Level) ([]byte, bool) {
+	capacity := numDataCodewords(version, level) * 8
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	if len(data) >= 1<<countBits || 4+countBits+len(data)*8 > capacity {
+		return nil, false
+	}
+
+	buf := &bitBuffer{}
+	buf.append(0x4, 4) // 字节模式
+	buf.append(uint32(len(data)), countBits)
+	for _, b := range data {
+		buf.append(uint32(b), 8)
+	}
+	terminator := capacity - buf.len()
+	if terminator > 4 {
+		terminator = 4
+	}
+	buf.append(0, terminator)
+	buf.append(0, (8-buf.len()%8)%8)
+	for pad := byte(0xEC); buf.len() < capacity; pad ^= 0xEC ^ 0x11 {
+		buf.append(uint32(pad), 8)
+	}
+	return buf.bytes(), true
+}
+
+// bitBuffer 按位追加，高位在前
+type bitBuffer struct {
+	bits []bool
+}
+
+func (b *bitBuffer) append(value uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		b.bits = append(b.bits, value>>uint(i)&1 == 1)
+	}
+}
+
+func (b *bitBuffer) len() int {
+	return len(b.bits)
+}
+
+func (b *bitBuffer) bytes() []byte {
+	result := make([]byte, (len(b.bits)+7)/8)
+	for i, bit := range b.bits {
+		if bit {
+			result[i>>3] |= 1 << uint(7-i&7)
+		}
+	}
+	return result
+}
+
+// numRawDataModules 版本中可用于数据与纠错码字的模块数（去掉功能图形与格式、版本信息）
+func numRawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		numAlign := version/7 + 2
+		result -= (25*numAlign-10)*numAlign - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+// numDataCodewords 版本与纠错等级对应的数据码字数
+func numDataCodewords(version int, level Level) int {
+	return numRawDataModules(version)/8 - eccCodewordsPerBlock[level][version]*numErrorCorrectionBlocks[level][version]
+}
+
+// alignmentPositions 校正图形中心的行列坐标
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	numAlign := version/7 + 2
+	step := (version*8 + numAlign*3 + 5) / (numAlign*4 - 4) * 2
+	size := version*4 + 17
+	result := make([]int, numAlign)
+	result[0] = 6
+	for i, pos := numAlign-1, size-7; i >= 1; i, pos = i-1, pos-step {
+		result[i] = pos
+	}
+	return result
+}
+
+// matrix 绘制过程中的模块矩阵，isFunction 标记功能图形，掩码不作用于这些模块
+type matrix struct {
+	size       int
+	modules    []bool
+	isFunction []bool
+}
+
+func (m *matrix) set(x, y int, black bool) {
+	m.modules[y*m.size+x] = black
+}
+
+func (m *matrix) setFunction(x, y int, black bool) {
+	m.modules[y*m.size+x] = black
+	m.isFunction[y*m.size+x] = true
+}
+
+// newCode 绘制功能图形与码字，mask 为 -1 时自动选择掩码
+func newCode(version int, level Level, dataCodewords []byte, mask int) *Code {
+	size := version*4 + 17
+	m := &matrix{
+		size:       size,
+		modules:    make([]bool, size*size),
+		isFunction: make([]bool, size*size),
+	}
+	m.drawFunctionPatterns(version, level)
+	m.drawCodewords(addECCAndInterleave(dataCodewords, version, level))
+
+	if mask < 0 {
+		minPenalty := -1
+		for candidate := 0; candidate < 8; candidate++ {
+			m.applyMask(candidate)
+			m.drawFormatBits(level, candidate)
+			if penalty := m.penalty(); minPenalty < 0 || penalty < minPenalty {
+				mask, minPenalty = candidate, penalty
+			}
+			m.applyMask(candidate) // 异或两次即还原
+		}
+	}
+	m.applyMask(mask)
+	m.drawFormatBits(level, mask)
+
+	return &Code{Version: version, Level: level, Size: size, modules: m.modules}
+}
+
+// drawFunctionPatterns 绘制定位、分隔、定时、校正图形与版本信息，并预留格式信息区域
+func (m *matrix) drawFunctionPatterns(version int, level Level) {
+	size := m.size
+	for i := 0; i < size; i++ {
+		m.setFunction(6, i, i%2 == 0)
+		m.setFunction(i, 6, i%2 == 0)
+	}
+
+	m.drawFinderPattern(3, 3)
+	m.drawFinderPattern(size-4, 3)
+	m.drawFinderPattern(3, size-4)
+
+	positions := alignmentPositions(version)
+	last := len(positions) - 1
+	for i, y := range positions {
+		for j, x := range positions {
+			// 与定位图形重叠的三个角不绘制
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			m.drawAlignmentPattern(x, y)
+		}
+	}
+
+	m.drawFormatBits(level, 0)
+	m.drawVersion(version)
+}
+
+// drawFinderPattern 以 (x, y) 为中心绘制定位图形及其分隔符
+func (m *matrix) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= m.size || yy >= m.size {
+				continue
+			}
+			dist := maxInt(absInt(dx), absInt(dy))
+			m.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignmentPattern 以 (x, y) 为中心绘制校正图形
+func (m *matrix) drawAlignmentPattern(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			m.setFunction(x+dx, y+dy, maxInt(absInt(dx), absInt(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits 绘制两份格式信息（纠错等级与掩码，BCH(15,5) 编码）及固定的深色模块
+func (m *matrix) drawFormatBits(level Level, mask int) {
+	data := formatLevelBits[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	bit := func(i int) bool { return bits>>uint(i)&1 == 1 }
+	size := m.size
+	for i := 0; i <= 5; i++ {
+		m.setFunction(8, i, bit(i))
+	}
+	m.setFunction(8, 7, bit(6))
+	m.setFunction(8, 8, bit(7))
+	m.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		m.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		m.setFunction(size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		m.setFunction(8, size-15+i, bit(i))
+	}
+	m.setFunction(8, size-8, true)
+}
+
+// drawVersion 版本7及以上绘制两份版本信息（BCH(18,6) 编码）
+func (m *matrix) drawVersion(version int) {
+	if version < 7 {
+		return
+	}
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	bits := version<<12 | rem
+	for i := 0; i < 18; i++ {
+		black := bits>>uint(i)&1 == 1
+		a, b := m.size-11+i%3, i/3
+		m.setFunction(a, b, black)
+		m.setFunction(b, a, black)
+	}
+}
+
+// addECCAndInterleave 数据码字分块并计算 Reed-Solomon 纠错码，再按列交织
+func addECCAndInterleave(data []byte, version int, level Level) []byte {
+	numBlocks := numErrorCorrectionBlocks[level][version]
+	blockECCLen := eccCodewordsPerBlock[level][version]
+	rawCodewords := numRawDataModules(version) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortBlockLen := rawCodewords / numBlocks
+
+	divisor := reedSolomonDivisor(blockECCLen)
+	blocks := make([][]byte, numBlocks)
+	for i, k := 0, 0; i < numBlocks; i++ {
+		dataLen := shortBlockLen - blockECCLen
+		if i >= numShortBlocks {
+			dataLen++
+		}
+		block := make([]byte, 0, shortBlockLen+1)
+		block = append(block, data[k:k+dataLen]...)
+		k += dataLen
+		ecc := reedSolomonRemainder(block, divisor)
+		if i < numShortBlocks {
+			block = append(block, 0) // 占位，使各块等长，交织时跳过
+		}
+		blocks[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	for i := 0; i < shortBlockLen+1; i++ {
+		for j, block := range blocks {
+			if i != shortBlockLen-blockECCLen || j >= numShortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// reedSolomonDivisor 生成次数为 degree 的生成多项式系数（不含最高次项）
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// reedSolomonRemainder 数据多项式除以生成多项式的余式，即纠错码字
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply GF(2^8) 乘法，本原多项式 x^8+x^4+x^3+x^2+1
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>uint(i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// drawCodewords 按两列一组、自右向左之字形放置码字，跳过功能图形与第6列定时图形
+func (m *matrix) drawCodewords(data []byte) {
+	size := m.size
+	i := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < size; vert++ {
+			y := vert
+			if upward {
+				y = size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if m.isFunction[y*size+x] || i >= len(data)*8 {
+					continue
+				}
+				m.set(x, y, data[i>>3]>>uint(7-i&7)&1 == 1)
+				i++
+			}
+		}
+	}
+}
+
+// applyMask 对数据模块按掩码取反
+func (m *matrix) applyMask(mask int) {
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !m.isFunction[y*m.size+x] {
+				m.modules[y*m.size+x] = !m.modules[y*m.size+x]
+			}
+		}
+	}
+}
+
+// penalty 按标准的四条规则计算惩罚分：同色连续、2x2同色块、类定位图形、深浅比例
+func (m *matrix) penalty() int {
+	size := m.size
+	at := func(x, y int) bool { return m.modules[y*size+x] }
+	result := 0
+
+	for i := 0; i < size; i++ {
+		rowRun, colRun := 1, 1
+		for j := 1; j < size; j++ {
+			if at(j, i) == at(j-1, i) {
+				rowRun++
+			} else {
+				rowRun = 1
+			}
+			if rowRun == 5 {
+				result += 3
+			} else if rowRun > 5 {
+				result++
+			}
+			if at(i, j) == at(i, j-1) {
+				colRun++
+			} else {
+				colRun = 1
+			}
+			if colRun == 5 {
+				result += 3
+			} else if colRun > 5 {
+				result++
+			}
+		}
+	}
+
+	for y := 0; y < size-1; y++ {
+		for x := 0; x < size-1; x++ {
+			c := at(x, y)
+			if c == at(x+1, y) && c == at(x, y+1) && c == at(x+1, y+1) {
+				result += 3
+			}
+		}
+	}
+
+	// 1:1:3:1:1 图形且一侧有4个浅色模块
+	pattern := []bool{true, false, true, true, true, false, true}
+	light := func(get func(int) bool, from, to int) bool {
+		for k := from; k < to; k++ {
+			if k >= 0 && k < size && get(k) {
+				return false
+			}
+		}
+		return true
+	}
+	for i := 0; i < size; i++ {
+		row := func(k int) bool { return at(k, i) }
+		col := func(k int) bool { return at(i, k) }
+		for _, get := range []func(int) bool{row, col} {
+			for j := 0; j+7 <= size; j++ {
+				matched := true
+				for k, black := range pattern {
+					if get(j+k) != black {
+						matched = false
+						break
+					}
+				}
+				if matched && (light(get, j-4, j) || light(get, j+7, j+11)) {
+					result += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for _, black := range m.modules {
+		if black {
+			dark++
+		}
+	}
+	total := size * size
+	// 深色比例每偏离50%达5%扣10分
+	if k := (absInt(dark*20-total*10)+total-1)/total - 1; k > 0 {
+		result += k * 10
+	}
+	return result
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,155 @@
+package qrcode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// encodeWithMask 与 Encode 一样选择最小版本，但使用指定的掩码
+func encodeWithMask(t *testing.T, data []byte, level Level, mask int) *Code {
+	t.Helper()
+	for version := minVersion; version <= maxVersion; version++ {
+		if codewords, ok := encodeSegment(data, version, level); ok {
+			return newCode(version, level, codewords, mask)
+		}
+	}
+	t.Fatalf("数据超出容量: %d 字节", len(data))
+	return nil
+}
+
+// TestEncodeReferenceMatrix 固定数据、纠错等级与掩码，与参考实现生成的矩阵逐模块比较；
+// testdata 中每行一排模块，# 为深色
+func TestEncodeReferenceMatrix(t *testing.T) {
+	tests := []struct {
+		file    string
+		data    string
+		level   Level
+		mask    int
+		version int
+	}{
+		{file: "v3_medium_mask3.txt", data: "lili://devices/scan/0123456789abcdef", level: Medium, mask: 3, version: 3},
+		{file: "v7_high_mask6.txt", data: "LILI-DEVICE-LABEL-2024-0001|Apple iPhone 15 Pro|SN:F2LXK9ABCD12", level: High, mask: 6, version: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			content, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("读取参考矩阵失败: %v", err)
+			}
+			want := strings.Split(strings.TrimSpace(string(content)), "\n")
+
+			code := encodeWithMask(t, []byte(tt.data), tt.level, tt.mask)
+			if code.Version != tt.version || code.Size != len(want) {
+				t.Fatalf("版本与尺寸为 %d/%d，期望 %d/%d", code.Version, code.Size, tt.version, len(want))
+			}
+			diff := 0
+			for y, row := range want {
+				for x := 0; x < code.Size; x++ {
+					if code.Black(x, y) != (row[x] == '#') {
+						if diff < 5 {
+							t.Errorf("模块 (%d, %d) 与参考矩阵不同", x, y)
+						}
+						diff++
+					}
+				}
+			}
+			if diff > 0 {
+				t.Errorf("共 %d 个模块与参考矩阵不同", diff)
+			}
+		})
+	}
+}
+
+// TestFormatBits 两份格式信息都与标准中各纠错等级、掩码的格式信息一致
+func TestFormatBits(t *testing.T) {
+	want := [4][8]int{
+		Low:      {0x77C4, 0x72F3, 0x7DAA, 0x789D, 0x662F, 0x6318, 0x6C41, 0x6976},
+		Medium:   {0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0},
+		Quartile: {0x355F, 0x3068, 0x3F31, 0x3A06, 0x24B4, 0x2183, 0x2EDA, 0x2BED},
+		High:     {0x1689, 0x13BE, 0x1CE7, 0x19D0, 0x0762, 0x0255, 0x0D0C, 0x083B},
+	}
+	for level := Low; level <= High; level++ {
+		for mask := 0; mask < 8; mask++ {
+			code := encodeWithMask(t, nil, level, mask)
+			size := code.Size
+			first, second := 0, 0
+			for i := 0; i < 15; i++ {
+				var x1, y1, x2, y2 int
+				switch {
+				case i <= 5:
+					x1, y1 = 8, i
+				case i <= 7:
+					x1, y1 = 8, i+1
+				case i == 8:
+					x1, y1 = 7, 8
+				default:
+					x1, y1 = 14-i, 8
+				}
+				if i < 8 {
+					x2, y2 = size-1-i, 8
+				} else {
+					x2, y2 = 8, size-15+i
+				}
+				if code.Black(x1, y1) {
+					first |= 1 << i
+				}
+				if code.Black(x2, y2) {
+					second |= 1 << i
+				}
+			}
+			if first != want[level][mask] || second != want[level][mask] {
+				t.Errorf("等级 %d 掩码 %d 的格式信息为 %#04x/%#04x，期望 %#04x", level, mask, first, second, want[level][mask])
+			}
+			if !code.Black(8, size-8) {
+				t.Errorf("等级 %d 掩码 %d 缺少固定的深色模块", level, mask)
+			}
+		}
+	}
+}
+
+// TestVersionBits 版本7及以上右上与左下两份版本信息都与标准中的版本信息一致
+func TestVersionBits(t *testing.T) {
+	want := map[int]int{
+		7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3, 11: 0x0BBF6, 12: 0x0C762, 13: 0x0D847,
+		14: 0x0E60D, 15: 0x0F928, 16: 0x10B78, 17: 0x1145D, 18: 0x12A17, 19: 0x13532, 20: 0x149A6,
+		21: 0x15683, 22: 0x168C9, 23: 0x177EC, 24: 0x18EC4, 25: 0x191E1, 26: 0x1AFAB, 27: 0x1B08E,
+		28: 0x1CC1A, 29: 0x1D33F, 30: 0x1ED75, 31: 0x1F250, 32: 0x209D5, 33: 0x216F0, 34: 0x228BA,
+		35: 0x2379F, 36: 0x24B0B, 37: 0x2542E, 38: 0x26A64, 39: 0x27541, 40: 0x28C69,
+	}
+	for version := 7; version <= maxVersion; version++ {
+		codewords, ok := encodeSegment(nil, version, Low)
+		if !ok {
+			t.Fatalf("版本 %d 无法编码空数据", version)
+		}
+		code := newCode(version, Low, codewords, 0)
+		// 右上角为 6x3 区域，左下角为其转置
+		topRight, bottomLeft := 0, 0
+		for i := 0; i < 18; i++ {
+			a, b := code.Size-11+i%3, i/3
+			if code.Black(a, b) {
+				topRight |= 1 << i
+			}
+			if code.Black(b, a) {
+				bottomLeft |= 1 << i
+			}
+		}
+		if topRight != want[version] || bottomLeft != want[version] {
+			t.Errorf("版本 %d 的版本信息为 %#05x/%#05x，期望 %#05x", version, topRight, bottomLeft, want[version])
+		}
+	}
+}
+
+func TestEncodeLimits(t *testing.T) {
+	if _, err := Encode([]byte("x"), Level(4)); err != ErrInvalidLevel {
+		t.Errorf("无效纠错等级返回 %v，期望 ErrInvalidLevel", err)
+	}
+	// 版本40、纠错等级L的字节模式容量为2953字节
+	if code, err := Encode(make([]byte, 2953), Low); err != nil || code.Version != maxVersion {
+		t.Errorf("2953字节返回 %v，期望版本40", err)
+	}
+	if _, err := Encode(make([]byte, 2954), Low); err != ErrDataTooLong {
+		t.Errorf("2954字节返回 %v，期望 ErrDataTooLong", err)
+	}
+}
@@ -0,0 +1,86 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// QuietZone 标准要求的静区宽度（模块数）
+const QuietZone = 4
+
+// Image 生成黑白图片，scale 为每个模块的像素数，quiet 为四周静区的模块数
+func (c *Code) Image(scale, quiet int) *image.Paletted {
+	if scale < 1 {
+		scale = 1
+	}
+	if quiet < 0 {
+		quiet = 0
+	}
+	side := (c.Size + 2*quiet) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Black(x, y) {
+				continue
+			}
+			left, top := (x+quiet)*scale, (y+quiet)*scale
+			for dy := 0; dy < scale; dy++ {
+				row := img.Pix[(top+dy)*img.Stride:]
+				for dx := 0; dx < scale; dx++ {
+					row[left+dx] = 1
+				}
+			}
+		}
+	}
+	return img
+}
+
+// PNG 编码为PNG图片
+func (c *Code) PNG(scale, quiet int) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, c.Image(scale, quiet)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG 生成SVG图片，深色模块按行合并为一条路径；scale 为每个模块的像素数
+func (c *Code) SVG(scale, quiet int) []byte {
+	if scale < 1 {
+		scale = 1
+	}
+	if quiet < 0 {
+		quiet = 0
+	}
+	side := c.Size + 2*quiet
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		side*scale, side*scale, side, side)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, side, side)
+	c.Runs(func(x, y, width int) {
+		fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", x+quiet, y+quiet, width, width)
+	})
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
+
+// Runs 逐行遍历连续的深色模块，回调参数为起点坐标与长度，便于矢量输出
+func (c *Code) Runs(fn func(x, y, width int)) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; {
+			if !c.Black(x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < c.Size && c.Black(x, y) {
+				x++
+			}
+			fn(start, y, x-start)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package sheet
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidDate 无法识别的日期
+	ErrInvalidDate = errors.New("无法识别的日期格式")
+	// ErrInvalidAmount 无法识别的金额
+	ErrInvalidAmount = errors.New("无法识别的金额格式")
+)
+
+// chineseDatePattern 匹配 2023年5月1日、2023年05月01号 等写法，可带时间
+var chineseDatePattern = regexp.MustCompile(`^(\d{4})\s*年\s*(\d{1,2})\s*月\s*(\d{1,2})\s*[日号]?`)
+
+// numericDatePattern 匹配 2023-5-1、2023/05/01、2023.5.1，可带时间
+var numericDatePattern = regexp.MustCompile(`^(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})`)
+
+// compactDatePattern 匹配 20230501
+var compactDatePattern = regexp.MustCompile(`^(\d{4})(\d{2})(\d{2})$`)
+
+// amountPattern 去除货币符号与千分位后的金额数字
+var amountPattern = regexp.MustCompile(`^\d+(\.\d+)?$|^\.\d+$`)
+
+// fullWidthReplacer 全角数字与符号转半角
+var fullWidthReplacer = strings.NewReplacer(
+	"０", "0", "１", "1", "２", "2", "３", "3", "４", "4",
+	"５", "5", "６", "6", "７", "7", "８", "8", "９", "9",
+	"．", ".", "，", ",", "－", "-", "／", "/", "：", ":", "　", " ",
+)
+
+// ParseDate 解析常见的中文日期写法，只保留日期部分；纯数字按Excel日期序列号处理
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(fullWidthReplacer.Replace(value))
+	if value == "" {
+		return time.Time{}, ErrInvalidDate
+	}
+
+	for _, pattern := range []*regexp.Regexp{chineseDatePattern, numericDatePattern, compactDatePattern} {
+		if m := pattern.FindStringSubmatch(value); m != nil {
+			return buildDate(m[1], m[2], m[3])
+		}
+	}
+
+	// Excel中未设置日期格式的单元格会以序列号出现，限定在 1950-2100 之间避免误判金额
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial >= 18264 && serial <= 73051 {
+		t := time.Date(1899, 12, 30, 0, 0, 0, 0, time.Local).AddDate(0, 0, int(serial))
+		return t, nil
+	}
+	return time.Time{}, ErrInvalidDate
+}
+
+// buildDate 校验年月日并构造日期，拒绝 2月30日 这类溢出日期
+func buildDate(yearStr, monthStr, dayStr string) (time.Time, error) {
+	year, _ := strconv.Atoi(yearStr)
+	month, _ := strconv.Atoi(monthStr)
+	day, _ := strconv.Atoi(dayStr)
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, ErrInvalidDate
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+	if t.Day() != day {
+		return time.Time{}, ErrInvalidDate
+	}
+	return t, nil
+}
+
+// ParseAmount 解析金额，支持 ¥1,299.00、￥1299元、RMB 1299、1.2万 等写法
+func ParseAmount(value string) (float64, error) {
+	value = strings.TrimSpace(fullWidthReplacer.Replace(value))
+	if value == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = strings.TrimSuffix(strings.TrimPrefix(value, "("), ")")
+	}
+
+	upper := strings.ToUpper(value)
+	for _, prefix := range []string{"RMB", "CNY"} {
+		upper = strings.TrimPrefix(upper, prefix)
+		upper = strings.TrimSuffix(upper, prefix)
+	}
+	value = strings.NewReplacer("¥", "", "￥", "", "元", "", "人民币", "", ",", "", " ", "").Replace(upper)
+
+	multiplier := 1.0
+	if strings.HasSuffix(value, "万") {
+		multiplier = 10000
+		value = strings.TrimSuffix(value, "万")
+	}
+	if strings.HasPrefix(value, "-") {
+		negative = !negative
+		value = strings.TrimPrefix(value, "-")
+	}
+
+	if !amountPattern.MatchString(value) {
+		return 0, ErrInvalidAmount
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	amount *= multiplier
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
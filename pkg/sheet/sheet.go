@@ -0,0 +1,111 @@
+package sheet
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedFormat 不支持的表格格式
+	ErrUnsupportedFormat = errors.New("仅支持CSV或XLSX文件")
+	// ErrEmptySheet 表格没有数据
+	ErrEmptySheet = errors.New("表格没有数据")
+	// ErrTooManyRows 表格行数超过上限
+	ErrTooManyRows = errors.New("表格行数超过上限")
+)
+
+// MaxRows 单个表格读取的最大行数（含表头）
+const MaxRows = 5000
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// DetectFormat 按文件内容识别格式，内容无法判断时参考扩展名
+func DetectFormat(data []byte, filename string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		if ext != "" && ext != ".xlsx" {
+			return "", ErrUnsupportedFormat
+		}
+		return FormatXLSX, nil
+	}
+	switch ext {
+	case ".csv", ".txt", "":
+		return FormatCSV, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Read 读取CSV或XLSX（第一个工作表），返回去除首尾空白的单元格文本，并丢弃末尾空行
+func Read(data []byte, filename string) ([][]string, error) {
+	format, err := DetectFormat(data, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	if format == FormatXLSX {
+		rows, err = ReadXLSX(data)
+	} else {
+		rows, err = ReadCSV(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+	}
+	for len(rows) > 0 && IsBlankRow(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+	if len(rows) == 0 {
+		return nil, ErrEmptySheet
+	}
+	if len(rows) > MaxRows {
+		return nil, ErrTooManyRows
+	}
+	return rows, nil
+}
+
+// IsBlankRow 判断整行是否为空
+func IsBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ColumnName 列序号（从0开始）转为Excel列字母，如 0 -> A、27 -> AB
+func ColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// ColumnIndex Excel列字母转为列序号（从0开始），非法时返回-1
+func ColumnIndex(name string) int {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "" || len(name) > 3 {
+		return -1
+	}
+	index := 0
+	for _, r := range name {
+		if r < 'A' || r > 'Z' {
+			return -1
+		}
+		index = index*26 + int(r-'A'+1)
+	}
+	return index - 1
+}
@@ -0,0 +1,285 @@
+package sheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxXLSXPartSize 单个XML部件解压后的大小上限，防止解压炸弹
+const maxXLSXPartSize = 50 * 1024 * 1024
+
+// ErrInvalidXLSX 文件不是有效的XLSX工作簿
+var ErrInvalidXLSX = errors.New("XLSX文件格式错误")
+
+type xlsxWorkbook struct {
+	WorkbookPr struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxRichText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) text() string {
+	if len(t.R) == 0 {
+		return t.T
+	}
+	var sb strings.Builder
+	sb.WriteString(t.T)
+	for _, run := range t.R {
+		sb.WriteString(run.T)
+	}
+	return sb.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string       `xml:"r,attr"`
+			S      int          `xml:"s,attr"`
+			T      string       `xml:"t,attr"`
+			V      string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSX 读取工作簿的第一个工作表；日期格式的数值单元格转换为 YYYY-MM-DD（含时间时追加 HH:MM:SS）
+func ReadXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrInvalidXLSX
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	var workbook xlsxWorkbook
+	if err := decodeXLSXPart(files, "xl/workbook.xml", &workbook); err != nil || len(workbook.Sheets) == 0 {
+		return nil, ErrInvalidXLSX
+	}
+	sheetPath, err := resolveSheetPath(files, workbook.Sheets[0].RID)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeXLSXPart(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, ErrInvalidXLSX
+		}
+	}
+	dateStyles := map[int]bool{}
+	if _, ok := files["xl/styles.xml"]; ok {
+		var styles xlsxStyles
+		if err := decodeXLSXPart(files, "xl/styles.xml", &styles); err != nil {
+			return nil, ErrInvalidXLSX
+		}
+		dateStyles = resolveDateStyles(&styles)
+	}
+
+	var worksheet xlsxWorksheet
+	if err := decodeXLSXPart(files, sheetPath, &worksheet); err != nil {
+		return nil, ErrInvalidXLSX
+	}
+
+	rows := make([][]string, 0, len(worksheet.Rows))
+	for i, row := range worksheet.Rows {
+		rowIndex := i
+		if row.R > 0 {
+			rowIndex = row.R - 1
+		}
+		if rowIndex >= MaxRows {
+			return nil, ErrTooManyRows
+		}
+		// 补齐中间缺失的空行，保持行号与Excel一致
+		for len(rows) < rowIndex {
+			rows = append(rows, []string{})
+		}
+
+		values := make([]string, 0, len(row.Cells))
+		for j, cell := range row.Cells {
+			col := j
+			if cell.R != "" {
+				if idx := ColumnIndex(strings.TrimRight(cell.R, "0123456789")); idx >= 0 {
+					col = idx
+				}
+			}
+			for len(values) < col {
+				values = append(values, "")
+			}
+
+			var value string
+			switch cell.T {
+			case "s":
+				idx, err := strconv.Atoi(cell.V)
+				if err != nil || idx < 0 || idx >= len(shared.Items) {
+					return nil, ErrInvalidXLSX
+				}
+				value = shared.Items[idx].text()
+			case "inlineStr":
+				value = cell.Inline.text()
+			case "b":
+				value = "FALSE"
+				if cell.V == "1" {
+					value = "TRUE"
+				}
+			case "str", "e":
+				value = cell.V
+			default:
+				value = cell.V
+				if dateStyles[cell.S] && value != "" {
+					if serial, err := strconv.ParseFloat(value, 64); err == nil {
+						value = formatSerialDate(serial, workbook.WorkbookPr.Date1904)
+					}
+				}
+			}
+			if col < len(values) {
+				values[col] = value
+			} else {
+				values = append(values, value)
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// decodeXLSXPart 解码压缩包内的XML部件
+func decodeXLSXPart(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return ErrInvalidXLSX
+	}
+	if f.UncompressedSize64 > maxXLSXPartSize {
+		return ErrInvalidXLSX
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(io.LimitReader(rc, maxXLSXPartSize)).Decode(v)
+}
+
+// resolveSheetPath 通过工作簿关系文件找到工作表路径，关系缺失时回退到默认的 sheet1.xml
+func resolveSheetPath(files map[string]*zip.File, rid string) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+	var rels xlsxRelationships
+	if err := decodeXLSXPart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		if _, ok := files[fallback]; ok {
+			return fallback, nil
+		}
+		return "", ErrInvalidXLSX
+	}
+	for _, rel := range rels.Items {
+		if rel.ID != rid {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	if _, ok := files[fallback]; ok {
+		return fallback, nil
+	}
+	return "", ErrInvalidXLSX
+}
+
+// resolveDateStyles 找出数值格式为日期的样式序号
+func resolveDateStyles(styles *xlsxStyles) map[int]bool {
+	customDates := map[int]bool{}
+	for _, numFmt := range styles.NumFmts {
+		customDates[numFmt.ID] = isDateFormatCode(numFmt.Code)
+	}
+	result := make(map[int]bool, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		if isBuiltinDateFormat(id) || customDates[id] {
+			result[i] = true
+		}
+	}
+	return result
+}
+
+// isBuiltinDateFormat 内置日期格式，含中文区域的 27-36、50-58
+func isBuiltinDateFormat(id int) bool {
+	return (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58)
+}
+
+// isDateFormatCode 自定义格式去掉引号文本与方括号段后包含年或日占位符即视为日期
+func isDateFormatCode(code string) bool {
+	var sb strings.Builder
+	inQuote, inBracket := false, false
+	for _, r := range code {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '[':
+			inBracket = true
+		case r == ']':
+			inBracket = false
+		case inBracket:
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	lower := strings.ToLower(sb.String())
+	return strings.ContainsAny(lower, "yd")
+}
+
+// formatSerialDate Excel日期序列号转文本，1900日期系统以1899-12-30为起点
+func formatSerialDate(serial float64, date1904 bool) string {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	t := epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+	if seconds == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "cn-test-1"
+	testBucket    = "lili"
+)
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+// fakeS3 内存中的S3兼容服务：按 path-style 地址读写对象，签名不正确时返回403，
+// failStatus 非0时所有签名正确的请求都返回该状态码
+type fakeS3 struct {
+	mu         sync.Mutex
+	objects    map[string]fakeObject
+	headers    []http.Header
+	failStatus int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	fake := &fakeS3{objects: make(map[string]fakeObject)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeS3) object(key string) (fakeObject, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, ok := f.objects[key]
+	return object, ok
+}
+
+func (f *fakeS3) requestHeaders() []http.Header {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.headers
+}
+
+func (f *fakeS3) setFailStatus(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failStatus = status
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.headers = append(f.headers, r.Header.Clone())
+
+	if err := verifySigV4(r); err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>%s</Message></Error>", err)
+		return
+	}
+	if f.failStatus != 0 {
+		w.WriteHeader(f.failStatus)
+		io.WriteString(w, "<Error><Code>InternalError</Code></Error>")
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/"+testBucket+"/")
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		if int64(len(data)) != r.ContentLength {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = fakeObject{data: data, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		object, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write(object.data)
+	case http.MethodDelete:
+		if _, ok := f.objects[key]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verifySigV4 按 AWS SigV4 规则从收到的请求重新计算签名并与 Authorization 头比较
+func verifySigV4(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") {
+		return errors.New("缺少 AWS4-HMAC-SHA256 签名")
+	}
+	fields := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return fmt.Errorf("x-amz-date 格式错误: %q", amzDate)
+	}
+	if d := time.Since(signedAt); d > 5*time.Minute || d < -5*time.Minute {
+		return fmt.Errorf("x-amz-date 偏差过大: %s", amzDate)
+	}
+	scope := amzDate[:8] + "/" + testRegion + "/s3/aws4_request"
+	if fields["Credential"] != testAccessKey+"/"+scope {
+		return fmt.Errorf("Credential 错误: %s", fields["Credential"])
+	}
+
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signedHeaders) {
+		return errors.New("SignedHeaders 未排序")
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	for _, required := range []string{"host", "x-amz-content-sha256", "x-amz-date"} {
+		if !strings.Contains(";"+fields["SignedHeaders"]+";", ";"+required+";") {
+			return fmt.Errorf("%s 未参与签名", required)
+		}
+	}
+	if r.Header.Get("Content-Type") != "" && !strings.Contains(fields["SignedHeaders"], "content-type") {
+		return errors.New("content-type 未参与签名")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		canonicalURI(r.URL.Path),
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	mac := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	key := mac([]byte("AWS4"+testSecretKey), amzDate[:8])
+	key = mac(key, testRegion)
+	key = mac(key, "s3")
+	key = mac(key, "aws4_request")
+	if want := hex.EncodeToString(mac(key, stringToSign)); fields["Signature"] != want {
+		return fmt.Errorf("签名不一致: %s", fields["Signature"])
+	}
+	return nil
+}
+
+// canonicalURI 服务端按解码后的路径重新编码：除 RFC3986 非保留字符与分隔符外全部编码为大写 %XX
+func canonicalURI(path string) string {
+	var b strings.Builder
+	for _, c := range []byte(path) {
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func newTestS3Store(t *testing.T, endpoint, secretKey string) *S3Store {
+	t.Helper()
+	store, err := NewS3Store(S3Config{
+		Endpoint:  endpoint,
+		Region:    testRegion,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: secretKey,
+	})
+	if err != nil {
+		t.Fatalf("创建S3存储失败: %v", err)
+	}
+	return store
+}
+
+func TestS3StorePutGetDelete(t *testing.T) {
+	fake, server := newFakeS3(t)
+	store := newTestS3Store(t, server.URL, testSecretKey)
+
+	// 含空格、中文与需要转义的字符，校验路径按 SigV4 规则编码
+	key := "images/ab/设备 照片(1)+原图.jpg"
+	data := []byte("fake image content")
+	if err := store.Put(key, strings.NewReader(string(data)), -1, "image/jpeg"); err != nil {
+		t.Fatalf("Put 返回错误: %v", err)
+	}
+	if object, ok := fake.object(key); !ok || string(object.data) != string(data) || object.contentType != "image/jpeg" {
+		t.Fatalf("服务端保存的对象为 %q/%q", object.data, object.contentType)
+	}
+
+	reader, info, err := store.Get(key)
+	if err != nil {
+		t.Fatalf("Get 返回错误: %v", err)
+	}
+	got, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(got) != string(data) {
+		t.Errorf("Get 读到 %q，期望 %q", got, data)
+	}
+	if info.Key != key || info.Size != int64(len(data)) || info.ContentType != "image/jpeg" || info.ModTime.IsZero() {
+		t.Errorf("对象信息为 %+v", info)
+	}
+
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("Delete 返回错误: %v", err)
+	}
+	if _, ok := fake.object(key); ok {
+		t.Fatal("Delete 后对象仍存在")
+	}
+	if _, _, err := store.Get(key); err != ErrNotFound {
+		t.Errorf("删除后 Get 返回 %v，期望 ErrNotFound", err)
+	}
+	// 对象不存在时删除不返回错误
+	if err := store.Delete(key); err != nil {
+		t.Errorf("删除不存在的对象返回 %v", err)
+	}
+}
+
+func TestS3StoreSignedHeaders(t *testing.T) {
+	fake, server := newFakeS3(t)
+	store := newTestS3Store(t, server.URL, testSecretKey)
+
+	if err := store.Put("a/b.txt", strings.NewReader("x"), 1, "text/plain"); err != nil {
+		t.Fatalf("Put 返回错误: %v", err)
+	}
+	headers := fake.requestHeaders()
+	if len(headers) != 1 {
+		t.Fatalf("服务端收到 %d 个请求，期望 1", len(headers))
+	}
+	header := headers[0]
+	if got := header.Get("X-Amz-Content-Sha256"); got != s3UnsignedPayload {
+		t.Errorf("x-amz-content-sha256 为 %q，期望 %q", got, s3UnsignedPayload)
+	}
+	if _, err := time.Parse(s3TimeFormat, header.Get("X-Amz-Date")); err != nil {
+		t.Errorf("x-amz-date 为 %q", header.Get("X-Amz-Date"))
+	}
+	auth := header.Get("Authorization")
+	for _, want := range []string{
+		"AWS4-HMAC-SHA256 Credential=" + testAccessKey + "/",
+		"/" + testRegion + "/s3/aws4_request",
+		"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date",
+		"Signature=",
+	} {
+		if !strings.Contains(auth, want) {
+			t.Errorf("Authorization 为 %q，缺少 %q", auth, want)
+		}
+	}
+}
+
+func TestS3StoreErrors(t *testing.T) {
+	fake, server := newFakeS3(t)
+
+	// 密钥错误时服务端拒绝签名
+	wrongKey := newTestS3Store(t, server.URL, "wrong-secret")
+	err := wrongKey.Put("a.txt", strings.NewReader("x"), 1, "text/plain")
+	if err == nil || !strings.Contains(err.Error(), "status=403") || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("签名错误时 Put 返回 %v，期望包含状态码与响应内容", err)
+	}
+
+	store := newTestS3Store(t, server.URL, testSecretKey)
+	if err := store.Put("a.txt", strings.NewReader("x"), 1, "text/plain"); err != nil {
+		t.Fatalf("Put 返回错误: %v", err)
+	}
+	fake.setFailStatus(http.StatusInternalServerError)
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "Put", call: func() error { return store.Put("a.txt", strings.NewReader("x"), 1, "") }},
+		{name: "Get", call: func() error { _, _, err := store.Get("a.txt"); return err }},
+		{name: "Delete", call: func() error { return store.Delete("a.txt") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if err == nil || err == ErrNotFound || !strings.Contains(err.Error(), "status=500") || !strings.Contains(err.Error(), "InternalError") {
+				t.Errorf("服务端返回500时为 %v，期望包含状态码与响应内容", err)
+			}
+		})
+	}
+
+	if _, _, err := store.Get("../a.txt"); err != ErrInvalidKey {
+		t.Errorf("非法对象键返回 %v，期望 ErrInvalidKey", err)
+	}
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	beego "github.com/beego/beego/v2/server/web"
+)
+
+// IsAdmin 判断用户是否为管理员，管理员用户ID通过配置项 admin_user_ids 以逗号分隔配置
+func IsAdmin(userID int) bool {
+	if userID <= 0 {
+		return false
+	}
+	ids, _ := beego.AppConfig.String("admin_user_ids")
+	for _, id := range strings.Split(ids, ",") {
+		if adminID, err := strconv.Atoi(strings.TrimSpace(id)); err == nil && adminID == userID {
+			return true
+		}
+	}
+	return false
+}
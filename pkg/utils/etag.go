@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+// FormatETag 把资源版本号格式化为 ETag
+func FormatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// SetETag 在响应头中写入资源版本号对应的 ETag
+func SetETag(ctx *context.Context, version int) {
+	ctx.Output.Header("ETag", FormatETag(version))
+}
+
+// ResolveVersion 读取客户端提交的版本号：优先使用 If-Match 请求头（兼容弱校验前缀 W/），
+// 其次使用请求体中的 version；fromHeader 表示版本号来自请求头，两者都没有时返回428。
+// If-Match: * 表示不限定版本，返回的 version 为空，由服务层按读取到的当前版本写入
+func ResolveVersion(ctx *context.Context, bodyVersion *int) (version *int, fromHeader bool, err error) {
+	if ifMatch := strings.TrimSpace(ctx.Input.Header("If-Match")); ifMatch != "" {
+		if ifMatch == "*" {
+			return nil, true, nil
+		}
+		tag := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+		value, convErr := strconv.Atoi(tag)
+		if convErr != nil || value < 0 {
+			return nil, true, NewBusinessError(ERROR_PARAM, "If-Match 格式错误")
+		}
+		return &value, true, nil
+	}
+	if bodyVersion != nil {
+		return bodyVersion, false, nil
+	}
+	return nil, false, NewBusinessError(ERROR_PRECONDITION_REQUIRED, "请通过 If-Match 请求头或 version 字段提供版本号")
+}
+
+// WriteVersionedError 输出修改接口的业务异常；版本号来自 If-Match 时，版本冲突按412返回
+func WriteVersionedError(ctx *context.Context, err *BusinessError, fromHeader bool) {
+	code := err.Code
+	if code == ERROR_CONFLICT && fromHeader {
+		code = ERROR_PRECONDITION_FAILED
+	}
+	if err.Details != nil {
+		WriteErrorWithDetails(ctx, code, err.Message, err.Details)
+	} else {
+		WriteError(ctx, code, err.Message)
+	}
+}
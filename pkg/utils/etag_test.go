@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+func newTestContext(ifMatch string) (*context.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest("PUT", "/api/v1/devices/1", nil)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	recorder := httptest.NewRecorder()
+	ctx := context.NewContext()
+	ctx.Reset(recorder, req)
+	return ctx, recorder
+}
+
+func TestResolveVersion(t *testing.T) {
+	bodyVersion := 7
+	tests := []struct {
+		name       string
+		ifMatch    string
+		body       *int
+		want       *int
+		fromHeader bool
+		wantCode   int
+	}{
+		{name: "If-Match", ifMatch: `"3"`, body: &bodyVersion, want: intPtr(3), fromHeader: true},
+		{name: "弱校验前缀", ifMatch: `W/"4"`, want: intPtr(4), fromHeader: true},
+		{name: "If-Match 为 * 时不限定版本", ifMatch: "*", body: &bodyVersion, fromHeader: true},
+		{name: "请求体版本号", body: &bodyVersion, want: intPtr(7)},
+		{name: "缺少版本号", wantCode: ERROR_PRECONDITION_REQUIRED},
+		{name: "If-Match 格式错误", ifMatch: `"abc"`, wantCode: ERROR_PARAM},
+		{name: "If-Match 为负数", ifMatch: `"-1"`, wantCode: ERROR_PARAM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := newTestContext(tt.ifMatch)
+			version, fromHeader, err := ResolveVersion(ctx, tt.body)
+			if tt.wantCode != 0 {
+				businessErr, ok := err.(*BusinessError)
+				if !ok || businessErr.Code != tt.wantCode {
+					t.Fatalf("返回错误 %v，期望错误码 %d", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("返回错误: %v", err)
+			}
+			if (version == nil) != (tt.want == nil) || (version != nil && *version != *tt.want) || fromHeader != tt.fromHeader {
+				t.Errorf("返回 %v/%v，期望 %v/%v", formatVersion(version), fromHeader, formatVersion(tt.want), tt.fromHeader)
+			}
+		})
+	}
+}
+
+// TestWriteVersionedError 第二个写入方提交的版本号已过期：通过请求体提交时返回409，通过 If-Match 提交时返回412
+func TestWriteVersionedError(t *testing.T) {
+	current := map[string]interface{}{"id": 1, "version": 4}
+	tests := []struct {
+		name     string
+		ifMatch  string
+		body     *int
+		err      *BusinessError
+		wantCode int
+	}{
+		{name: "请求体版本号过期", body: intPtr(3), err: NewBusinessErrorWithDetails(ERROR_CONFLICT, "设备已被修改，请刷新后重试", current), wantCode: ERROR_CONFLICT},
+		{name: "If-Match 过期", ifMatch: FormatETag(3), err: NewBusinessErrorWithDetails(ERROR_CONFLICT, "设备已被修改，请刷新后重试", current), wantCode: ERROR_PRECONDITION_FAILED},
+		{name: "其他错误不受 If-Match 影响", ifMatch: FormatETag(3), err: NewBusinessError(ERROR_NOT_FOUND, "设备不存在"), wantCode: ERROR_NOT_FOUND},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, recorder := newTestContext(tt.ifMatch)
+			_, fromHeader, err := ResolveVersion(ctx, tt.body)
+			if err != nil {
+				t.Fatalf("ResolveVersion 返回错误: %v", err)
+			}
+			WriteVersionedError(ctx, tt.err, fromHeader)
+
+			var resp Response
+			if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("响应不是JSON: %v", err)
+			}
+			if resp.Code != tt.wantCode {
+				t.Errorf("错误码为 %d，期望 %d", resp.Code, tt.wantCode)
+			}
+			if tt.err.Details != nil && resp.Details == nil {
+				t.Error("冲突响应缺少当前版本的数据")
+			}
+		})
+	}
+}
+
+func TestFormatETag(t *testing.T) {
+	if tag := FormatETag(12); tag != `"12"` {
+		t.Errorf("FormatETag(12) = %s，期望 \"12\"", tag)
+	}
+	ctx, recorder := newTestContext("")
+	SetETag(ctx, 5)
+	if tag := recorder.Header().Get("ETag"); tag != `"5"` {
+		t.Errorf("ETag 响应头为 %s，期望 \"5\"", tag)
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func formatVersion(v *int) string {
+	if v == nil {
+		return "不限定"
+	}
+	return FormatETag(*v)
+}
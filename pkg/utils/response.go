@@ -33,6 +33,10 @@ const (
 	ERROR_SERVER    = 500
 	ERROR_BUSINESS  = 600 // 业务逻辑错误
 	ERROR_WECHAT    = 1001
+
+	ERROR_CONFLICT              = 409 // 版本冲突：请求体中的 version 与当前版本不一致
+	ERROR_PRECONDITION_FAILED   = 412 // 版本冲突：If-Match 与当前 ETag 不一致
+	ERROR_PRECONDITION_REQUIRED = 428 // 修改时缺少版本号
 )
 
 // 业务错误码常量
@@ -67,6 +71,10 @@ var ErrorMessages = map[int]string{
 	ERROR_DECRYPT_FAILED:      "数据解密失败",
 	ERROR_DATABASE:            "数据库操作失败",
 	ERROR_EXTERNAL_API:        "外部接口调用失败",
+
+	ERROR_CONFLICT:              "数据已被修改",
+	ERROR_PRECONDITION_FAILED:   "数据已被修改",
+	ERROR_PRECONDITION_REQUIRED: "缺少版本号",
 }
 
 // 成功响应